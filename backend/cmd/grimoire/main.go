@@ -6,8 +6,8 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	_ "time/tzdata" // users' time zones must resolve without system zoneinfo
 
-	"github.com/jehufrayle/grimoire/internal/database"
 	"github.com/jehufrayle/grimoire/internal/server"
 	"github.com/joho/godotenv"
 )
@@ -18,11 +18,6 @@ func main() {
 		log.Println("No .env file found, using environment variables directly")
 	}
 
-	// Connect to the database
-	database.Connect()
-
-	defer database.Close()
-
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
@@ -0,0 +1,55 @@
+// Command rotatekey re-wraps every user data key with a new master key.
+// Notes themselves are not re-encrypted.
+//
+// Stop the API before rotating and start it with the new key once this
+// finishes. A server left running keeps the old key, and the data keys it
+// creates for users opting in meanwhile cannot be opened with the new one.
+// Should that happen, run rotatekey again with the old key as the current
+// one: keys already wrapped by the new key are skipped.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"github.com/jehufrayle/grimoire/internal/database"
+	"github.com/jehufrayle/grimoire/internal/encryption"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	newKeyFile := flag.String("new-key-file", "", "file holding the new master key (32 raw bytes or base64)")
+	flag.Parse()
+
+	if *newKeyFile == "" {
+		log.Fatal("❌ -new-key-file is required")
+	}
+
+	if err := godotenv.Load("../.env"); err != nil {
+		log.Println("No .env file found, using environment variables directly")
+	}
+
+	oldMaster, err := encryption.LoadMasterKey()
+	if err != nil {
+		log.Fatalf("❌ Failed to load current master key: %v", err)
+	}
+	if oldMaster == nil {
+		log.Fatal("❌ No current master key configured (GRIMOIRE_MASTER_KEY_FILE or GRIMOIRE_MASTER_KEY)")
+	}
+	newMaster, err := encryption.ReadKeyFile(*newKeyFile)
+	if err != nil {
+		log.Fatalf("❌ Failed to load new master key: %v", err)
+	}
+
+	database.Connect()
+	defer database.Close()
+
+	keyring := encryption.NewKeyring(oldMaster, encryption.NewPgKeyStore(database.DB))
+	count, err := keyring.Rotate(context.Background(), newMaster)
+	if err != nil {
+		log.Fatalf("❌ Rotation failed: %v", err)
+	}
+
+	log.Printf("🔑 Re-wrapped %d data keys with master key %s", count, encryption.KeyID(newMaster))
+}
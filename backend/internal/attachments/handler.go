@@ -0,0 +1,152 @@
+package attachments
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/notes"
+	"github.com/jehufrayle/grimoire/middleware"
+	"github.com/jehufrayle/grimoire/utils"
+)
+
+type Handler struct {
+	repo  AttachmentRepository
+	notes notes.NoteRepository
+}
+
+func NewHandler(repo AttachmentRepository, noteRepo notes.NoteRepository) *Handler {
+	return &Handler{repo: repo, notes: noteRepo}
+}
+
+// readableNote loads the note and checks that the user can read it. It writes
+// the error response itself and returns nil when the request should stop.
+func (h *Handler) readableNote(ctx context.Context, w http.ResponseWriter, noteID string, userID uuid.UUID) *notes.Note {
+	note, err := h.notes.GetByID(ctx, noteID)
+	if err != nil || note == nil {
+		http.Error(w, "Note not found", http.StatusNotFound)
+		return nil
+	}
+	allowed, err := notes.CanRead(ctx, h.notes, note, userID)
+	if err != nil {
+		http.Error(w, "Failed to check note access", http.StatusInternalServerError)
+		return nil
+	}
+	if !allowed {
+		http.Error(w, "Unauthorized access to this note", http.StatusForbidden)
+		return nil
+	}
+	return note
+}
+
+func currentUserID(r *http.Request) (uuid.UUID, bool) {
+	userIDstr, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(userIDstr)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// GetNoteAttachments lists the attachments of a note the caller can read.
+func (h *Handler) GetNoteAttachments(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	note := h.readableNote(r.Context(), w, r.PathValue("id"), userID)
+	if note == nil {
+		return
+	}
+
+	list, err := h.repo.ListByNote(r.Context(), note.ID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve attachments", http.StatusInternalServerError)
+		return
+	}
+	if list == nil {
+		list = []Attachment{}
+	}
+	utils.JSONResponse(w, list, http.StatusOK)
+}
+
+// GetAttachment downloads an attachment of a note the caller can read.
+func (h *Handler) GetAttachment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	attachment := h.attachment(w, r)
+	if attachment == nil {
+		return
+	}
+	if h.readableNote(r.Context(), w, attachment.NoteID.String(), userID) == nil {
+		return
+	}
+
+	// Always a download: attachments come from elsewhere, such as emails,
+	// and must not be rendered as pages of the API's origin
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})
+	if disposition == "" {
+		disposition = "attachment"
+	}
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("Content-Length", strconv.Itoa(len(attachment.Data)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(attachment.Data)
+}
+
+// DeleteAttachment removes an attachment. Only the note's owner can.
+func (h *Handler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	attachment := h.attachment(w, r)
+	if attachment == nil {
+		return
+	}
+	note, err := h.notes.GetByID(r.Context(), attachment.NoteID.String())
+	if err != nil || note == nil {
+		http.Error(w, "Note not found", http.StatusNotFound)
+		return
+	}
+	if note.UserID != userID {
+		http.Error(w, "Only the note owner can delete attachments", http.StatusForbidden)
+		return
+	}
+
+	if err := h.repo.Delete(r.Context(), attachment.ID); err != nil {
+		http.Error(w, "Failed to delete attachment", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// attachment loads the attachment named in the path. It writes the error
+// response itself and returns nil when the request should stop.
+func (h *Handler) attachment(w http.ResponseWriter, r *http.Request) *Attachment {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid attachment ID format", http.StatusBadRequest)
+		return nil
+	}
+	attachment, err := h.repo.GetByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Attachment not found", http.StatusNotFound)
+		return nil
+	}
+	return attachment
+}
@@ -0,0 +1,158 @@
+package attachments
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/journal"
+)
+
+// InMemoryAttachmentRepository keeps attachments in memory. Opened with
+// OpenInMemoryAttachmentRepository it also persists them to disk.
+type InMemoryAttachmentRepository struct {
+	mu          sync.RWMutex
+	attachments map[uuid.UUID]*Attachment
+	journal     *journal.Store[[]attachmentRecord, attachmentEntry] // nil when not persisted
+}
+
+func NewInMemoryAttachmentRepository() *InMemoryAttachmentRepository {
+	return &InMemoryAttachmentRepository{
+		attachments: make(map[uuid.UUID]*Attachment),
+	}
+}
+
+func (r *InMemoryAttachmentRepository) GetByID(ctx context.Context, id uuid.UUID) (*Attachment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	a, ok := r.attachments[id]
+	if !ok {
+		return nil, errors.New("attachment not found")
+	}
+	result := *a
+	result.Data = slices.Clone(a.Data)
+	return &result, nil
+}
+
+func (r *InMemoryAttachmentRepository) ListByNote(ctx context.Context, noteID uuid.UUID) ([]Attachment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []Attachment
+	for _, a := range r.attachments {
+		if a.NoteID == noteID {
+			listed := *a
+			listed.Data = nil
+			result = append(result, listed)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+	return result, nil
+}
+
+func (r *InMemoryAttachmentRepository) Create(ctx context.Context, attachment *Attachment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attachment.ID = uuid.New()
+	attachment.Size = len(attachment.Data)
+	attachment.CreatedAt = time.Now()
+	stored := *attachment
+	stored.Data = slices.Clone(attachment.Data)
+	r.attachments[attachment.ID] = &stored
+	if err := r.journal.Log(attachmentEntry{Attachment: recordOf(&stored)}); err != nil {
+		delete(r.attachments, attachment.ID)
+		return err
+	}
+	return nil
+}
+
+func (r *InMemoryAttachmentRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.attachments[id]
+	if !ok {
+		return errors.New("attachment not found")
+	}
+	delete(r.attachments, id)
+	if err := r.journal.Log(attachmentEntry{Deleted: &id}); err != nil {
+		r.attachments[id] = existing
+		return err
+	}
+	return nil
+}
+
+// attachmentRecord is an attachment as persisted, with the data Attachment
+// keeps out of JSON.
+type attachmentRecord struct {
+	Attachment
+	Data []byte `json:"data"`
+}
+
+func recordOf(a *Attachment) *attachmentRecord {
+	return &attachmentRecord{Attachment: *a, Data: a.Data}
+}
+
+func (rec *attachmentRecord) attachment() *Attachment {
+	a := rec.Attachment
+	a.Data = rec.Data
+	return &a
+}
+
+// attachmentEntry is one change in the journal's log.
+type attachmentEntry struct {
+	Attachment *attachmentRecord `json:"attachment,omitempty"`
+	Deleted    *uuid.UUID        `json:"deleted,omitempty"`
+}
+
+// OpenInMemoryAttachmentRepository returns an InMemoryAttachmentRepository
+// persisted in dir. It starts from what dir holds and logs every write there
+// before returning. Snapshot keeps the log short and Close takes a last
+// snapshot.
+func OpenInMemoryAttachmentRepository(dir string) (*InMemoryAttachmentRepository, error) {
+	r := NewInMemoryAttachmentRepository()
+	j, err := journal.OpenStore(dir, &r.mu, r.records,
+		func(records []attachmentRecord) {
+			for _, rec := range records {
+				r.attachments[rec.ID] = rec.attachment()
+			}
+		},
+		func(entry attachmentEntry) {
+			if entry.Attachment != nil {
+				r.attachments[entry.Attachment.ID] = entry.Attachment.attachment()
+			} else if entry.Deleted != nil {
+				delete(r.attachments, *entry.Deleted)
+			}
+		})
+	if err != nil {
+		return nil, err
+	}
+	r.journal = j
+	return r, nil
+}
+
+// Callers must hold at least a read lock.
+func (r *InMemoryAttachmentRepository) records() []attachmentRecord {
+	records := make([]attachmentRecord, 0, len(r.attachments))
+	for _, a := range r.attachments {
+		records = append(records, *recordOf(a))
+	}
+	return records
+}
+
+// Snapshot writes every attachment to disk and empties the log. It is a
+// no-op when the repository is not persisted.
+func (r *InMemoryAttachmentRepository) Snapshot() error {
+	return r.journal.Snapshot()
+}
+
+// Close takes a last snapshot and closes the journal.
+func (r *InMemoryAttachmentRepository) Close() error {
+	return r.journal.Close()
+}
@@ -0,0 +1,97 @@
+package attachments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgAttachmentRepository implements the AttachmentRepository interface for
+// PostgreSQL. File contents are stored in the database with the rest.
+type PgAttachmentRepository struct {
+	DB *pgxpool.Pool
+}
+
+// NewPgAttachmentRepository creates a new instance of PgAttachmentRepository.
+func NewPgAttachmentRepository(db *pgxpool.Pool) *PgAttachmentRepository {
+	return &PgAttachmentRepository{DB: db}
+}
+
+// GetByID retrieves an attachment with its data.
+func (r *PgAttachmentRepository) GetByID(ctx context.Context, id uuid.UUID) (*Attachment, error) {
+	query := `
+        SELECT id, note_id, user_id, filename, content_type, size, data, created_at
+        FROM attachments WHERE id = $1`
+	var a Attachment
+	err := r.DB.QueryRow(ctx, query, id).
+		Scan(&a.ID, &a.NoteID, &a.UserID, &a.Filename, &a.ContentType, &a.Size, &a.Data, &a.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("attachment not found")
+		}
+		return nil, fmt.Errorf("failed to scan attachment: %w", err)
+	}
+	return &a, nil
+}
+
+// ListByNote retrieves the attachments of a note without their data.
+func (r *PgAttachmentRepository) ListByNote(ctx context.Context, noteID uuid.UUID) ([]Attachment, error) {
+	query := `
+        SELECT id, note_id, user_id, filename, content_type, size, created_at
+        FROM attachments WHERE note_id = $1 ORDER BY created_at`
+	rows, err := r.DB.Query(ctx, query, noteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attachments: %w", err)
+	}
+	defer rows.Close()
+
+	var result []Attachment
+	for rows.Next() {
+		var a Attachment
+		if err := rows.Scan(&a.ID, &a.NoteID, &a.UserID, &a.Filename, &a.ContentType, &a.Size, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan attachment row: %w", err)
+		}
+		result = append(result, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return result, nil
+}
+
+// Create inserts an attachment.
+func (r *PgAttachmentRepository) Create(ctx context.Context, attachment *Attachment) error {
+	attachment.ID = uuid.New()
+	attachment.Size = len(attachment.Data)
+	query := `
+        INSERT INTO attachments (id, note_id, user_id, filename, content_type, size, data)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING created_at`
+	err := r.DB.QueryRow(ctx, query, attachment.ID, attachment.NoteID, attachment.UserID, attachment.Filename,
+		attachment.ContentType, attachment.Size, attachment.Data).Scan(&attachment.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign key violation
+			return fmt.Errorf("note or user not found: %w", err)
+		}
+		return fmt.Errorf("failed to insert attachment: %w", err)
+	}
+	return nil
+}
+
+// Delete removes an attachment.
+func (r *PgAttachmentRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.DB.Exec(ctx, `DELETE FROM attachments WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("attachment not found")
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package attachments
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Interface
+type AttachmentRepository interface {
+	// GetByID returns the attachment with its data.
+	GetByID(ctx context.Context, id uuid.UUID) (*Attachment, error)
+	// ListByNote returns the note's attachments, oldest first, without data.
+	ListByNote(ctx context.Context, noteID uuid.UUID) ([]Attachment, error)
+	Create(ctx context.Context, attachment *Attachment) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+var (
+	_ AttachmentRepository = (*PgAttachmentRepository)(nil)
+	_ AttachmentRepository = (*InMemoryAttachmentRepository)(nil)
+	_ AttachmentRepository = (*SQLiteAttachmentRepository)(nil)
+)
@@ -0,0 +1,69 @@
+package attachments
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/database/sqlitetest"
+	"github.com/jehufrayle/grimoire/internal/journal/journaltest"
+	"github.com/jehufrayle/grimoire/internal/notes"
+)
+
+// checkRoundTrip stores an attachment and a deleted one in repo and returns
+// the one that is kept.
+func checkRoundTrip(t *testing.T, repo AttachmentRepository, noteID, userID uuid.UUID) Attachment {
+	t.Helper()
+	ctx := context.Background()
+	kept := Attachment{NoteID: noteID, UserID: userID, Filename: "map.png", ContentType: "image/png", Data: []byte("png")}
+	if err := repo.Create(ctx, &kept); err != nil || kept.Size != 3 {
+		t.Fatalf("kept = %+v, %v", kept, err)
+	}
+	gone := Attachment{NoteID: noteID, UserID: userID, Filename: "empty.txt", ContentType: "text/plain"}
+	if err := repo.Create(ctx, &gone); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(ctx, gone.ID); err != nil {
+		t.Fatal(err)
+	}
+	return kept
+}
+
+// checkKept checks that repo holds kept, with its data, and nothing else.
+func checkKept(t *testing.T, repo AttachmentRepository, kept Attachment) {
+	t.Helper()
+	ctx := context.Background()
+	got, err := repo.GetByID(ctx, kept.ID)
+	if err != nil || string(got.Data) != "png" || got.Filename != kept.Filename || !got.CreatedAt.Equal(kept.CreatedAt) {
+		t.Fatalf("got = %+v, %v", got, err)
+	}
+	list, err := repo.ListByNote(ctx, kept.NoteID)
+	if err != nil || len(list) != 1 || list[0].ID != kept.ID || list[0].Data != nil {
+		t.Fatalf("list = %+v, %v", list, err)
+	}
+}
+
+func TestPersistedMemoryRepositoryReopens(t *testing.T) {
+	var kept Attachment
+	reopened := journaltest.Reopen(t, OpenInMemoryAttachmentRepository, func(repo *InMemoryAttachmentRepository) {
+		kept = checkRoundTrip(t, repo, uuid.New(), uuid.New())
+	})
+	checkKept(t, reopened, kept)
+}
+
+func TestSQLiteRepository(t *testing.T) {
+	ctx := context.Background()
+	db := sqlitetest.Open(t)
+	userID := sqlitetest.NewUser(t, db)
+	note := notes.Note{UserID: userID, Title: "Trip"}
+	if err := notes.NewSQLiteNoteRepository(db).Create(ctx, &note); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewSQLiteAttachmentRepository(db)
+	kept := checkRoundTrip(t, repo, note.ID, userID)
+	checkKept(t, repo, kept)
+	if err := repo.Create(ctx, &Attachment{NoteID: uuid.New(), UserID: userID, Filename: "x"}); err == nil {
+		t.Fatal("attachment on a missing note was stored")
+	}
+}
@@ -0,0 +1,103 @@
+package attachments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/database"
+)
+
+// SQLiteAttachmentRepository implements the AttachmentRepository interface
+// for the embedded SQLite backend. File contents are stored in the database
+// with the rest.
+type SQLiteAttachmentRepository struct {
+	DB *sql.DB
+}
+
+// NewSQLiteAttachmentRepository creates a new instance of SQLiteAttachmentRepository.
+func NewSQLiteAttachmentRepository(db *sql.DB) *SQLiteAttachmentRepository {
+	return &SQLiteAttachmentRepository{DB: db}
+}
+
+// GetByID retrieves an attachment with its data.
+func (r *SQLiteAttachmentRepository) GetByID(ctx context.Context, id uuid.UUID) (*Attachment, error) {
+	query := `
+        SELECT id, note_id, user_id, filename, content_type, size, data, created_at
+        FROM attachments WHERE id = ?`
+	var a Attachment
+	err := r.DB.QueryRowContext(ctx, query, id).
+		Scan(&a.ID, &a.NoteID, &a.UserID, &a.Filename, &a.ContentType, &a.Size, &a.Data, database.ScanTime{T: &a.CreatedAt})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("attachment not found")
+		}
+		return nil, fmt.Errorf("failed to scan attachment: %w", err)
+	}
+	return &a, nil
+}
+
+// ListByNote retrieves the attachments of a note without their data.
+func (r *SQLiteAttachmentRepository) ListByNote(ctx context.Context, noteID uuid.UUID) ([]Attachment, error) {
+	query := `
+        SELECT id, note_id, user_id, filename, content_type, size, created_at
+        FROM attachments WHERE note_id = ? ORDER BY created_at`
+	rows, err := r.DB.QueryContext(ctx, query, noteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attachments: %w", err)
+	}
+	defer rows.Close()
+
+	var result []Attachment
+	for rows.Next() {
+		var a Attachment
+		if err := rows.Scan(&a.ID, &a.NoteID, &a.UserID, &a.Filename, &a.ContentType, &a.Size, database.ScanTime{T: &a.CreatedAt}); err != nil {
+			return nil, fmt.Errorf("failed to scan attachment row: %w", err)
+		}
+		result = append(result, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return result, nil
+}
+
+// Create inserts an attachment.
+func (r *SQLiteAttachmentRepository) Create(ctx context.Context, attachment *Attachment) error {
+	id := uuid.New()
+	created := time.Now().UTC()
+	data := attachment.Data
+	if data == nil {
+		data = []byte{} // a nil blob would be NULL
+	}
+	query := `
+        INSERT INTO attachments (id, note_id, user_id, filename, content_type, size, data, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := r.DB.ExecContext(ctx, query, id, attachment.NoteID, attachment.UserID, attachment.Filename,
+		attachment.ContentType, len(data), data, database.Timestamp(created))
+	if err != nil {
+		if database.IsSQLiteConstraint(err, "FOREIGN KEY constraint failed") {
+			return fmt.Errorf("note or user not found: %w", err)
+		}
+		return fmt.Errorf("failed to insert attachment: %w", err)
+	}
+	attachment.ID = id
+	attachment.Size = len(data)
+	attachment.CreatedAt = created
+	return nil
+}
+
+// Delete removes an attachment.
+func (r *SQLiteAttachmentRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.DB.ExecContext(ctx, `DELETE FROM attachments WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return fmt.Errorf("attachment not found")
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package attachments
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Attachment is a file attached to a note. Listings leave Data out.
+type Attachment struct {
+	ID          uuid.UUID `json:"id"`
+	NoteID      uuid.UUID `json:"note_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int       `json:"size"`
+	Data        []byte    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,97 @@
+// Package calendar serves date-based views of a user's notes: daily journal
+// notes and a per-month calendar. Days follow the user's time zone setting.
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	dateLayout  = "2006-01-02"
+	monthLayout = "2006-01"
+	longLayout  = "Monday, January 2, 2006"
+
+	// DefaultDailyTemplate is used for users who have not set their own.
+	DefaultDailyTemplate = "# {{.Long}}\n\n"
+
+	maxTemplateLength = 10000
+)
+
+// TemplateData is what a daily note template can refer to.
+type TemplateData struct {
+	Date      string // 2006-01-02
+	Weekday   string // Monday
+	Long      string // Monday, January 2, 2006
+	Yesterday string // 2006-01-01
+	Tomorrow  string // 2006-01-03
+	Time      time.Time
+}
+
+func templateData(day time.Time) TemplateData {
+	return TemplateData{
+		Date:      day.Format(dateLayout),
+		Weekday:   day.Weekday().String(),
+		Long:      day.Format(longLayout),
+		Yesterday: day.AddDate(0, 0, -1).Format(dateLayout),
+		Tomorrow:  day.AddDate(0, 0, 1).Format(dateLayout),
+		Time:      day,
+	}
+}
+
+// ValidateTemplate checks that a daily note template parses and executes.
+func ValidateTemplate(text string) error {
+	if len(text) > maxTemplateLength {
+		return fmt.Errorf("daily template must be at most %d characters", maxTemplateLength)
+	}
+	_, err := RenderTemplate(text, time.Date(2006, time.January, 2, 0, 0, 0, 0, time.UTC))
+	return err
+}
+
+// RenderTemplate renders the content of the daily note for day. An empty
+// template renders DefaultDailyTemplate.
+func RenderTemplate(text string, day time.Time) (string, error) {
+	if text == "" {
+		text = DefaultDailyTemplate
+	}
+	tmpl, err := template.New("daily").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid daily template: %w", err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, templateData(day)); err != nil {
+		return "", fmt.Errorf("invalid daily template: %w", err)
+	}
+	return b.String(), nil
+}
+
+// ParseDay resolves a date parameter, YYYY-MM-DD or "today", to midnight of
+// that day in loc.
+func ParseDay(value string, now time.Time, loc *time.Location) (time.Time, error) {
+	if value == "today" {
+		y, m, d := now.In(loc).Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, loc), nil
+	}
+	day, err := time.ParseInLocation(dateLayout, value, loc)
+	if err != nil {
+		return time.Time{}, errors.New("date must be YYYY-MM-DD or today")
+	}
+	return day, nil
+}
+
+// ParseMonth resolves a month parameter, YYYY-MM or empty for the current
+// month, to midnight of its first day in loc.
+func ParseMonth(value string, now time.Time, loc *time.Location) (time.Time, error) {
+	if value == "" {
+		y, m, _ := now.In(loc).Date()
+		return time.Date(y, m, 1, 0, 0, 0, 0, loc), nil
+	}
+	month, err := time.ParseInLocation(monthLayout, value, loc)
+	if err != nil {
+		return time.Time{}, errors.New("month must be YYYY-MM")
+	}
+	return month, nil
+}
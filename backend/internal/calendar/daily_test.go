@@ -0,0 +1,83 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/notes"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	day := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	got, err := RenderTemplate("", day)
+	if err != nil || got != "# Friday, March 1, 2024\n\n" {
+		t.Fatalf("default template = %q, %v", got, err)
+	}
+
+	got, err = RenderTemplate("[[{{.Yesterday}}]] {{.Weekday}} [[{{.Tomorrow}}]]", day)
+	if err != nil || got != "[[2024-02-29]] Friday [[2024-03-02]]" {
+		t.Fatalf("custom template = %q, %v", got, err)
+	}
+
+	if err := ValidateTemplate("{{.Nope}}"); err == nil {
+		t.Fatal("unknown field should not validate")
+	}
+	if err := ValidateTemplate("{{.Date"); err == nil {
+		t.Fatal("unterminated action should not validate")
+	}
+}
+
+func TestParseDayFollowsTimezone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, time.May, 1, 20, 0, 0, 0, time.UTC) // already May 2 in Tokyo
+
+	day, err := ParseDay("today", now, tokyo)
+	if err != nil || day.Format(dateLayout) != "2024-05-02" {
+		t.Fatalf("today in Tokyo = %v, %v", day, err)
+	}
+	day, _ = ParseDay("today", now, time.UTC)
+	if day.Format(dateLayout) != "2024-05-01" {
+		t.Fatalf("today in UTC = %v", day)
+	}
+	if _, err := ParseDay("2024-13-01", now, time.UTC); err == nil {
+		t.Fatal("invalid date should not parse")
+	}
+}
+
+func TestBuildMonthGroupsByLocalDay(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, _ := ParseMonth("2024-02", time.Now(), newYork)
+
+	// 03:00 UTC on March 1 is still February 29 in New York
+	leap := notes.Note{ID: uuid.New(), Title: "leap",
+		CreatedAt: time.Date(2024, time.March, 1, 3, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2024, time.March, 1, 3, 0, 0, 0, time.UTC)}
+	edited := notes.Note{ID: uuid.New(), Title: "edited",
+		CreatedAt: time.Date(2024, time.January, 20, 12, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2024, time.February, 10, 12, 0, 0, 0, time.UTC)}
+	daily := notes.Note{ID: uuid.New(), Title: "2024-02-05", DailyDate: "2024-02-05",
+		CreatedAt: time.Date(2024, time.February, 5, 12, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2024, time.February, 6, 12, 0, 0, 0, time.UTC)}
+
+	month := BuildMonth(start, []notes.Note{leap, edited, daily})
+	if len(month.Days) != 29 {
+		t.Fatalf("February 2024 has %d days", len(month.Days))
+	}
+	if got := month.Days[28].Created; len(got) != 1 || got[0].ID != leap.ID {
+		t.Fatalf("Feb 29 created = %v", got)
+	}
+	if got := month.Days[9].Updated; len(got) != 1 || got[0].ID != edited.ID {
+		t.Fatalf("Feb 10 updated = %v", got)
+	}
+	if month.Days[4].DailyNoteID != daily.ID.String() || len(month.Days[5].Updated) != 1 {
+		t.Fatalf("daily note not placed: %+v %+v", month.Days[4], month.Days[5])
+	}
+}
@@ -0,0 +1,305 @@
+package calendar
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/notes"
+	"github.com/jehufrayle/grimoire/internal/users"
+	"github.com/jehufrayle/grimoire/middleware"
+	"github.com/jehufrayle/grimoire/utils"
+)
+
+type Handler struct {
+	users      users.UserRepository
+	notes      notes.NoteRepository
+	properties notes.PropertyDefinitionRepository
+}
+
+func NewHandler(userRepo users.UserRepository, noteRepo notes.NoteRepository, properties notes.PropertyDefinitionRepository) *Handler {
+	return &Handler{users: userRepo, notes: noteRepo, properties: properties}
+}
+
+// settings loads the caller's settings. It writes the error response itself
+// and returns nil when the request should stop.
+func (h *Handler) settings(w http.ResponseWriter, r *http.Request) (uuid.UUID, *users.Settings) {
+	userIDstr, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return uuid.Nil, nil
+	}
+	userID, err := uuid.Parse(userIDstr)
+	if err != nil {
+		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		return uuid.Nil, nil
+	}
+	settings, err := h.users.GetSettings(r.Context(), userID.String())
+	if err != nil {
+		http.Error(w, "Failed to retrieve settings", http.StatusInternalServerError)
+		return uuid.Nil, nil
+	}
+	return userID, settings
+}
+
+func (h *Handler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	_, settings := h.settings(w, r)
+	if settings == nil {
+		return
+	}
+	utils.JSONResponse(w, settings, http.StatusOK)
+}
+
+// UpdateSettings changes the fields present in the request body.
+func (h *Handler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	userID, settings := h.settings(w, r)
+	if settings == nil {
+		return
+	}
+
+	var body struct {
+		Timezone      *string `json:"timezone"`
+		DailyTemplate *string `json:"daily_template"`
+		WeeklyDigest  *bool   `json:"weekly_digest"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if body.Timezone != nil {
+		// Only IANA names; the empty string would load as UTC too
+		if _, err := time.LoadLocation(*body.Timezone); err != nil || *body.Timezone == "" || *body.Timezone == "Local" {
+			http.Error(w, "Unknown time zone", http.StatusBadRequest)
+			return
+		}
+		settings.Timezone = *body.Timezone
+	}
+	if body.DailyTemplate != nil {
+		if err := ValidateTemplate(*body.DailyTemplate); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		settings.DailyTemplate = *body.DailyTemplate
+	}
+	if body.WeeklyDigest != nil {
+		settings.WeeklyDigest = *body.WeeklyDigest
+	}
+
+	if err := h.users.UpdateSettings(r.Context(), userID.String(), settings); err != nil {
+		http.Error(w, "Failed to update settings", http.StatusInternalServerError)
+		return
+	}
+	utils.JSONResponse(w, settings, http.StatusOK)
+}
+
+// GetDailyNote returns the caller's daily note for the date, creating it from
+// their template on first access.
+func (h *Handler) GetDailyNote(w http.ResponseWriter, r *http.Request) {
+	userID, settings := h.settings(w, r)
+	if settings == nil {
+		return
+	}
+	day, err := ParseDay(r.PathValue("date"), time.Now(), settings.Location())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	date := day.Format(dateLayout)
+
+	note, err := h.notes.GetDaily(r.Context(), userID, date)
+	if err == nil {
+		utils.JSONResponse(w, note, http.StatusOK)
+		return
+	}
+	if !errors.Is(err, notes.ErrNoDailyNote) {
+		http.Error(w, "Failed to retrieve daily note", http.StatusInternalServerError)
+		return
+	}
+
+	content, err := RenderTemplate(settings.DailyTemplate, day)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	note = &notes.Note{Title: date, Content: content, UserID: userID, Tags: []notes.Tag{}, DailyDate: date}
+
+	// Templates may carry front matter, which has to hold up like any other
+	defs, err := h.properties.GetByUserID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve properties", http.StatusInternalServerError)
+		return
+	}
+	if err := notes.SyncProperties(note, defs); err != nil {
+		http.Error(w, "Daily template: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.notes.Create(r.Context(), note); err != nil {
+		if !errors.Is(err, notes.ErrDailyNoteExists) {
+			http.Error(w, "Failed to create daily note", http.StatusInternalServerError)
+			return
+		}
+		// A concurrent request created it first
+		note, err = h.notes.GetDaily(r.Context(), userID, date)
+		if err != nil {
+			http.Error(w, "Failed to retrieve daily note", http.StatusInternalServerError)
+			return
+		}
+		utils.JSONResponse(w, note, http.StatusOK)
+		return
+	}
+	utils.JSONResponse(w, note, http.StatusCreated)
+}
+
+// GetCalendar groups the caller's notes by the day they were created and
+// last updated, for the month in the month query parameter (YYYY-MM,
+// default the current one).
+func (h *Handler) GetCalendar(w http.ResponseWriter, r *http.Request) {
+	userID, settings := h.settings(w, r)
+	if settings == nil {
+		return
+	}
+	loc := settings.Location()
+	start, err := ParseMonth(r.URL.Query().Get("month"), time.Now(), loc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	end := start.AddDate(0, 1, 0).Add(-time.Nanosecond)
+
+	// Anything created or last updated in the month was updated after it
+	// began and created before it ended
+	monthNotes, err := h.notes.Find(r.Context(), userID, &notes.Filter{UpdatedFrom: &start, CreatedTo: &end})
+	if err != nil {
+		http.Error(w, "Failed to retrieve notes", http.StatusInternalServerError)
+		return
+	}
+
+	month := BuildMonth(start, monthNotes)
+	month.Timezone = loc.String()
+	utils.JSONResponse(w, month, http.StatusOK)
+}
+
+// BuildMonth lays out notes over the days of the month starting at start.
+// Days are taken in start's location.
+func BuildMonth(start time.Time, monthNotes []notes.Note) Month {
+	loc := start.Location()
+	month := Month{Month: start.Format(monthLayout), Timezone: loc.String()}
+	index := make(map[string]int)
+	for day := start; day.Month() == start.Month(); day = day.AddDate(0, 0, 1) {
+		date := day.Format(dateLayout)
+		index[date] = len(month.Days)
+		month.Days = append(month.Days, Day{Date: date, Created: []notes.NoteSummary{}, Updated: []notes.NoteSummary{}})
+	}
+
+	for _, note := range monthNotes {
+		summary := notes.NoteSummary{ID: note.ID, Title: note.Title, UpdatedAt: note.UpdatedAt}
+		created := note.CreatedAt.In(loc).Format(dateLayout)
+		updated := note.UpdatedAt.In(loc).Format(dateLayout)
+		if i, ok := index[created]; ok {
+			month.Days[i].Created = append(month.Days[i].Created, summary)
+		}
+		if i, ok := index[updated]; ok && updated != created {
+			month.Days[i].Updated = append(month.Days[i].Updated, summary)
+		}
+		if i, ok := index[note.DailyDate]; ok {
+			month.Days[i].DailyNoteID = note.ID.String()
+		}
+	}
+	return month
+}
+
+// CreateFeedToken issues a new calendar feed token and returns the
+// subscription URL. Any previous URL stops working.
+func (h *Handler) CreateFeedToken(w http.ResponseWriter, r *http.Request) {
+	userIDstr, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+	if err := h.users.SetCalendarToken(r.Context(), userIDstr, hashToken(token)); err != nil {
+		http.Error(w, "Failed to store token", http.StatusInternalServerError)
+		return
+	}
+
+	// The token is only ever shown here; only its hash is stored
+	url := utils.BaseURL(r) + "/api/public/calendar/" + token + ".ics"
+	utils.JSONResponse(w, FeedSubscription{URL: url}, http.StatusCreated)
+}
+
+// DeleteFeedToken revokes the calendar feed.
+func (h *Handler) DeleteFeedToken(w http.ResponseWriter, r *http.Request) {
+	userIDstr, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	if err := h.users.SetCalendarToken(r.Context(), userIDstr, ""); err != nil {
+		http.Error(w, "Failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetFeed serves the iCalendar feed named by the token in the URL. Calendar
+// clients cannot send a JWT, so the token is the credential.
+func (h *Handler) GetFeed(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutSuffix(r.PathValue("file"), ".ics")
+	if !ok || token == "" {
+		http.Error(w, "Calendar not found", http.StatusNotFound)
+		return
+	}
+	user, err := h.users.GetByCalendarToken(r.Context(), hashToken(token))
+	if err != nil || user == nil || !user.Active {
+		http.Error(w, "Calendar not found", http.StatusNotFound)
+		return
+	}
+	userID, err := uuid.Parse(user.ID)
+	if err != nil {
+		http.Error(w, "Calendar not found", http.StatusNotFound)
+		return
+	}
+
+	userNotes, err := h.notes.GetByUserID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve notes", http.StatusInternalServerError)
+		return
+	}
+
+	app := appURL(r)
+	body := BuildICS("Grimoire – "+user.Username, userNotes, func(note *notes.Note) string {
+		return app + "/edit-note/" + note.ID.String()
+	})
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.Write([]byte(body))
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// appURL is where the web app is served, for links back to notes.
+// GRIMOIRE_APP_URL defaults to the API's own base URL.
+func appURL(r *http.Request) string {
+	if app := os.Getenv("GRIMOIRE_APP_URL"); app != "" {
+		return strings.TrimRight(app, "/")
+	}
+	return utils.BaseURL(r)
+}
@@ -0,0 +1,114 @@
+package calendar
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jehufrayle/grimoire/internal/notes"
+)
+
+const (
+	icsProdID      = "-//Grimoire//Notes//EN"
+	icsUTCLayout   = "20060102T150405Z"
+	icsDateLayout  = "20060102"
+	icsLineOctets  = 75
+	reminderLength = "PT15M"
+
+	// DueProperty is the date property read as a note's due date.
+	DueProperty = "due"
+)
+
+// icsWriter builds an iCalendar (RFC 5545) document, folding long lines and
+// ending every line with CRLF.
+type icsWriter struct {
+	b strings.Builder
+}
+
+func (w *icsWriter) line(name, value string) {
+	line := name + ":" + value
+	limit := icsLineOctets
+	for len(line) > limit {
+		cut := limit
+		for !utf8.RuneStart(line[cut]) { // do not split a character
+			cut--
+		}
+		w.b.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		limit = icsLineOctets - 1 // continuation lines start with a space
+	}
+	w.b.WriteString(line + "\r\n")
+}
+
+func (w *icsWriter) text(name, value string) {
+	w.line(name, icsEscaper.Replace(value))
+}
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// BuildICS renders the dated notes as an iCalendar document: a VEVENT with
+// an alarm for each reminder and a VTODO for each due date, linking to link. UIDs derive from
+// the note ID so clients update entries in place across refreshes.
+func BuildICS(name string, userNotes []notes.Note, link func(note *notes.Note) string) string {
+	sort.Slice(userNotes, func(i, j int) bool { return userNotes[i].CreatedAt.Before(userNotes[j].CreatedAt) })
+
+	var w icsWriter
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", icsProdID)
+	w.line("CALSCALE", "GREGORIAN")
+	w.line("METHOD", "PUBLISH")
+	w.text("X-WR-CALNAME", name)
+
+	for i := range userNotes {
+		note := &userNotes[i]
+		stamp := note.UpdatedAt.UTC().Format(icsUTCLayout)
+		url := link(note)
+
+		if note.RemindAt != nil {
+			w.line("BEGIN", "VEVENT")
+			w.line("UID", fmt.Sprintf("%s-reminder@grimoire", note.ID))
+			w.line("DTSTAMP", stamp)
+			w.line("LAST-MODIFIED", stamp)
+			w.line("DTSTART", note.RemindAt.UTC().Format(icsUTCLayout))
+			w.line("DURATION", reminderLength)
+			w.text("SUMMARY", note.Title)
+			w.line("URL", url)
+			w.text("DESCRIPTION", url)
+			w.line("BEGIN", "VALARM")
+			w.line("ACTION", "DISPLAY")
+			w.line("TRIGGER", "PT0S")
+			w.text("DESCRIPTION", note.Title)
+			w.line("END", "VALARM")
+			w.line("END", "VEVENT")
+		}
+
+		if due, ok := dueDate(note); ok {
+			w.line("BEGIN", "VTODO")
+			w.line("UID", fmt.Sprintf("%s-due@grimoire", note.ID))
+			w.line("DTSTAMP", stamp)
+			w.line("LAST-MODIFIED", stamp)
+			w.line("DUE;VALUE=DATE", due.Format(icsDateLayout))
+			w.text("SUMMARY", note.Title)
+			w.line("URL", url)
+			w.text("DESCRIPTION", url)
+			w.line("END", "VTODO")
+		}
+	}
+
+	w.line("END", "VCALENDAR")
+	return w.b.String()
+}
+
+// dueDate reads the note's due property. Properties are normalized on save,
+// so a date property holds YYYY-MM-DD.
+func dueDate(note *notes.Note) (time.Time, bool) {
+	value, ok := note.Properties[DueProperty].(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	due, err := time.Parse(dateLayout, value)
+	return due, err == nil
+}
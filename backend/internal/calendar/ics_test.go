@@ -0,0 +1,55 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/notes"
+)
+
+func TestBuildICS(t *testing.T) {
+	remindAt := time.Date(2024, time.May, 1, 9, 30, 0, 0, time.UTC)
+	reminder := notes.Note{ID: uuid.New(), Title: "Call Ana; bring notes, pens", RemindAt: &remindAt}
+	due := notes.Note{ID: uuid.New(), Title: "Taxes", Properties: map[string]any{"due": "2024-06-30"}}
+	undated := notes.Note{ID: uuid.New(), Title: "Ideas"}
+
+	ics := BuildICS("Grimoire – jdoe", []notes.Note{reminder, due, undated}, func(note *notes.Note) string {
+		return "https://grimoire.test/edit-note/" + note.ID.String()
+	})
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"UID:" + reminder.ID.String() + "-reminder@grimoire\r\n",
+		"DTSTART:20240501T093000Z\r\n",
+		`SUMMARY:Call Ana\; bring notes\, pens` + "\r\n",
+		"UID:" + due.ID.String() + "-due@grimoire\r\n",
+		"DUE;VALUE=DATE:20240630\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("missing %q in\n%s", want, ics)
+		}
+	}
+	if strings.Contains(ics, undated.ID.String()) {
+		t.Error("notes without dates should not be in the feed")
+	}
+}
+
+func TestICSLinesAreFolded(t *testing.T) {
+	var w icsWriter
+	w.text("SUMMARY", strings.Repeat("ñ", 100))
+	for _, line := range strings.Split(strings.TrimSuffix(w.b.String(), "\r\n"), "\r\n") {
+		if len(line) > icsLineOctets {
+			t.Fatalf("line of %d octets", len(line))
+		}
+		if !strings.HasPrefix(line, "SUMMARY:") && !strings.HasPrefix(line, " ") {
+			t.Fatalf("continuation line %q does not start with a space", line)
+		}
+	}
+	unfolded := strings.ReplaceAll(w.b.String(), "\r\n ", "")
+	if unfolded != "SUMMARY:"+strings.Repeat("ñ", 100)+"\r\n" {
+		t.Fatalf("unfolded = %q", unfolded)
+	}
+}
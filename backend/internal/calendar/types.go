@@ -0,0 +1,26 @@
+package calendar
+
+import "github.com/jehufrayle/grimoire/internal/notes"
+
+// Month is the calendar view of a month: one entry per day, in the user's
+// time zone.
+type Month struct {
+	Month    string `json:"month"` // YYYY-MM
+	Timezone string `json:"timezone"`
+	Days     []Day  `json:"days"`
+}
+
+// Day lists the notes created and the notes last updated on a day. A note
+// created and last updated on the same day is only listed as created.
+type Day struct {
+	Date        string              `json:"date"` // YYYY-MM-DD
+	DailyNoteID string              `json:"daily_note_id,omitempty"`
+	Created     []notes.NoteSummary `json:"created"`
+	Updated     []notes.NoteSummary `json:"updated"`
+}
+
+// FeedSubscription is the URL calendar apps subscribe to. It embeds the feed
+// token and is only shown when the token is issued.
+type FeedSubscription struct {
+	URL string `json:"url"`
+}
@@ -0,0 +1,64 @@
+package clipper
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Clipping is a page reduced to what a note needs.
+type Clipping struct {
+	Title   string
+	Content string // Markdown
+}
+
+// Clip converts a page to Markdown. When the reader selected part of the
+// page, the HTML of the selection is clipped instead of the article Extract
+// finds. source resolves relative links and images, and titles pages
+// without a title of their own.
+func Clip(page, selection string, source *url.URL) (Clipping, error) {
+	doc, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		return Clipping{}, fmt.Errorf("failed to parse page: %w", err)
+	}
+	clip := Clipping{Title: Title(doc)}
+	if clip.Title == "" {
+		clip.Title = source.Host
+	}
+
+	base := source
+	if b := find(doc, atom.Base); b != nil {
+		if href, err := source.Parse(attr(b, "href")); err == nil {
+			base = href
+		}
+	}
+
+	if strings.TrimSpace(selection) == "" {
+		clip.Content = ToMarkdown(Extract(doc), base)
+		return clip, nil
+	}
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(selection), body)
+	if err != nil {
+		return Clipping{}, fmt.Errorf("failed to parse selection: %w", err)
+	}
+	clip.Content = ToMarkdown(nodes, base)
+	return clip, nil
+}
+
+// HTMLToMarkdown converts a whole HTML document, such as the body of an
+// email, to Markdown, without looking for an article in it.
+func HTMLToMarkdown(document string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	root := find(doc, atom.Body)
+	if root == nil {
+		root = doc
+	}
+	return ToMarkdown([]*html.Node{root}, nil), nil
+}
@@ -0,0 +1,130 @@
+package clipper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/notes"
+	"github.com/jehufrayle/grimoire/middleware"
+	"github.com/jehufrayle/grimoire/utils"
+)
+
+const (
+	// Tag marks clipped notes.
+	Tag = "clipped"
+	// SourceProperty holds the URL a note was clipped from. It is defined
+	// as a URL property the first time a user clips a page.
+	SourceProperty = "source"
+
+	maxClipSize = 10 << 20
+)
+
+type Handler struct {
+	notes      notes.NoteRepository
+	properties notes.PropertyDefinitionRepository
+}
+
+func NewHandler(noteRepo notes.NoteRepository, properties notes.PropertyDefinitionRepository) *Handler {
+	return &Handler{notes: noteRepo, properties: properties}
+}
+
+// ClipNote creates a note from a page sent by the browser extension: the
+// page's HTML, its URL and the HTML of what the reader selected, if
+// anything.
+func (h *Handler) ClipNote(w http.ResponseWriter, r *http.Request) {
+	userIDstr, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	userID, err := uuid.Parse(userIDstr)
+	if err != nil {
+		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		HTML      string `json:"html"`
+		URL       string `json:"url"`
+		Selection string `json:"selection"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxClipSize)).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Page too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	source, err := url.Parse(req.URL)
+	if err != nil || (source.Scheme != "http" && source.Scheme != "https") || source.Host == "" {
+		http.Error(w, "url must be an absolute http or https URL", http.StatusBadRequest)
+		return
+	}
+	if req.HTML == "" && req.Selection == "" {
+		http.Error(w, "html or selection is required", http.StatusBadRequest)
+		return
+	}
+
+	clip, err := Clip(req.HTML, req.Selection, source)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	defs, ok := h.definitions(w, r, userID)
+	if !ok {
+		return
+	}
+	note := notes.Note{
+		Title:      clip.Title,
+		Content:    clip.Content,
+		UserID:     userID,
+		Tags:       []notes.Tag{{Name: Tag}},
+		Properties: map[string]any{SourceProperty: source.String()},
+	}
+	if err := notes.SyncProperties(&note, defs); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.notes.Create(r.Context(), &note); err != nil {
+		http.Error(w, "Failed to create note", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, note, http.StatusCreated)
+}
+
+// definitions returns the user's property definitions, defining the source
+// property if they have not. It writes the error response itself and
+// returns false when the request should stop.
+func (h *Handler) definitions(w http.ResponseWriter, r *http.Request, userID uuid.UUID) ([]notes.PropertyDefinition, bool) {
+	defs, err := h.properties.GetByUserID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve properties", http.StatusInternalServerError)
+		return nil, false
+	}
+	for _, def := range defs {
+		if def.Name != SourceProperty {
+			continue
+		}
+		// A source property the user defined themselves must hold a URL
+		if def.Type != notes.PropertyURL && def.Type != notes.PropertyText {
+			http.Error(w, fmt.Sprintf("Property %q is a %s property and cannot hold the page's URL", SourceProperty, def.Type), http.StatusConflict)
+			return nil, false
+		}
+		return defs, true
+	}
+
+	def := notes.PropertyDefinition{UserID: userID, Name: SourceProperty, Type: notes.PropertyURL}
+	if err := h.properties.Save(r.Context(), &def); err != nil {
+		http.Error(w, "Failed to define the source property", http.StatusInternalServerError)
+		return nil, false
+	}
+	return append(defs, def), true
+}
@@ -0,0 +1,421 @@
+package clipper
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// blockElements start a block of their own in Markdown.
+var blockElements = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Blockquote: true, atom.Body: true, atom.Dd: true, atom.Div: true,
+	atom.Dl: true, atom.Dt: true, atom.Figcaption: true, atom.Figure: true, atom.Footer: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Header: true, atom.Hr: true, atom.Li: true, atom.Main: true, atom.Ol: true, atom.P: true,
+	atom.Pre: true, atom.Section: true, atom.Table: true, atom.Ul: true,
+}
+
+func isBlock(n *html.Node) bool {
+	return n.Type == html.ElementNode && blockElements[n.DataAtom]
+}
+
+// ToMarkdown converts HTML nodes to Markdown. Relative links and images are
+// resolved against base, which may be nil. Whatever has no Markdown
+// equivalent is reduced to its text.
+func ToMarkdown(nodes []*html.Node, base *url.URL) string {
+	c := converter{base: base}
+	var blocks []string
+	for _, n := range nodes {
+		blocks = append(blocks, c.blocks(n, true)...)
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+type converter struct {
+	base *url.URL
+}
+
+// blocks converts n, or only its children when self is false, to Markdown
+// blocks. Inline content between block elements becomes paragraphs.
+func (c *converter) blocks(n *html.Node, self bool) []string {
+	var blocks []string
+	var inline strings.Builder
+	flush := func() {
+		if p := paragraph(inline.String()); p != "" {
+			blocks = append(blocks, p)
+		}
+		inline.Reset()
+	}
+
+	visit := func(n *html.Node) {
+		if n.Type == html.ElementNode && dropped[n.DataAtom] {
+			return
+		}
+		if !isBlock(n) {
+			inline.WriteString(c.inline(n))
+			return
+		}
+		flush()
+		if block := c.block(n); block != "" {
+			blocks = append(blocks, block)
+		}
+	}
+	if self && n.Type != html.DocumentNode {
+		visit(n)
+	} else {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			visit(child)
+		}
+	}
+	flush()
+	return blocks
+}
+
+// block converts a block element.
+func (c *converter) block(n *html.Node) string {
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		text := collapse(strings.ReplaceAll(c.children(n), "\n", " "))
+		if text == "" {
+			return ""
+		}
+		level := int(n.Data[1] - '0')
+		return strings.Repeat("#", level) + " " + text
+	case atom.Hr:
+		return "---"
+	case atom.Pre:
+		return c.codeBlock(n)
+	case atom.Blockquote:
+		return prefixLines(strings.Join(c.blocks(n, false), "\n\n"), "> ", ">")
+	case atom.Ul, atom.Ol:
+		return c.list(n)
+	case atom.Table:
+		if table := c.table(n); table != "" {
+			return table
+		}
+	case atom.Dt:
+		if term := collapse(c.children(n)); term != "" {
+			return "**" + term + "**"
+		}
+		return ""
+	}
+	return strings.Join(c.blocks(n, false), "\n\n")
+}
+
+func (c *converter) codeBlock(n *html.Node) string {
+	code := strings.Trim(textContent(n), "\n")
+	if code == "" {
+		return ""
+	}
+	language := languageOf(n)
+	if inner := find(n, atom.Code); inner != nil && language == "" {
+		language = languageOf(inner)
+	}
+	fence := fenceFor(code, '`', 3)
+	return fence + language + "\n" + code + "\n" + fence
+}
+
+// languageOf reads the language-xxx or lang-xxx class highlighters use.
+func languageOf(n *html.Node) string {
+	for _, class := range strings.Fields(attr(n, "class")) {
+		for _, prefix := range []string{"language-", "lang-"} {
+			if lang, ok := strings.CutPrefix(class, prefix); ok {
+				return lang
+			}
+		}
+	}
+	return ""
+}
+
+func (c *converter) list(n *html.Node) string {
+	start := 1
+	if s, err := strconv.Atoi(attr(n, "start")); err == nil {
+		start = s
+	}
+	var items []string
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.DataAtom != atom.Li {
+			continue
+		}
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = strconv.Itoa(start+len(items)) + ". "
+		}
+		body := strings.Join(c.blocks(li, false), "\n\n")
+		if body == "" {
+			continue
+		}
+		indent := strings.Repeat(" ", len(marker))
+		items = append(items, marker+strings.TrimPrefix(prefixLines(body, indent, ""), indent))
+	}
+	return strings.Join(items, "\n")
+}
+
+// table converts a table to a GitHub table, or returns "" for layout tables
+// nesting other tables or blocks, which are better read as their contents.
+func (c *converter) table(n *html.Node) string {
+	var rows [][]string
+	header := false
+	nested := false
+	walk(n, func(x *html.Node) bool {
+		if x == n {
+			return true
+		}
+		switch x.DataAtom {
+		case atom.Table, atom.Pre, atom.Ul, atom.Ol, atom.Blockquote:
+			nested = true
+		case atom.Tr:
+			var row []string
+			for cell := x.FirstChild; cell != nil; cell = cell.NextSibling {
+				if cell.DataAtom != atom.Td && cell.DataAtom != atom.Th {
+					continue
+				}
+				if len(rows) == 0 && cell.DataAtom == atom.Th {
+					header = true
+				}
+				text := collapse(strings.ReplaceAll(c.children(cell), "\n", " "))
+				row = append(row, strings.ReplaceAll(text, "|", `\|`))
+			}
+			if len(row) > 0 {
+				rows = append(rows, row)
+			}
+			return false
+		}
+		return !nested
+	})
+	if nested || len(rows) == 0 {
+		return ""
+	}
+
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	if !header {
+		rows = append([][]string{make([]string, width)}, rows...)
+	}
+	var lines []string
+	for i, row := range rows {
+		for len(row) < width {
+			row = append(row, "")
+		}
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", width))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// inline converts an inline node. Line breaks come out as "\n", for
+// paragraph to turn into hard breaks.
+func (c *converter) inline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return escape(whitespace(n.Data))
+	case html.ElementNode:
+	default:
+		return ""
+	}
+	if dropped[n.DataAtom] {
+		return ""
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		return "\n"
+	case atom.Img:
+		src := c.resolve(attr(n, "src"))
+		if src == "" {
+			src = c.resolve(attr(n, "data-src")) // lazy loading
+		}
+		// Embedded images, inline or referring to an email's parts, cannot
+		// be linked to
+		if src == "" || strings.HasPrefix(src, "data:") || strings.HasPrefix(src, "cid:") {
+			return ""
+		}
+		return "![" + escape(collapse(attr(n, "alt"))) + "](" + destination(src) + ")"
+	case atom.Code, atom.Kbd, atom.Samp, atom.Tt:
+		code := whitespace(textContent(n))
+		if strings.TrimSpace(code) == "" {
+			return code
+		}
+		fence := fenceFor(code, '`', 1)
+		if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+			return fence + " " + code + " " + fence
+		}
+		return fence + code + fence
+	case atom.A:
+		text := c.children(n)
+		href := attr(n, "href")
+		if strings.TrimSpace(text) == "" || href == "" || strings.HasPrefix(href, "#") {
+			return text
+		}
+		target := c.resolve(href)
+		if target == "" || strings.HasPrefix(strings.ToLower(target), "javascript:") {
+			return text
+		}
+		return wrap(text, "[", "]("+destination(target)+")")
+	case atom.Strong, atom.B:
+		return wrap(c.children(n), "**", "**")
+	case atom.Em, atom.I, atom.Cite:
+		return wrap(c.children(n), "_", "_")
+	case atom.Del, atom.S, atom.Strike:
+		return wrap(c.children(n), "~~", "~~")
+	}
+	return c.children(n)
+}
+
+// children converts the children of n as inline content, block elements
+// included.
+func (c *converter) children(n *html.Node) string {
+	var sb strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if isBlock(child) {
+			sb.WriteString(" " + strings.Join(c.blocks(child, false), " ") + " ")
+			continue
+		}
+		sb.WriteString(c.inline(child))
+	}
+	return sb.String()
+}
+
+func (c *converter) resolve(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || c.base == nil {
+		return ref
+	}
+	u, err := c.base.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	return u.String()
+}
+
+// wrap puts markers around inline content, keeping them next to the text so
+// Markdown recognizes them: "<b> bold </b>" is " **bold** ".
+func wrap(s, open, close string) string {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		return s
+	}
+	lead := s[:len(s)-len(strings.TrimLeftFunc(s, unicode.IsSpace))]
+	trail := s[len(strings.TrimRightFunc(s, unicode.IsSpace)):]
+	return lead + open + trimmed + close + trail
+}
+
+// paragraph tidies the inline content of a paragraph: spaces collapsed,
+// line breaks made hard breaks, and a start that would read as a heading,
+// list or quote escaped.
+func paragraph(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = collapse(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	for i, line := range lines {
+		lines[i] = escapeLineStart(line)
+	}
+	return strings.Join(lines, "  \n")
+}
+
+func escapeLineStart(line string) string {
+	switch {
+	case strings.HasPrefix(line, "#"), strings.HasPrefix(line, ">"),
+		strings.HasPrefix(line, "- "), strings.HasPrefix(line, "+ "), line == "-", line == "+",
+		strings.HasPrefix(line, "==="), strings.HasPrefix(line, "---"):
+		return `\` + line
+	}
+	digits := len(line) - len(strings.TrimLeft(line, "0123456789"))
+	if digits > 0 && digits < len(line) && (line[digits] == '.' || line[digits] == ')') {
+		return line[:digits] + `\` + line[digits:]
+	}
+	return line
+}
+
+// whitespace turns each run of whitespace into a single space, keeping a
+// leading and trailing one so words from neighbouring nodes stay apart.
+func whitespace(s string) string {
+	collapsed := collapse(s)
+	if collapsed == "" {
+		if s == "" {
+			return ""
+		}
+		return " "
+	}
+	if strings.TrimLeftFunc(s, unicode.IsSpace) != s {
+		collapsed = " " + collapsed
+	}
+	if strings.TrimRightFunc(s, unicode.IsSpace) != s {
+		collapsed += " "
+	}
+	return collapsed
+}
+
+// escape backslash-escapes the characters that would otherwise format text.
+// Underscores inside words are left alone, as Markdown ignores them there.
+func escape(s string) string {
+	var sb strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		switch r {
+		case '\\', '*', '`', '[', ']':
+			sb.WriteRune('\\')
+		case '_':
+			if i == 0 || i == len(runes)-1 || !isWordRune(runes[i-1]) || !isWordRune(runes[i+1]) {
+				sb.WriteRune('\\')
+			}
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// destination formats a URL as a link destination, which cannot hold
+// spaces or unbalanced parentheses.
+func destination(u string) string {
+	if strings.ContainsAny(u, " ()<>") {
+		return "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(u) + ">"
+	}
+	return u
+}
+
+// fenceFor returns a run of fence characters longer than any in s.
+func fenceFor(s string, char rune, least int) string {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == char {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat(string(char), max(least, longest+1))
+}
+
+// prefixLines puts prefix in front of every line of s, or blank before
+// empty lines.
+func prefixLines(s, prefix, blank string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = blank
+		} else {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
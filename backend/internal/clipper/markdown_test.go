@@ -0,0 +1,52 @@
+package clipper
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+func convert(t *testing.T, fragment string) string {
+	t.Helper()
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	base, _ := url.Parse("https://example.com/docs/page")
+	return ToMarkdown(nodes, base)
+}
+
+func TestToMarkdown(t *testing.T) {
+	tests := []struct {
+		name, html, want string
+	}{
+		{"inline", `<p>A <strong> bold </strong>, <em>soft</em> and <del>gone</del> <code>x := 1</code></p>`,
+			"A **bold** , _soft_ and ~~gone~~ `x := 1`"},
+		{"links and images", `<p><a href="../guide">Guide</a> <a href="#top">Top</a> <img src="/a b.png" alt="Map"></p>`,
+			"[Guide](https://example.com/guide) Top ![Map](https://example.com/a%20b.png)"},
+		{"headings and rules", `<h2>Title</h2><hr><p>Text</p>`, "## Title\n\n---\n\nText"},
+		{"line breaks", `<p>one<br>two</p>`, "one  \ntwo"},
+		{"escaping", `<p>1. Not a list, *not* [a link] or snake_case _x_</p>`,
+			`1\. Not a list, \*not\* \[a link\] or snake_case \_x\_`},
+		{"lists", `<ol start="3"><li>Three<ul><li>Nested</li></ul></li><li><p>Four</p><p>More</p></li></ol>`,
+			"3. Three\n\n   - Nested\n4. Four\n\n   More"},
+		{"quote", `<blockquote><p>Said</p><p>Twice</p></blockquote>`, "> Said\n>\n> Twice"},
+		{"code block", "<pre><code class=\"language-go\">fmt.Println(\"```\")\n</code></pre>",
+			"````go\nfmt.Println(\"```\")\n````"},
+		{"table", `<table><tr><th>Name</th><th>Use</th></tr><tr><td>Sage</td><td>a|b</td></tr></table>`,
+			"| Name | Use |\n| --- | --- |\n| Sage | a\\|b |"},
+		{"loose text", `Before<div>Inside</div>After`, "Before\n\nInside\n\nAfter"},
+		{"dropped", `<p>Kept</p><script>alert(1)</script><form><input></form>`, "Kept"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := convert(t, tt.html); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
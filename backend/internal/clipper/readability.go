@@ -0,0 +1,326 @@
+// Package clipper turns pages sent by the browser extension into notes. It
+// finds the article in a page and converts it to Markdown from the HTML
+// alone, without fetching anything.
+package clipper
+
+import (
+	"math"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Class names and IDs that say whether an element holds the article or the
+// clutter around it.
+var (
+	positiveNames = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|story|text|blog`)
+	negativeNames = regexp.MustCompile(`(?i)ad-|ads|banner|breadcrumb|comment|cookie|footer|foot|masthead|menu|meta|modal|nav|newsletter|outbrain|popup|promo|related|share|sidebar|skip|social|sponsor|subscribe|tags|widget`)
+)
+
+// dropped elements never hold article text.
+var dropped = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Iframe: true, atom.Object: true, atom.Embed: true, atom.Canvas: true, atom.Svg: true,
+	atom.Form: true, atom.Button: true, atom.Input: true, atom.Select: true, atom.Textarea: true,
+	atom.Nav: true, atom.Aside: true, atom.Footer: true, atom.Link: true, atom.Meta: true,
+}
+
+// minParagraph is how long a paragraph must be to count towards scores.
+const minParagraph = 25
+
+// Title returns the page's title: its Open Graph title, else its <title>,
+// else its first heading.
+func Title(doc *html.Node) string {
+	var og, title, heading string
+	walk(doc, func(n *html.Node) bool {
+		switch n.DataAtom {
+		case atom.Meta:
+			if og == "" && (attr(n, "property") == "og:title" || attr(n, "name") == "twitter:title") {
+				og = collapse(attr(n, "content"))
+			}
+		case atom.Title:
+			if title == "" {
+				title = collapse(textContent(n))
+			}
+		case atom.H1:
+			if heading == "" {
+				heading = collapse(textContent(n))
+			}
+		}
+		return true
+	})
+	for _, t := range []string{og, title, heading} {
+		if t != "" {
+			return t
+		}
+	}
+	return ""
+}
+
+// Extract finds the main content of a page, Readability style: paragraphs
+// score the elements containing them, scores are discounted by how much of
+// an element is links, and the best element is returned together with the
+// siblings that look like part of the same article. It returns nil for a
+// page without a body.
+//
+// Extract removes clutter such as scripts and navigation from doc.
+func Extract(doc *html.Node) []*html.Node {
+	body := find(doc, atom.Body)
+	if body == nil {
+		return nil
+	}
+	prune(body)
+
+	scores := make(map[*html.Node]float64)
+	var candidates []*html.Node
+	walk(body, func(n *html.Node) bool {
+		if !isParagraph(n) {
+			return true
+		}
+		text := collapse(textContent(n))
+		if len(text) < minParagraph {
+			return true
+		}
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+		ancestor := n.Parent
+		for level := 0; level < 3 && ancestor != nil && ancestor.Type == html.ElementNode; level++ {
+			if _, ok := scores[ancestor]; !ok {
+				scores[ancestor] = baseScore(ancestor)
+				candidates = append(candidates, ancestor)
+			}
+			// Parents get the full score, grandparents half, then a third
+			scores[ancestor] += score / float64(level+1)
+			ancestor = ancestor.Parent
+		}
+		return false
+	})
+
+	var top *html.Node
+	for _, c := range candidates {
+		scores[c] *= 1 - linkDensity(c)
+		if top == nil || scores[c] > scores[top] {
+			top = c
+		}
+	}
+	if top == nil {
+		return []*html.Node{body}
+	}
+
+	// Articles split into several containers show up as siblings of the top
+	// candidate
+	threshold := math.Max(10, scores[top]*0.2)
+	class := attr(top, "class")
+	var content []*html.Node
+	for s := top.Parent.FirstChild; s != nil; s = s.NextSibling {
+		if s == top {
+			content = append(content, s)
+			continue
+		}
+		if s.Type != html.ElementNode {
+			continue
+		}
+		var bonus float64
+		if class != "" && attr(s, "class") == class {
+			bonus = scores[top] * 0.2
+		}
+		if score, ok := scores[s]; ok && score+bonus >= threshold {
+			content = append(content, s)
+			continue
+		}
+		if s.DataAtom == atom.P {
+			text := collapse(textContent(s))
+			density := linkDensity(s)
+			if len(text) > 80 && density < 0.25 || len(text) > 0 && density == 0 && strings.Contains(text, ". ") {
+				content = append(content, s)
+			}
+		}
+	}
+	for _, n := range content {
+		removeJunk(n)
+	}
+	return content
+}
+
+// removeJunk removes the containers inside the article that turn out to be
+// clutter after all.
+func removeJunk(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.ElementNode && isJunk(c) {
+			n.RemoveChild(c)
+		} else {
+			removeJunk(c)
+		}
+		c = next
+	}
+}
+
+// prune removes elements that cannot be part of the article: scripts, forms,
+// navigation, hidden elements and ones whose class or ID marks them as
+// clutter.
+func prune(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.CommentNode || c.Type == html.ElementNode && isClutter(c) {
+			n.RemoveChild(c)
+		} else {
+			prune(c)
+		}
+		c = next
+	}
+}
+
+func isClutter(n *html.Node) bool {
+	if dropped[n.DataAtom] || hasAttr(n, "hidden") || attr(n, "aria-hidden") == "true" {
+		return true
+	}
+	style := strings.ReplaceAll(attr(n, "style"), " ", "")
+	if strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") {
+		return true
+	}
+	switch n.DataAtom {
+	case atom.Body, atom.Article, atom.Main, atom.A:
+		return false
+	}
+	names := attr(n, "class") + " " + attr(n, "id")
+	return negativeNames.MatchString(names) && !positiveNames.MatchString(names)
+}
+
+// isJunk reports whether a container inside the article is clutter after
+// all, such as a list of links to other articles.
+func isJunk(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Div, atom.Section, atom.Ul, atom.Ol, atom.Table:
+	default:
+		return false
+	}
+	weight := classWeight(n)
+	return weight < 0 || weight < 25 && linkDensity(n) > 0.5 && len(collapse(textContent(n))) < 500
+}
+
+// isParagraph reports whether n is a unit of text to score: a paragraph, or
+// a div used as one.
+func isParagraph(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.P, atom.Pre, atom.Td, atom.Blockquote:
+		return true
+	case atom.Div:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if isBlock(c) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// baseScore is what an element starts with before its paragraphs count.
+func baseScore(n *html.Node) float64 {
+	score := classWeight(n)
+	switch n.DataAtom {
+	case atom.Article:
+		score += 10
+	case atom.Div, atom.Main, atom.Section:
+		score += 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score += 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li:
+		score -= 3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score -= 5
+	}
+	return score
+}
+
+// classWeight rewards and penalizes class names and IDs.
+func classWeight(n *html.Node) float64 {
+	var weight float64
+	for _, name := range []string{attr(n, "class"), attr(n, "id")} {
+		if name == "" {
+			continue
+		}
+		if negativeNames.MatchString(name) {
+			weight -= 25
+		}
+		if positiveNames.MatchString(name) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// linkDensity is the share of an element's text that is link text.
+func linkDensity(n *html.Node) float64 {
+	text := len(collapse(textContent(n)))
+	if text == 0 {
+		return 0
+	}
+	var links int
+	walk(n, func(c *html.Node) bool {
+		if c.DataAtom == atom.A {
+			links += len(collapse(textContent(c)))
+			return false
+		}
+		return true
+	})
+	return float64(links) / float64(text)
+}
+
+// walk calls visit on n and its descendants in document order, skipping
+// the descendants of nodes for which visit returns false.
+func walk(n *html.Node, visit func(*html.Node) bool) {
+	if !visit(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, visit)
+	}
+}
+
+func find(n *html.Node, a atom.Atom) *html.Node {
+	var found *html.Node
+	walk(n, func(c *html.Node) bool {
+		if found == nil && c.DataAtom == a {
+			found = c
+		}
+		return found == nil
+	})
+	return found
+}
+
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	walk(n, func(c *html.Node) bool {
+		if c.Type == html.TextNode {
+			sb.WriteString(c.Data)
+		}
+		return true
+	})
+	return sb.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// collapse trims s and turns each run of whitespace into a single space.
+func collapse(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
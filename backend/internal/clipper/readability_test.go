@@ -0,0 +1,72 @@
+package clipper
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const articlePage = `<!doctype html>
+<html><head>
+<title>Brewing potions | The Alchemist</title>
+<meta property="og:title" content="Brewing potions">
+<script>track()</script>
+</head><body>
+<header class="masthead"><a href="/">The Alchemist</a></header>
+<nav><a href="/a">Home</a> <a href="/b">Archive</a></nav>
+<div id="main">
+  <div class="article-body">
+    <h1>Brewing potions</h1>
+    <p>Every potion starts with water, drawn at dawn from a spring that has never seen the sun, or so the old books say.</p>
+    <p>Next comes the <a href="/herbs">herb</a> of your choice, crushed, sifted and weighed, since a pinch too much ruins the brew.</p>
+    <div class="share-links"><a href="/tw">Tweet</a> <a href="/fb">Share</a></div>
+    <p>Finally, stir clockwise until the mixture turns the color of a winter sky, then let it rest.</p>
+  </div>
+  <div class="sidebar"><p>Subscribe to our newsletter for more recipes, tips, and tricks from the guild.</p></div>
+</div>
+<div class="comments"><p>Great article, thanks for writing it up, I will try this tonight!</p></div>
+<footer>© The Alchemist</footer>
+</body></html>`
+
+func TestClipExtractsArticle(t *testing.T) {
+	source, _ := url.Parse("https://example.com/posts/potions")
+	clip, err := Clip(articlePage, "", source)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if clip.Title != "Brewing potions" {
+		t.Errorf("title = %q", clip.Title)
+	}
+
+	for _, want := range []string{
+		"# Brewing potions",
+		"Every potion starts with water",
+		"[herb](https://example.com/herbs)",
+		"Finally, stir clockwise",
+	} {
+		if !strings.Contains(clip.Content, want) {
+			t.Errorf("content is missing %q:\n%s", want, clip.Content)
+		}
+	}
+	for _, unwanted := range []string{"track()", "Archive", "Tweet", "newsletter", "Great article", "©"} {
+		if strings.Contains(clip.Content, unwanted) {
+			t.Errorf("content has %q:\n%s", unwanted, clip.Content)
+		}
+	}
+}
+
+func TestClipPrefersSelection(t *testing.T) {
+	source, _ := url.Parse("https://example.com/posts/potions")
+	clip, err := Clip(articlePage, `<p>Only <b>this</b> part</p>`, source)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if clip.Title != "Brewing potions" || clip.Content != "Only **this** part" {
+		t.Fatalf("clip = %+v", clip)
+	}
+
+	clip, _ = Clip("", "Just text", source)
+	if clip.Title != "example.com" || clip.Content != "Just text" {
+		t.Fatalf("clip without page = %+v", clip)
+	}
+}
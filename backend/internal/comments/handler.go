@@ -0,0 +1,287 @@
+package comments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/notes"
+	"github.com/jehufrayle/grimoire/internal/notifications"
+	"github.com/jehufrayle/grimoire/middleware"
+	"github.com/jehufrayle/grimoire/utils"
+)
+
+type Handler struct {
+	repo          CommentRepository
+	notes         notes.NoteRepository
+	notifications notifications.Emitter
+}
+
+func NewHandler(repo CommentRepository, noteRepo notes.NoteRepository, emitter notifications.Emitter) *Handler {
+	return &Handler{repo: repo, notes: noteRepo, notifications: emitter}
+}
+
+// readableNote loads the version of the note the user can read: the draft
+// for the owner and shared users, the published snapshot for everyone else,
+// and reports whether it is the draft. It writes the error response itself
+// and returns nil when the request should stop.
+func (h *Handler) readableNote(ctx context.Context, w http.ResponseWriter, noteID string, userID uuid.UUID) (*notes.Note, bool) {
+	note, err := h.notes.GetByID(ctx, noteID)
+	if err != nil || note == nil {
+		http.Error(w, "Note not found", http.StatusNotFound)
+		return nil, false
+	}
+	version, err := notes.ReadableVersion(ctx, h.notes, note, userID)
+	if err != nil {
+		http.Error(w, "Failed to check note access", http.StatusInternalServerError)
+		return nil, false
+	}
+	if version == nil {
+		http.Error(w, "Unauthorized access to this note", http.StatusForbidden)
+		return nil, false
+	}
+	return version, version == note
+}
+
+func currentUserID(r *http.Request) (uuid.UUID, bool) {
+	userIDstr, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(userIDstr)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func (h *Handler) GetNoteComments(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	note, draft := h.readableNote(r.Context(), w, r.PathValue("id"), userID)
+	if note == nil {
+		return
+	}
+
+	page, perPage := utils.Pagination(r, 20, 100)
+	threads, total, err := h.repo.ListByNote(r.Context(), note.ID, perPage, (page-1)*perPage)
+	if err != nil {
+		http.Error(w, "Failed to retrieve comments", http.StatusInternalServerError)
+		return
+	}
+	if threads == nil {
+		threads = []Comment{}
+	}
+	if !draft {
+		hideStaleAnchors(threads, note.Content)
+	}
+
+	utils.JSONResponse(w, Page{Comments: threads, Page: page, PerPage: perPage, Total: total}, http.StatusOK)
+}
+
+func (h *Handler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Body     string     `json:"body"`
+		ParentID *uuid.UUID `json:"parent_id"`
+		Anchor   *Anchor    `json:"anchor"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Body) == "" {
+		http.Error(w, "Comment body is required", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	note, _ := h.readableNote(r.Context(), w, r.PathValue("id"), userID)
+	if note == nil {
+		return
+	}
+
+	if req.Anchor != nil && note.Encrypted {
+		http.Error(w, notes.ErrEncryptedNote.Error(), http.StatusConflict)
+		return
+	}
+	if req.Anchor != nil {
+		content := []rune(note.Content)
+		a := req.Anchor
+		if a.Start < 0 || a.End <= a.Start || a.End > len(content) {
+			http.Error(w, "Anchor range is out of bounds", http.StatusBadRequest)
+			return
+		}
+		quote := string(content[a.Start:a.End])
+		if a.Quote == "" {
+			a.Quote = quote
+		} else if a.Quote != quote {
+			http.Error(w, "Anchor quote does not match the note content", http.StatusBadRequest)
+			return
+		}
+	}
+
+	comment := Comment{
+		NoteID:   note.ID,
+		UserID:   userID,
+		ParentID: req.ParentID,
+		Body:     req.Body,
+		Anchor:   req.Anchor,
+	}
+	if err := h.repo.Create(r.Context(), &comment); err != nil {
+		if errors.Is(err, ErrParentNotFound) {
+			http.Error(w, "Parent comment not found", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to create comment", http.StatusInternalServerError)
+		return
+	}
+	h.notify(r.Context(), note, &comment)
+
+	utils.JSONResponse(w, comment, http.StatusCreated)
+}
+
+// notify tells the note's owner about a new comment, and the author of the
+// comment it replies to. The comment is saved either way, so failures are
+// only logged.
+func (h *Handler) notify(ctx context.Context, note *notes.Note, comment *Comment) {
+	recipients := []uuid.UUID{note.UserID}
+	if comment.ParentID != nil {
+		if parent, err := h.repo.GetByID(ctx, *comment.ParentID); err == nil {
+			recipients = append(recipients, parent.UserID)
+		}
+	}
+
+	seen := map[uuid.UUID]bool{comment.UserID: true}
+	for _, userID := range recipients {
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+		// The author of the parent may have lost access to the note since
+		if readable, err := notes.CanRead(ctx, h.notes, note, userID); err != nil || !readable {
+			continue
+		}
+		notification := notifications.Notification{
+			UserID:    userID,
+			Type:      notifications.TypeComment,
+			ActorID:   &comment.UserID,
+			NoteID:    &note.ID,
+			CommentID: &comment.ID,
+		}
+		if err := h.notifications.Emit(ctx, &notification); err != nil {
+			log.Printf("❌ Failed to notify %s of comment %s: %v", userID, comment.ID, err)
+		}
+	}
+}
+
+func (h *Handler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Body     *string `json:"body"`
+		Resolved *bool   `json:"resolved"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	commentID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid comment ID format", http.StatusBadRequest)
+		return
+	}
+	comment, err := h.repo.GetByID(r.Context(), commentID)
+	if err != nil {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return
+	}
+
+	note, _ := h.readableNote(r.Context(), w, comment.NoteID.String(), userID)
+	if note == nil {
+		return
+	}
+	isAuthor := comment.UserID == userID
+	isModerator := note.UserID == userID
+
+	if req.Body != nil {
+		// Only the author may reword a comment
+		if !isAuthor {
+			http.Error(w, "Only the author can edit this comment", http.StatusForbidden)
+			return
+		}
+		if strings.TrimSpace(*req.Body) == "" {
+			http.Error(w, "Comment body is required", http.StatusBadRequest)
+			return
+		}
+		comment.Body = *req.Body
+	}
+	if req.Resolved != nil {
+		if !isAuthor && !isModerator {
+			http.Error(w, "Only the author or the note owner can resolve this comment", http.StatusForbidden)
+			return
+		}
+		comment.Resolved = *req.Resolved
+	}
+
+	if err := h.repo.Update(r.Context(), comment); err != nil {
+		http.Error(w, "Failed to update comment", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, comment, http.StatusOK)
+}
+
+func (h *Handler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	commentID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid comment ID format", http.StatusBadRequest)
+		return
+	}
+	comment, err := h.repo.GetByID(r.Context(), commentID)
+	if err != nil {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return
+	}
+
+	note, err := h.notes.GetByID(r.Context(), comment.NoteID.String())
+	if err != nil {
+		http.Error(w, "Note not found", http.StatusNotFound)
+		return
+	}
+	// The author can remove their own comment and the note owner can moderate any
+	if comment.UserID != userID && note.UserID != userID {
+		http.Error(w, "Only the author or the note owner can delete this comment", http.StatusForbidden)
+		return
+	}
+
+	if err := h.repo.Delete(r.Context(), commentID); err != nil {
+		http.Error(w, "Failed to delete comment", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
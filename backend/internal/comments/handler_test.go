@@ -0,0 +1,127 @@
+package comments
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/notes"
+	"github.com/jehufrayle/grimoire/internal/notifications"
+	"github.com/jehufrayle/grimoire/middleware"
+)
+
+// post sends a new comment on the note as userID and decodes the response.
+func post(t *testing.T, h *Handler, userID uuid.UUID, noteID uuid.UUID, body string) (int, Comment) {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/api/notes/"+noteID.String()+"/comments", strings.NewReader(body))
+	r.SetPathValue("id", noteID.String())
+	r = r.WithContext(context.WithValue(r.Context(), middleware.UserIDKey, userID.String()))
+	w := httptest.NewRecorder()
+	h.CreateComment(w, r)
+
+	var comment Comment
+	if w.Code == http.StatusCreated {
+		if err := json.NewDecoder(w.Body).Decode(&comment); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w.Code, comment
+}
+
+func TestCreateReply(t *testing.T) {
+	ctx := context.Background()
+	noteRepo := notes.NewInMemoryNoteRepository()
+	repo := NewInMemoryCommentRepository()
+	h := NewHandler(repo, noteRepo, notifications.NewCenter(notifications.NewInMemoryNotificationRepository()))
+
+	owner := uuid.New()
+	note := notes.Note{UserID: owner, Title: "Plans", Content: "Go to the coast"}
+	if err := noteRepo.Create(ctx, &note); err != nil {
+		t.Fatal(err)
+	}
+	other := notes.Note{UserID: owner, Title: "Other"}
+	if err := noteRepo.Create(ctx, &other); err != nil {
+		t.Fatal(err)
+	}
+
+	code, parent := post(t, h, owner, note.ID, `{"body": "Which coast?"}`)
+	if code != http.StatusCreated {
+		t.Fatalf("comment: %d", code)
+	}
+	code, reply := post(t, h, owner, note.ID, `{"body": "North", "parent_id": "`+parent.ID.String()+`"}`)
+	if code != http.StatusCreated || reply.ThreadID != parent.ID {
+		t.Fatalf("reply: %d, thread %v", code, reply.ThreadID)
+	}
+
+	// Replies must go to a live comment on the same note
+	if code, _ := post(t, h, owner, other.ID, `{"body": "Elsewhere", "parent_id": "`+parent.ID.String()+`"}`); code != http.StatusBadRequest {
+		t.Errorf("reply on another note: %d", code)
+	}
+	if err := repo.Delete(ctx, parent.ID); err != nil {
+		t.Fatal(err)
+	}
+	if code, _ := post(t, h, owner, note.ID, `{"body": "Too late", "parent_id": "`+parent.ID.String()+`"}`); code != http.StatusBadRequest {
+		t.Errorf("reply to a deleted comment: %d", code)
+	}
+}
+
+func TestAnchorsFollowReadableVersion(t *testing.T) {
+	ctx := context.Background()
+	noteRepo := notes.NewInMemoryNoteRepository()
+	h := NewHandler(NewInMemoryCommentRepository(), noteRepo, notifications.NewCenter(notifications.NewInMemoryNotificationRepository()))
+
+	owner, reader := uuid.New(), uuid.New()
+	note := notes.Note{UserID: owner, Title: "Plans", Content: "Go to the coast"}
+	if err := noteRepo.Create(ctx, &note); err != nil {
+		t.Fatal(err)
+	}
+	if err := noteRepo.Publish(ctx, note.ID.String()); err != nil {
+		t.Fatal(err)
+	}
+	draft, _ := noteRepo.GetByID(ctx, note.ID.String())
+	draft.Content = "Go to the mountains"
+	if err := noteRepo.Update(ctx, draft); err != nil {
+		t.Fatal(err)
+	}
+
+	// The reader sees the published text, not the draft
+	if code, _ := post(t, h, reader, note.ID, `{"body": "Which?", "anchor": {"start": 10, "end": 19}}`); code != http.StatusBadRequest {
+		t.Errorf("reader anchoring into the draft: %d", code)
+	}
+	code, comment := post(t, h, reader, note.ID, `{"body": "Which one?", "anchor": {"start": 10, "end": 15}}`)
+	if code != http.StatusCreated || comment.Anchor == nil || comment.Anchor.Quote != "coast" {
+		t.Fatalf("reader anchoring into the published note: %d, %+v", code, comment.Anchor)
+	}
+	if code, _ := post(t, h, owner, note.ID, `{"body": "Still unsure", "anchor": {"start": 10, "end": 19}}`); code != http.StatusCreated {
+		t.Fatalf("owner anchoring into the draft: %d", code)
+	}
+
+	list := func(userID uuid.UUID) map[string]*Anchor {
+		r := httptest.NewRequest(http.MethodGet, "/api/notes/"+note.ID.String()+"/comments", nil)
+		r.SetPathValue("id", note.ID.String())
+		r = r.WithContext(context.WithValue(r.Context(), middleware.UserIDKey, userID.String()))
+		w := httptest.NewRecorder()
+		h.GetNoteComments(w, r)
+		var page Page
+		if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+			t.Fatal(err)
+		}
+		anchors := make(map[string]*Anchor)
+		for _, c := range page.Comments {
+			anchors[c.Body] = c.Anchor
+		}
+		return anchors
+	}
+
+	// Quotes from the draft stay hidden from readers of the published note
+	if anchors := list(reader); anchors["Still unsure"] != nil || anchors["Which one?"] == nil {
+		t.Errorf("reader sees anchors %+v", anchors)
+	}
+	if anchors := list(owner); anchors["Still unsure"] == nil || anchors["Which one?"] == nil {
+		t.Errorf("owner sees anchors %+v", anchors)
+	}
+}
@@ -0,0 +1,185 @@
+package comments
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/journal"
+)
+
+// InMemoryCommentRepository keeps comments in memory. Opened with
+// OpenInMemoryCommentRepository it also persists them to disk.
+type InMemoryCommentRepository struct {
+	mu       sync.RWMutex
+	comments map[uuid.UUID]*Comment
+	journal  *journal.Store[[]Comment, *Comment] // nil when not persisted
+}
+
+func NewInMemoryCommentRepository() *InMemoryCommentRepository {
+	return &InMemoryCommentRepository{
+		comments: make(map[uuid.UUID]*Comment),
+	}
+}
+
+func (r *InMemoryCommentRepository) GetByID(ctx context.Context, id uuid.UUID) (*Comment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.comments[id]
+	if !ok || c.DeletedAt != nil {
+		return nil, errors.New("comment not found")
+	}
+	result := *c
+	return &result, nil
+}
+
+func (r *InMemoryCommentRepository) ListByNote(ctx context.Context, noteID uuid.UUID, limit, offset int) ([]Comment, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	liveReplies := make(map[uuid.UUID]bool)
+	var replies []Comment
+	for _, c := range r.comments {
+		if c.NoteID != noteID || c.ParentID == nil {
+			continue
+		}
+		replies = append(replies, *c)
+		if c.DeletedAt == nil {
+			liveReplies[c.ThreadID] = true
+		}
+	}
+
+	var roots []Comment
+	for _, c := range r.comments {
+		if c.NoteID == noteID && c.ParentID == nil && (c.DeletedAt == nil || liveReplies[c.ID]) {
+			roots = append(roots, *c)
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i].CreatedAt.Before(roots[j].CreatedAt) })
+	sort.Slice(replies, func(i, j int) bool { return replies[i].CreatedAt.Before(replies[j].CreatedAt) })
+
+	total := len(roots)
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+
+	return buildThreads(roots[offset:end], replies), total, nil
+}
+
+func (r *InMemoryCommentRepository) Create(ctx context.Context, comment *Comment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comment.ID = uuid.New()
+	comment.ThreadID = comment.ID
+	if comment.ParentID != nil {
+		parent, ok := r.comments[*comment.ParentID]
+		if !ok || parent.NoteID != comment.NoteID || parent.DeletedAt != nil {
+			return ErrParentNotFound
+		}
+		comment.ThreadID = parent.ThreadID
+	}
+	now := time.Now()
+	comment.CreatedAt = now
+	comment.UpdatedAt = now
+	comment.DeletedAt = nil
+
+	stored := *comment
+	r.comments[comment.ID] = &stored
+	if err := r.journal.Log(&stored); err != nil {
+		delete(r.comments, comment.ID)
+		return err
+	}
+	return nil
+}
+
+func (r *InMemoryCommentRepository) Update(ctx context.Context, comment *Comment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.comments[comment.ID]
+	if !ok || existing.DeletedAt != nil {
+		return errors.New("comment not found")
+	}
+
+	before := *existing
+	existing.Body = comment.Body
+	existing.Resolved = comment.Resolved
+	existing.UpdatedAt = time.Now()
+	if err := r.journal.Log(existing); err != nil {
+		*existing = before
+		return err
+	}
+	*comment = *existing
+	return nil
+}
+
+func (r *InMemoryCommentRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.comments[id]
+	if !ok || c.DeletedAt != nil {
+		return errors.New("comment not found or already deleted")
+	}
+
+	before := *c
+	now := time.Now()
+	c.DeletedAt = &now
+	c.UpdatedAt = now
+	if err := r.journal.Log(c); err != nil {
+		*c = before
+		return err
+	}
+	return nil
+}
+
+// OpenInMemoryCommentRepository returns an InMemoryCommentRepository
+// persisted in dir. It starts from what dir holds and logs every write there
+// before returning; each entry is a comment as it now is. Snapshot keeps the
+// log short and Close takes a last snapshot.
+func OpenInMemoryCommentRepository(dir string) (*InMemoryCommentRepository, error) {
+	r := NewInMemoryCommentRepository()
+	j, err := journal.OpenStore(dir, &r.mu, r.all,
+		func(comments []Comment) {
+			for i := range comments {
+				r.comments[comments[i].ID] = &comments[i]
+			}
+		},
+		func(c *Comment) {
+			r.comments[c.ID] = c
+		})
+	if err != nil {
+		return nil, err
+	}
+	r.journal = j
+	return r, nil
+}
+
+// Callers must hold at least a read lock.
+func (r *InMemoryCommentRepository) all() []Comment {
+	comments := make([]Comment, 0, len(r.comments))
+	for _, c := range r.comments {
+		comments = append(comments, *c)
+	}
+	return comments
+}
+
+// Snapshot writes every comment to disk and empties the log. It is a no-op
+// when the repository is not persisted.
+func (r *InMemoryCommentRepository) Snapshot() error {
+	return r.journal.Snapshot()
+}
+
+// Close takes a last snapshot and closes the journal.
+func (r *InMemoryCommentRepository) Close() error {
+	return r.journal.Close()
+}
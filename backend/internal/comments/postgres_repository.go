@@ -0,0 +1,205 @@
+package comments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const selectCommentQuery = `
+SELECT
+    c.id,
+    c.note_id,
+    c.user_id,
+    c.parent_id,
+    c.thread_id,
+    c.body,
+    c.anchor_quote,
+    c.anchor_start,
+    c.anchor_end,
+    c.resolved,
+    c.created_at,
+    c.updated_at,
+    c.deleted_at
+FROM
+    comments c
+`
+
+// rowScanner is satisfied by both pgx.Row and pgx.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanComment(row rowScanner) (Comment, error) {
+	var c Comment
+	var quote *string
+	var start, end *int
+	err := row.Scan(&c.ID, &c.NoteID, &c.UserID, &c.ParentID, &c.ThreadID, &c.Body, &quote, &start, &end,
+		&c.Resolved, &c.CreatedAt, &c.UpdatedAt, &c.DeletedAt)
+	if err != nil {
+		return c, err
+	}
+	if quote != nil && start != nil && end != nil {
+		c.Anchor = &Anchor{Quote: *quote, Start: *start, End: *end}
+	}
+	return c, nil
+}
+
+func collectComments(rows pgx.Rows) ([]Comment, error) {
+	defer rows.Close()
+
+	var comments []Comment
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment row: %w", err)
+		}
+		comments = append(comments, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return comments, nil
+}
+
+// PgCommentRepository implements the CommentRepository interface for PostgreSQL.
+type PgCommentRepository struct {
+	DB *pgxpool.Pool
+}
+
+// NewPgCommentRepository creates a new instance of PgCommentRepository.
+func NewPgCommentRepository(db *pgxpool.Pool) *PgCommentRepository {
+	return &PgCommentRepository{DB: db}
+}
+
+// GetByID retrieves a single non-deleted comment.
+func (r *PgCommentRepository) GetByID(ctx context.Context, id uuid.UUID) (*Comment, error) {
+	row := r.DB.QueryRow(ctx, selectCommentQuery+" WHERE c.id = $1 AND c.deleted_at IS NULL", id)
+	c, err := scanComment(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("comment not found")
+		}
+		return nil, fmt.Errorf("failed to scan comment: %w", err)
+	}
+	return &c, nil
+}
+
+// ListByNote retrieves a page of threads for a note. Deleted top-level
+// comments are kept while they still have live replies.
+func (r *PgCommentRepository) ListByNote(ctx context.Context, noteID uuid.UUID, limit, offset int) ([]Comment, int, error) {
+	rootFilter := `
+        WHERE c.note_id = $1 AND c.parent_id IS NULL
+          AND (c.deleted_at IS NULL OR EXISTS (
+              SELECT 1 FROM comments r
+              WHERE r.thread_id = c.id AND r.id <> c.id AND r.deleted_at IS NULL
+          ))`
+
+	var total int
+	if err := r.DB.QueryRow(ctx, "SELECT COUNT(*) FROM comments c"+rootFilter, noteID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count comments: %w", err)
+	}
+
+	rows, err := r.DB.Query(ctx, selectCommentQuery+rootFilter+" ORDER BY c.created_at LIMIT $2 OFFSET $3", noteID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query comments: %w", err)
+	}
+	roots, err := collectComments(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(roots) == 0 {
+		return []Comment{}, total, nil
+	}
+
+	threadIDs := make([]uuid.UUID, len(roots))
+	for i, root := range roots {
+		threadIDs[i] = root.ID
+	}
+	rows, err = r.DB.Query(ctx, selectCommentQuery+" WHERE c.thread_id = ANY($1) AND c.parent_id IS NOT NULL ORDER BY c.created_at", threadIDs)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query replies: %w", err)
+	}
+	replies, err := collectComments(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return buildThreads(roots, replies), total, nil
+}
+
+// Create inserts a comment. Replies inherit the thread of their parent.
+func (r *PgCommentRepository) Create(ctx context.Context, comment *Comment) error {
+	comment.ID = uuid.New()
+	comment.ThreadID = comment.ID
+	if comment.ParentID != nil {
+		err := r.DB.QueryRow(ctx, `SELECT thread_id FROM comments WHERE id = $1 AND note_id = $2 AND deleted_at IS NULL`, *comment.ParentID, comment.NoteID).
+			Scan(&comment.ThreadID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrParentNotFound
+			}
+			return fmt.Errorf("failed to look up parent comment: %w", err)
+		}
+	}
+
+	var quote *string
+	var start, end *int
+	if comment.Anchor != nil {
+		quote, start, end = &comment.Anchor.Quote, &comment.Anchor.Start, &comment.Anchor.End
+	}
+
+	query := `
+        INSERT INTO comments (id, note_id, user_id, parent_id, thread_id, body, anchor_quote, anchor_start, anchor_end)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        RETURNING resolved, created_at, updated_at`
+	err := r.DB.QueryRow(ctx, query, comment.ID, comment.NoteID, comment.UserID, comment.ParentID, comment.ThreadID,
+		comment.Body, quote, start, end).Scan(&comment.Resolved, &comment.CreatedAt, &comment.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign key violation
+			return fmt.Errorf("note or user not found: %w", err)
+		}
+		return fmt.Errorf("failed to insert comment: %w", err)
+	}
+	return nil
+}
+
+// Update changes the body and resolved state of a comment.
+func (r *PgCommentRepository) Update(ctx context.Context, comment *Comment) error {
+	query := `
+        UPDATE comments
+        SET body = $1, resolved = $2, updated_at = now()
+        WHERE id = $3 AND deleted_at IS NULL
+        RETURNING updated_at`
+	err := r.DB.QueryRow(ctx, query, comment.Body, comment.Resolved, comment.ID).Scan(&comment.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("comment not found")
+		}
+		return fmt.Errorf("failed to update comment: %w", err)
+	}
+	return nil
+}
+
+// Delete performs a soft delete on a comment.
+func (r *PgCommentRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := `
+        UPDATE comments
+        SET deleted_at = now(), updated_at = now()
+        WHERE id = $1 AND deleted_at IS NULL`
+
+	result, err := r.DB.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to soft delete comment: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("comment not found or already deleted")
+	}
+	return nil
+}
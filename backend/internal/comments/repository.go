@@ -0,0 +1,23 @@
+package comments
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// ErrParentNotFound is returned by Create for a reply to a comment that is
+// not on the same note or has been deleted.
+var ErrParentNotFound = errors.New("parent comment not found")
+
+// Interface
+type CommentRepository interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*Comment, error)
+	// ListByNote returns a page of threads for the note along with the total
+	// number of threads.
+	ListByNote(ctx context.Context, noteID uuid.UUID, limit, offset int) ([]Comment, int, error)
+	Create(ctx context.Context, comment *Comment) error
+	Update(ctx context.Context, comment *Comment) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
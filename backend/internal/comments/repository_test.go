@@ -0,0 +1,91 @@
+package comments
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/database/sqlitetest"
+	"github.com/jehufrayle/grimoire/internal/journal/journaltest"
+	"github.com/jehufrayle/grimoire/internal/notes"
+)
+
+func TestPersistedMemoryRepositoryReopens(t *testing.T) {
+	ctx := context.Background()
+	noteID, userID := uuid.New(), uuid.New()
+	root := Comment{NoteID: noteID, UserID: userID, Body: "Which coast?", Anchor: &Anchor{Quote: "coast", Start: 10, End: 15}}
+	reopened := journaltest.Reopen(t, OpenInMemoryCommentRepository, func(repo *InMemoryCommentRepository) {
+		if err := repo.Create(ctx, &root); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Snapshot(); err != nil {
+			t.Fatal(err)
+		}
+		// Changes after the snapshot only live in the log
+		reply := Comment{NoteID: noteID, UserID: userID, ParentID: &root.ID, Body: "North"}
+		if err := repo.Create(ctx, &reply); err != nil {
+			t.Fatal(err)
+		}
+		gone := Comment{NoteID: noteID, UserID: userID, Body: "Never mind"}
+		if err := repo.Create(ctx, &gone); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Delete(ctx, gone.ID); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	threads, total, err := reopened.ListByNote(ctx, noteID, 10, 0)
+	if err != nil || total != 1 || threads[0].Anchor == nil || *threads[0].Anchor != *root.Anchor || len(threads[0].Replies) != 1 {
+		t.Fatalf("threads = %+v, %d, %v", threads, total, err)
+	}
+	if threads[0].Replies[0].Body != "North" || threads[0].Replies[0].ThreadID != root.ID {
+		t.Fatalf("reply = %+v", threads[0].Replies[0])
+	}
+}
+
+func TestSQLiteRepository(t *testing.T) {
+	ctx := context.Background()
+	db := sqlitetest.Open(t)
+	userID := sqlitetest.NewUser(t, db)
+	note := notes.Note{UserID: userID, Title: "Plans", Content: "Go to the coast"}
+	if err := notes.NewSQLiteNoteRepository(db).Create(ctx, &note); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewSQLiteCommentRepository(db)
+	root := Comment{NoteID: note.ID, UserID: userID, Body: "Which coast?", Anchor: &Anchor{Quote: "coast", Start: 10, End: 15}}
+	if err := repo.Create(ctx, &root); err != nil {
+		t.Fatal(err)
+	}
+	reply := Comment{NoteID: note.ID, UserID: userID, ParentID: &root.ID, Body: "North"}
+	if err := repo.Create(ctx, &reply); err != nil || reply.ThreadID != root.ID {
+		t.Fatalf("reply = %+v, %v", reply, err)
+	}
+	stray := Comment{NoteID: note.ID, UserID: userID, ParentID: &note.ID, Body: "?"}
+	if err := repo.Create(ctx, &stray); err != ErrParentNotFound {
+		t.Fatalf("reply to a missing comment: %v", err)
+	}
+	if err := repo.Create(ctx, &Comment{NoteID: uuid.New(), UserID: userID, Body: "?"}); err == nil {
+		t.Fatal("comment on a missing note was stored")
+	}
+
+	if got, err := repo.GetByID(ctx, root.ID); err != nil || got.Anchor == nil || *got.Anchor != *root.Anchor {
+		t.Fatalf("root = %+v, %v", got, err)
+	}
+
+	// A deleted top-level comment stays while it has live replies
+	if err := repo.Delete(ctx, root.ID); err != nil {
+		t.Fatal(err)
+	}
+	threads, total, err := repo.ListByNote(ctx, note.ID, 10, 0)
+	if err != nil || total != 1 || threads[0].DeletedAt == nil || len(threads[0].Replies) != 1 {
+		t.Fatalf("threads = %+v, %d, %v", threads, total, err)
+	}
+	if err := repo.Delete(ctx, reply.ID); err != nil {
+		t.Fatal(err)
+	}
+	if threads, total, err := repo.ListByNote(ctx, note.ID, 10, 0); err != nil || total != 0 || len(threads) != 0 {
+		t.Fatalf("threads = %+v, %d, %v", threads, total, err)
+	}
+}
@@ -0,0 +1,212 @@
+package comments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/database"
+)
+
+const selectSQLiteCommentQuery = `
+SELECT
+    c.id,
+    c.note_id,
+    c.user_id,
+    c.parent_id,
+    c.thread_id,
+    c.body,
+    c.anchor_quote,
+    c.anchor_start,
+    c.anchor_end,
+    c.resolved,
+    c.created_at,
+    c.updated_at,
+    c.deleted_at
+FROM
+    comments c
+`
+
+func scanSQLiteComment(row rowScanner) (Comment, error) {
+	var c Comment
+	var quote *string
+	var start, end *int
+	err := row.Scan(&c.ID, &c.NoteID, &c.UserID, &c.ParentID, &c.ThreadID, &c.Body, &quote, &start, &end,
+		&c.Resolved, database.ScanTime{T: &c.CreatedAt}, database.ScanTime{T: &c.UpdatedAt}, database.ScanNullTime{T: &c.DeletedAt})
+	if err != nil {
+		return c, err
+	}
+	if quote != nil && start != nil && end != nil {
+		c.Anchor = &Anchor{Quote: *quote, Start: *start, End: *end}
+	}
+	return c, nil
+}
+
+func collectSQLiteComments(rows *sql.Rows) ([]Comment, error) {
+	defer rows.Close()
+
+	var comments []Comment
+	for rows.Next() {
+		c, err := scanSQLiteComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment row: %w", err)
+		}
+		comments = append(comments, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return comments, nil
+}
+
+// SQLiteCommentRepository implements the CommentRepository interface for the
+// embedded SQLite backend.
+type SQLiteCommentRepository struct {
+	DB *sql.DB
+}
+
+// NewSQLiteCommentRepository creates a new instance of SQLiteCommentRepository.
+func NewSQLiteCommentRepository(db *sql.DB) *SQLiteCommentRepository {
+	return &SQLiteCommentRepository{DB: db}
+}
+
+// GetByID retrieves a single non-deleted comment.
+func (r *SQLiteCommentRepository) GetByID(ctx context.Context, id uuid.UUID) (*Comment, error) {
+	row := r.DB.QueryRowContext(ctx, selectSQLiteCommentQuery+" WHERE c.id = ? AND c.deleted_at IS NULL", id)
+	c, err := scanSQLiteComment(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("comment not found")
+		}
+		return nil, fmt.Errorf("failed to scan comment: %w", err)
+	}
+	return &c, nil
+}
+
+// ListByNote retrieves a page of threads for a note. Deleted top-level
+// comments are kept while they still have live replies.
+func (r *SQLiteCommentRepository) ListByNote(ctx context.Context, noteID uuid.UUID, limit, offset int) ([]Comment, int, error) {
+	rootFilter := `
+        WHERE c.note_id = ? AND c.parent_id IS NULL
+          AND (c.deleted_at IS NULL OR EXISTS (
+              SELECT 1 FROM comments r
+              WHERE r.thread_id = c.id AND r.id <> c.id AND r.deleted_at IS NULL
+          ))`
+
+	var total int
+	if err := r.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM comments c"+rootFilter, noteID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count comments: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, selectSQLiteCommentQuery+rootFilter+" ORDER BY c.created_at LIMIT ? OFFSET ?", noteID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query comments: %w", err)
+	}
+	roots, err := collectSQLiteComments(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(roots) == 0 {
+		return []Comment{}, total, nil
+	}
+
+	threadIDs := make([]any, len(roots))
+	for i, root := range roots {
+		threadIDs[i] = root.ID
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(roots)), ", ")
+	rows, err = r.DB.QueryContext(ctx, selectSQLiteCommentQuery+" WHERE c.thread_id IN ("+placeholders+") AND c.parent_id IS NOT NULL ORDER BY c.created_at", threadIDs...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query replies: %w", err)
+	}
+	replies, err := collectSQLiteComments(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return buildThreads(roots, replies), total, nil
+}
+
+// Create inserts a comment. Replies inherit the thread of their parent.
+func (r *SQLiteCommentRepository) Create(ctx context.Context, comment *Comment) error {
+	id := uuid.New()
+	threadID := id
+	if comment.ParentID != nil {
+		err := r.DB.QueryRowContext(ctx, `SELECT thread_id FROM comments WHERE id = ? AND note_id = ? AND deleted_at IS NULL`, *comment.ParentID, comment.NoteID).
+			Scan(&threadID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrParentNotFound
+			}
+			return fmt.Errorf("failed to look up parent comment: %w", err)
+		}
+	}
+
+	var quote *string
+	var start, end *int
+	if comment.Anchor != nil {
+		quote, start, end = &comment.Anchor.Quote, &comment.Anchor.Start, &comment.Anchor.End
+	}
+
+	created := time.Now().UTC()
+	now := database.Timestamp(created)
+	query := `
+        INSERT INTO comments (id, note_id, user_id, parent_id, thread_id, body, anchor_quote, anchor_start, anchor_end, created_at, updated_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := r.DB.ExecContext(ctx, query, id, comment.NoteID, comment.UserID, comment.ParentID, threadID,
+		comment.Body, quote, start, end, now, now)
+	if err != nil {
+		if database.IsSQLiteConstraint(err, "FOREIGN KEY constraint failed") {
+			return fmt.Errorf("note or user not found: %w", err)
+		}
+		return fmt.Errorf("failed to insert comment: %w", err)
+	}
+
+	comment.ID = id
+	comment.ThreadID = threadID
+	comment.Resolved = false
+	comment.CreatedAt = created
+	comment.UpdatedAt = created
+	comment.DeletedAt = nil
+	return nil
+}
+
+// Update changes the body and resolved state of a comment.
+func (r *SQLiteCommentRepository) Update(ctx context.Context, comment *Comment) error {
+	updated := time.Now().UTC()
+	query := `
+        UPDATE comments
+        SET body = ?, resolved = ?, updated_at = ?
+        WHERE id = ? AND deleted_at IS NULL`
+	result, err := r.DB.ExecContext(ctx, query, comment.Body, comment.Resolved, database.Timestamp(updated), comment.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update comment: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return fmt.Errorf("comment not found")
+	}
+	comment.UpdatedAt = updated
+	return nil
+}
+
+// Delete performs a soft delete on a comment.
+func (r *SQLiteCommentRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	now := database.Timestamp(time.Now())
+	query := `
+        UPDATE comments
+        SET deleted_at = ?, updated_at = ?
+        WHERE id = ? AND deleted_at IS NULL`
+
+	result, err := r.DB.ExecContext(ctx, query, now, now, id)
+	if err != nil {
+		return fmt.Errorf("failed to soft delete comment: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return fmt.Errorf("comment not found or already deleted")
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package comments
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Comment struct {
+	ID        uuid.UUID  `json:"id"`
+	NoteID    uuid.UUID  `json:"note_id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	ParentID  *uuid.UUID `json:"parent_id,omitempty"`
+	ThreadID  uuid.UUID  `json:"thread_id"` // ID of the top-level comment of the thread
+	Body      string     `json:"body"`
+	Anchor    *Anchor    `json:"anchor,omitempty"`
+	Resolved  bool       `json:"resolved"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	Replies   []Comment  `json:"replies,omitempty"`
+}
+
+// Anchor ties a comment to a quoted range of the note content.
+// Start and End are rune offsets, End being exclusive.
+type Anchor struct {
+	Quote string `json:"quote"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// Page is a page of top-level threads with their replies nested.
+type Page struct {
+	Comments []Comment `json:"comments"`
+	Page     int       `json:"page"`
+	PerPage  int       `json:"per_page"`
+	Total    int       `json:"total"`
+}
+
+// buildThreads nests replies under their parents. Deleted comments are kept
+// as tombstones with an empty body so their replies stay in place.
+func buildThreads(roots []Comment, replies []Comment) []Comment {
+	children := make(map[uuid.UUID][]Comment)
+	for _, c := range replies {
+		if c.ParentID != nil {
+			children[*c.ParentID] = append(children[*c.ParentID], c)
+		}
+	}
+
+	var attach func(c Comment) Comment
+	attach = func(c Comment) Comment {
+		if c.DeletedAt != nil {
+			c.Body = ""
+			c.Anchor = nil
+		}
+		for _, child := range children[c.ID] {
+			c.Replies = append(c.Replies, attach(child))
+		}
+		return c
+	}
+
+	threads := make([]Comment, len(roots))
+	for i, root := range roots {
+		threads[i] = attach(root)
+	}
+	return threads
+}
+
+// hideStaleAnchors drops anchors whose quote is not at its range in content,
+// so readers of the published version never see quotes from the draft.
+func hideStaleAnchors(comments []Comment, content string) {
+	runes := []rune(content)
+	for i := range comments {
+		if a := comments[i].Anchor; a != nil {
+			if a.Start < 0 || a.End > len(runes) || a.Start >= a.End || string(runes[a.Start:a.End]) != a.Quote {
+				comments[i].Anchor = nil
+			}
+		}
+		hideStaleAnchors(comments[i].Replies, content)
+	}
+}
@@ -0,0 +1,150 @@
+package database
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // pure Go, so the binary stays cgo-free
+)
+
+//go:embed sqlite/*.sql
+var sqliteMigrations embed.FS
+
+// OpenSQLite opens the SQLite database at path, creating it if needed, and
+// applies the embedded migrations it has not seen yet.
+func OpenSQLite(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+
+	// SQLite allows one writer at a time, and pragmas are per connection.
+	// A single connection serializes writes in-process and keeps the
+	// pragmas in force.
+	db.SetMaxOpenConns(1)
+	pragmas := []string{"PRAGMA foreign_keys = ON", "PRAGMA busy_timeout = 5000", "PRAGMA journal_mode = WAL"}
+	for _, pragma := range pragmas {
+		if _, err := db.Exec(pragma); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to set %q: %w", pragma, err)
+		}
+	}
+
+	if err := migrateSQLite(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	log.Printf("🐉 Opened SQLite database %s", path)
+	return db, nil
+}
+
+// migrateSQLite runs the numbered migrations above the database's
+// user_version, each in its own transaction.
+func migrateSQLite(db *sql.DB) error {
+	names, err := fs.Glob(sqliteMigrations, "sqlite/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for i, name := range names[min(version, len(names)):] {
+		script, err := sqliteMigrations.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		if _, err := tx.Exec(string(script)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply %s: %w", name, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version+i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record schema version: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to apply %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// timestampLayout is how the SQLite schema stores times: fixed-width UTC
+// text, so that comparing or ordering the text compares the times.
+const timestampLayout = "2006-01-02T15:04:05.000000000Z"
+
+// Timestamp formats t for a SQLite timestamp column.
+func Timestamp(t time.Time) string {
+	return t.UTC().Format(timestampLayout)
+}
+
+// NullTimestamp formats t for a nullable SQLite timestamp column.
+func NullTimestamp(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return Timestamp(*t)
+}
+
+// ScanTime scans a SQLite timestamp column into T.
+type ScanTime struct{ T *time.Time }
+
+func (s ScanTime) Scan(src any) error {
+	text, ok := columnText(src)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into a time", src)
+	}
+	t, err := time.Parse(timestampLayout, text)
+	if err != nil {
+		return err
+	}
+	*s.T = t
+	return nil
+}
+
+// ScanNullTime scans a nullable SQLite timestamp column into T.
+type ScanNullTime struct{ T **time.Time }
+
+func (s ScanNullTime) Scan(src any) error {
+	if src == nil {
+		*s.T = nil
+		return nil
+	}
+	var t time.Time
+	if err := (ScanTime{&t}).Scan(src); err != nil {
+		return err
+	}
+	*s.T = &t
+	return nil
+}
+
+func columnText(src any) (string, bool) {
+	switch v := src.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	}
+	return "", false
+}
+
+// IsSQLiteConstraint reports whether err is SQLite refusing a write with
+// the given constraint message, such as "UNIQUE constraint failed: tags.name".
+// Drivers wrap the error in their own types but all keep SQLite's message.
+func IsSQLiteConstraint(err error, message string) bool {
+	return err != nil && strings.Contains(err.Error(), message)
+}
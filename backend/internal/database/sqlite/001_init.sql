@@ -0,0 +1,139 @@
+-- Grimoire schema for the embedded SQLite backend. It mirrors the PostgreSQL
+-- schema and its migrations for the tables the SQLite repositories use.
+--
+-- Times are stored as fixed-width UTC text (see database.Timestamp) so that
+-- comparing and ordering the text compares and orders the times. UUIDs are
+-- stored as text and booleans as 0/1.
+
+CREATE TABLE users (
+    id text PRIMARY KEY,
+    username text NOT NULL UNIQUE,
+    email text NOT NULL UNIQUE,
+    password_hash text DEFAULT '' NOT NULL,
+    role text NOT NULL CHECK (role IN ('admin', 'user', 'guest')),
+    active integer DEFAULT 1 NOT NULL,
+    created_at text NOT NULL,
+    updated_at text NOT NULL,
+    deleted_at text,
+    last_login text,
+    timezone text DEFAULT 'UTC' NOT NULL,
+    daily_template text DEFAULT '' NOT NULL,
+    calendar_token_hash text UNIQUE
+);
+
+CREATE VIEW active_users AS SELECT * FROM users WHERE deleted_at IS NULL;
+
+CREATE TABLE profiles (
+    user_id text PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    first_name text,
+    last_name text,
+    bio text,
+    avatar_url text
+);
+
+CREATE TABLE links (
+    id integer PRIMARY KEY,
+    user_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    url text NOT NULL,
+    title text,
+    icon text,
+    active integer DEFAULT 1 NOT NULL
+);
+
+-- seq gives the full-text index a rowid that VACUUM cannot renumber.
+CREATE TABLE notes (
+    seq integer PRIMARY KEY,
+    id text NOT NULL UNIQUE,
+    user_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    title text NOT NULL,
+    content text NOT NULL,
+    is_public integer DEFAULT 0 NOT NULL,
+    created_at text NOT NULL,
+    updated_at text NOT NULL,
+    deleted_at text,
+    forked_from_note_id text REFERENCES notes (id) ON DELETE SET NULL,
+    forked_from_user_id text REFERENCES users (id) ON DELETE SET NULL,
+    envelope text,
+    properties text DEFAULT '{}' NOT NULL,
+    published_title text,
+    published_content text,
+    published_at text,
+    publish_at text,
+    expires_at text,
+    expire_action text CHECK (expire_action IN ('delete', 'unpublish')),
+    schedule_lease_until text,
+    daily_date text,
+    remind_at text,
+    version integer DEFAULT 1 NOT NULL,
+    CHECK (expires_at IS NULL OR expire_action IS NOT NULL)
+);
+
+CREATE INDEX notes_user_id_idx ON notes (user_id, updated_at DESC) WHERE deleted_at IS NULL;
+CREATE INDEX notes_forked_from_note_id_idx ON notes (forked_from_note_id) WHERE forked_from_note_id IS NOT NULL;
+CREATE INDEX notes_schedule_idx ON notes (publish_at, expires_at) WHERE publish_at IS NOT NULL OR expires_at IS NOT NULL;
+CREATE UNIQUE INDEX notes_user_id_daily_date_idx ON notes (user_id, daily_date) WHERE daily_date IS NOT NULL AND deleted_at IS NULL;
+
+CREATE VIEW active_notes AS SELECT * FROM notes WHERE deleted_at IS NULL;
+
+-- Substring search over titles and content. The trigram tokenizer matches
+-- anywhere inside words, like the ILIKE search on PostgreSQL.
+CREATE VIRTUAL TABLE notes_fts USING fts5 (
+    title,
+    content,
+    content = 'notes',
+    content_rowid = 'seq',
+    tokenize = 'trigram'
+);
+
+CREATE TRIGGER notes_fts_insert AFTER INSERT ON notes BEGIN
+    INSERT INTO notes_fts (rowid, title, content) VALUES (new.seq, new.title, new.content);
+END;
+
+CREATE TRIGGER notes_fts_delete AFTER DELETE ON notes BEGIN
+    INSERT INTO notes_fts (notes_fts, rowid, title, content) VALUES ('delete', old.seq, old.title, old.content);
+END;
+
+CREATE TRIGGER notes_fts_update AFTER UPDATE OF title, content ON notes BEGIN
+    INSERT INTO notes_fts (notes_fts, rowid, title, content) VALUES ('delete', old.seq, old.title, old.content);
+    INSERT INTO notes_fts (rowid, title, content) VALUES (new.seq, new.title, new.content);
+END;
+
+CREATE TABLE tags (
+    id text PRIMARY KEY,
+    name text NOT NULL UNIQUE
+);
+
+CREATE TABLE note_tags (
+    note_id text NOT NULL REFERENCES notes (id) ON DELETE CASCADE,
+    tag_id text NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (note_id, tag_id)
+);
+
+CREATE INDEX note_tags_tag_id_idx ON note_tags (tag_id);
+
+CREATE TABLE shared_notes (
+    note_id text NOT NULL REFERENCES notes (id) ON DELETE CASCADE,
+    shared_with_user_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    shared_at text,
+    can_edit integer DEFAULT 0,
+    PRIMARY KEY (note_id, shared_with_user_id)
+);
+
+CREATE TABLE note_slugs (
+    user_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    slug text NOT NULL,
+    note_id text NOT NULL REFERENCES notes (id) ON DELETE CASCADE,
+    assigned_at text NOT NULL,
+    PRIMARY KEY (user_id, slug)
+);
+
+CREATE INDEX note_slugs_note_id_idx ON note_slugs (note_id, assigned_at DESC);
+
+CREATE TABLE note_locks (
+    note_id text PRIMARY KEY REFERENCES notes (id) ON DELETE CASCADE,
+    user_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token text NOT NULL,
+    owner text DEFAULT '' NOT NULL,
+    version integer NOT NULL,
+    expires_at text NOT NULL
+);
@@ -0,0 +1,6 @@
+-- The per-user inbox address emails are forwarded to. As in PostgreSQL,
+-- only a hash of the token in the address is stored.
+
+ALTER TABLE users ADD COLUMN inbox_token_hash text;
+
+CREATE UNIQUE INDEX users_inbox_token_hash_idx ON users (inbox_token_hash);
@@ -0,0 +1,6 @@
+-- Opt-in weekly digest emails. digest_week is the Monday, as YYYY-MM-DD,
+-- starting the last week whose digest was sent.
+
+ALTER TABLE users ADD COLUMN weekly_digest integer DEFAULT 0 NOT NULL;
+
+ALTER TABLE users ADD COLUMN digest_week text;
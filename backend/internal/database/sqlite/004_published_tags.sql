@@ -0,0 +1,10 @@
+-- Published snapshots keep the tags the note had when it was published, as a
+-- JSON array like the tags column of the note queries.
+
+ALTER TABLE notes ADD COLUMN published_tags text;
+
+UPDATE notes
+    SET published_tags = (
+        SELECT json_group_array(json_object('id', t.id, 'name', t.name))
+        FROM note_tags nt JOIN tags t ON nt.tag_id = t.id WHERE nt.note_id = notes.id)
+    WHERE published_at IS NOT NULL;
@@ -0,0 +1,89 @@
+-- The rest of the PostgreSQL schema: saved searches, property definitions,
+-- comments, attachments, mentions and notifications, which SQLite
+-- deployments used to keep in memory only. JSON columns hold what
+-- PostgreSQL keeps as jsonb or arrays.
+
+CREATE TABLE saved_searches (
+    id text PRIMARY KEY,
+    user_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name text NOT NULL,
+    filter text DEFAULT '{}' NOT NULL,
+    created_at text NOT NULL,
+    updated_at text NOT NULL
+);
+
+CREATE INDEX saved_searches_user_id_idx ON saved_searches (user_id);
+
+CREATE TABLE property_definitions (
+    user_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name text NOT NULL,
+    type text NOT NULL CHECK (type IN ('text', 'select', 'number', 'date', 'url')),
+    options text DEFAULT '[]' NOT NULL,
+    created_at text NOT NULL,
+    PRIMARY KEY (user_id, name)
+);
+
+-- thread_id is the id of the top-level comment.
+CREATE TABLE comments (
+    id text PRIMARY KEY,
+    note_id text NOT NULL REFERENCES notes (id) ON DELETE CASCADE,
+    user_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    parent_id text REFERENCES comments (id) ON DELETE CASCADE,
+    thread_id text NOT NULL,
+    body text NOT NULL,
+    anchor_quote text,
+    anchor_start integer,
+    anchor_end integer,
+    resolved integer DEFAULT 0 NOT NULL,
+    created_at text NOT NULL,
+    updated_at text NOT NULL,
+    deleted_at text,
+    CHECK (anchor_start IS NULL OR (anchor_start >= 0 AND anchor_end > anchor_start))
+);
+
+CREATE INDEX comments_note_id_idx ON comments (note_id, created_at);
+CREATE INDEX comments_thread_id_idx ON comments (thread_id);
+
+CREATE TABLE attachments (
+    id text PRIMARY KEY,
+    note_id text NOT NULL REFERENCES notes (id) ON DELETE CASCADE,
+    user_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    filename text NOT NULL,
+    content_type text NOT NULL,
+    size integer NOT NULL,
+    data blob NOT NULL,
+    created_at text NOT NULL
+);
+
+CREATE INDEX attachments_note_id_idx ON attachments (note_id, created_at);
+
+CREATE TABLE note_mentions (
+    note_id text NOT NULL REFERENCES notes (id) ON DELETE CASCADE,
+    user_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at text NOT NULL,
+    PRIMARY KEY (note_id, user_id)
+);
+
+CREATE INDEX note_mentions_user_id_idx ON note_mentions (user_id, created_at DESC);
+
+CREATE TABLE notifications (
+    id text PRIMARY KEY,
+    user_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    type text NOT NULL,
+    actor_id text REFERENCES users (id) ON DELETE SET NULL,
+    note_id text REFERENCES notes (id) ON DELETE CASCADE,
+    comment_id text REFERENCES comments (id) ON DELETE CASCADE,
+    created_at text NOT NULL,
+    read_at text
+);
+
+CREATE INDEX notifications_user_id_idx ON notifications (user_id, created_at DESC);
+CREATE INDEX notifications_unread_idx ON notifications (user_id) WHERE read_at IS NULL;
+
+-- Types without a row are on.
+CREATE TABLE notification_preferences (
+    user_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    type text NOT NULL,
+    enabled integer NOT NULL,
+    PRIMARY KEY (user_id, type)
+);
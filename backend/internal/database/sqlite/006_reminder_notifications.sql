@@ -0,0 +1,11 @@
+-- Due reminders notify the owner once per remind_at. reminded_at is the
+-- remind_at last delivered, so moving the reminder makes it due again.
+-- Reminders already past are not delivered late.
+
+ALTER TABLE notes ADD COLUMN reminded_at text;
+
+UPDATE notes
+    SET reminded_at = remind_at
+    WHERE remind_at <= strftime('%Y-%m-%dT%H:%M:%f000000Z', 'now');
+
+CREATE INDEX notes_remind_at_idx ON notes (remind_at) WHERE remind_at IS NOT NULL AND deleted_at IS NULL;
@@ -0,0 +1,43 @@
+// Package sqlitetest sets up SQLite databases for repository tests.
+package sqlitetest
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/database"
+)
+
+// Open opens a fresh, migrated database that is closed when the test ends.
+func Open(t testing.TB) *sql.DB {
+	t.Helper()
+	return OpenFile(t, filepath.Join(t.TempDir(), "grimoire.db"))
+}
+
+// OpenFile opens the database at path, creating and migrating it if needed,
+// and closes it when the test ends.
+func OpenFile(t testing.TB, path string) *sql.DB {
+	t.Helper()
+	db, err := database.OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// NewUser adds a user for rows that reference users(id) and returns its ID.
+func NewUser(t testing.TB, db *sql.DB) uuid.UUID {
+	t.Helper()
+	id := uuid.New()
+	now := database.Timestamp(time.Now())
+	_, err := db.Exec("INSERT INTO users (id, username, email, role, created_at, updated_at) VALUES (?, ?, ?, 'user', ?, ?)",
+		id, id.String(), id.String()+"@example.com", now, now)
+	if err != nil {
+		t.Fatalf("failed to add user: %v", err)
+	}
+	return id
+}
@@ -0,0 +1,847 @@
+// Package dav serves a user's notes over WebDAV, so a Grimoire can be
+// mounted in a file manager or editor. Tags are directories and notes are
+// Markdown files in them; see tree for the layout.
+package dav
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/auth"
+	"github.com/jehufrayle/grimoire/internal/notes"
+	"github.com/jehufrayle/grimoire/internal/users"
+)
+
+const (
+	// Prefix is where the WebDAV tree is mounted.
+	Prefix = "/dav"
+
+	maxFileSize        = 10 << 20
+	maxXMLBody         = 1 << 20
+	defaultLockTimeout = 10 * time.Minute
+	maxLockTimeout     = time.Hour
+
+	allowedMethods = "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, PROPPATCH, MKCOL, COPY, MOVE, LOCK, UNLOCK"
+)
+
+type Handler struct {
+	users      users.UserRepository
+	notes      notes.NoteRepository
+	locks      notes.LockStore
+	properties notes.PropertyDefinitionRepository
+}
+
+func NewHandler(userRepo users.UserRepository, noteRepo notes.NoteRepository, locks notes.LockStore, properties notes.PropertyDefinitionRepository) *Handler {
+	return &Handler{users: userRepo, notes: noteRepo, locks: locks, properties: properties}
+}
+
+// ServeHTTP authenticates the request and dispatches on the WebDAV method.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticate(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="Grimoire", charset="UTF-8"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	h.serve(w, r, userID)
+}
+
+// authenticate accepts an API token as a Bearer token, or HTTP Basic
+// credentials where the password is either the account password or an API
+// token. Basic users are looked up by username or email.
+func (h *Handler) authenticate(r *http.Request) (uuid.UUID, bool) {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return tokenUser(token)
+	}
+	login, password, ok := r.BasicAuth()
+	if !ok {
+		return uuid.Nil, false
+	}
+
+	email := login
+	if !strings.Contains(login, "@") {
+		user, err := h.users.GetByUsername(r.Context(), login)
+		if err != nil || user == nil {
+			return uuid.Nil, false
+		}
+		email = user.Email
+	}
+	user, err := h.users.GetByEmail(r.Context(), email)
+	if err != nil || user == nil || !user.Active {
+		return uuid.Nil, false
+	}
+	if userID, ok := tokenUser(password); ok {
+		return userID, userID.String() == user.ID
+	}
+	if !user.VerifyPassword(password) {
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(user.ID)
+	return userID, err == nil
+}
+
+func tokenUser(token string) (uuid.UUID, bool) {
+	claims, err := auth.ValidateToken(token)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(claims.UserID)
+	return userID, err == nil
+}
+
+// request carries what every method needs.
+type request struct {
+	w      http.ResponseWriter
+	r      *http.Request
+	userID uuid.UUID
+	tree   *tree
+	res    resource
+}
+
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	if r.Method == http.MethodOptions {
+		w.Header().Set("DAV", "1, 2")
+		w.Header().Set("Allow", allowedMethods)
+		w.Header().Set("MS-Author-Via", "DAV")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	p, ok := strings.CutPrefix(r.URL.Path, Prefix)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	t, err := h.load(r, userID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve notes", http.StatusInternalServerError)
+		return
+	}
+	req := &request{w: w, r: r, userID: userID, tree: t, res: t.resolve(p)}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		h.get(req)
+	case http.MethodPut:
+		h.put(req)
+	case http.MethodDelete:
+		h.delete(req)
+	case "PROPFIND":
+		h.propfind(req)
+	case "PROPPATCH":
+		h.proppatch(req)
+	case "MKCOL":
+		// Directories are tags, which only exist while notes carry them
+		http.Error(w, "Directories are created by saving a note in them; add the tag to a note first", http.StatusForbidden)
+	case "COPY", "MOVE":
+		h.copyMove(req)
+	case "LOCK":
+		h.lock(req)
+	case "UNLOCK":
+		h.unlock(req)
+	default:
+		w.Header().Set("Allow", allowedMethods)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) load(r *http.Request, userID uuid.UUID) (*tree, error) {
+	userNotes, err := h.notes.GetByUserID(r.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+	return buildTree(userNotes), nil
+}
+
+func etag(note *notes.Note) string {
+	return fmt.Sprintf(`"%s-%d"`, note.ID, note.Version)
+}
+
+func href(p string) string {
+	u := url.URL{Path: Prefix + p}
+	return u.EscapedPath()
+}
+
+// checkConditions evaluates If-Match and If-None-Match against the note, or
+// against nothing when note is nil. It returns 0 when the request may go on.
+func checkConditions(r *http.Request, note *notes.Note) int {
+	matches := func(header string) bool {
+		if strings.TrimSpace(header) == "*" {
+			return note != nil
+		}
+		if note == nil {
+			return false
+		}
+		current := etag(note)
+		for _, tag := range strings.Split(header, ",") {
+			if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == current {
+				return true
+			}
+		}
+		return false
+	}
+	if header := r.Header.Get("If-Match"); header != "" && !matches(header) {
+		return http.StatusPreconditionFailed
+	}
+	if header := r.Header.Get("If-None-Match"); header != "" && matches(header) {
+		return http.StatusPreconditionFailed
+	}
+	return 0
+}
+
+var lockTokenPattern = regexp.MustCompile(`<(opaquelocktoken:[^>]+)>`)
+
+// submittedTokens lists the lock tokens in the If header.
+func submittedTokens(r *http.Request) []string {
+	var tokens []string
+	for _, m := range lockTokenPattern.FindAllStringSubmatch(r.Header.Get("If"), -1) {
+		tokens = append(tokens, m[1])
+	}
+	return tokens
+}
+
+// writeLock checks that the request may write the note. It returns the lock
+// the request holds, if any, or a non-zero status when another client's lock
+// is in the way.
+func (h *Handler) writeLock(req *request, note *notes.Note) (*notes.NoteLock, int) {
+	lock, err := h.locks.GetLock(req.r.Context(), note.ID, time.Now())
+	if errors.Is(err, notes.ErrNotLocked) {
+		return nil, 0
+	}
+	if err != nil {
+		return nil, http.StatusInternalServerError
+	}
+	if !slices.Contains(submittedTokens(req.r), lock.Token) {
+		return nil, http.StatusLocked
+	}
+	return lock, 0
+}
+
+// update saves the note. Under a lock, the write only applies if the note is
+// still at the version the lock holder last saw, and the lock then moves on
+// to the new version.
+func (h *Handler) update(req *request, note *notes.Note, lock *notes.NoteLock) int {
+	if lock != nil {
+		note.Version = lock.Version
+	}
+	if err := h.notes.Update(req.r.Context(), note); err != nil {
+		if errors.Is(err, notes.ErrVersionConflict) {
+			return http.StatusPreconditionFailed
+		}
+		return http.StatusInternalServerError
+	}
+	if lock != nil {
+		lock.Version = note.Version
+		if err := h.locks.PutLock(req.r.Context(), lock, time.Now()); err != nil {
+			return http.StatusInternalServerError
+		}
+	}
+	return 0
+}
+
+// removeFromDir takes the note out of a directory: the tag is dropped if
+// the note is also in other directories, otherwise the note is deleted.
+func (h *Handler) removeFromDir(req *request, note *notes.Note, dir string, lock *notes.NoteLock) int {
+	if dir != "" && len(note.Tags) > 1 {
+		updated := *note
+		updated.Tags = withoutTag(note.Tags, dir)
+		return h.update(req, &updated, lock)
+	}
+	if err := h.notes.Delete(req.r.Context(), note.ID.String()); err != nil {
+		return http.StatusInternalServerError
+	}
+	if lock != nil {
+		h.locks.DeleteLock(req.r.Context(), note.ID, lock.Token)
+	}
+	return 0
+}
+
+func withoutTag(tags []notes.Tag, name string) []notes.Tag {
+	result := make([]notes.Tag, 0, len(tags))
+	for _, tag := range tags {
+		if tag.Name != name {
+			result = append(result, notes.Tag{Name: tag.Name})
+		}
+	}
+	return result
+}
+
+func withTag(tags []notes.Tag, name string) []notes.Tag {
+	result := withoutTag(tags, name)
+	if name != "" {
+		result = append(result, notes.Tag{Name: name})
+	}
+	return result
+}
+
+// syncProperties validates the front matter of content written by a client.
+func (h *Handler) syncProperties(req *request, note *notes.Note) int {
+	defs, err := h.properties.GetByUserID(req.r.Context(), req.userID)
+	if err != nil {
+		return http.StatusInternalServerError
+	}
+	if err := notes.SyncProperties(note, defs); err != nil {
+		return http.StatusUnprocessableEntity
+	}
+	return 0
+}
+
+func fail(w http.ResponseWriter, status int) {
+	http.Error(w, http.StatusText(status), status)
+}
+
+func (h *Handler) get(req *request) {
+	res := req.res
+	if res.collection {
+		req.w.Header().Set("Allow", allowedMethods)
+		http.Error(req.w, "Directories cannot be downloaded", http.StatusMethodNotAllowed)
+		return
+	}
+	if res.note == nil {
+		http.NotFound(req.w, req.r)
+		return
+	}
+	req.w.Header().Set("ETag", etag(res.note))
+	req.w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	http.ServeContent(req.w, req.r, res.name, res.note.UpdatedAt, strings.NewReader(res.note.Content))
+}
+
+// put saves the body as the note's content, creating the note with the
+// file name as title and the directory as tag if there is none yet.
+func (h *Handler) put(req *request) {
+	res := req.res
+	switch {
+	case res.collection:
+		http.Error(req.w, "Cannot write to a directory", http.StatusMethodNotAllowed)
+		return
+	case res.orphan:
+		http.Error(req.w, "Parent directory does not exist", http.StatusConflict)
+		return
+	case !validFileName(res.name):
+		http.Error(req.w, "Only .md files can be stored", http.StatusForbidden)
+		return
+	}
+	if status := checkConditions(req.r, res.note); status != 0 {
+		fail(req.w, status)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(req.w, req.r.Body, maxFileSize))
+	if err != nil {
+		fail(req.w, http.StatusRequestEntityTooLarge)
+		return
+	}
+	if !utf8.Valid(body) {
+		http.Error(req.w, "Notes must be UTF-8 text", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	if res.note == nil {
+		note := notes.Note{Title: titleFromName(res.name), Content: string(body), UserID: req.userID, Tags: withTag(nil, res.dir)}
+		if status := h.syncProperties(req, &note); status != 0 {
+			fail(req.w, status)
+			return
+		}
+		if err := h.notes.Create(req.r.Context(), &note); err != nil {
+			fail(req.w, http.StatusInternalServerError)
+			return
+		}
+		req.w.Header().Set("ETag", etag(&note))
+		req.w.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	lock, status := h.writeLock(req, res.note)
+	if status != 0 {
+		fail(req.w, status)
+		return
+	}
+	note := *res.note
+	note.Content = string(body)
+	if status := h.syncProperties(req, &note); status != 0 {
+		fail(req.w, status)
+		return
+	}
+	if status := h.update(req, &note, lock); status != 0 {
+		fail(req.w, status)
+		return
+	}
+	req.w.Header().Set("ETag", etag(&note))
+	req.w.WriteHeader(http.StatusNoContent)
+}
+
+// delete removes a file or every file of a directory; see removeFromDir.
+func (h *Handler) delete(req *request) {
+	res := req.res
+	var targets []resource
+	switch {
+	case res.collection && res.dir == "":
+		http.Error(req.w, "Cannot delete the root", http.StatusForbidden)
+		return
+	case res.collection:
+		targets = req.tree.children(res.dir)
+	case res.note != nil:
+		if status := checkConditions(req.r, res.note); status != 0 {
+			fail(req.w, status)
+			return
+		}
+		targets = []resource{res}
+	default:
+		http.NotFound(req.w, req.r)
+		return
+	}
+
+	// Check every lock before touching anything
+	locks := make([]*notes.NoteLock, len(targets))
+	for i, target := range targets {
+		lock, status := h.writeLock(req, target.note)
+		if status != 0 {
+			fail(req.w, status)
+			return
+		}
+		locks[i] = lock
+	}
+	for i, target := range targets {
+		if status := h.removeFromDir(req, target.note, target.dir, locks[i]); status != 0 {
+			fail(req.w, status)
+			return
+		}
+	}
+	req.w.WriteHeader(http.StatusNoContent)
+}
+
+// depth reads the Depth header. Infinity is fine: the tree is two levels.
+func depth(r *http.Request) (int, bool) {
+	switch r.Header.Get("Depth") {
+	case "0":
+		return 0, true
+	case "1":
+		return 1, true
+	case "", "infinity":
+		return 2, true
+	}
+	return 0, false
+}
+
+func (h *Handler) propfind(req *request) {
+	if !req.res.exists() {
+		http.NotFound(req.w, req.r)
+		return
+	}
+	d, ok := depth(req.r)
+	if !ok {
+		http.Error(req.w, "Invalid Depth header", http.StatusBadRequest)
+		return
+	}
+	var pf propfindRequest
+	hasBody, err := decodeBody(req.r, &pf)
+	if err != nil {
+		http.Error(req.w, "Invalid PROPFIND body", http.StatusBadRequest)
+		return
+	}
+	allProp := !hasBody || pf.AllProp != nil
+
+	ms := newMultistatus()
+	var visit func(res resource, d int)
+	visit = func(res resource, d int) {
+		available := h.props(req, res)
+		switch {
+		case pf.PropName != nil:
+			names := make([]prop, len(available))
+			for i, p := range available {
+				names[i] = prop{name: p.name}
+			}
+			ms.response(href(res.path), propstat{status: http.StatusOK, props: names})
+		case allProp:
+			ms.response(href(res.path), propstat{status: http.StatusOK, props: available})
+		default:
+			var found, missing []prop
+			for _, name := range pf.Prop {
+				if i := slices.IndexFunc(available, func(p prop) bool { return p.name == name }); i >= 0 {
+					found = append(found, available[i])
+				} else {
+					missing = append(missing, prop{name: name})
+				}
+			}
+			ms.response(href(res.path), propstat{status: http.StatusOK, props: found}, propstat{status: http.StatusNotFound, props: missing})
+		}
+		if res.collection && d > 0 {
+			for _, child := range req.tree.children(res.dir) {
+				if res.dir == "" || !child.collection {
+					visit(child, d-1)
+				}
+			}
+		}
+	}
+	visit(req.res, d)
+	ms.send(req.w)
+}
+
+// props lists the live properties of a resource.
+func (h *Handler) props(req *request, res resource) []prop {
+	dav := func(local, value string) prop {
+		return prop{name: xmlName(local), value: value}
+	}
+	name := res.name
+	if res.collection {
+		name = dirName(res.dir)
+		if res.dir == "" {
+			name = "Grimoire"
+		}
+	}
+	props := []prop{
+		dav("displayname", escape(name)),
+		dav("supportedlock", "<D:lockentry><D:lockscope><D:exclusive/></D:lockscope><D:locktype><D:write/></D:locktype></D:lockentry>"),
+	}
+	if res.collection {
+		return append(props, dav("resourcetype", "<D:collection/>"))
+	}
+
+	note := res.note
+	lockdiscovery := ""
+	if lock, err := h.locks.GetLock(req.r.Context(), note.ID, time.Now()); err == nil {
+		lockdiscovery = activeLock(lock, res.path)
+	}
+	return append(props,
+		dav("resourcetype", ""),
+		dav("getcontenttype", "text/markdown; charset=utf-8"),
+		dav("getcontentlength", strconv.Itoa(len(note.Content))),
+		dav("getetag", escape(etag(note))),
+		dav("getlastmodified", note.UpdatedAt.UTC().Format(http.TimeFormat)),
+		dav("creationdate", note.CreatedAt.UTC().Format(time.RFC3339)),
+		dav("lockdiscovery", lockdiscovery),
+	)
+}
+
+func xmlName(local string) xml.Name {
+	return xml.Name{Space: davNS, Local: local}
+}
+
+// proppatch refuses every change: all properties are derived from notes.
+func (h *Handler) proppatch(req *request) {
+	if !req.res.exists() {
+		http.NotFound(req.w, req.r)
+		return
+	}
+	var update propertyUpdate
+	if ok, err := decodeBody(req.r, &update); !ok || err != nil {
+		http.Error(req.w, "Invalid PROPPATCH body", http.StatusBadRequest)
+		return
+	}
+	var refused []prop
+	for _, set := range update.Set {
+		for _, name := range set.Prop {
+			refused = append(refused, prop{name: name})
+		}
+	}
+	for _, remove := range update.Remove {
+		for _, name := range remove.Prop {
+			refused = append(refused, prop{name: name})
+		}
+	}
+	ms := newMultistatus()
+	ms.response(href(req.res.path), propstat{status: http.StatusForbidden, props: refused})
+	ms.send(req.w)
+}
+
+// copyMove handles COPY and MOVE. Moving a file renames the note and moves
+// it between tags; moving a directory renames the tag. Directories cannot
+// be copied.
+func (h *Handler) copyMove(req *request) {
+	src := req.res
+	if !src.exists() {
+		http.NotFound(req.w, req.r)
+		return
+	}
+	destURL, err := url.Parse(req.r.Header.Get("Destination"))
+	if err != nil || req.r.Header.Get("Destination") == "" {
+		http.Error(req.w, "Invalid Destination header", http.StatusBadRequest)
+		return
+	}
+	destPath, ok := strings.CutPrefix(destURL.Path, Prefix+"/")
+	if !ok {
+		http.Error(req.w, "Destination is outside the WebDAV tree", http.StatusBadGateway)
+		return
+	}
+	dest := req.tree.resolve(destPath)
+	overwrite := req.r.Header.Get("Overwrite") != "F"
+	move := req.r.Method == "MOVE"
+
+	if src.collection {
+		if !move || src.dir == "" {
+			http.Error(req.w, "Directories can only be moved, and not the root", http.StatusForbidden)
+			return
+		}
+		h.moveDir(req, dest, overwrite)
+		return
+	}
+
+	switch {
+	case dest.collection:
+		http.Error(req.w, "Destination is a directory", http.StatusConflict)
+		return
+	case dest.orphan:
+		http.Error(req.w, "Destination directory does not exist", http.StatusConflict)
+		return
+	case !validFileName(dest.name):
+		http.Error(req.w, "Only .md files can be stored", http.StatusForbidden)
+		return
+	case dest.note != nil && dest.note.ID == src.note.ID && dest.name == src.name:
+		http.Error(req.w, "Source and destination are the same", http.StatusForbidden)
+		return
+	case dest.note != nil && !overwrite:
+		fail(req.w, http.StatusPreconditionFailed)
+		return
+	}
+
+	var srcLock *notes.NoteLock
+	if move {
+		var status int
+		if srcLock, status = h.writeLock(req, src.note); status != 0 {
+			fail(req.w, status)
+			return
+		}
+	}
+	// The note is already in the destination directory under another name
+	sameNote := dest.note != nil && dest.note.ID == src.note.ID
+	if dest.note != nil && !sameNote {
+		destLock, status := h.writeLock(req, dest.note)
+		if status != 0 {
+			fail(req.w, status)
+			return
+		}
+		if status := h.removeFromDir(req, dest.note, dest.dir, destLock); status != 0 {
+			fail(req.w, status)
+			return
+		}
+	}
+
+	if move {
+		note := *src.note
+		note.Title = titleFromName(dest.name)
+		if src.dir != "" {
+			note.Tags = withoutTag(note.Tags, src.dir)
+		}
+		note.Tags = withTag(note.Tags, dest.dir)
+		if status := h.update(req, &note, srcLock); status != 0 {
+			fail(req.w, status)
+			return
+		}
+	} else {
+		note := notes.Note{
+			Title:      titleFromName(dest.name),
+			Content:    src.note.Content,
+			UserID:     req.userID,
+			Tags:       withTag(nil, dest.dir),
+			Properties: src.note.Properties,
+		}
+		if err := h.notes.Create(req.r.Context(), &note); err != nil {
+			fail(req.w, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if dest.note != nil {
+		req.w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	req.w.WriteHeader(http.StatusCreated)
+}
+
+// moveDir renames a tag on every note in the directory. Moving onto an
+// existing directory merges the two.
+func (h *Handler) moveDir(req *request, dest resource, overwrite bool) {
+	src := req.res
+	newTag := dest.dir
+	switch {
+	case dest.orphan || dest.name != "" && dest.dir != "":
+		http.Error(req.w, "Directories can only live at the root", http.StatusConflict)
+		return
+	case dest.collection && dest.dir == "":
+		http.Error(req.w, "Cannot replace the root", http.StatusForbidden)
+		return
+	case dest.collection && !overwrite:
+		fail(req.w, http.StatusPreconditionFailed)
+		return
+	case !dest.collection:
+		if dest.note != nil || !validDirName(dest.name) {
+			http.Error(req.w, "Invalid directory name", http.StatusForbidden)
+			return
+		}
+		newTag = tagFromDir(dest.name)
+	}
+	if newTag == src.dir {
+		http.Error(req.w, "Source and destination are the same", http.StatusForbidden)
+		return
+	}
+
+	children := req.tree.children(src.dir)
+	locks := make([]*notes.NoteLock, len(children))
+	for i, child := range children {
+		lock, status := h.writeLock(req, child.note)
+		if status != 0 {
+			fail(req.w, status)
+			return
+		}
+		locks[i] = lock
+	}
+	for i, child := range children {
+		note := *child.note
+		note.Tags = withTag(withoutTag(note.Tags, src.dir), newTag)
+		if status := h.update(req, &note, locks[i]); status != 0 {
+			fail(req.w, status)
+			return
+		}
+	}
+
+	if dest.collection {
+		req.w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	req.w.WriteHeader(http.StatusCreated)
+}
+
+// lock takes or refreshes an exclusive write lock on a file. Locking a name
+// with no note behind it creates an empty note, as RFC 4918 asks.
+func (h *Handler) lock(req *request) {
+	res := req.res
+	if res.collection {
+		http.Error(req.w, "Only files can be locked", http.StatusForbidden)
+		return
+	}
+	timeout := lockTimeout(req.r)
+	now := time.Now()
+
+	var info lockInfo
+	hasBody, err := decodeBody(req.r, &info)
+	if err != nil {
+		http.Error(req.w, "Invalid LOCK body", http.StatusBadRequest)
+		return
+	}
+
+	// Without a body, LOCK refreshes a lock the client already holds
+	if !hasBody {
+		if res.note == nil {
+			http.NotFound(req.w, req.r)
+			return
+		}
+		lock, err := h.locks.GetLock(req.r.Context(), res.note.ID, now)
+		if err != nil || !slices.Contains(submittedTokens(req.r), lock.Token) {
+			fail(req.w, http.StatusPreconditionFailed)
+			return
+		}
+		lock.ExpiresAt = now.Add(timeout)
+		if err := h.locks.PutLock(req.r.Context(), lock, now); err != nil {
+			fail(req.w, http.StatusInternalServerError)
+			return
+		}
+		writeLockResponse(req.w, lock, res.path, http.StatusOK)
+		return
+	}
+
+	if info.Scope.Exclusive == nil || info.Type.Write == nil {
+		http.Error(req.w, "Only exclusive write locks are supported", http.StatusForbidden)
+		return
+	}
+	status := http.StatusOK
+	note := res.note
+	if note == nil {
+		switch {
+		case res.orphan:
+			http.Error(req.w, "Parent directory does not exist", http.StatusConflict)
+			return
+		case !validFileName(res.name):
+			http.Error(req.w, "Only .md files can be stored", http.StatusForbidden)
+			return
+		}
+		note = &notes.Note{Title: titleFromName(res.name), UserID: req.userID, Tags: withTag(nil, res.dir)}
+		if err := h.notes.Create(req.r.Context(), note); err != nil {
+			fail(req.w, http.StatusInternalServerError)
+			return
+		}
+		status = http.StatusCreated
+	}
+
+	lock := &notes.NoteLock{
+		NoteID:    note.ID,
+		UserID:    req.userID,
+		Token:     "opaquelocktoken:" + uuid.NewString(),
+		Owner:     info.owner(),
+		Version:   note.Version,
+		ExpiresAt: now.Add(timeout),
+	}
+	if err := h.locks.PutLock(req.r.Context(), lock, now); err != nil {
+		if errors.Is(err, notes.ErrLocked) {
+			fail(req.w, http.StatusLocked)
+			return
+		}
+		fail(req.w, http.StatusInternalServerError)
+		return
+	}
+	req.w.Header().Set("Lock-Token", "<"+lock.Token+">")
+	writeLockResponse(req.w, lock, res.path, status)
+}
+
+func (h *Handler) unlock(req *request) {
+	if req.res.note == nil {
+		http.NotFound(req.w, req.r)
+		return
+	}
+	token := strings.Trim(req.r.Header.Get("Lock-Token"), "<> ")
+	if err := h.locks.DeleteLock(req.r.Context(), req.res.note.ID, token); err != nil {
+		if errors.Is(err, notes.ErrNotLocked) {
+			http.Error(req.w, "Lock token does not hold this file", http.StatusConflict)
+			return
+		}
+		fail(req.w, http.StatusInternalServerError)
+		return
+	}
+	req.w.WriteHeader(http.StatusNoContent)
+}
+
+// lockTimeout reads the first usable value of the Timeout header, capped at
+// maxLockTimeout.
+func lockTimeout(r *http.Request) time.Duration {
+	for _, value := range strings.Split(r.Header.Get("Timeout"), ",") {
+		value = strings.TrimSpace(value)
+		if value == "Infinite" {
+			return maxLockTimeout
+		}
+		if seconds, ok := strings.CutPrefix(value, "Second-"); ok {
+			if n, err := strconv.Atoi(seconds); err == nil && n > 0 {
+				return min(time.Duration(n)*time.Second, maxLockTimeout)
+			}
+		}
+	}
+	return defaultLockTimeout
+}
+
+func activeLock(lock *notes.NoteLock, p string) string {
+	var b strings.Builder
+	b.WriteString("<D:activelock><D:locktype><D:write/></D:locktype><D:lockscope><D:exclusive/></D:lockscope><D:depth>0</D:depth>")
+	if lock.Owner != "" {
+		b.WriteString("<D:owner><D:href>" + escape(lock.Owner) + "</D:href></D:owner>")
+	}
+	seconds := max(int(time.Until(lock.ExpiresAt).Seconds()), 1)
+	fmt.Fprintf(&b, "<D:timeout>Second-%d</D:timeout>", seconds)
+	b.WriteString("<D:locktoken><D:href>" + escape(lock.Token) + "</D:href></D:locktoken>")
+	b.WriteString("<D:lockroot><D:href>" + escape(href(p)) + "</D:href></D:lockroot></D:activelock>")
+	return b.String()
+}
+
+func writeLockResponse(w http.ResponseWriter, lock *notes.NoteLock, p string, status int) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(status)
+	io.WriteString(w, xml.Header+`<D:prop xmlns:D="DAV:"><D:lockdiscovery>`+activeLock(lock, p)+`</D:lockdiscovery></D:prop>`)
+}
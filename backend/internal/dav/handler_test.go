@@ -0,0 +1,164 @@
+package dav
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/notes"
+)
+
+type fixture struct {
+	t      *testing.T
+	h      *Handler
+	repo   *notes.InMemoryNoteRepository
+	userID uuid.UUID
+}
+
+func newFixture(t *testing.T) *fixture {
+	repo := notes.NewInMemoryNoteRepository()
+	return &fixture{
+		t:      t,
+		h:      NewHandler(nil, repo, repo, notes.NewInMemoryPropertyDefinitionRepository()),
+		repo:   repo,
+		userID: uuid.New(),
+	}
+}
+
+func (f *fixture) do(method, p, body string, headers ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, Prefix+p, strings.NewReader(body))
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	f.h.serve(w, r, f.userID)
+	return w
+}
+
+func (f *fixture) expect(w *httptest.ResponseRecorder, status int) {
+	f.t.Helper()
+	if w.Code != status {
+		f.t.Fatalf("status = %d, want %d: %s", w.Code, status, w.Body.String())
+	}
+}
+
+func (f *fixture) only() notes.Note {
+	f.t.Helper()
+	all, _ := f.repo.GetByUserID(context.Background(), f.userID)
+	if len(all) != 1 {
+		f.t.Fatalf("%d notes, want 1", len(all))
+	}
+	return all[0]
+}
+
+func TestPutCreatesAndUpdatesNotes(t *testing.T) {
+	f := newFixture(t)
+	f.expect(f.do(http.MethodPut, "/Plan.md", "first"), http.StatusCreated)
+	f.expect(f.do(http.MethodPut, "/work/Plan.md", "orphan"), http.StatusConflict)
+
+	note := f.only()
+	if note.Title != "Plan" || note.Content != "first" || len(note.Tags) != 0 {
+		t.Fatalf("created %+v", note)
+	}
+
+	// Moving into a directory needs one to exist, so tag the note by hand
+	note.Tags = []notes.Tag{{Name: "work"}}
+	f.repo.Update(context.Background(), &note)
+
+	w := f.do(http.MethodPut, "/work/Plan.md", "second")
+	f.expect(w, http.StatusNoContent)
+	if got := f.only(); got.Content != "second" || w.Header().Get("ETag") != etag(&got) {
+		t.Fatalf("updated %+v, etag %s", got, w.Header().Get("ETag"))
+	}
+
+	f.expect(f.do(http.MethodPut, "/work/Plan.md", "stale", "If-Match", etag(&note)), http.StatusPreconditionFailed)
+	f.expect(f.do(http.MethodPut, "/work/.DS_Store", "junk"), http.StatusForbidden)
+
+	get := f.do(http.MethodGet, "/work/Plan.md", "")
+	f.expect(get, http.StatusOK)
+	if get.Body.String() != "second" {
+		t.Fatalf("GET = %q", get.Body.String())
+	}
+}
+
+func TestPropfindListsTagsAsDirectories(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	for _, n := range []notes.Note{
+		{Title: "Ideas", UserID: f.userID, Tags: []notes.Tag{{Name: "work"}, {Name: "home"}}},
+		{Title: "Ideas", UserID: f.userID, Tags: []notes.Tag{{Name: "work"}}},
+		{Title: "Loose", UserID: f.userID},
+	} {
+		f.repo.Create(ctx, &n)
+	}
+
+	w := f.do("PROPFIND", "/", "", "Depth", "1")
+	f.expect(w, http.StatusMultiStatus)
+	body := w.Body.String()
+	for _, want := range []string{"<D:href>/dav/home/</D:href>", "<D:href>/dav/work/</D:href>", "<D:href>/dav/Loose.md</D:href>"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %s in %s", want, body)
+		}
+	}
+
+	w = f.do("PROPFIND", "/work/", `<?xml version="1.0"?><propfind xmlns="DAV:"><prop><getetag/><quota xmlns="urn:x"/></prop></propfind>`, "Depth", "1")
+	body = w.Body.String()
+	if !strings.Contains(body, "/dav/work/Ideas.md") || !strings.Contains(body, "/dav/work/Ideas%20%282%29.md") {
+		t.Errorf("duplicate titles not numbered: %s", body)
+	}
+	if !strings.Contains(body, `<x:quota xmlns:x="urn:x"/>`) || !strings.Contains(body, "404 Not Found") {
+		t.Errorf("unknown property not reported missing: %s", body)
+	}
+}
+
+func TestMoveRenamesAndRetags(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	f.repo.Create(ctx, &notes.Note{Title: "Draft", UserID: f.userID, Tags: []notes.Tag{{Name: "inbox"}}})
+	f.repo.Create(ctx, &notes.Note{Title: "Other", UserID: f.userID, Tags: []notes.Tag{{Name: "archive"}}})
+
+	f.expect(f.do("MOVE", "/inbox/Draft.md", "", "Destination", "http://example.com/dav/archive/Final.md"), http.StatusCreated)
+	all, _ := f.repo.Find(ctx, f.userID, &notes.Filter{Tags: []string{"archive"}})
+	if len(all) != 2 {
+		t.Fatalf("archive holds %d notes", len(all))
+	}
+
+	f.expect(f.do("MOVE", "/archive/", "", "Destination", "/dav/old/"), http.StatusCreated)
+	all, _ = f.repo.Find(ctx, f.userID, &notes.Filter{Tags: []string{"old"}})
+	if len(all) != 2 || all[0].Title != "Final" && all[1].Title != "Final" {
+		t.Fatalf("tag not renamed: %+v", all)
+	}
+}
+
+func TestLocksFollowTheNoteVersion(t *testing.T) {
+	f := newFixture(t)
+	lockBody := `<?xml version="1.0"?><lockinfo xmlns="DAV:"><lockscope><exclusive/></lockscope><locktype><write/></locktype><owner><href>me</href></owner></lockinfo>`
+
+	// Locking an unmapped name creates an empty note
+	w := f.do("LOCK", "/Todo.md", lockBody)
+	f.expect(w, http.StatusCreated)
+	token := strings.Trim(w.Header().Get("Lock-Token"), "<>")
+	if f.only().Title != "Todo" {
+		t.Fatal("lock-null note not created")
+	}
+
+	f.expect(f.do(http.MethodPut, "/Todo.md", "no token"), http.StatusLocked)
+	f.expect(f.do("LOCK", "/Todo.md", lockBody), http.StatusLocked)
+	f.expect(f.do(http.MethodPut, "/Todo.md", "v2", "If", "(<"+token+">)"), http.StatusNoContent)
+	f.expect(f.do(http.MethodPut, "/Todo.md", "v3", "If", "(<"+token+">)"), http.StatusNoContent)
+
+	// A change made elsewhere while locked is not overwritten
+	note := f.only()
+	note.Content = "from the web"
+	f.repo.Update(context.Background(), &note)
+	f.expect(f.do(http.MethodPut, "/Todo.md", "v4", "If", "(<"+token+">)"), http.StatusPreconditionFailed)
+	if got := f.only(); got.Content != "from the web" {
+		t.Fatalf("content = %q", got.Content)
+	}
+
+	f.expect(f.do("UNLOCK", "/Todo.md", "", "Lock-Token", "<"+token+">"), http.StatusNoContent)
+	f.expect(f.do(http.MethodPut, "/Todo.md", "v5"), http.StatusNoContent)
+}
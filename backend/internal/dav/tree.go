@@ -0,0 +1,182 @@
+package dav
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/jehufrayle/grimoire/internal/notes"
+)
+
+const (
+	fileExt      = ".md"
+	untitled     = "Untitled"
+	slashStandIn = "∕" // U+2215, stands in for "/" in file names
+)
+
+// entry is a note as listed in one directory.
+type entry struct {
+	name string
+	note *notes.Note
+}
+
+// tree is a user's notes laid out as files: one directory per tag, each
+// holding the notes with that tag, and untagged notes at the root. A note
+// with several tags appears in each of their directories.
+type tree struct {
+	dirs map[string][]entry // tag -> entries; "" is the root
+	tags []string           // sorted
+}
+
+// buildTree lays out the notes. End-to-end encrypted notes are left out
+// since their content is only readable by clients holding the key.
+func buildTree(userNotes []notes.Note) *tree {
+	sort.Slice(userNotes, func(i, j int) bool {
+		if !userNotes[i].CreatedAt.Equal(userNotes[j].CreatedAt) {
+			return userNotes[i].CreatedAt.Before(userNotes[j].CreatedAt)
+		}
+		return userNotes[i].ID.String() < userNotes[j].ID.String()
+	})
+
+	t := &tree{dirs: map[string][]entry{"": nil}}
+	taken := make(map[string]map[string]bool) // dir -> lowercased names in use
+	add := func(dir string, note *notes.Note) {
+		if taken[dir] == nil {
+			taken[dir] = make(map[string]bool)
+			if dir != "" {
+				t.tags = append(t.tags, dir)
+			}
+		}
+		// Older notes keep the plain name; later ones with the same title
+		// are numbered. Names are compared case-insensitively for the
+		// benefit of case-insensitive file systems.
+		base := fileBase(note.Title)
+		name := base + fileExt
+		for n := 2; taken[dir][strings.ToLower(name)]; n++ {
+			name = fmt.Sprintf("%s (%d)%s", base, n, fileExt)
+		}
+		taken[dir][strings.ToLower(name)] = true
+		t.dirs[dir] = append(t.dirs[dir], entry{name: name, note: note})
+	}
+
+	for i := range userNotes {
+		note := &userNotes[i]
+		if note.Encrypted {
+			continue
+		}
+		if len(note.Tags) == 0 {
+			add("", note)
+		}
+		for _, tag := range note.Tags {
+			add(tag.Name, note)
+		}
+	}
+	sort.Strings(t.tags)
+	return t
+}
+
+// fileBase turns a title into a file name without extension.
+func fileBase(title string) string {
+	title = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(title))
+	title = strings.ReplaceAll(title, "/", slashStandIn)
+	if title == "" || strings.HasPrefix(title, ".") {
+		title = untitled + title
+	}
+	return title
+}
+
+// dirName is the directory name of a tag, and tagFromDir the reverse.
+func dirName(tag string) string     { return strings.ReplaceAll(tag, "/", slashStandIn) }
+func tagFromDir(name string) string { return strings.ReplaceAll(name, slashStandIn, "/") }
+
+// titleFromName is the title a note written under name gets.
+func titleFromName(name string) string {
+	base := name[:len(name)-len(fileExt)]
+	return strings.ReplaceAll(base, slashStandIn, "/")
+}
+
+// validFileName reports whether a note may be stored under name: Markdown
+// files only, and no hidden files, which file managers scatter around.
+func validFileName(name string) bool {
+	return strings.HasSuffix(strings.ToLower(name), fileExt) && len(name) > len(fileExt) && !strings.HasPrefix(name, ".")
+}
+
+// validDirName reports whether a directory may be named name. Directory
+// names are tags.
+func validDirName(name string) bool {
+	return name != "" && !strings.HasPrefix(name, ".") && !strings.HasSuffix(strings.ToLower(name), fileExt)
+}
+
+// resource is what a path under /dav/ refers to.
+type resource struct {
+	path       string      // without the /dav prefix, e.g. "/work/Plan.md"
+	dir        string      // tag of the directory holding it, or of the directory itself; "" is the root
+	name       string      // file name; empty for directories
+	note       *notes.Note // the note of an existing file
+	collection bool
+	// orphan is set when the parent directory does not exist.
+	orphan bool
+}
+
+func (r *resource) exists() bool {
+	return r.collection || r.note != nil
+}
+
+// resolve finds what p, a cleaned path below the prefix, refers to.
+func (t *tree) resolve(p string) resource {
+	p = path.Clean("/" + p)
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	switch {
+	case p == "/":
+		return resource{path: "/", collection: true}
+	case len(segments) == 1:
+		if tag := tagFromDir(segments[0]); t.hasDir(tag) {
+			return resource{path: p + "/", dir: tag, collection: true}
+		}
+		return t.file("", segments[0])
+	case len(segments) == 2:
+		if tag := tagFromDir(segments[0]); !t.hasDir(tag) {
+			return resource{path: p, dir: tag, name: segments[1], orphan: true}
+		}
+		return t.file(tagFromDir(segments[0]), segments[1])
+	default:
+		return resource{path: p, orphan: true}
+	}
+}
+
+func (t *tree) hasDir(tag string) bool {
+	_, ok := t.dirs[tag]
+	return ok && tag != ""
+}
+
+func (t *tree) file(dir, name string) resource {
+	res := resource{path: path.Join("/", dirName(dir), name), dir: dir, name: name}
+	for _, e := range t.dirs[dir] {
+		if e.name == name {
+			res.note = e.note
+			break
+		}
+	}
+	return res
+}
+
+// children lists what a directory holds: the tag directories (at the root)
+// followed by the files.
+func (t *tree) children(dir string) []resource {
+	var result []resource
+	if dir == "" {
+		for _, tag := range t.tags {
+			result = append(result, resource{path: "/" + dirName(tag) + "/", dir: tag, collection: true})
+		}
+	}
+	for _, e := range t.dirs[dir] {
+		result = append(result, resource{path: path.Join("/", dirName(dir), e.name), dir: dir, name: e.name, note: e.note})
+	}
+	return result
+}
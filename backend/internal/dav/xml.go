@@ -0,0 +1,153 @@
+package dav
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const davNS = "DAV:"
+
+// propNames collects the names of the elements inside a <prop>.
+type propNames []xml.Name
+
+func (p *propNames) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			*p = append(*p, t.Name)
+			if err := d.Skip(); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+type propfindRequest struct {
+	XMLName  xml.Name  `xml:"DAV: propfind"`
+	AllProp  *struct{} `xml:"DAV: allprop"`
+	PropName *struct{} `xml:"DAV: propname"`
+	Prop     propNames `xml:"DAV: prop"`
+}
+
+type propertyUpdate struct {
+	XMLName xml.Name `xml:"DAV: propertyupdate"`
+	Set     []struct {
+		Prop propNames `xml:"DAV: prop"`
+	} `xml:"DAV: set"`
+	Remove []struct {
+		Prop propNames `xml:"DAV: prop"`
+	} `xml:"DAV: remove"`
+}
+
+type lockInfo struct {
+	XMLName xml.Name `xml:"DAV: lockinfo"`
+	Scope   struct {
+		Exclusive *struct{} `xml:"DAV: exclusive"`
+		Shared    *struct{} `xml:"DAV: shared"`
+	} `xml:"DAV: lockscope"`
+	Type struct {
+		Write *struct{} `xml:"DAV: write"`
+	} `xml:"DAV: locktype"`
+	Owner *struct {
+		Href string `xml:"DAV: href"`
+		Text string `xml:",chardata"`
+	} `xml:"DAV: owner"`
+}
+
+// owner is what the client said identifies it, usually a URL.
+func (l *lockInfo) owner() string {
+	if l.Owner == nil {
+		return ""
+	}
+	if l.Owner.Href != "" {
+		return strings.TrimSpace(l.Owner.Href)
+	}
+	return strings.TrimSpace(l.Owner.Text)
+}
+
+// decodeBody decodes an XML request body into v. It reports false for an
+// empty body.
+func decodeBody(r *http.Request, v any) (bool, error) {
+	err := xml.NewDecoder(io.LimitReader(r.Body, maxXMLBody)).Decode(v)
+	if err == io.EOF {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// prop is a property in a response. Value is inner XML, already escaped.
+type prop struct {
+	name  xml.Name
+	value string
+}
+
+func (p prop) write(b *strings.Builder) {
+	open, close := "D:"+p.name.Local, "D:"+p.name.Local
+	if p.name.Space != davNS {
+		open = fmt.Sprintf(`x:%s xmlns:x="%s"`, p.name.Local, escape(p.name.Space))
+		close = "x:" + p.name.Local
+	}
+	if p.value == "" {
+		b.WriteString("<" + open + "/>")
+		return
+	}
+	b.WriteString("<" + open + ">" + p.value + "</" + close + ">")
+}
+
+// propstat groups the properties of a response that share a status.
+type propstat struct {
+	status int
+	props  []prop
+}
+
+// multistatus builds a 207 Multi-Status body.
+type multistatus struct {
+	b strings.Builder
+}
+
+func newMultistatus() *multistatus {
+	m := &multistatus{}
+	m.b.WriteString(xml.Header + `<D:multistatus xmlns:D="DAV:">`)
+	return m
+}
+
+func (m *multistatus) response(href string, propstats ...propstat) {
+	m.b.WriteString("<D:response><D:href>" + escape(href) + "</D:href>")
+	for _, ps := range propstats {
+		if len(ps.props) == 0 {
+			continue
+		}
+		m.b.WriteString("<D:propstat><D:prop>")
+		for _, p := range ps.props {
+			p.write(&m.b)
+		}
+		m.b.WriteString("</D:prop><D:status>" + statusLine(ps.status) + "</D:status></D:propstat>")
+	}
+	m.b.WriteString("</D:response>")
+}
+
+func (m *multistatus) send(w http.ResponseWriter) {
+	m.b.WriteString("</D:multistatus>")
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	io.WriteString(w, m.b.String())
+}
+
+func statusLine(status int) string {
+	return fmt.Sprintf("HTTP/1.1 %d %s", status, http.StatusText(status))
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
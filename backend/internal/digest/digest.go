@@ -0,0 +1,166 @@
+// Package digest emails users who ask for it a summary of their week: the
+// notes they created, notes shared with them, comments, and the reminders
+// coming up. Each digest covers Monday to Sunday in the user's time zone
+// and goes out from Monday morning on.
+package digest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/notes"
+	"github.com/jehufrayle/grimoire/internal/notifications"
+	"github.com/jehufrayle/grimoire/internal/users"
+)
+
+const (
+	// sendHour is when on Monday, in the user's time zone, the digest of the
+	// week before goes out.
+	sendHour = 8
+	// reminderWindow is how far ahead reminders are listed.
+	reminderWindow = 7 * 24 * time.Hour
+	// notificationBatch is how many notifications are read at a time while
+	// looking for the week's.
+	notificationBatch = 100
+)
+
+// Entry is one line of a digest.
+type Entry struct {
+	Title string
+	URL   string // empty when there is no app URL to link to
+	Actor string // username of who shared or commented, if anyone
+	At    time.Time
+}
+
+// Digest is what one user's weekly email says.
+type Digest struct {
+	Username  string
+	Start     time.Time // first day of the week covered
+	End       time.Time // the Monday after it
+	Created   []Entry
+	Shared    []Entry
+	Comments  []Entry
+	Reminders []Entry
+}
+
+func (d *Digest) empty() bool {
+	return len(d.Created) == 0 && len(d.Shared) == 0 && len(d.Comments) == 0 && len(d.Reminders) == 0
+}
+
+// lastWeek returns the week whose digest is due at now: the Monday to
+// Sunday before the latest Monday at sendHour.
+func lastWeek(now time.Time, loc *time.Location) (start, end time.Time) {
+	local := now.In(loc)
+	sinceMonday := (int(local.Weekday()) + 6) % 7
+	end = time.Date(local.Year(), local.Month(), local.Day()-sinceMonday, 0, 0, 0, 0, loc)
+	if local.Before(time.Date(end.Year(), end.Month(), end.Day(), sendHour, 0, 0, 0, loc)) {
+		end = end.AddDate(0, 0, -7)
+	}
+	return end.AddDate(0, 0, -7), end
+}
+
+// builder gathers one digest, loading each note and user once.
+type builder struct {
+	job    *Job
+	userID uuid.UUID
+	notes  map[uuid.UUID]*notes.Note
+	names  map[uuid.UUID]string
+}
+
+func (j *Job) build(ctx context.Context, user *users.User, start, end, now time.Time) (*Digest, error) {
+	userID, err := uuid.Parse(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+	b := &builder{job: j, userID: userID, notes: make(map[uuid.UUID]*notes.Note), names: make(map[uuid.UUID]string)}
+	d := &Digest{Username: user.Username, Start: start, End: end}
+
+	owned, err := j.notes.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load notes: %w", err)
+	}
+	for i := range owned {
+		note := &owned[i]
+		if !note.CreatedAt.Before(start) && note.CreatedAt.Before(end) {
+			d.Created = append(d.Created, Entry{Title: note.Title, URL: j.noteURL(note.ID), At: note.CreatedAt})
+		}
+		if r := note.RemindAt; r != nil && !r.Before(now) && r.Before(now.Add(reminderWindow)) {
+			d.Reminders = append(d.Reminders, Entry{Title: note.Title, URL: j.noteURL(note.ID), At: r.In(start.Location())})
+		}
+	}
+
+	// Shares and comments are read back from the user's notifications
+	for offset := 0; ; offset += notificationBatch {
+		batch, _, err := j.notifications.ListByUser(ctx, userID, false, notificationBatch, offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load notifications: %w", err)
+		}
+		for _, n := range batch {
+			if n.CreatedAt.Before(start) {
+				break
+			}
+			if !n.CreatedAt.Before(end) || n.NoteID == nil {
+				continue
+			}
+			var list *[]Entry
+			switch n.Type {
+			case notifications.TypeShare:
+				list = &d.Shared
+			case notifications.TypeComment:
+				list = &d.Comments
+			default:
+				continue
+			}
+			entry, ok, err := b.entry(ctx, n)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				*list = append(*list, entry)
+			}
+		}
+		if len(batch) < notificationBatch || batch[len(batch)-1].CreatedAt.Before(start) {
+			break
+		}
+	}
+
+	for _, list := range [][]Entry{d.Created, d.Shared, d.Comments, d.Reminders} {
+		sort.SliceStable(list, func(i, k int) bool { return list[i].At.Before(list[k].At) })
+	}
+	return d, nil
+}
+
+// entry describes a notification, or reports false when its note is gone
+// or no longer readable by the user.
+func (b *builder) entry(ctx context.Context, n notifications.Notification) (Entry, bool, error) {
+	note, ok := b.notes[*n.NoteID]
+	if !ok {
+		found, err := b.job.notes.GetByID(ctx, n.NoteID.String())
+		if err == nil && found != nil {
+			note, err = notes.ReadableVersion(ctx, b.job.notes, found, b.userID)
+			if err != nil {
+				return Entry{}, false, fmt.Errorf("failed to check note access: %w", err)
+			}
+		}
+		b.notes[*n.NoteID] = note
+	}
+	if note == nil {
+		return Entry{}, false, nil
+	}
+
+	entry := Entry{Title: note.Title, URL: b.job.noteURL(note.ID), At: n.CreatedAt}
+	if n.ActorID != nil {
+		name, ok := b.names[*n.ActorID]
+		if !ok {
+			if actor, err := b.job.users.GetByID(ctx, n.ActorID.String()); err == nil && actor != nil {
+				name = actor.Username
+			}
+			b.names[*n.ActorID] = name
+		}
+		entry.Actor = name
+	}
+	return entry, true, nil
+}
@@ -0,0 +1,128 @@
+package digest
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/mailer"
+	"github.com/jehufrayle/grimoire/internal/notes"
+	"github.com/jehufrayle/grimoire/internal/notifications"
+	"github.com/jehufrayle/grimoire/internal/users"
+)
+
+func TestLastWeek(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Skip("no time zone data")
+	}
+	tests := []struct {
+		now   string
+		start string
+	}{
+		{"2024-03-13T12:00:00+01:00", "2024-03-04"}, // Wednesday
+		{"2024-03-11T07:59:00+01:00", "2024-02-26"}, // Monday before sendHour
+		{"2024-03-11T08:00:00+01:00", "2024-03-04"}, // Monday at sendHour
+		{"2024-03-10T23:00:00Z", "2024-02-26"},      // already Monday in Madrid, but too early
+		{"2024-04-01T09:00:00+02:00", "2024-03-25"}, // week with the change to summer time
+	}
+	for _, tt := range tests {
+		now, _ := time.Parse(time.RFC3339, tt.now)
+		start, end := lastWeek(now, madrid)
+		if got := start.Format(time.DateOnly); got != tt.start {
+			t.Errorf("lastWeek(%s) starts %s, want %s", tt.now, got, tt.start)
+		}
+		if end.Weekday() != time.Monday || end.Hour() != 0 || end.AddDate(0, 0, -7) != start {
+			t.Errorf("lastWeek(%s) ends %s", tt.now, end)
+		}
+	}
+}
+
+// outbox records what is sent, failing while fail is set.
+type outbox struct {
+	mu   sync.Mutex
+	sent []*mailer.Message
+	fail bool
+}
+
+func (o *outbox) Send(ctx context.Context, msg *mailer.Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.fail {
+		return context.DeadlineExceeded
+	}
+	o.sent = append(o.sent, msg)
+	return nil
+}
+
+func TestJobSendsOncePerWeek(t *testing.T) {
+	ctx := context.Background()
+	userRepo := users.NewMemUserRepository()
+	newUser := func(name string, digest bool) uuid.UUID {
+		user := &users.User{Username: name, Email: name + "@example.com", Active: true}
+		if err := userRepo.Create(ctx, user, "secret"); err != nil {
+			t.Fatal(err)
+		}
+		if err := userRepo.UpdateSettings(ctx, user.ID, &users.Settings{WeeklyDigest: digest}); err != nil {
+			t.Fatal(err)
+		}
+		return uuid.MustParse(user.ID)
+	}
+	ana, bob := newUser("ana", true), newUser("bob", false)
+
+	noteRepo := notes.NewInMemoryNoteRepository()
+	remindAt := time.Now().Add(48 * time.Hour)
+	created := notes.Note{UserID: ana, Title: "Trip plan", RemindAt: &remindAt}
+	if err := noteRepo.Create(ctx, &created); err != nil {
+		t.Fatal(err)
+	}
+	if err := noteRepo.Create(ctx, &notes.Note{UserID: bob, Title: "Bob's"}); err != nil {
+		t.Fatal(err)
+	}
+	notificationRepo := notifications.NewInMemoryNotificationRepository()
+	comment := notifications.Notification{UserID: ana, Type: notifications.TypeComment, ActorID: &bob, NoteID: &created.ID}
+	if err := notificationRepo.Create(ctx, &comment); err != nil {
+		t.Fatal(err)
+	}
+
+	out := &outbox{fail: true}
+	job := NewJob(userRepo, noteRepo, notificationRepo, out, "https://grimoire.test/", time.Hour)
+	// The notes were written this week: run as of next week's Monday morning
+	now := time.Now().AddDate(0, 0, 7)
+	now = time.Date(now.Year(), now.Month(), now.Day()-(int(now.Weekday())+6)%7, 9, 0, 0, 0, time.UTC)
+
+	// A failed send is retried on the next run, and a sent one is not
+	for _, fail := range []bool{true, false, false} {
+		out.fail = fail
+		if err := job.RunOnce(ctx, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(out.sent) != 1 {
+		t.Fatalf("sent %d digests, want 1", len(out.sent))
+	}
+	msg := out.sent[0]
+	if msg.To != "ana@example.com" || !strings.HasPrefix(msg.Subject, "Your week in Grimoire") {
+		t.Errorf("sent %q to %s", msg.Subject, msg.To)
+	}
+	link := "https://grimoire.test/edit-note/" + created.ID.String()
+	for _, want := range []string{"Notes you created", "Trip plan", "New comments", "(bob)", link} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("text lacks %q:\n%s", want, msg.Text)
+		}
+	}
+	if !strings.Contains(msg.HTML, `<a href="`+link+`">Trip plan</a>`) {
+		t.Errorf("HTML lacks the link:\n%s", msg.HTML)
+	}
+
+	// The week after is quiet apart from the reminder, which has passed
+	if err := job.RunOnce(ctx, now.AddDate(0, 0, 7)); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.sent) != 1 {
+		t.Errorf("sent a digest for a quiet week")
+	}
+}
@@ -0,0 +1,142 @@
+package digest
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/mailer"
+	"github.com/jehufrayle/grimoire/internal/notes"
+	"github.com/jehufrayle/grimoire/internal/notifications"
+	"github.com/jehufrayle/grimoire/internal/users"
+)
+
+//go:embed templates
+var templates embed.FS
+
+var digestTemplate = mustParse("templates", "digest")
+
+func mustParse(dir, name string) *mailer.Template {
+	fsys, err := fs.Sub(templates, dir)
+	if err != nil {
+		panic(err)
+	}
+	t, err := mailer.ParseTemplate(fsys, name, map[string]any{
+		"day": func(t time.Time) string { return t.Format("Monday, January 2") },
+	})
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Job sends the weekly digests that are due. Each user's digest is claimed
+// before it is sent, so restarts and other instances running the job never
+// send one twice; a digest that fails to send is released and retried on
+// the next run.
+type Job struct {
+	users         users.UserRepository
+	notes         notes.NoteRepository
+	notifications notifications.NotificationRepository
+	mailer        mailer.Mailer
+	appURL        string
+	interval      time.Duration
+}
+
+// NewJob creates a new instance of Job. appURL is where the web app is
+// served, for links to notes; it may be empty.
+func NewJob(userRepo users.UserRepository, noteRepo notes.NoteRepository, notificationRepo notifications.NotificationRepository,
+	m mailer.Mailer, appURL string, interval time.Duration) *Job {
+	return &Job{
+		users:         userRepo,
+		notes:         noteRepo,
+		notifications: notificationRepo,
+		mailer:        m,
+		appURL:        strings.TrimRight(appURL, "/"),
+		interval:      interval,
+	}
+}
+
+// Run sends due digests until ctx is cancelled.
+func (j *Job) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.RunOnce(ctx, time.Now()); err != nil {
+			log.Printf("digest: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends the digests due at now that have not been sent yet.
+func (j *Job) RunOnce(ctx context.Context, now time.Time) error {
+	all, err := j.users.GetAll(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list users: %w", err)
+	}
+	for i := range all {
+		user := &all[i]
+		if !user.Active {
+			continue
+		}
+		if err := j.send(ctx, user, now); err != nil {
+			log.Printf("digest: user %s: %v", user.ID, err)
+		}
+	}
+	return nil
+}
+
+func (j *Job) send(ctx context.Context, user *users.User, now time.Time) error {
+	settings, err := j.users.GetSettings(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+	if !settings.WeeklyDigest {
+		return nil
+	}
+	start, end := lastWeek(now, settings.Location())
+	week := start.Format(time.DateOnly)
+
+	claimed, err := j.users.ClaimDigest(ctx, user.ID, week)
+	if err != nil || !claimed {
+		return err
+	}
+	if err := j.deliver(ctx, user, start, end, now); err != nil {
+		if rerr := j.users.ReleaseDigest(ctx, user.ID, week); rerr != nil {
+			log.Printf("digest: user %s: failed to release week %s: %v", user.ID, week, rerr)
+		}
+		return err
+	}
+	return nil
+}
+
+// deliver builds and sends a digest. Quiet weeks send nothing.
+func (j *Job) deliver(ctx context.Context, user *users.User, start, end, now time.Time) error {
+	d, err := j.build(ctx, user, start, end, now)
+	if err != nil || d.empty() {
+		return err
+	}
+	msg, err := digestTemplate.Render(user.Email, d)
+	if err != nil {
+		return err
+	}
+	return j.mailer.Send(ctx, msg)
+}
+
+func (j *Job) noteURL(id uuid.UUID) string {
+	if j.appURL == "" {
+		return ""
+	}
+	return j.appURL + "/edit-note/" + id.String()
+}
@@ -0,0 +1,75 @@
+package encryption
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/notes"
+	"github.com/jehufrayle/grimoire/middleware"
+	"github.com/jehufrayle/grimoire/utils"
+)
+
+type Handler struct {
+	keyring *Keyring
+	notes   notes.NoteRepository
+}
+
+// NewHandler expects noteRepo to be wrapped by notes.NewEncryptedNoteRepository
+// so that re-saving a note encrypts it.
+func NewHandler(keyring *Keyring, noteRepo notes.NoteRepository) *Handler {
+	return &Handler{keyring: keyring, notes: noteRepo}
+}
+
+func (h *Handler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	userIDstr, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	userID, err := uuid.Parse(userIDstr)
+	if err != nil {
+		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	enabled, err := h.keyring.Enabled(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to check encryption status", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, map[string]bool{"enabled": enabled}, http.StatusOK)
+}
+
+// Enable opts the user in and encrypts the notes they already have.
+func (h *Handler) Enable(w http.ResponseWriter, r *http.Request) {
+	userIDstr, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	userID, err := uuid.Parse(userIDstr)
+	if err != nil {
+		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	// Read the notes before the data key exists, while they are all plaintext
+	existing, err := h.notes.GetByUserID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve notes for user", http.StatusInternalServerError)
+		return
+	}
+	if err := h.keyring.Enable(r.Context(), userID); err != nil {
+		http.Error(w, "Failed to enable encryption", http.StatusInternalServerError)
+		return
+	}
+	for i := range existing {
+		if err := h.notes.Update(r.Context(), &existing[i]); err != nil {
+			http.Error(w, "Failed to encrypt existing notes", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	utils.JSONResponse(w, map[string]bool{"enabled": true}, http.StatusOK)
+}
@@ -0,0 +1,140 @@
+package encryption
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Keyring hands out per-user data keys, unwrapping them with the master key
+// on first use. It implements notes.ContentCipher.
+type Keyring struct {
+	mu     sync.RWMutex
+	master []byte
+	store  KeyStore
+	cache  map[uuid.UUID][]byte // unwrapped data keys
+}
+
+func NewKeyring(master []byte, store KeyStore) *Keyring {
+	return &Keyring{
+		master: master,
+		store:  store,
+		cache:  make(map[uuid.UUID][]byte),
+	}
+}
+
+// dataKey returns the user's unwrapped data key, or nil if they have not opted in.
+func (k *Keyring) dataKey(ctx context.Context, userID uuid.UUID) ([]byte, error) {
+	k.mu.RLock()
+	key, ok := k.cache[userID]
+	master := k.master
+	k.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	wrapped, err := k.store.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if wrapped == nil {
+		return nil, nil
+	}
+	if wrapped.MasterKeyID != KeyID(master) {
+		return nil, fmt.Errorf("data key for user %s is wrapped by master key %s, not the loaded one", userID, wrapped.MasterKeyID)
+	}
+	key, err = Unwrap(master, wrapped.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	k.cache[userID] = key
+	k.mu.Unlock()
+	return key, nil
+}
+
+// Enabled reports whether the user has opted in to encryption at rest.
+func (k *Keyring) Enabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	key, err := k.dataKey(ctx, userID)
+	return key != nil, err
+}
+
+// Enable creates a data key for the user. It is a no-op if one already exists.
+func (k *Keyring) Enable(ctx context.Context, userID uuid.UUID) error {
+	enabled, err := k.Enabled(ctx, userID)
+	if err != nil || enabled {
+		return err
+	}
+
+	dataKey, err := NewDataKey()
+	if err != nil {
+		return err
+	}
+	k.mu.RLock()
+	master := k.master
+	k.mu.RUnlock()
+	wrapped, err := Wrap(master, dataKey)
+	if err != nil {
+		return fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	return k.store.Create(ctx, &WrappedKey{
+		UserID:      userID,
+		WrappedKey:  wrapped,
+		MasterKeyID: KeyID(master),
+	})
+}
+
+// Encrypt seals a note field for the user. Users who have not opted in get
+// the plaintext back unchanged.
+func (k *Keyring) Encrypt(ctx context.Context, userID uuid.UUID, plaintext string) (string, error) {
+	key, err := k.dataKey(ctx, userID)
+	if err != nil || key == nil {
+		return plaintext, err
+	}
+	return Seal(key, plaintext, userID[:])
+}
+
+// Decrypt opens a note field sealed by Encrypt. Values that were never
+// sealed are returned as-is. Only users with a data key have sealed fields,
+// so for anyone else a value that merely starts like one is their own text.
+func (k *Keyring) Decrypt(ctx context.Context, userID uuid.UUID, stored string) (string, error) {
+	if !IsSealed(stored) {
+		return stored, nil
+	}
+	key, err := k.dataKey(ctx, userID)
+	if err != nil || key == nil {
+		return stored, err
+	}
+	return Open(key, stored, userID[:])
+}
+
+// Rotate re-wraps every data key with newMaster and switches the keyring to
+// it. Note contents are untouched since the data keys themselves do not change.
+// Keys already wrapped by newMaster are skipped, so an interrupted or
+// repeated rotation picks up where it left off.
+func (k *Keyring) Rotate(ctx context.Context, newMaster []byte) (int, error) {
+	if len(newMaster) != KeySize {
+		return 0, fmt.Errorf("master key must be %d bytes, got %d", KeySize, len(newMaster))
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	oldMaster := k.master
+	count, err := k.store.RewrapAll(ctx, KeyID(newMaster), func(wrapped []byte) ([]byte, error) {
+		dataKey, err := Unwrap(oldMaster, wrapped)
+		if err != nil {
+			return nil, err
+		}
+		return Wrap(newMaster, dataKey)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	k.master = newMaster
+	return count, nil
+}
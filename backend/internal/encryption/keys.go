@@ -0,0 +1,148 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeySize is the size in bytes of both master keys and data keys (AES-256).
+const KeySize = 32
+
+// sealedPrefix marks values produced by Seal so plaintext written before a
+// user opted in can still be told apart and read as-is.
+const sealedPrefix = "enc:v1:"
+
+// LoadMasterKey reads the master key from the file named by
+// GRIMOIRE_MASTER_KEY_FILE or, failing that, from GRIMOIRE_MASTER_KEY.
+// It returns a nil key when neither is set, which disables encryption.
+func LoadMasterKey() ([]byte, error) {
+	if path := os.Getenv("GRIMOIRE_MASTER_KEY_FILE"); path != "" {
+		return ReadKeyFile(path)
+	}
+	if value := os.Getenv("GRIMOIRE_MASTER_KEY"); value != "" {
+		return parseKey([]byte(value))
+	}
+	return nil, nil
+}
+
+// ReadKeyFile reads a master key stored either as 32 raw bytes or base64.
+func ReadKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read master key file: %w", err)
+	}
+	if len(data) == KeySize {
+		return data, nil
+	}
+	return parseKey(data)
+}
+
+func parseKey(data []byte) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("master key is not valid base64: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", KeySize, len(key))
+	}
+	return key, nil
+}
+
+// KeyID returns a short fingerprint identifying a master key without revealing it.
+func KeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// NewDataKey generates a random data key.
+func NewDataKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	return key, nil
+}
+
+// encrypt seals plaintext with AES-GCM and returns nonce || ciphertext.
+func encrypt(key, plaintext, aad []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// decrypt opens a value produced by encrypt.
+func decrypt(key, sealed, aad []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, aad)
+}
+
+// Wrap encrypts a data key with the master key.
+func Wrap(master, dataKey []byte) ([]byte, error) {
+	return encrypt(master, dataKey, []byte("grimoire-data-key"))
+}
+
+// Unwrap decrypts a data key wrapped by Wrap.
+func Unwrap(master, wrapped []byte) ([]byte, error) {
+	key, err := decrypt(master, wrapped, []byte("grimoire-data-key"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	return key, nil
+}
+
+// Seal encrypts a text field with a data key. The aad binds the ciphertext to
+// its owner so it cannot be moved between users.
+func Seal(dataKey []byte, plaintext string, aad []byte) (string, error) {
+	sealed, err := encrypt(dataKey, []byte(plaintext), aad)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt: %w", err)
+	}
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value produced by Seal.
+func Open(dataKey []byte, value string, aad []byte) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, sealedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("invalid ciphertext encoding: %w", err)
+	}
+	plaintext, err := decrypt(dataKey, raw, aad)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// IsSealed reports whether a stored value was produced by Seal.
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, sealedPrefix)
+}
@@ -0,0 +1,146 @@
+package encryption
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSealAndOpen(t *testing.T) {
+	key, err := NewDataKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	owner := uuid.New()
+
+	sealed, err := Seal(key, "incident notes", owner[:])
+	if err != nil {
+		t.Fatalf("failed to seal: %v", err)
+	}
+	if !IsSealed(sealed) {
+		t.Fatalf("expected sealed value, got %q", sealed)
+	}
+
+	plaintext, err := Open(key, sealed, owner[:])
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	if plaintext != "incident notes" {
+		t.Errorf("expected %q, got %q", "incident notes", plaintext)
+	}
+
+	other := uuid.New()
+	if _, err := Open(key, sealed, other[:]); err == nil {
+		t.Error("expected opening with another owner to fail")
+	}
+}
+
+func TestKeyringRotate(t *testing.T) {
+	ctx := context.Background()
+	oldMaster, _ := NewDataKey()
+	newMaster, _ := NewDataKey()
+	store := NewInMemoryKeyStore()
+	userID := uuid.New()
+	optedOut := uuid.New()
+
+	keyring := NewKeyring(oldMaster, store)
+	if err := keyring.Enable(ctx, userID); err != nil {
+		t.Fatalf("failed to enable: %v", err)
+	}
+	sealed, err := keyring.Encrypt(ctx, userID, "secret")
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	plain, err := keyring.Encrypt(ctx, optedOut, "public")
+	if err != nil || plain != "public" {
+		t.Fatalf("expected opted-out content to stay plaintext, got %q (%v)", plain, err)
+	}
+
+	count, err := keyring.Rotate(ctx, newMaster)
+	if err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 rewrapped key, got %d", count)
+	}
+
+	// A fresh keyring with only the new master must read old ciphertext
+	rotated := NewKeyring(newMaster, store)
+	got, err := rotated.Decrypt(ctx, userID, sealed)
+	if err != nil {
+		t.Fatalf("failed to decrypt after rotation: %v", err)
+	}
+	if got != "secret" {
+		t.Errorf("expected %q, got %q", "secret", got)
+	}
+
+	stale := NewKeyring(oldMaster, store)
+	if _, err := stale.Decrypt(ctx, userID, sealed); err == nil {
+		t.Error("expected the old master key to be rejected after rotation")
+	}
+}
+
+func TestKeyringDecryptLookalike(t *testing.T) {
+	ctx := context.Background()
+	master, _ := NewDataKey()
+	keyring := NewKeyring(master, NewInMemoryKeyStore())
+	optedOut := uuid.New()
+
+	// Text that only looks sealed belongs to a user without a data key
+	lookalike := sealedPrefix + "not really"
+	got, err := keyring.Decrypt(ctx, optedOut, lookalike)
+	if err != nil || got != lookalike {
+		t.Errorf("Decrypt(%q) = %q, %v", lookalike, got, err)
+	}
+
+	// Once they opt in, the same text is sealed like anything else
+	if err := keyring.Enable(ctx, optedOut); err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := keyring.Encrypt(ctx, optedOut, lookalike)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := keyring.Decrypt(ctx, optedOut, sealed); err != nil || got != lookalike {
+		t.Errorf("round trip = %q, %v", got, err)
+	}
+}
+
+func TestKeyringRotateResumes(t *testing.T) {
+	ctx := context.Background()
+	oldMaster, _ := NewDataKey()
+	newMaster, _ := NewDataKey()
+	store := NewInMemoryKeyStore()
+	early, late := uuid.New(), uuid.New()
+
+	if err := NewKeyring(oldMaster, store).Enable(ctx, early); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewKeyring(oldMaster, store).Rotate(ctx, newMaster); err != nil {
+		t.Fatal(err)
+	}
+
+	// A server still running with the old key lets another user opt in
+	running := NewKeyring(oldMaster, store)
+	if err := running.Enable(ctx, late); err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := running.Encrypt(ctx, late, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotating again re-wraps only the straggler
+	count, err := NewKeyring(oldMaster, store).Rotate(ctx, newMaster)
+	if err != nil {
+		t.Fatalf("second rotation: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("second rotation re-wrapped %d keys, want 1", count)
+	}
+	if got, err := NewKeyring(newMaster, store).Decrypt(ctx, late, sealed); err != nil || got != "secret" {
+		t.Errorf("after the second rotation: %q, %v", got, err)
+	}
+}
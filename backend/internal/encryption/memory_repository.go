@@ -0,0 +1,73 @@
+package encryption
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type InMemoryKeyStore struct {
+	mu   sync.RWMutex
+	keys map[uuid.UUID]WrappedKey
+}
+
+func NewInMemoryKeyStore() *InMemoryKeyStore {
+	return &InMemoryKeyStore{
+		keys: make(map[uuid.UUID]WrappedKey),
+	}
+}
+
+func (s *InMemoryKeyStore) Get(ctx context.Context, userID uuid.UUID) (*WrappedKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.keys[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &key, nil
+}
+
+func (s *InMemoryKeyStore) Create(ctx context.Context, key *WrappedKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.keys[key.UserID]; exists {
+		return fmt.Errorf("data key for user %s already exists", key.UserID)
+	}
+	now := time.Now()
+	key.CreatedAt = now
+	key.RotatedAt = now
+	s.keys[key.UserID] = *key
+	return nil
+}
+
+func (s *InMemoryKeyStore) RewrapAll(ctx context.Context, newMasterKeyID string, rewrap func(wrapped []byte) ([]byte, error)) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Rewrap everything first so a failure leaves the store untouched
+	updated := make(map[uuid.UUID]WrappedKey, len(s.keys))
+	now := time.Now()
+	count := 0
+	for id, key := range s.keys {
+		if key.MasterKeyID == newMasterKeyID {
+			updated[id] = key
+			continue
+		}
+		wrapped, err := rewrap(key.WrappedKey)
+		if err != nil {
+			return 0, fmt.Errorf("failed to rewrap key for user %s: %w", id, err)
+		}
+		key.WrappedKey = wrapped
+		key.MasterKeyID = newMasterKeyID
+		key.RotatedAt = now
+		updated[id] = key
+		count++
+	}
+	s.keys = updated
+	return count, nil
+}
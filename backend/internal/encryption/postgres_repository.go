@@ -0,0 +1,94 @@
+package encryption
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgKeyStore implements the KeyStore interface for PostgreSQL.
+type PgKeyStore struct {
+	DB *pgxpool.Pool
+}
+
+// NewPgKeyStore creates a new instance of PgKeyStore.
+func NewPgKeyStore(db *pgxpool.Pool) *PgKeyStore {
+	return &PgKeyStore{DB: db}
+}
+
+// Get retrieves the wrapped data key of a user.
+func (s *PgKeyStore) Get(ctx context.Context, userID uuid.UUID) (*WrappedKey, error) {
+	query := `SELECT user_id, wrapped_key, master_key_id, created_at, rotated_at FROM user_data_keys WHERE user_id = $1`
+	var key WrappedKey
+	err := s.DB.QueryRow(ctx, query, userID).Scan(&key.UserID, &key.WrappedKey, &key.MasterKeyID, &key.CreatedAt, &key.RotatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get data key: %w", err)
+	}
+	return &key, nil
+}
+
+// Create stores a new wrapped data key.
+func (s *PgKeyStore) Create(ctx context.Context, key *WrappedKey) error {
+	query := `
+        INSERT INTO user_data_keys (user_id, wrapped_key, master_key_id)
+        VALUES ($1, $2, $3)
+        RETURNING created_at, rotated_at`
+	err := s.DB.QueryRow(ctx, query, key.UserID, key.WrappedKey, key.MasterKeyID).Scan(&key.CreatedAt, &key.RotatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert data key: %w", err)
+	}
+	return nil
+}
+
+// RewrapAll re-wraps the data keys inside a single transaction.
+func (s *PgKeyStore) RewrapAll(ctx context.Context, newMasterKeyID string, rewrap func(wrapped []byte) ([]byte, error)) (int, error) {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `SELECT user_id, wrapped_key FROM user_data_keys WHERE master_key_id <> $1 FOR UPDATE`, newMasterKeyID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query data keys: %w", err)
+	}
+	var keys []WrappedKey
+	for rows.Next() {
+		var key WrappedKey
+		if err := rows.Scan(&key.UserID, &key.WrappedKey); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan data key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	for _, key := range keys {
+		wrapped, err := rewrap(key.WrappedKey)
+		if err != nil {
+			return 0, fmt.Errorf("failed to rewrap key for user %s: %w", key.UserID, err)
+		}
+		_, err = tx.Exec(ctx, `
+            UPDATE user_data_keys
+            SET wrapped_key = $1, master_key_id = $2, rotated_at = now()
+            WHERE user_id = $3`, wrapped, newMasterKeyID, key.UserID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to update data key: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit rotation: %w", err)
+	}
+	return len(keys), nil
+}
@@ -0,0 +1,18 @@
+package encryption
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Interface
+type KeyStore interface {
+	// Get returns the user's wrapped data key, or nil if they have not opted in.
+	Get(ctx context.Context, userID uuid.UUID) (*WrappedKey, error)
+	Create(ctx context.Context, key *WrappedKey) error
+	// RewrapAll replaces every key not yet wrapped by newMasterKeyID with the
+	// result of rewrap as a single atomic operation, and returns how many it
+	// replaced.
+	RewrapAll(ctx context.Context, newMasterKeyID string, rewrap func(wrapped []byte) ([]byte, error)) (int, error)
+}
@@ -0,0 +1,16 @@
+package encryption
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WrappedKey is a user's data key encrypted with a master key.
+type WrappedKey struct {
+	UserID      uuid.UUID `json:"user_id"`
+	WrappedKey  []byte    `json:"-"`
+	MasterKeyID string    `json:"master_key_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	RotatedAt   time.Time `json:"rotated_at"`
+}
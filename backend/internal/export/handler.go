@@ -0,0 +1,190 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/notes"
+	"github.com/jehufrayle/grimoire/middleware"
+	"github.com/jehufrayle/grimoire/utils"
+)
+
+type Handler struct {
+	notes      notes.NoteRepository
+	searches   notes.SavedSearchRepository
+	properties notes.PropertyDefinitionRepository
+}
+
+func NewHandler(noteRepo notes.NoteRepository, searches notes.SavedSearchRepository, properties notes.PropertyDefinitionRepository) *Handler {
+	return &Handler{notes: noteRepo, searches: searches, properties: properties}
+}
+
+func (h *Handler) Export(w http.ResponseWriter, r *http.Request) {
+	userIDstr, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	userID, err := uuid.Parse(userIDstr)
+	if err != nil {
+		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	// The export can be narrowed like any other note listing
+	filter, err := notes.FilterFromRequest(r.Context(), r, userID, h.searches)
+	if err != nil {
+		if errors.Is(err, notes.ErrSavedSearchNotFound) {
+			http.Error(w, "Saved search not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var userNotes []notes.Note
+	if filter.IsEmpty() {
+		userNotes, err = h.notes.GetByUserID(r.Context(), userID)
+	} else {
+		userNotes, err = h.notes.Find(r.Context(), userID, filter)
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve notes for user", http.StatusInternalServerError)
+		return
+	}
+	if userNotes == nil {
+		userNotes = []notes.Note{}
+	}
+
+	searches, err := h.searches.GetByUserID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve saved searches", http.StatusInternalServerError)
+		return
+	}
+	if searches == nil {
+		searches = []notes.SavedSearch{}
+	}
+
+	defs, err := h.properties.GetByUserID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve properties", http.StatusInternalServerError)
+		return
+	}
+
+	archive := Archive{
+		Version:       ArchiveVersion,
+		ExportedAt:    time.Now().UTC(),
+		Notes:         userNotes,
+		SavedSearches: searches,
+		Properties:    defs,
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="grimoire-export.json"`)
+	utils.JSONResponse(w, archive, http.StatusOK)
+}
+
+// Import recreates the notes of an archive under the caller's account. IDs
+// and timestamps are assigned anew; everything else is kept as-is.
+func (h *Handler) Import(w http.ResponseWriter, r *http.Request) {
+	userIDstr, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	userID, err := uuid.Parse(userIDstr)
+	if err != nil {
+		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	var archive Archive
+	if err := json.NewDecoder(r.Body).Decode(&archive); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if archive.Version != ArchiveVersion {
+		http.Error(w, fmt.Sprintf("Unsupported archive version %d", archive.Version), http.StatusBadRequest)
+		return
+	}
+
+	// Validate everything up front so a bad entry does not leave a partial import.
+	// Notes are checked against the archived property definitions on top of
+	// the ones the account already has.
+	defs, err := h.properties.GetByUserID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve properties", http.StatusInternalServerError)
+		return
+	}
+	for i := range archive.Properties {
+		def := &archive.Properties[i]
+		def.UserID = userID
+		if err := def.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("Property %q: %v", def.Name, err), http.StatusBadRequest)
+			return
+		}
+		defs = append(defs, *def) // later entries win in ValidateProperties
+	}
+	for i := range archive.Notes {
+		if err := notes.ValidateEncryption(&archive.Notes[i]); err != nil {
+			http.Error(w, fmt.Sprintf("Note %d: %v", i, err), http.StatusBadRequest)
+			return
+		}
+		if err := notes.ValidateSchedule(&archive.Notes[i]); err != nil {
+			http.Error(w, fmt.Sprintf("Note %d: %v", i, err), http.StatusBadRequest)
+			return
+		}
+		archive.Notes[i].UserID = userID
+		if err := notes.SyncProperties(&archive.Notes[i], defs); err != nil {
+			http.Error(w, fmt.Sprintf("Note %d: %v", i, err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	for i := range archive.Properties {
+		if err := h.properties.Save(r.Context(), &archive.Properties[i]); err != nil {
+			http.Error(w, "Failed to import properties", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	imported := make([]notes.Note, 0, len(archive.Notes))
+	for _, source := range archive.Notes {
+		tags := make([]notes.Tag, len(source.Tags))
+		for i, tag := range source.Tags {
+			tags[i] = notes.Tag{Name: tag.Name}
+		}
+		note := notes.Note{
+			Title:        source.Title,
+			Content:      source.Content,
+			UserID:       userID,
+			IsPublic:     source.IsPublic,
+			Tags:         tags,
+			Encrypted:    source.Encrypted,
+			Envelope:     source.Envelope,
+			Properties:   source.Properties,
+			PublishAt:    source.PublishAt,
+			ExpiresAt:    source.ExpiresAt,
+			ExpireAction: source.ExpireAction,
+			RemindAt:     source.RemindAt,
+		}
+		if err := h.notes.Create(r.Context(), &note); err != nil {
+			http.Error(w, "Failed to import notes", http.StatusInternalServerError)
+			return
+		}
+		imported = append(imported, note)
+	}
+
+	for _, source := range archive.SavedSearches {
+		search := notes.SavedSearch{UserID: userID, Name: source.Name, Filter: source.Filter}
+		if err := h.searches.Create(r.Context(), &search); err != nil {
+			http.Error(w, "Failed to import saved searches", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	utils.JSONResponse(w, imported, http.StatusCreated)
+}
@@ -0,0 +1,21 @@
+package export
+
+import (
+	"time"
+
+	"github.com/jehufrayle/grimoire/internal/notes"
+)
+
+// ArchiveVersion is bumped whenever the archive layout changes incompatibly.
+const ArchiveVersion = 1
+
+// Archive is the portable dump of an account. End-to-end encrypted notes are
+// carried as their envelopes, byte for byte.
+type Archive struct {
+	Version       int                 `json:"version"`
+	ExportedAt    time.Time           `json:"exported_at"`
+	Notes         []notes.Note        `json:"notes"`
+	SavedSearches []notes.SavedSearch `json:"saved_searches"`
+	// Properties are the property definitions the notes are validated against.
+	Properties []notes.PropertyDefinition `json:"properties,omitempty"`
+}
@@ -0,0 +1,231 @@
+// Package feeds publishes a user's public notes as RSS and Atom feeds.
+package feeds
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/markdown"
+	"github.com/jehufrayle/grimoire/internal/notes"
+	"github.com/jehufrayle/grimoire/internal/profiles"
+	"github.com/jehufrayle/grimoire/internal/users"
+	"github.com/jehufrayle/grimoire/utils"
+)
+
+// maxItems is how many of the most recently published notes a feed lists.
+const maxItems = 50
+
+type Handler struct {
+	users       users.UserRepository
+	notes       notes.NoteRepository
+	transcluder *notes.Transcluder
+}
+
+func NewHandler(userRepo users.UserRepository, noteRepo notes.NoteRepository, transcluder *notes.Transcluder) *Handler {
+	return &Handler{users: userRepo, notes: noteRepo, transcluder: transcluder}
+}
+
+// item is a published note ready to be written in either format.
+type item struct {
+	note *notes.Note
+	html string
+	link string
+}
+
+// feed is what both formats are built from.
+type feed struct {
+	user    *users.User
+	tags    []string
+	items   []item
+	self    string
+	home    string
+	updated time.Time
+}
+
+// GetRSS serves the user's public notes as RSS 2.0.
+func (h *Handler) GetRSS(w http.ResponseWriter, r *http.Request) {
+	f := h.load(w, r)
+	if f == nil {
+		return
+	}
+
+	channel := rssChannel{
+		Title:       f.title(),
+		Link:        f.home,
+		Description: "Public notes by " + f.user.Username,
+		SelfLink:    atomLink{Href: f.self, Rel: "self", Type: "application/rss+xml"},
+	}
+	if !f.updated.IsZero() {
+		channel.LastBuildDate = f.updated.Format(time.RFC1123Z)
+	}
+	for _, it := range f.items {
+		channel.Items = append(channel.Items, rssItem{
+			Title:       it.note.Title,
+			Link:        it.link,
+			GUID:        rssGUID{Value: it.note.ID.String()},
+			PubDate:     it.note.UpdatedAt.Format(time.RFC1123Z),
+			Description: it.html,
+			Categories:  tagNames(it.note),
+		})
+	}
+
+	serve(w, r, rssFeed{Version: "2.0", AtomNS: "http://www.w3.org/2005/Atom", Channel: channel},
+		"application/rss+xml; charset=utf-8", f.updated)
+}
+
+// GetAtom serves the user's public notes as Atom 1.0.
+func (h *Handler) GetAtom(w http.ResponseWriter, r *http.Request) {
+	f := h.load(w, r)
+	if f == nil {
+		return
+	}
+
+	updated := f.updated
+	if updated.IsZero() {
+		updated = f.user.CreatedAt // Atom requires an updated date even for an empty feed
+	}
+	atom := atomFeed{
+		Title:   f.title(),
+		ID:      f.self,
+		Updated: updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: f.self, Rel: "self", Type: "application/atom+xml"},
+			{Href: f.home, Rel: "alternate"},
+		},
+		Author: atomAuthor{Name: f.user.Username},
+	}
+	for _, it := range f.items {
+		entry := atomEntry{
+			Title:     it.note.Title,
+			ID:        "urn:uuid:" + it.note.ID.String(),
+			Updated:   it.note.UpdatedAt.UTC().Format(time.RFC3339),
+			Published: it.note.CreatedAt.UTC().Format(time.RFC3339),
+			Link:      atomLink{Href: it.link, Rel: "alternate"},
+			Content:   atomContent{Type: "html", Value: it.html},
+		}
+		for _, tag := range tagNames(it.note) {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		atom.Entries = append(atom.Entries, entry)
+	}
+
+	serve(w, r, atom, "application/atom+xml; charset=utf-8", f.updated)
+}
+
+// load gathers the published notes of the user named in the path, narrowed
+// by any `tag` parameters. It writes the error response itself and returns
+// nil when the request should stop.
+func (h *Handler) load(w http.ResponseWriter, r *http.Request) *feed {
+	user, err := h.users.GetByUsername(r.Context(), r.PathValue("username"))
+	if err != nil || user == nil || !user.Active {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return nil
+	}
+	userID, err := uuid.Parse(user.ID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return nil
+	}
+
+	userNotes, err := h.notes.GetByUserID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve notes", http.StatusInternalServerError)
+		return nil
+	}
+
+	// Only published snapshots go out, never drafts, so tags are matched
+	// against the snapshot too
+	tags := r.URL.Query()["tag"]
+	filter := &notes.Filter{Tags: tags}
+	var published []*notes.Note
+	for i := range userNotes {
+		if version := userNotes[i].PublishedVersion(); version != nil && filter.Matches(version) {
+			published = append(published, version)
+		}
+	}
+	sort.Slice(published, func(i, j int) bool { return published[i].UpdatedAt.After(published[j].UpdatedAt) })
+	if len(published) > maxItems {
+		published = published[:maxItems]
+	}
+
+	base := utils.BaseURL(r)
+	f := &feed{
+		user: user,
+		tags: tags,
+		self: base + r.URL.RequestURI(),
+		home: base + "/api/public/users/" + user.Username,
+	}
+	for _, note := range published {
+		// Feed readers are anonymous, so embeds resolve to published notes only
+		body, err := h.transcluder.Expand(r.Context(), note, uuid.Nil)
+		if err != nil {
+			http.Error(w, "Failed to render notes", http.StatusInternalServerError)
+			return nil
+		}
+		html, err := markdown.Render(body)
+		if err != nil {
+			http.Error(w, "Failed to render notes", http.StatusInternalServerError)
+			return nil
+		}
+		link := base + "/api/public/notes/" + note.ID.String()
+		if note.Published.Slug != "" {
+			link = base + profiles.NotePath(user.Username, note.Published.Slug)
+		}
+		f.items = append(f.items, item{note: note, html: html, link: link})
+		if note.UpdatedAt.After(f.updated) {
+			f.updated = note.UpdatedAt
+		}
+	}
+	return f
+}
+
+func (f *feed) title() string {
+	if len(f.tags) > 0 {
+		return f.user.Username + "'s notes tagged " + strings.Join(f.tags, ", ")
+	}
+	return f.user.Username + "'s notes"
+}
+
+func tagNames(note *notes.Note) []string {
+	names := make([]string, len(note.Tags))
+	for i, tag := range note.Tags {
+		names[i] = tag.Name
+	}
+	return names
+}
+
+// serve writes the feed, answering If-None-Match with 304 Not Modified.
+// If-Modified-Since is not honored: lastModified is the newest item, which
+// stays put when a note is unpublished or deleted, so only the ETag notices
+// the feed shrinking.
+func serve(w http.ResponseWriter, r *http.Request, doc any, contentType string, lastModified time.Time) {
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		http.Error(w, "Failed to serialize feed", http.StatusInternalServerError)
+		return
+	}
+	body = append([]byte(xml.Header), body...)
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if match := r.Header.Get("If-None-Match"); match == etag || match == "*" {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
@@ -0,0 +1,68 @@
+package inbox
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"net/http"
+	"strings"
+
+	"github.com/jehufrayle/grimoire/internal/users"
+	"github.com/jehufrayle/grimoire/middleware"
+	"github.com/jehufrayle/grimoire/utils"
+)
+
+// Address is a user's inbox address.
+type Address struct {
+	Address string `json:"address"`
+}
+
+// tokenEncoding spells tokens in lowercase letters and digits, which
+// survive mail servers that change the case of addresses.
+var tokenEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type Handler struct {
+	users  users.UserRepository
+	domain string
+}
+
+func NewHandler(userRepo users.UserRepository, domain string) *Handler {
+	return &Handler{users: userRepo, domain: domain}
+}
+
+// CreateAddress issues a new inbox address and returns it. Any previous
+// address stops working.
+func (h *Handler) CreateAddress(w http.ResponseWriter, r *http.Request) {
+	userIDstr, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+	token := strings.ToLower(tokenEncoding.EncodeToString(secret))
+	if err := h.users.SetInboxToken(r.Context(), userIDstr, hashToken(token)); err != nil {
+		http.Error(w, "Failed to store token", http.StatusInternalServerError)
+		return
+	}
+
+	// The token is only ever shown here; only its hash is stored
+	utils.JSONResponse(w, Address{Address: addressPrefix + token + "@" + h.domain}, http.StatusCreated)
+}
+
+// DeleteAddress retires the inbox address.
+func (h *Handler) DeleteAddress(w http.ResponseWriter, r *http.Request) {
+	userIDstr, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	if err := h.users.SetInboxToken(r.Context(), userIDstr, ""); err != nil {
+		http.Error(w, "Failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,134 @@
+// Package inbox turns emails into notes. Each user can have a secret
+// address such as notes+<token>@grimoire.local; mail for it is received by
+// a built-in SMTP server and saved as a note in their account.
+package inbox
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+
+	"github.com/emersion/go-message"
+	_ "github.com/emersion/go-message/charset" // decode non-UTF-8 mail
+	"github.com/emersion/go-message/mail"
+	"github.com/jehufrayle/grimoire/internal/clipper"
+)
+
+// Message is an email reduced to what a note needs.
+type Message struct {
+	Title       string
+	Tags        []string
+	Content     string // Markdown
+	Attachments []File
+}
+
+// File is an attachment of a Message.
+type File struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// hashtag matches #tags in a subject, at its start or after a space.
+var hashtag = regexp.MustCompile(`(^|\s)#([\p{L}\p{N}_/-]+)`)
+
+// ParseMessage reads a MIME message. The subject is the title, its hashtags
+// the tags, and the HTML body, else the text one, the content. Every other
+// part, inline images included, is an attachment.
+func ParseMessage(r io.Reader) (*Message, error) {
+	mr, err := mail.CreateReader(r)
+	if err != nil && !message.IsUnknownCharset(err) {
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+	defer mr.Close()
+
+	subject, _ := mr.Header.Subject()
+	msg := &Message{}
+	msg.Title, msg.Tags = splitSubject(subject)
+
+	var texts, htmls []string
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil && !message.IsUnknownCharset(err) {
+			return nil, fmt.Errorf("failed to read message part: %w", err)
+		}
+		data, err := io.ReadAll(part.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read message part: %w", err)
+		}
+
+		switch h := part.Header.(type) {
+		case *mail.InlineHeader:
+			contentType, _, _ := h.ContentType()
+			switch contentType {
+			case "text/plain":
+				texts = append(texts, string(data))
+				continue
+			case "text/html":
+				htmls = append(htmls, string(data))
+				continue
+			}
+			msg.Attachments = append(msg.Attachments, file(&h.Header, "", data))
+		case *mail.AttachmentHeader:
+			filename, _ := h.Filename()
+			msg.Attachments = append(msg.Attachments, file(&h.Header, filename, data))
+		}
+	}
+
+	if len(htmls) > 0 {
+		for _, body := range htmls {
+			content, err := clipper.HTMLToMarkdown(body)
+			if err != nil {
+				return nil, err
+			}
+			msg.Content = joinBlocks(msg.Content, content)
+		}
+	} else {
+		for _, body := range texts {
+			msg.Content = joinBlocks(msg.Content, strings.TrimSpace(strings.ReplaceAll(body, "\r\n", "\n")))
+		}
+	}
+	return msg, nil
+}
+
+// splitSubject takes the hashtags out of a subject.
+func splitSubject(subject string) (string, []string) {
+	var tags []string
+	for _, m := range hashtag.FindAllStringSubmatch(subject, -1) {
+		tags = append(tags, m[2])
+	}
+	title := strings.Join(strings.Fields(hashtag.ReplaceAllString(subject, "$1")), " ")
+	if title == "" {
+		title = "Untitled email"
+	}
+	return title, tags
+}
+
+// file describes an attachment, naming it after its content type when the
+// message does not.
+func file(h *message.Header, filename string, data []byte) File {
+	contentType, _, err := h.ContentType()
+	if err != nil || contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	if filename == "" {
+		filename = "attachment"
+		if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+			filename += exts[0]
+		}
+	}
+	return File{Filename: filename, ContentType: contentType, Data: data}
+}
+
+func joinBlocks(a, b string) string {
+	if a == "" || b == "" {
+		return a + b
+	}
+	return a + "\n\n" + b
+}
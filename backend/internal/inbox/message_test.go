@@ -0,0 +1,86 @@
+package inbox
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMessage(t *testing.T) {
+	raw := strings.ReplaceAll(`From: Ana <ana@example.com>
+To: notes+abc@grimoire.local
+Subject: =?UTF-8?Q?Recibo_de_la_cena?= #receipts #2024/q1
+MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="outer"
+
+--outer
+Content-Type: multipart/alternative; boundary="inner"
+
+--inner
+Content-Type: text/plain; charset=utf-8
+
+Dinner was *great*.
+--inner
+Content-Type: text/html; charset=iso-8859-1
+Content-Transfer-Encoding: quoted-printable
+
+<html><body><p>Dinner was <b>great</b>, se=F1or.</p><img src=3D"cid:logo"></b=
+ody></html>
+--inner--
+--outer
+Content-Type: image/png
+Content-Disposition: attachment; filename="receipt.png"
+Content-Transfer-Encoding: base64
+
+iVBORw0KGgo=
+--outer
+Content-Type: image/gif
+Content-Disposition: inline
+Content-ID: <logo>
+Content-Transfer-Encoding: base64
+
+R0lGODlh
+--outer--
+`, "\n", "\r\n")
+
+	msg, err := ParseMessage(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("ParseMessage: %v", err)
+	}
+	if msg.Title != "Recibo de la cena" {
+		t.Errorf("title = %q", msg.Title)
+	}
+	if strings.Join(msg.Tags, ",") != "receipts,2024/q1" {
+		t.Errorf("tags = %q", msg.Tags)
+	}
+	// The HTML body wins over the text one, and cid: images are dropped
+	if msg.Content != "Dinner was **great**, señor." {
+		t.Errorf("content = %q", msg.Content)
+	}
+	if len(msg.Attachments) != 2 {
+		t.Fatalf("got %d attachments, want 2", len(msg.Attachments))
+	}
+	if a := msg.Attachments[0]; a.Filename != "receipt.png" || a.ContentType != "image/png" || string(a.Data) != "\x89PNG\r\n\x1a\n" {
+		t.Errorf("first attachment = %q %q %q", a.Filename, a.ContentType, a.Data)
+	}
+	if a := msg.Attachments[1]; a.Filename != "attachment.gif" || string(a.Data) != "GIF89a" {
+		t.Errorf("inline image = %q %q", a.Filename, a.Data)
+	}
+}
+
+func TestParsePlainMessage(t *testing.T) {
+	raw := "Subject: #todo\r\n\r\n  Buy milk\r\nand bread\r\n\r\n"
+
+	msg, err := ParseMessage(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("ParseMessage: %v", err)
+	}
+	if msg.Title != "Untitled email" || len(msg.Tags) != 1 || msg.Tags[0] != "todo" {
+		t.Errorf("title, tags = %q, %q", msg.Title, msg.Tags)
+	}
+	if msg.Content != "Buy milk\nand bread" {
+		t.Errorf("content = %q", msg.Content)
+	}
+	if len(msg.Attachments) != 0 {
+		t.Errorf("got %d attachments, want none", len(msg.Attachments))
+	}
+}
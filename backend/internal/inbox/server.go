@@ -0,0 +1,204 @@
+package inbox
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-smtp"
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/attachments"
+	"github.com/jehufrayle/grimoire/internal/notes"
+	"github.com/jehufrayle/grimoire/internal/users"
+)
+
+const (
+	// addressPrefix starts the local part of every inbox address.
+	addressPrefix = "notes+"
+
+	maxMessageSize = 25 << 20
+	maxRecipients  = 20
+)
+
+var (
+	errNoMailbox  = &smtp.SMTPError{Code: 550, EnhancedCode: smtp.EnhancedCode{5, 1, 1}, Message: "No such mailbox"}
+	errBadMessage = &smtp.SMTPError{Code: 554, EnhancedCode: smtp.EnhancedCode{5, 6, 0}, Message: "Message could not be read"}
+	errNotSaved   = &smtp.SMTPError{Code: 451, EnhancedCode: smtp.EnhancedCode{4, 3, 0}, Message: "Message could not be saved, try again later"}
+)
+
+// Server receives mail for inbox addresses over SMTP. It does not relay
+// and takes no logins: the secret address is what lets mail in.
+type Server struct {
+	smtp *smtp.Server
+}
+
+// NewServer returns a Server for the addresses of domain, listening on addr
+// once started.
+func NewServer(addr, domain string, userRepo users.UserRepository, noteRepo notes.NoteRepository, attachmentRepo attachments.AttachmentRepository) *Server {
+	s := smtp.NewServer(&backend{domain: domain, users: userRepo, notes: noteRepo, attachments: attachmentRepo})
+	s.Addr = addr
+	s.Domain = domain
+	s.MaxMessageBytes = maxMessageSize
+	s.MaxRecipients = maxRecipients
+	s.ReadTimeout = time.Minute
+	s.WriteTimeout = time.Minute
+	s.AuthDisabled = true
+	s.ErrorLog = log.Default()
+	return &Server{smtp: s}
+}
+
+// ListenAndServe listens on the server's address and serves until Close.
+func (s *Server) ListenAndServe() error {
+	return s.smtp.ListenAndServe()
+}
+
+// Serve serves connections from l until Close.
+func (s *Server) Serve(l net.Listener) error {
+	return s.smtp.Serve(l)
+}
+
+// Close stops the server and drops open connections.
+func (s *Server) Close() error {
+	return s.smtp.Close()
+}
+
+type backend struct {
+	domain      string
+	users       users.UserRepository
+	notes       notes.NoteRepository
+	attachments attachments.AttachmentRepository
+}
+
+func (b *backend) Login(state *smtp.ConnectionState, username, password string) (smtp.Session, error) {
+	return nil, smtp.ErrAuthUnsupported
+}
+
+func (b *backend) AnonymousLogin(state *smtp.ConnectionState) (smtp.Session, error) {
+	return &session{backend: b}, nil
+}
+
+// session is one SMTP conversation. Each message is saved as a note for
+// every recipient.
+type session struct {
+	*backend
+	recipients []*users.User
+}
+
+func (s *session) Reset() {
+	s.recipients = nil
+}
+
+func (s *session) Logout() error {
+	return nil
+}
+
+func (s *session) Mail(from string, opts smtp.MailOptions) error {
+	return nil
+}
+
+func (s *session) Rcpt(to string) error {
+	user := s.lookup(context.Background(), to)
+	if user == nil {
+		return errNoMailbox
+	}
+	s.recipients = append(s.recipients, user)
+	return nil
+}
+
+func (s *session) Data(r io.Reader) error {
+	msg, err := ParseMessage(r)
+	if err != nil {
+		var smtpErr *smtp.SMTPError
+		if errors.As(err, &smtpErr) {
+			return smtpErr
+		}
+		return errBadMessage
+	}
+
+	// Once a recipient has the note, a retry would give it a second one, so
+	// the message is accepted as soon as anyone got it
+	ctx := context.Background()
+	saved := 0
+	for _, user := range s.recipients {
+		if err := s.save(ctx, user, msg); err != nil {
+			log.Printf("❌ Failed to save email for user %s: %v", user.ID, err)
+			continue
+		}
+		saved++
+	}
+	if saved == 0 && len(s.recipients) > 0 {
+		return errNotSaved
+	}
+	return nil
+}
+
+// lookup finds the user an address belongs to, or returns nil.
+func (b *backend) lookup(ctx context.Context, address string) *users.User {
+	local, domain, ok := strings.Cut(address, "@")
+	if !ok || !strings.EqualFold(domain, b.domain) {
+		return nil
+	}
+	// Tokens are lowercase, and mail servers may change the case of addresses
+	token, ok := strings.CutPrefix(strings.ToLower(local), addressPrefix)
+	if !ok || token == "" {
+		return nil
+	}
+	user, err := b.users.GetByInboxToken(ctx, hashToken(token))
+	if err != nil || user == nil || !user.Active {
+		return nil
+	}
+	return user
+}
+
+// save creates the note and its attachments in the user's account. If an
+// attachment cannot be saved, the note and the attachments saved so far are
+// deleted again.
+func (b *backend) save(ctx context.Context, user *users.User, msg *Message) error {
+	userID, err := uuid.Parse(user.ID)
+	if err != nil {
+		return err
+	}
+	tags := make([]notes.Tag, len(msg.Tags))
+	for i, tag := range msg.Tags {
+		tags[i] = notes.Tag{Name: tag}
+	}
+	note := notes.Note{Title: msg.Title, Content: msg.Content, UserID: userID, Tags: tags}
+	if err := b.notes.Create(ctx, &note); err != nil {
+		return err
+	}
+
+	var saved []uuid.UUID
+	for _, f := range msg.Attachments {
+		attachment := attachments.Attachment{
+			NoteID:      note.ID,
+			UserID:      userID,
+			Filename:    f.Filename,
+			ContentType: f.ContentType,
+			Data:        f.Data,
+		}
+		if err := b.attachments.Create(ctx, &attachment); err != nil {
+			for _, id := range saved {
+				if err := b.attachments.Delete(ctx, id); err != nil {
+					log.Printf("❌ Failed to delete attachment %s of unsaved email: %v", id, err)
+				}
+			}
+			if err := b.notes.Delete(ctx, note.ID.String()); err != nil {
+				log.Printf("❌ Failed to delete note %s of unsaved email: %v", note.ID, err)
+			}
+			return err
+		}
+		saved = append(saved, attachment.ID)
+	}
+	return nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
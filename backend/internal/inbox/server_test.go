@@ -0,0 +1,171 @@
+package inbox
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/smtp"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/attachments"
+	"github.com/jehufrayle/grimoire/internal/notes"
+	"github.com/jehufrayle/grimoire/internal/users"
+)
+
+func TestServerSavesMail(t *testing.T) {
+	ctx := context.Background()
+	userRepo := users.NewMemUserRepository()
+	noteRepo := notes.NewInMemoryNoteRepository()
+	attachmentRepo := attachments.NewInMemoryAttachmentRepository()
+
+	user := &users.User{Username: "inbox", Email: "inbox@example.com", Active: true}
+	if err := userRepo.Create(ctx, user, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := userRepo.SetInboxToken(ctx, user.ID, hashToken("tok3n")); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer("", "grimoire.test", userRepo, noteRepo, attachmentRepo)
+	go server.Serve(l)
+	defer server.Close()
+
+	raw := strings.ReplaceAll(`Subject: Boarding pass #travel
+MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="b"
+
+--b
+Content-Type: text/plain
+
+Gate 12.
+--b
+Content-Type: application/pdf
+Content-Disposition: attachment; filename="pass.pdf"
+
+%PDF-1.4
+--b--
+`, "\n", "\r\n")
+	// The local part is matched whatever its case
+	if err := smtp.SendMail(l.Addr().String(), nil, "me@example.com", []string{"Notes+TOK3N@Grimoire.test"}, []byte(raw)); err != nil {
+		t.Fatalf("SendMail: %v", err)
+	}
+
+	owned, err := noteRepo.GetByUserID(ctx, uuid.MustParse(user.ID))
+	if err != nil || len(owned) != 1 {
+		t.Fatalf("notes = %v, %v", owned, err)
+	}
+	note := owned[0]
+	if note.Title != "Boarding pass" || note.Content != "Gate 12." || len(note.Tags) != 1 || note.Tags[0].Name != "travel" {
+		t.Errorf("note = %q %q %v", note.Title, note.Content, note.Tags)
+	}
+	files, err := attachmentRepo.ListByNote(ctx, note.ID)
+	if err != nil || len(files) != 1 || files[0].Filename != "pass.pdf" || files[0].Size != len("%PDF-1.4") {
+		t.Errorf("attachments = %+v, %v", files, err)
+	}
+
+	for _, to := range []string{"notes+wrong@grimoire.test", "notes+tok3n@elsewhere.test", "tok3n@grimoire.test"} {
+		if err := smtp.SendMail(l.Addr().String(), nil, "me@example.com", []string{to}, []byte(raw)); err == nil {
+			t.Errorf("mail to %s was accepted", to)
+		}
+	}
+}
+
+// failingAttachments fails to save the second attachment of a note for one
+// user.
+type failingAttachments struct {
+	attachments.AttachmentRepository
+	userID uuid.UUID
+	saved  map[uuid.UUID]int
+}
+
+func (r *failingAttachments) Create(ctx context.Context, attachment *attachments.Attachment) error {
+	if attachment.UserID == r.userID {
+		if r.saved[attachment.NoteID]++; r.saved[attachment.NoteID] == 2 {
+			return errors.New("disk full")
+		}
+	}
+	return r.AttachmentRepository.Create(ctx, attachment)
+}
+
+func TestServerSavesEachRecipientWhole(t *testing.T) {
+	ctx := context.Background()
+	userRepo := users.NewMemUserRepository()
+	noteRepo := notes.NewInMemoryNoteRepository()
+	newUser := func(name string) uuid.UUID {
+		user := &users.User{Username: name, Email: name + "@example.com", Active: true}
+		if err := userRepo.Create(ctx, user, "secret"); err != nil {
+			t.Fatal(err)
+		}
+		if err := userRepo.SetInboxToken(ctx, user.ID, hashToken(name)); err != nil {
+			t.Fatal(err)
+		}
+		return uuid.MustParse(user.ID)
+	}
+	alice, bob := newUser("alice"), newUser("bob")
+	attachmentRepo := &failingAttachments{
+		AttachmentRepository: attachments.NewInMemoryAttachmentRepository(),
+		userID:               bob,
+		saved:                make(map[uuid.UUID]int),
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer("", "grimoire.test", userRepo, noteRepo, attachmentRepo)
+	go server.Serve(l)
+	defer server.Close()
+
+	raw := strings.ReplaceAll(`Subject: Tickets
+MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="b"
+
+--b
+Content-Type: text/plain
+
+Both ways.
+--b
+Content-Type: application/pdf
+Content-Disposition: attachment; filename="out.pdf"
+
+%PDF-1.4
+--b
+Content-Type: application/pdf
+Content-Disposition: attachment; filename="back.pdf"
+
+%PDF-1.4
+--b--
+`, "\n", "\r\n")
+
+	// Alice got the note, so the message is accepted and not sent again
+	if err := smtp.SendMail(l.Addr().String(), nil, "me@example.com", []string{"notes+alice@grimoire.test", "notes+bob@grimoire.test"}, []byte(raw)); err != nil {
+		t.Fatalf("SendMail: %v", err)
+	}
+	owned, err := noteRepo.GetByUserID(ctx, alice)
+	if err != nil || len(owned) != 1 {
+		t.Fatalf("alice's notes = %v, %v", owned, err)
+	}
+	if files, err := attachmentRepo.ListByNote(ctx, owned[0].ID); err != nil || len(files) != 2 {
+		t.Fatalf("alice's attachments = %+v, %v", files, err)
+	}
+
+	// Bob is left with no half-saved note, and a message only for him is
+	// refused so that it is retried
+	if err := smtp.SendMail(l.Addr().String(), nil, "me@example.com", []string{"notes+bob@grimoire.test"}, []byte(raw)); err == nil {
+		t.Fatal("mail that could not be saved was accepted")
+	}
+	if owned, err := noteRepo.GetByUserID(ctx, bob); err != nil || len(owned) != 0 {
+		t.Fatalf("bob's notes = %v, %v", owned, err)
+	}
+	for noteID := range attachmentRepo.saved {
+		if files, err := attachmentRepo.ListByNote(ctx, noteID); err != nil || len(files) != 0 {
+			t.Fatalf("bob's attachments = %+v, %v", files, err)
+		}
+	}
+}
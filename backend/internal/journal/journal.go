@@ -0,0 +1,189 @@
+// Package journal persists in-memory stores as a snapshot plus an
+// append-only log of the changes made since, replayed on startup.
+//
+// Log entries must be idempotent, such as "this record now looks like
+// this": a crash between writing a snapshot and emptying the log replays
+// entries the snapshot already holds.
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	snapshotFile = "snapshot.json"
+	logFile      = "log.jsonl"
+)
+
+// Journal is the on-disk state of one store, kept in its own directory.
+type Journal struct {
+	mu  sync.Mutex
+	dir string
+	log *os.File
+}
+
+// Open opens the journal in dir, creating the directory if needed, and
+// replays it: load receives the snapshot, when there is one, and replay
+// each logged entry in order. A last entry cut short by a crash is dropped.
+func Open(dir string, load func(snapshot []byte) error, replay func(entry []byte) error) (*Journal, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %w", err)
+	}
+
+	snapshot, err := os.ReadFile(filepath.Join(dir, snapshotFile))
+	switch {
+	case err == nil:
+		if err := load(snapshot); err != nil {
+			return nil, fmt.Errorf("failed to load snapshot: %w", err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, logFile), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log: %w", err)
+	}
+	valid, err := replayLog(f, replay)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	// Cut off a torn last entry so new entries start on a line of their own
+	if err := f.Truncate(valid); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to truncate log: %w", err)
+	}
+	if _, err := f.Seek(valid, io.SeekStart); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to seek log: %w", err)
+	}
+
+	return &Journal{dir: dir, log: f}, nil
+}
+
+// replayLog feeds each complete line of the log to replay and returns the
+// length of the log up to the last complete line.
+func replayLog(r io.Reader, replay func(entry []byte) error) (int64, error) {
+	reader := bufio.NewReader(r)
+	var valid int64
+	for n := 1; ; n++ {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return valid, nil // anything read is a torn entry
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read log: %w", err)
+		}
+		if entry := bytes.TrimSpace(line); len(entry) > 0 {
+			if err := replay(entry); err != nil {
+				return 0, fmt.Errorf("failed to replay log entry %d: %w", n, err)
+			}
+		}
+		valid += int64(len(line))
+	}
+}
+
+// Append writes entry to the log as one line of JSON and syncs it to disk.
+func (j *Journal) Append(entry any) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode log entry: %w", err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := j.log.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write log entry: %w", err)
+	}
+	if err := j.log.Sync(); err != nil {
+		return fmt.Errorf("failed to sync log: %w", err)
+	}
+	return nil
+}
+
+// Snapshot replaces the snapshot with state and empties the log. Callers
+// must keep entries from being appended until it returns, or they are lost.
+func (j *Journal) Snapshot(state any) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	// Write aside and rename, so a crash leaves the old snapshot or the new
+	// one but never half of either
+	tmp := filepath.Join(j.dir, snapshotFile+".tmp")
+	if err := writeFileSync(tmp, data); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(j.dir, snapshotFile)); err != nil {
+		return fmt.Errorf("failed to replace snapshot: %w", err)
+	}
+	if dir, err := os.Open(j.dir); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+
+	if err := j.log.Truncate(0); err != nil {
+		return fmt.Errorf("failed to empty log: %w", err)
+	}
+	if _, err := j.log.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek log: %w", err)
+	}
+	return nil
+}
+
+func writeFileSync(name string, data []byte) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Close closes the log.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.log.Close()
+}
+
+// Every calls snapshot every interval until ctx is done.
+func Every(ctx context.Context, interval time.Duration, snapshot func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := snapshot(); err != nil {
+				log.Printf("❌ Snapshot failed: %v", err)
+			}
+		}
+	}
+}
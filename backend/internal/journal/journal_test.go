@@ -0,0 +1,119 @@
+package journal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// open opens the journal in dir and returns it with the snapshot and the
+// entries it replayed.
+func open(t *testing.T, dir string) (*Journal, []int, []int) {
+	t.Helper()
+	var snapshot, entries []int
+	j, err := Open(dir,
+		func(data []byte) error { return json.Unmarshal(data, &snapshot) },
+		func(data []byte) error {
+			var n int
+			err := json.Unmarshal(data, &n)
+			entries = append(entries, n)
+			return err
+		})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { j.Close() })
+	return j, snapshot, entries
+}
+
+func TestJournalReplaysSnapshotAndLog(t *testing.T) {
+	dir := t.TempDir()
+	j, _, _ := open(t, dir)
+	j.Append(1)
+	j.Append(2)
+	if err := j.Snapshot([]int{1, 2}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	j.Append(3)
+	j.Close()
+
+	_, snapshot, entries := open(t, dir)
+	if !slices.Equal(snapshot, []int{1, 2}) || !slices.Equal(entries, []int{3}) {
+		t.Fatalf("snapshot = %v, entries = %v", snapshot, entries)
+	}
+}
+
+func TestJournalDropsTornEntry(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, logFile), []byte("1\n2\n3"), 0o600)
+
+	j, _, entries := open(t, dir)
+	if !slices.Equal(entries, []int{1, 2}) {
+		t.Fatalf("entries = %v", entries)
+	}
+	j.Append(4)
+	j.Close()
+
+	_, _, entries = open(t, dir)
+	if !slices.Equal(entries, []int{1, 2, 4}) {
+		t.Fatalf("entries after append = %v", entries)
+	}
+}
+
+// counter is an in-memory store persisted through a Store.
+type counter struct {
+	mu      sync.RWMutex
+	n       int
+	journal *Store[int, int]
+}
+
+func openCounter(t *testing.T, dir string) *counter {
+	t.Helper()
+	c := &counter{}
+	var err error
+	c.journal, err = OpenStore(dir, &c.mu,
+		func() int { return c.n },
+		func(n int) { c.n = n },
+		func(delta int) { c.n += delta })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return c
+}
+
+func (c *counter) add(delta int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.n += delta
+	return c.journal.Log(delta)
+}
+
+func TestStoreReopens(t *testing.T) {
+	dir := t.TempDir()
+	c := openCounter(t, dir)
+	c.add(2)
+	if err := c.journal.Snapshot(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.add(3)
+
+	reopened := openCounter(t, dir) // as after a crash: no Close
+	if reopened.n != 5 {
+		t.Fatalf("n = %d", reopened.n)
+	}
+	if err := reopened.journal.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if closed := openCounter(t, dir); closed.n != 5 {
+		t.Fatalf("n after close = %d", closed.n)
+	}
+
+	// A store that is not persisted logs nothing
+	var unpersisted *Store[int, int]
+	if err := unpersisted.Log(1); err != nil || unpersisted.Snapshot() != nil || unpersisted.Close() != nil {
+		t.Fatalf("unpersisted store: %v", err)
+	}
+}
@@ -0,0 +1,24 @@
+// Package journaltest checks in-memory stores persisted with package journal.
+package journaltest
+
+import "testing"
+
+// Reopen opens a store in a fresh directory and runs write against it, then
+// opens the directory again without closing the first store, as after a
+// crash, and returns the second. write may snapshot midway so that what
+// follows only lives in the log.
+func Reopen[R any](t testing.TB, open func(dir string) (R, error), write func(R)) R {
+	t.Helper()
+	dir := t.TempDir()
+	store, err := open(dir)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	write(store)
+
+	reopened, err := open(dir)
+	if err != nil {
+		t.Fatalf("failed to reopen store: %v", err)
+	}
+	return reopened
+}
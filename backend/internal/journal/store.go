@@ -0,0 +1,76 @@
+package journal
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// Store is the journal of an in-memory store whose state is guarded by a
+// read-write lock. S is what a snapshot holds and E one logged change, both
+// kept as JSON. A nil *Store is a store that is not persisted: logging,
+// snapshots and closing do nothing.
+type Store[S, E any] struct {
+	journal *Journal
+	mu      *sync.RWMutex
+	state   func() S
+}
+
+// OpenStore opens the journal in dir for a store guarded by mu and replays
+// it: load receives the snapshot, when there is one, and apply each logged
+// change in order. state returns what the next snapshot should hold; it is
+// called under a read lock on mu.
+func OpenStore[S, E any](dir string, mu *sync.RWMutex, state func() S, load func(S), apply func(E)) (*Store[S, E], error) {
+	j, err := Open(dir,
+		func(data []byte) error {
+			var snapshot S
+			if err := json.Unmarshal(data, &snapshot); err != nil {
+				return err
+			}
+			load(snapshot)
+			return nil
+		},
+		func(data []byte) error {
+			var entry E
+			if err := json.Unmarshal(data, &entry); err != nil {
+				return err
+			}
+			apply(entry)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return &Store[S, E]{journal: j, mu: mu, state: state}, nil
+}
+
+// Log appends a change. Callers must hold the write lock on mu, so the
+// change cannot fall between a snapshot and the emptying of the log, and
+// should undo the change in memory when Log fails.
+func (s *Store[S, E]) Log(entry E) error {
+	if s == nil {
+		return nil
+	}
+	return s.journal.Append(entry)
+}
+
+// Snapshot writes the store's state to disk and empties the log.
+func (s *Store[S, E]) Snapshot() error {
+	if s == nil {
+		return nil
+	}
+	// Writers log under the write lock, so none can log in the meantime
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.journal.Snapshot(s.state())
+}
+
+// Close takes a last snapshot and closes the journal.
+func (s *Store[S, E]) Close() error {
+	if s == nil {
+		return nil
+	}
+	if err := s.Snapshot(); err != nil {
+		return err
+	}
+	return s.journal.Close()
+}
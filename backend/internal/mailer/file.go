@@ -0,0 +1,46 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FileMailer writes each message to its own .eml file in a directory
+// instead of sending it, for development and tests.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+// NewFileMailer returns a mailer dropping messages in dir, creating it if
+// needed.
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+// Send writes the message to a file named after when it was sent, so the
+// files list in order. It is renamed into place once complete.
+func (m *FileMailer) Send(ctx context.Context, msg *Message) error {
+	data, err := compose(m.from, msg)
+	if err != nil {
+		return err
+	}
+	name := time.Now().UTC().Format("20060102T150405.000000000Z") + "-" + uuid.NewString()[:8] + ".eml"
+	tmp := filepath.Join(m.dir, "."+name)
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write mail: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(m.dir, name)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write mail: %w", err)
+	}
+	return nil
+}
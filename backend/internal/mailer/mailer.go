@@ -0,0 +1,88 @@
+// Package mailer sends email from the backend, such as weekly digests. Mail
+// goes out through an SMTP relay or, for development, is dropped as .eml
+// files in a directory.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/emersion/go-message/mail"
+)
+
+// Message is an email with a plain text and an HTML version of its body.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer sends messages. Implementations are safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// FromEnv returns the mailer configured by the environment, or nil when mail
+// is not configured. GRIMOIRE_MAIL_SMTP_ADDR (host:port) sends through an
+// SMTP relay, logging in with GRIMOIRE_MAIL_SMTP_USERNAME and
+// GRIMOIRE_MAIL_SMTP_PASSWORD when they are set; otherwise GRIMOIRE_MAIL_DIR
+// drops messages in a directory. GRIMOIRE_MAIL_FROM is the sender.
+func FromEnv() (Mailer, error) {
+	from := os.Getenv("GRIMOIRE_MAIL_FROM")
+	addr, dir := os.Getenv("GRIMOIRE_MAIL_SMTP_ADDR"), os.Getenv("GRIMOIRE_MAIL_DIR")
+	if addr == "" && dir == "" {
+		return nil, nil
+	}
+	if from == "" {
+		return nil, errors.New("GRIMOIRE_MAIL_FROM is required to send mail")
+	}
+	if addr != "" {
+		return NewSMTPMailer(addr, from, os.Getenv("GRIMOIRE_MAIL_SMTP_USERNAME"), os.Getenv("GRIMOIRE_MAIL_SMTP_PASSWORD")), nil
+	}
+	return NewFileMailer(dir, from)
+}
+
+// compose encodes msg as a MIME message with both versions of the body.
+func compose(from string, msg *Message) ([]byte, error) {
+	var h mail.Header
+	h.SetDate(time.Now())
+	h.SetAddressList("From", []*mail.Address{{Address: from}})
+	h.SetAddressList("To", []*mail.Address{{Address: msg.To}})
+	h.SetSubject(msg.Subject)
+	if err := h.GenerateMessageID(); err != nil {
+		return nil, fmt.Errorf("failed to generate message ID: %w", err)
+	}
+
+	var buf bytes.Buffer
+	w, err := mail.CreateInlineWriter(&buf, h)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write message: %w", err)
+	}
+	// Clients show the last alternative they understand, so HTML goes last
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", msg.Text},
+		{"text/html", msg.HTML},
+	} {
+		var ph mail.InlineHeader
+		ph.SetContentType(part.contentType, map[string]string{"charset": "utf-8"})
+		pw, err := w.CreatePart(ph)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write message: %w", err)
+		}
+		if _, err := pw.Write([]byte(part.body)); err != nil {
+			return nil, fmt.Errorf("failed to write message: %w", err)
+		}
+		if err := pw.Close(); err != nil {
+			return nil, fmt.Errorf("failed to write message: %w", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write message: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,85 @@
+package mailer
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/emersion/go-message/mail"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewFileMailer(dir, "grimoire@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := &Message{To: "ana@example.com", Subject: "Tu semana", Text: "Hola", HTML: "<p>Hola</p>"}
+	if err := m.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("got %d files, want 1", len(files))
+	}
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	r, err := mail.CreateReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject, _ := r.Header.Subject(); subject != msg.Subject {
+		t.Errorf("subject = %q", subject)
+	}
+	if to, _ := r.Header.AddressList("To"); len(to) != 1 || to[0].Address != msg.To {
+		t.Errorf("to = %v", to)
+	}
+	var bodies []string
+	for {
+		part, err := r.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		contentType, _, _ := part.Header.(*mail.InlineHeader).ContentType()
+		body, _ := io.ReadAll(part.Body)
+		bodies = append(bodies, contentType+": "+string(body))
+	}
+	if strings.Join(bodies, "\n") != "text/plain: Hola\ntext/html: <p>Hola</p>" {
+		t.Errorf("parts = %q", bodies)
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	fsys := fstest.MapFS{
+		"hello.txt":  {Data: []byte(`{{define "subject"}} Hi {{.}} {{end}}Hello {{.}}`)},
+		"hello.html": {Data: []byte(`<p>Hello {{.}}</p>`)},
+	}
+	tmpl, err := ParseTemplate(fsys, "hello", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := tmpl.Render("ana@example.com", "<Ana>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "Hi <Ana>" || msg.Text != "Hello <Ana>" || msg.HTML != "<p>Hello &lt;Ana&gt;</p>" {
+		t.Errorf("rendered %+v", msg)
+	}
+
+	fsys["hello.txt"] = &fstest.MapFile{Data: []byte(`Hello`)}
+	if _, err := ParseTemplate(fsys, "hello", nil); err == nil {
+		t.Error("a template without a subject parsed")
+	}
+}
@@ -0,0 +1,38 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+)
+
+// SMTPMailer sends mail through an SMTP relay, upgrading to TLS when the
+// relay offers it.
+type SMTPMailer struct {
+	addr string
+	from string
+	auth smtp.Auth // nil to send without logging in
+}
+
+// NewSMTPMailer returns a mailer for the relay at addr. An empty username
+// sends without logging in.
+func NewSMTPMailer(addr, from, username, password string) *SMTPMailer {
+	m := &SMTPMailer{addr: addr, from: from}
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg *Message) error {
+	data, err := compose(m.from, msg)
+	if err != nil {
+		return err
+	}
+	if err := smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, data); err != nil {
+		return fmt.Errorf("failed to send mail to %s: %w", msg.To, err)
+	}
+	return nil
+}
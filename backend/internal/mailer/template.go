@@ -0,0 +1,49 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	texttemplate "text/template"
+)
+
+// Template renders one kind of email from a pair of templates, NAME.txt
+// and NAME.html, the latter escaped as HTML. NAME.txt also defines the
+// "subject" template.
+type Template struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// ParseTemplate parses NAME.txt and NAME.html from fsys. funcs are available
+// to both.
+func ParseTemplate(fsys fs.FS, name string, funcs map[string]any) (*Template, error) {
+	text, err := texttemplate.New(name+".txt").Funcs(funcs).ParseFS(fsys, name+".txt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse text template: %w", err)
+	}
+	html, err := htmltemplate.New(name+".html").Funcs(funcs).ParseFS(fsys, name+".html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML template: %w", err)
+	}
+	if text.Lookup("subject") == nil {
+		return nil, fmt.Errorf("%s.txt does not define a subject", name)
+	}
+	return &Template{text: text, html: html}, nil
+}
+
+// Render executes the templates for data and returns the message for to.
+func (t *Template) Render(to string, data any) (*Message, error) {
+	var subject, text, html bytes.Buffer
+	if err := t.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("failed to render subject: %w", err)
+	}
+	if err := t.text.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("failed to render text: %w", err)
+	}
+	if err := t.html.Execute(&html, data); err != nil {
+		return nil, fmt.Errorf("failed to render HTML: %w", err)
+	}
+	return &Message{To: to, Subject: string(bytes.TrimSpace(subject.Bytes())), Text: text.String(), HTML: html.String()}, nil
+}
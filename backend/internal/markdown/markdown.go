@@ -0,0 +1,26 @@
+// Package markdown renders note content to HTML for readers outside the app,
+// such as feed readers.
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// Raw HTML in notes is escaped rather than passed through, since the output
+// ends up in other people's readers.
+var renderer = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+)
+
+// Render converts Markdown to HTML.
+func Render(source string) (string, error) {
+	var buf bytes.Buffer
+	if err := renderer.Convert([]byte(source), &buf); err != nil {
+		return "", fmt.Errorf("failed to render markdown: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,60 @@
+package mentions
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/notes"
+	"github.com/jehufrayle/grimoire/middleware"
+	"github.com/jehufrayle/grimoire/utils"
+)
+
+type Handler struct {
+	repo  MentionRepository
+	notes notes.NoteRepository
+}
+
+func NewHandler(repo MentionRepository, noteRepo notes.NoteRepository) *Handler {
+	return &Handler{repo: repo, notes: noteRepo}
+}
+
+// GetMyMentions lists the notes that mention the current user, most recently
+// mentioned first. Notes the user can no longer read are left out of the
+// page but still count towards the total.
+func (h *Handler) GetMyMentions(w http.ResponseWriter, r *http.Request) {
+	userIDstr, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	userID, err := uuid.Parse(userIDstr)
+	if err != nil {
+		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	page, perPage := utils.Pagination(r, 20, 100)
+	mentions, total, err := h.repo.ListByUser(r.Context(), userID, perPage, (page-1)*perPage)
+	if err != nil {
+		http.Error(w, "Failed to retrieve mentions", http.StatusInternalServerError)
+		return
+	}
+
+	result := []notes.Note{}
+	for _, m := range mentions {
+		note, err := h.notes.GetByID(r.Context(), m.NoteID.String())
+		if err != nil || note == nil {
+			continue
+		}
+		readable, err := notes.ReadableVersion(r.Context(), h.notes, note, userID)
+		if err != nil {
+			http.Error(w, "Failed to check note access", http.StatusInternalServerError)
+			return
+		}
+		if readable != nil {
+			result = append(result, *readable)
+		}
+	}
+
+	utils.JSONResponse(w, notes.NotePage{Notes: result, Page: page, PerPage: perPage, Total: total}, http.StatusOK)
+}
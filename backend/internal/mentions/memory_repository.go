@@ -0,0 +1,149 @@
+package mentions
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/journal"
+)
+
+// InMemoryMentionRepository keeps mentions in memory. Opened with
+// OpenInMemoryMentionRepository it also persists them to disk.
+type InMemoryMentionRepository struct {
+	mu       sync.RWMutex
+	mentions map[uuid.UUID]map[uuid.UUID]time.Time        // note ID -> user ID -> mentioned at
+	journal  *journal.Store[[]mentionEntry, mentionEntry] // nil when not persisted
+}
+
+func NewInMemoryMentionRepository() *InMemoryMentionRepository {
+	return &InMemoryMentionRepository{
+		mentions: make(map[uuid.UUID]map[uuid.UUID]time.Time),
+	}
+}
+
+func (r *InMemoryMentionRepository) Replace(ctx context.Context, noteID uuid.UUID, userIDs []uuid.UUID) ([]uuid.UUID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old := r.mentions[noteID]
+	current := make(map[uuid.UUID]time.Time, len(userIDs))
+	var added []uuid.UUID
+	for _, id := range userIDs {
+		if _, ok := current[id]; ok {
+			continue
+		}
+		if at, ok := old[id]; ok {
+			current[id] = at
+			continue
+		}
+		current[id] = time.Now()
+		added = append(added, id)
+	}
+
+	r.set(noteID, current)
+	if err := r.journal.Log(mentionEntry{NoteID: noteID, Users: current}); err != nil {
+		r.set(noteID, old)
+		return nil, err
+	}
+	return added, nil
+}
+
+// set replaces who the note mentions.
+// Callers must hold the write lock.
+func (r *InMemoryMentionRepository) set(noteID uuid.UUID, users map[uuid.UUID]time.Time) {
+	if len(users) == 0 {
+		delete(r.mentions, noteID)
+	} else {
+		r.mentions[noteID] = users
+	}
+}
+
+func (r *InMemoryMentionRepository) ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]Mention, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []Mention
+	for noteID, users := range r.mentions {
+		if at, ok := users[userID]; ok {
+			result = append(result, Mention{NoteID: noteID, UserID: userID, CreatedAt: at})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.After(result[j].CreatedAt) })
+
+	total := len(result)
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return result[offset:end], total, nil
+}
+
+func (r *InMemoryMentionRepository) DeleteByNote(ctx context.Context, noteID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old, ok := r.mentions[noteID]
+	if !ok {
+		return nil
+	}
+	delete(r.mentions, noteID)
+	if err := r.journal.Log(mentionEntry{NoteID: noteID}); err != nil {
+		r.set(noteID, old)
+		return err
+	}
+	return nil
+}
+
+// mentionEntry is one change in the journal's log, and one note in a
+// snapshot: the users the note now mentions and since when.
+type mentionEntry struct {
+	NoteID uuid.UUID               `json:"note_id"`
+	Users  map[uuid.UUID]time.Time `json:"users,omitempty"`
+}
+
+// OpenInMemoryMentionRepository returns an InMemoryMentionRepository
+// persisted in dir. It starts from what dir holds and logs every write there
+// before returning. Snapshot keeps the log short and Close takes a last
+// snapshot.
+func OpenInMemoryMentionRepository(dir string) (*InMemoryMentionRepository, error) {
+	r := NewInMemoryMentionRepository()
+	apply := func(entry mentionEntry) { r.set(entry.NoteID, entry.Users) }
+	j, err := journal.OpenStore(dir, &r.mu, r.entries,
+		func(entries []mentionEntry) {
+			for _, entry := range entries {
+				apply(entry)
+			}
+		},
+		apply)
+	if err != nil {
+		return nil, err
+	}
+	r.journal = j
+	return r, nil
+}
+
+// Callers must hold at least a read lock.
+func (r *InMemoryMentionRepository) entries() []mentionEntry {
+	entries := make([]mentionEntry, 0, len(r.mentions))
+	for noteID, users := range r.mentions {
+		entries = append(entries, mentionEntry{NoteID: noteID, Users: users})
+	}
+	return entries
+}
+
+// Snapshot writes every mention to disk and empties the log. It is a no-op
+// when the repository is not persisted.
+func (r *InMemoryMentionRepository) Snapshot() error {
+	return r.journal.Snapshot()
+}
+
+// Close takes a last snapshot and closes the journal.
+func (r *InMemoryMentionRepository) Close() error {
+	return r.journal.Close()
+}
@@ -0,0 +1,58 @@
+// Package mentions tracks which users a note mentions with @username and
+// notifies them when they are first mentioned.
+package mentions
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/jehufrayle/grimoire/internal/notes"
+)
+
+// maxMentions caps how many users one note can mention, and so how many
+// lookups saving it costs.
+const maxMentions = 50
+
+var (
+	// mentionPattern matches @username at the start of the text or after a
+	// character that cannot end an email address or a path, so that
+	// ana@example.com and ./@scope/package mention nobody.
+	mentionPattern = regexp.MustCompile(`(^|[^A-Za-z0-9_@./\\-])@([A-Za-z0-9_][A-Za-z0-9_.-]*)`)
+	codeSpan       = regexp.MustCompile("`+[^`]*`+")
+)
+
+// Parse returns the usernames mentioned in Markdown content, in order of
+// first appearance. Mentions in front matter and code are ignored.
+func Parse(content string) []string {
+	if _, body, ok := notes.SplitFrontMatter(content); ok {
+		content = body
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	fenced := false
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fenced = !fenced
+			continue
+		}
+		if fenced || !strings.Contains(line, "@") {
+			continue
+		}
+		line = codeSpan.ReplaceAllString(line, " ")
+		for _, m := range mentionPattern.FindAllStringSubmatch(line, -1) {
+			// A mention ending a sentence does not take the full stop with it
+			name := strings.TrimRight(m[2], ".-")
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+			if len(names) == maxMentions {
+				return names
+			}
+		}
+	}
+	return names
+}
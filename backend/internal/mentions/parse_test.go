@@ -0,0 +1,33 @@
+package mentions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"start and middle", "@alice, ask @bob", []string{"alice", "bob"}},
+		{"repeated", "@alice and @alice again", []string{"alice"}},
+		{"sentence end", "Thanks @carol.\nAnd @dan-", []string{"carol", "dan"}},
+		{"dotted username", "cc @j.doe_2", []string{"j.doe_2"}},
+		{"punctuation", "(@alice) [@bob] **@carol**", []string{"alice", "bob", "carol"}},
+		{"email", "mail ana@example.com", nil},
+		{"path", "npm i ./@scope/pkg and @@alice", nil},
+		{"code span", "run `@alice` but tell @bob", []string{"bob"}},
+		{"fenced code", "```\n@alice\n```\n@bob", []string{"bob"}},
+		{"front matter", "---\nowner: \"@alice\"\n---\n@bob", []string{"bob"}},
+		{"lone at", "meet @ noon", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package mentions
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgMentionRepository implements the MentionRepository interface for PostgreSQL.
+type PgMentionRepository struct {
+	DB *pgxpool.Pool
+}
+
+// NewPgMentionRepository creates a new instance of PgMentionRepository.
+func NewPgMentionRepository(db *pgxpool.Pool) *PgMentionRepository {
+	return &PgMentionRepository{DB: db}
+}
+
+// Replace drops the mentions the note no longer makes and adds the new ones,
+// returning who was added.
+func (r *PgMentionRepository) Replace(ctx context.Context, noteID uuid.UUID, userIDs []uuid.UUID) ([]uuid.UUID, error) {
+	if userIDs == nil {
+		userIDs = []uuid.UUID{} // ANY(NULL) would match nothing and keep every mention
+	}
+
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx) // Rollback is a no-op if tx has been committed.
+
+	_, err = tx.Exec(ctx, `DELETE FROM note_mentions WHERE note_id = $1 AND NOT (user_id = ANY($2))`, noteID, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete mentions: %w", err)
+	}
+
+	query := `
+        INSERT INTO note_mentions (note_id, user_id)
+        SELECT $1, unnest($2::uuid[])
+        ON CONFLICT DO NOTHING
+        RETURNING user_id`
+	rows, err := tx.Query(ctx, query, noteID, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert mentions: %w", err)
+	}
+	var added []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan mention row: %w", err)
+		}
+		added = append(added, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to insert mentions: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return added, nil
+}
+
+// ListByUser retrieves a page of the user's mentions, most recent first.
+func (r *PgMentionRepository) ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]Mention, int, error) {
+	var total int
+	if err := r.DB.QueryRow(ctx, `SELECT COUNT(*) FROM note_mentions WHERE user_id = $1`, userID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count mentions: %w", err)
+	}
+
+	query := `
+        SELECT note_id, user_id, created_at
+        FROM note_mentions WHERE user_id = $1
+        ORDER BY created_at DESC
+        LIMIT $2 OFFSET $3`
+	rows, err := r.DB.Query(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query mentions: %w", err)
+	}
+	defer rows.Close()
+
+	var result []Mention
+	for rows.Next() {
+		var m Mention
+		if err := rows.Scan(&m.NoteID, &m.UserID, &m.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan mention row: %w", err)
+		}
+		result = append(result, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("row iteration error: %w", err)
+	}
+	return result, total, nil
+}
+
+// DeleteByNote removes the note's mentions. Deleting the note in the
+// database removes them too.
+func (r *PgMentionRepository) DeleteByNote(ctx context.Context, noteID uuid.UUID) error {
+	if _, err := r.DB.Exec(ctx, `DELETE FROM note_mentions WHERE note_id = $1`, noteID); err != nil {
+		return fmt.Errorf("failed to delete mentions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package mentions
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Interface
+type MentionRepository interface {
+	// Replace sets the users a note mentions and returns those it did not
+	// mention before.
+	Replace(ctx context.Context, noteID uuid.UUID, userIDs []uuid.UUID) ([]uuid.UUID, error)
+	// ListByUser returns the mentions of the user, most recent first, and
+	// how many there are in all.
+	ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]Mention, int, error)
+	DeleteByNote(ctx context.Context, noteID uuid.UUID) error
+}
+
+var (
+	_ MentionRepository = (*PgMentionRepository)(nil)
+	_ MentionRepository = (*InMemoryMentionRepository)(nil)
+	_ MentionRepository = (*SQLiteMentionRepository)(nil)
+)
@@ -0,0 +1,72 @@
+package mentions
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/database/sqlitetest"
+	"github.com/jehufrayle/grimoire/internal/journal/journaltest"
+	"github.com/jehufrayle/grimoire/internal/notes"
+)
+
+// checkReplace runs the same mentions through repo, which must know noteID
+// and the users.
+func checkReplace(t *testing.T, repo MentionRepository, noteID, alice, bob uuid.UUID) {
+	t.Helper()
+	ctx := context.Background()
+	if added, err := repo.Replace(ctx, noteID, []uuid.UUID{alice}); err != nil || !slices.Equal(added, []uuid.UUID{alice}) {
+		t.Fatalf("added = %v, %v", added, err)
+	}
+	// Alice is already mentioned, so only Bob is new
+	if added, err := repo.Replace(ctx, noteID, []uuid.UUID{alice, bob}); err != nil || !slices.Equal(added, []uuid.UUID{bob}) {
+		t.Fatalf("added = %v, %v", added, err)
+	}
+	if added, err := repo.Replace(ctx, noteID, []uuid.UUID{bob}); err != nil || len(added) != 0 {
+		t.Fatalf("added = %v, %v", added, err)
+	}
+	if list, total, err := repo.ListByUser(ctx, alice, 10, 0); err != nil || total != 0 {
+		t.Fatalf("alice = %v, %d, %v", list, total, err)
+	}
+	if list, total, err := repo.ListByUser(ctx, bob, 10, 0); err != nil || total != 1 || list[0].NoteID != noteID {
+		t.Fatalf("bob = %v, %d, %v", list, total, err)
+	}
+}
+
+func TestPersistedMemoryRepositoryReopens(t *testing.T) {
+	ctx := context.Background()
+	noteID, alice, bob := uuid.New(), uuid.New(), uuid.New()
+	reopened := journaltest.Reopen(t, OpenInMemoryMentionRepository, func(repo *InMemoryMentionRepository) {
+		checkReplace(t, repo, noteID, alice, bob)
+	})
+	if list, total, err := reopened.ListByUser(ctx, bob, 10, 0); err != nil || total != 1 || list[0].NoteID != noteID {
+		t.Fatalf("bob = %v, %d, %v", list, total, err)
+	}
+	if _, total, err := reopened.ListByUser(ctx, alice, 10, 0); err != nil || total != 0 {
+		t.Fatalf("alice = %d, %v", total, err)
+	}
+}
+
+func TestSQLiteRepository(t *testing.T) {
+	ctx := context.Background()
+	db := sqlitetest.Open(t)
+	owner, alice, bob := sqlitetest.NewUser(t, db), sqlitetest.NewUser(t, db), sqlitetest.NewUser(t, db)
+	noteRepo := notes.NewSQLiteNoteRepository(db)
+	note := notes.Note{UserID: owner, Title: "Plans"}
+	if err := noteRepo.Create(ctx, &note); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewSQLiteMentionRepository(db)
+	checkReplace(t, repo, note.ID, alice, bob)
+	if err := noteRepo.Delete(ctx, note.ID.String()); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeleteByNote(ctx, note.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, total, err := repo.ListByUser(ctx, bob, 10, 0); err != nil || total != 0 {
+		t.Fatalf("bob = %d, %v", total, err)
+	}
+}
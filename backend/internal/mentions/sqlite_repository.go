@@ -0,0 +1,110 @@
+package mentions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/database"
+)
+
+// SQLiteMentionRepository implements the MentionRepository interface for the
+// embedded SQLite backend.
+type SQLiteMentionRepository struct {
+	DB *sql.DB
+}
+
+// NewSQLiteMentionRepository creates a new instance of SQLiteMentionRepository.
+func NewSQLiteMentionRepository(db *sql.DB) *SQLiteMentionRepository {
+	return &SQLiteMentionRepository{DB: db}
+}
+
+// Replace drops the mentions the note no longer makes and adds the new ones,
+// returning who was added.
+func (r *SQLiteMentionRepository) Replace(ctx context.Context, noteID uuid.UUID, userIDs []uuid.UUID) ([]uuid.UUID, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback is a no-op if tx has been committed.
+
+	args := []any{noteID}
+	for _, id := range userIDs {
+		args = append(args, id)
+	}
+	query := `DELETE FROM note_mentions WHERE note_id = ?`
+	if len(userIDs) > 0 {
+		query += ` AND user_id NOT IN (` + strings.TrimSuffix(strings.Repeat("?, ", len(userIDs)), ", ") + `)`
+	}
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to delete mentions: %w", err)
+	}
+
+	now := database.Timestamp(time.Now())
+	var added []uuid.UUID
+	for _, id := range userIDs {
+		var inserted uuid.UUID
+		err := tx.QueryRowContext(ctx, `
+            INSERT INTO note_mentions (note_id, user_id, created_at)
+            VALUES (?, ?, ?)
+            ON CONFLICT DO NOTHING
+            RETURNING user_id`, noteID, id, now).Scan(&inserted)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue // already mentioned
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert mentions: %w", err)
+		}
+		added = append(added, inserted)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return added, nil
+}
+
+// ListByUser retrieves a page of the user's mentions, most recent first.
+func (r *SQLiteMentionRepository) ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]Mention, int, error) {
+	var total int
+	if err := r.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM note_mentions WHERE user_id = ?`, userID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count mentions: %w", err)
+	}
+
+	query := `
+        SELECT note_id, user_id, created_at
+        FROM note_mentions WHERE user_id = ?
+        ORDER BY created_at DESC
+        LIMIT ? OFFSET ?`
+	rows, err := r.DB.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query mentions: %w", err)
+	}
+	defer rows.Close()
+
+	var result []Mention
+	for rows.Next() {
+		var m Mention
+		if err := rows.Scan(&m.NoteID, &m.UserID, database.ScanTime{T: &m.CreatedAt}); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan mention row: %w", err)
+		}
+		result = append(result, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("row iteration error: %w", err)
+	}
+	return result, total, nil
+}
+
+// DeleteByNote removes the note's mentions. Deleting the note in the
+// database removes them too.
+func (r *SQLiteMentionRepository) DeleteByNote(ctx context.Context, noteID uuid.UUID) error {
+	if _, err := r.DB.ExecContext(ctx, `DELETE FROM note_mentions WHERE note_id = ?`, noteID); err != nil {
+		return fmt.Errorf("failed to delete mentions: %w", err)
+	}
+	return nil
+}
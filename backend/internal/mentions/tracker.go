@@ -0,0 +1,100 @@
+package mentions
+
+import (
+	"context"
+	"log"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/notes"
+	"github.com/jehufrayle/grimoire/internal/notifications"
+	"github.com/jehufrayle/grimoire/internal/users"
+)
+
+// Tracker keeps mentions in step with note writes and notifies users the
+// first time a note mentions them. It is a notes.NoteObserver.
+//
+// Only users who can read the mention count as mentioned, so mentioning
+// someone in a private note tells them nothing; sharing the note with them
+// and saving it again does. Readers of the published version are mentioned
+// by what it says, not by the draft. Owners mentioning themselves are
+// ignored.
+type Tracker struct {
+	repo          MentionRepository
+	users         users.UserRepository
+	notes         notes.NoteRepository
+	notifications notifications.Emitter
+}
+
+// NewTracker returns a Tracker. noteRepo is only read from, to check who can
+// read a note.
+func NewTracker(repo MentionRepository, userRepo users.UserRepository, noteRepo notes.NoteRepository, emitter notifications.Emitter) *Tracker {
+	return &Tracker{repo: repo, users: userRepo, notes: noteRepo, notifications: emitter}
+}
+
+func (t *Tracker) NoteSaved(ctx context.Context, note *notes.Note) {
+	if err := t.track(ctx, note); err != nil {
+		log.Printf("❌ Failed to track mentions in note %s: %v", note.ID, err)
+	}
+}
+
+func (t *Tracker) NoteDeleted(ctx context.Context, note *notes.Note) {
+	if err := t.repo.DeleteByNote(ctx, note.ID); err != nil {
+		log.Printf("❌ Failed to delete mentions of note %s: %v", note.ID, err)
+	}
+}
+
+func (t *Tracker) track(ctx context.Context, note *notes.Note) error {
+	var mentioned []uuid.UUID
+	// The content of encrypted notes is ciphertext
+	if !note.Encrypted {
+		// Each version is parsed once, keyed by its content
+		parsed := map[string][]string{note.Content: Parse(note.Content)}
+		candidates := slices.Clone(parsed[note.Content])
+		if published := note.PublishedVersion(); published != nil {
+			if _, ok := parsed[published.Content]; !ok {
+				parsed[published.Content] = Parse(published.Content)
+			}
+			for _, username := range parsed[published.Content] {
+				if !slices.Contains(candidates, username) {
+					candidates = append(candidates, username)
+				}
+			}
+		}
+
+		for _, username := range candidates {
+			user, err := t.users.GetByUsername(ctx, username)
+			if err != nil || user == nil || !user.Active {
+				continue // not a mention of anyone
+			}
+			userID, err := uuid.Parse(user.ID)
+			if err != nil || userID == note.UserID {
+				continue
+			}
+			version, err := notes.ReadableVersion(ctx, t.notes, note, userID)
+			if err != nil {
+				return err
+			}
+			if version != nil && slices.Contains(parsed[version.Content], username) {
+				mentioned = append(mentioned, userID)
+			}
+		}
+	}
+
+	added, err := t.repo.Replace(ctx, note.ID, mentioned)
+	if err != nil {
+		return err
+	}
+	for _, userID := range added {
+		notification := notifications.Notification{
+			UserID:  userID,
+			Type:    notifications.TypeMention,
+			ActorID: &note.UserID,
+			NoteID:  &note.ID,
+		}
+		if err := t.notifications.Emit(ctx, &notification); err != nil {
+			return err
+		}
+	}
+	return nil
+}
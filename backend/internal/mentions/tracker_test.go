@@ -0,0 +1,153 @@
+package mentions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/notes"
+	"github.com/jehufrayle/grimoire/internal/notifications"
+	"github.com/jehufrayle/grimoire/internal/users"
+)
+
+// sharedNotes stands in for shares, which the in-memory repository has no
+// way to create.
+type sharedNotes struct {
+	notes.NoteRepository
+	shared map[uuid.UUID]bool
+}
+
+func (r *sharedNotes) IsSharedWith(ctx context.Context, noteID, userID uuid.UUID) (bool, error) {
+	return r.shared[userID], nil
+}
+
+func TestTrackerNotifiesReaders(t *testing.T) {
+	ctx := context.Background()
+	userRepo := users.NewMemUserRepository()
+	newUser := func(name string) uuid.UUID {
+		user := &users.User{Username: name, Email: name + "@example.com", Active: true}
+		if err := userRepo.Create(ctx, user, "secret"); err != nil {
+			t.Fatal(err)
+		}
+		return uuid.MustParse(user.ID)
+	}
+	owner, alice, bob := newUser("owner"), newUser("alice"), newUser("bob")
+
+	noteRepo := &sharedNotes{NoteRepository: notes.NewInMemoryNoteRepository(), shared: map[uuid.UUID]bool{alice: true}}
+	mentionRepo := NewInMemoryMentionRepository()
+	notificationRepo := notifications.NewInMemoryNotificationRepository()
+	observed := notes.NewObservedNoteRepository(noteRepo, NewTracker(mentionRepo, userRepo, noteRepo, notifications.NewCenter(notificationRepo)))
+
+	notified := func(userID uuid.UUID) int {
+		_, total, err := notificationRepo.ListByUser(ctx, userID, false, 10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return total
+	}
+
+	note := notes.Note{UserID: owner, Title: "Plan", Content: "@alice @bob @owner @nobody"}
+	if err := observed.Create(ctx, &note); err != nil {
+		t.Fatal(err)
+	}
+	// Bob cannot read the note, the owner mentioned themselves and nobody is
+	// not a user
+	if notified(alice) != 1 || notified(bob) != 0 || notified(owner) != 0 {
+		t.Fatalf("notifications: alice %d, bob %d, owner %d", notified(alice), notified(bob), notified(owner))
+	}
+
+	// Saving again does not notify again, but sharing with Bob first does
+	noteRepo.shared[bob] = true
+	if err := observed.Update(ctx, &note); err != nil {
+		t.Fatal(err)
+	}
+	if notified(alice) != 1 || notified(bob) != 1 {
+		t.Fatalf("after update: alice %d, bob %d", notified(alice), notified(bob))
+	}
+
+	mentioned, total, err := mentionRepo.ListByUser(ctx, bob, 10, 0)
+	if err != nil || total != 1 || mentioned[0].NoteID != note.ID {
+		t.Fatalf("bob's mentions = %v, %d, %v", mentioned, total, err)
+	}
+
+	note.Content = "just @bob"
+	if err := observed.Update(ctx, &note); err != nil {
+		t.Fatal(err)
+	}
+	if _, total, _ := mentionRepo.ListByUser(ctx, alice, 10, 0); total != 0 {
+		t.Errorf("alice is still mentioned after being edited out")
+	}
+
+	if err := observed.Delete(ctx, note.ID.String()); err != nil {
+		t.Fatal(err)
+	}
+	if _, total, _ := mentionRepo.ListByUser(ctx, bob, 10, 0); total != 0 {
+		t.Errorf("bob is still mentioned in a deleted note")
+	}
+}
+
+func TestTrackerReadsPublishedVersion(t *testing.T) {
+	ctx := context.Background()
+	userRepo := users.NewMemUserRepository()
+	newUser := func(name string) uuid.UUID {
+		user := &users.User{Username: name, Email: name + "@example.com", Active: true}
+		if err := userRepo.Create(ctx, user, "secret"); err != nil {
+			t.Fatal(err)
+		}
+		return uuid.MustParse(user.ID)
+	}
+	owner, carol, dave := newUser("owner"), newUser("carol"), newUser("dave")
+
+	noteRepo := notes.NewInMemoryNoteRepository()
+	mentionRepo := NewInMemoryMentionRepository()
+	notificationRepo := notifications.NewInMemoryNotificationRepository()
+	observed := notes.NewObservedNoteRepository(noteRepo, NewTracker(mentionRepo, userRepo, noteRepo, notifications.NewCenter(notificationRepo)))
+	notified := func(userID uuid.UUID) int {
+		_, total, err := notificationRepo.ListByUser(ctx, userID, false, 10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return total
+	}
+
+	note := notes.Note{UserID: owner, Title: "Plan", Content: "Ask @dave"}
+	if err := observed.Create(ctx, &note); err != nil {
+		t.Fatal(err)
+	}
+	if err := observed.Publish(ctx, note.ID.String()); err != nil {
+		t.Fatal(err)
+	}
+	if notified(dave) != 1 {
+		t.Fatalf("dave was not notified of the published mention")
+	}
+
+	// Carol can read the note, but only the draft mentions Carol. Dave is
+	// edited out of the draft but still mentioned in the version Dave reads.
+	published, err := noteRepo.GetByID(ctx, note.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	published.Content = "Ask @carol"
+	if err := observed.Update(ctx, published); err != nil {
+		t.Fatal(err)
+	}
+	if notified(carol) != 0 {
+		t.Fatalf("carol was notified of a draft-only mention")
+	}
+	if _, total, _ := mentionRepo.ListByUser(ctx, carol, 10, 0); total != 0 {
+		t.Errorf("carol is mentioned by the draft")
+	}
+	if _, total, _ := mentionRepo.ListByUser(ctx, dave, 10, 0); total != 1 {
+		t.Errorf("dave is no longer mentioned by the published version")
+	}
+
+	if err := observed.Publish(ctx, note.ID.String()); err != nil {
+		t.Fatal(err)
+	}
+	if notified(carol) != 1 {
+		t.Fatalf("carol was not notified once the mention was published")
+	}
+	if _, total, _ := mentionRepo.ListByUser(ctx, dave, 10, 0); total != 0 {
+		t.Errorf("dave is still mentioned after the edit was published")
+	}
+}
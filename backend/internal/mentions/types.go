@@ -0,0 +1,14 @@
+package mentions
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Mention records that a note mentions a user who can read it.
+type Mention struct {
+	NoteID    uuid.UUID `json:"note_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,55 @@
+package notes
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// CanRead reports whether the user may read the note: they own it, it is
+// published, or it has been shared with them.
+func CanRead(ctx context.Context, repo NoteRepository, note *Note, userID uuid.UUID) (bool, error) {
+	version, err := ReadableVersion(ctx, repo, note, userID)
+	return version != nil, err
+}
+
+// ReadableVersion returns the version of the note the user may read: the
+// draft for the owner and users it is shared with, the published snapshot
+// for everyone else, or nil.
+func ReadableVersion(ctx context.Context, repo NoteRepository, note *Note, userID uuid.UUID) (*Note, error) {
+	if note.UserID == userID {
+		return note, nil
+	}
+	shared, err := repo.IsSharedWith(ctx, note.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if shared {
+		return note, nil
+	}
+	return note.PublishedVersion(), nil
+}
+
+// PublishedVersion returns a copy of the note showing its published snapshot
+// in place of the draft, or nil when the note is not public. Draft-only
+// fields such as the schedule, the reminder and the version are left out.
+func (n *Note) PublishedVersion() *Note {
+	if !n.IsPublic || n.Published == nil {
+		return nil
+	}
+	published := *n
+	published.Title = n.Published.Title
+	published.Content = n.Published.Content
+	published.Tags = n.Published.Tags
+	published.UpdatedAt = n.Published.PublishedAt
+	// The rest of the draft's metadata is the owner's business. Properties
+	// went out with the content's front matter.
+	published.Properties = nil
+	published.PublishAt = nil
+	published.ExpiresAt = nil
+	published.ExpireAction = ""
+	published.RemindAt = nil
+	published.DailyDate = ""
+	published.Version = 0
+	return &published
+}
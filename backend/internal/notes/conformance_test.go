@@ -0,0 +1,588 @@
+package notes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jehufrayle/grimoire/internal/database/sqlitetest"
+	"github.com/jehufrayle/grimoire/internal/journal/journaltest"
+	"github.com/jehufrayle/grimoire/internal/notifications"
+	"github.com/jehufrayle/grimoire/middleware"
+)
+
+// backend is a Storage under test and a way to make users for it.
+type backend struct {
+	repo    Storage
+	newUser func(t *testing.T) uuid.UUID
+}
+
+func randomUser(*testing.T) uuid.UUID { return uuid.New() }
+
+// backends lists the storages every behaviour test runs against. Postgres
+// only runs when GRIMOIRE_TEST_DATABASE_URL points at a migrated database.
+func backends(t *testing.T) map[string]func(t *testing.T) backend {
+	all := map[string]func(t *testing.T) backend{
+		"memory": func(t *testing.T) backend {
+			return backend{repo: NewInMemoryNoteRepository(), newUser: randomUser}
+		},
+		"persisted memory": func(t *testing.T) backend {
+			repo, err := OpenInMemoryNoteRepository(t.TempDir())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			t.Cleanup(func() { repo.Close() })
+			return backend{repo: repo, newUser: randomUser}
+		},
+		"git": func(t *testing.T) backend {
+			if _, err := exec.LookPath("git"); err != nil {
+				t.Skip("git not installed")
+			}
+			repo, err := NewGitNoteRepository(t.TempDir(), nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			return backend{repo: repo, newUser: randomUser}
+		},
+		"sqlite": func(t *testing.T) backend {
+			db := sqlitetest.Open(t)
+			newUser := func(t *testing.T) uuid.UUID { return sqlitetest.NewUser(t, db) }
+			return backend{repo: NewSQLiteNoteRepository(db), newUser: newUser}
+		},
+	}
+	if url := os.Getenv("GRIMOIRE_TEST_DATABASE_URL"); url != "" {
+		all["postgres"] = func(t *testing.T) backend {
+			db, err := pgxpool.New(context.Background(), url)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			t.Cleanup(db.Close)
+			newUser := func(t *testing.T) uuid.UUID {
+				var id uuid.UUID
+				name := "test-" + uuid.NewString()[:8]
+				err := db.QueryRow(context.Background(),
+					"INSERT INTO users (username, email, role) VALUES ($1, $2, 'user') RETURNING id", name, name+"@example.com").Scan(&id)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return id
+			}
+			return backend{repo: NewPgNoteRepository(db), newUser: newUser}
+		}
+	}
+	return all
+}
+
+func forEachBackend(t *testing.T, test func(t *testing.T, ctx context.Context, b backend)) {
+	for name, open := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			test(t, context.Background(), open(t))
+		})
+	}
+}
+
+func mustCreate(t *testing.T, ctx context.Context, repo NoteRepository, note Note) Note {
+	t.Helper()
+	if err := repo.Create(ctx, &note); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return note
+}
+
+func tagNames(note *Note) string {
+	names := make([]string, len(note.Tags))
+	for i, tag := range note.Tags {
+		names[i] = tag.Name
+	}
+	return strings.Join(names, ",")
+}
+
+func TestRepositoryCreateAndGet(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ctx context.Context, b backend) {
+		owner, other := b.newUser(t), b.newUser(t)
+		created := mustCreate(t, ctx, b.repo, Note{Title: "Plan", Content: "Steps", UserID: owner, Tags: []Tag{{Name: "Work"}}})
+		if created.ID == uuid.Nil || created.Version != 1 || created.CreatedAt.IsZero() {
+			t.Fatalf("created = %+v", created)
+		}
+
+		got, err := b.repo.GetByID(ctx, created.ID.String())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.Title != "Plan" || got.Content != "Steps" || got.UserID != owner || tagNames(got) != "work" {
+			t.Fatalf("got = %+v", got)
+		}
+
+		mine, _ := b.repo.GetByUserID(ctx, owner)
+		theirs, _ := b.repo.GetByUserID(ctx, other)
+		if len(mine) != 1 || len(theirs) != 0 {
+			t.Fatalf("owner has %d notes, other user %d", len(mine), len(theirs))
+		}
+		if _, err := b.repo.GetByID(ctx, uuid.NewString()); err == nil {
+			t.Fatal("unknown note should not be found")
+		}
+	})
+}
+
+func TestRepositoryUpdateChecksVersion(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ctx context.Context, b backend) {
+		note := mustCreate(t, ctx, b.repo, Note{Title: "Plan", Content: "v1", UserID: b.newUser(t)})
+
+		edit := note
+		edit.Content, edit.Tags = "v2", []Tag{{Name: "b"}, {Name: "a"}}
+		if err := b.repo.Update(ctx, &edit); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if edit.Version != 2 {
+			t.Fatalf("version = %d, want 2", edit.Version)
+		}
+
+		stale := note
+		stale.Content = "lost"
+		if err := b.repo.Update(ctx, &stale); !errors.Is(err, ErrVersionConflict) {
+			t.Fatalf("stale update: err = %v", err)
+		}
+
+		blind := note
+		blind.Version, blind.Content = 0, "v3"
+		if err := b.repo.Update(ctx, &blind); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, _ := b.repo.GetByID(ctx, note.ID.String())
+		if got.Content != "v3" || got.Version != 3 {
+			t.Fatalf("got = %+v", got)
+		}
+	})
+}
+
+func TestRepositoryDelete(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ctx context.Context, b backend) {
+		owner := b.newUser(t)
+		note := mustCreate(t, ctx, b.repo, Note{Title: "Gone", UserID: owner})
+		if err := b.repo.Delete(ctx, note.ID.String()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := b.repo.GetByID(ctx, note.ID.String()); err == nil {
+			t.Fatal("deleted note should not be found")
+		}
+		if notes, _ := b.repo.GetByUserID(ctx, owner); len(notes) != 0 {
+			t.Fatalf("deleted note still listed: %+v", notes)
+		}
+		if err := b.repo.Update(ctx, &note); err == nil {
+			t.Fatal("deleted note should not update")
+		}
+		if err := b.repo.Delete(ctx, note.ID.String()); err == nil {
+			t.Fatal("deleting twice should fail")
+		}
+	})
+}
+
+func TestRepositoryFindAndTags(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ctx context.Context, b backend) {
+		owner := b.newUser(t)
+		mustCreate(t, ctx, b.repo, Note{Title: "Garden", Content: "Plant tomatoes", UserID: owner, Tags: []Tag{{Name: "home"}}})
+		both := mustCreate(t, ctx, b.repo, Note{Title: "Budget", Content: "Tomatoes cost", UserID: owner, Tags: []Tag{{Name: "home"}, {Name: "money"}}})
+
+		found, _ := b.repo.Find(ctx, owner, &Filter{Tags: []string{"home", "money"}})
+		if len(found) != 1 || found[0].ID != both.ID {
+			t.Fatalf("tag filter = %+v", found)
+		}
+		found, _ = b.repo.Find(ctx, owner, &Filter{Text: "TOMATOES"})
+		if len(found) != 2 || found[0].ID != both.ID {
+			t.Fatalf("text filter = %+v, want newest first", found)
+		}
+		found, _ = b.repo.Find(ctx, owner, &Filter{Text: "GA"})
+		if len(found) != 1 || found[0].Title != "Garden" {
+			t.Fatalf("short text filter = %+v", found)
+		}
+		tagged, _ := b.repo.GetByTags(ctx, []string{"MONEY"})
+		if len(tagged) != 1 || tagged[0].ID != both.ID {
+			t.Fatalf("GetByTags = %+v", tagged)
+		}
+	})
+}
+
+func TestRepositoryPublishKeepsSnapshot(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ctx context.Context, b backend) {
+		note := mustCreate(t, ctx, b.repo, Note{Title: "Essay", Content: "First take", UserID: b.newUser(t), Tags: []Tag{{Name: "essays"}}})
+		if err := b.repo.Publish(ctx, note.ID.String()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		draft, _ := b.repo.GetByID(ctx, note.ID.String())
+		draft.Content = "Second take"
+		draft.Tags = []Tag{{Name: "secret"}}
+		if err := b.repo.Update(ctx, draft); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, _ := b.repo.GetByID(ctx, note.ID.String())
+		if !got.IsPublic || got.Published == nil || got.Published.Content != "First take" || got.Content != "Second take" {
+			t.Fatalf("got = %+v", got)
+		}
+		if len(got.Published.Tags) != 1 || got.Published.Tags[0].Name != "essays" {
+			t.Fatalf("published tags = %+v", got.Published.Tags)
+		}
+		if version := got.PublishedVersion(); len(version.Tags) != 1 || version.Tags[0].Name != "essays" {
+			t.Fatalf("published version tags = %+v", version.Tags)
+		} else if version.Version != 0 {
+			t.Fatalf("published version leaks the draft's version %d", version.Version)
+		}
+
+		published, total, _ := b.repo.GetPublished(ctx, 100, 0)
+		if total < 1 || !containsNote(published, note.ID) {
+			t.Fatalf("published feed misses the note: %d %+v", total, published)
+		}
+
+		if err := b.repo.Unpublish(ctx, note.ID.String()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, _ = b.repo.GetByID(ctx, note.ID.String())
+		if got.IsPublic || got.Published != nil {
+			t.Fatalf("unpublished = %+v", got)
+		}
+	})
+}
+
+func TestRepositoryDailyNotesAreUnique(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ctx context.Context, b backend) {
+		owner := b.newUser(t)
+		daily := mustCreate(t, ctx, b.repo, Note{Title: "2024-05-01", UserID: owner, DailyDate: "2024-05-01"})
+		if err := b.repo.Create(ctx, &Note{Title: "again", UserID: owner, DailyDate: "2024-05-01"}); !errors.Is(err, ErrDailyNoteExists) {
+			t.Fatalf("second daily note: err = %v", err)
+		}
+		got, err := b.repo.GetDaily(ctx, owner, "2024-05-01")
+		if err != nil || got.ID != daily.ID {
+			t.Fatalf("GetDaily = %+v, %v", got, err)
+		}
+		if _, err := b.repo.GetDaily(ctx, owner, "2024-05-02"); !errors.Is(err, ErrNoDailyNote) {
+			t.Fatalf("missing daily note: err = %v", err)
+		}
+	})
+}
+
+func TestRepositoryLocksAndSlugs(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ctx context.Context, b backend) {
+		owner := b.newUser(t)
+		note := mustCreate(t, ctx, b.repo, Note{Title: "Locked", UserID: owner})
+		now := time.Now()
+
+		lock := NoteLock{NoteID: note.ID, UserID: owner, Token: "a", Version: 1, ExpiresAt: now.Add(time.Minute)}
+		if err := b.repo.PutLock(ctx, &lock, now); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		other := lock
+		other.Token = "b"
+		if err := b.repo.PutLock(ctx, &other, now); !errors.Is(err, ErrLocked) {
+			t.Fatalf("second lock: err = %v", err)
+		}
+		if err := b.repo.PutLock(ctx, &other, now.Add(2*time.Minute)); err != nil {
+			t.Fatalf("expired lock should be taken over: %v", err)
+		}
+		if _, err := b.repo.GetLock(ctx, note.ID, now.Add(time.Hour)); !errors.Is(err, ErrNotLocked) {
+			t.Fatalf("GetLock after expiry: err = %v", err)
+		}
+		if err := b.repo.DeleteLock(ctx, note.ID, "a"); !errors.Is(err, ErrNotLocked) {
+			t.Fatalf("unlock with a stale token: err = %v", err)
+		}
+
+		first, _ := b.repo.AssignSlug(ctx, owner, note.ID, "locked")
+		second := mustCreate(t, ctx, b.repo, Note{Title: "Locked", UserID: owner})
+		taken, _ := b.repo.AssignSlug(ctx, owner, second.ID, "locked")
+		if first != "locked" || taken != "locked-2" {
+			t.Fatalf("slugs = %q, %q", first, taken)
+		}
+		b.repo.AssignSlug(ctx, owner, note.ID, "renamed")
+		id, current, err := b.repo.ResolveSlug(ctx, owner, "locked")
+		if err != nil || id != note.ID || current != "renamed" {
+			t.Fatalf("ResolveSlug = %v, %q, %v", id, current, err)
+		}
+	})
+}
+
+func containsNote(notes []Note, id uuid.UUID) bool {
+	for _, n := range notes {
+		if n.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPersistedMemoryRepositoryReopens(t *testing.T) {
+	ctx := context.Background()
+	owner := uuid.New()
+	remindAt := time.Now().Add(-time.Minute)
+	var note, gone Note
+	reopened := journaltest.Reopen(t, OpenInMemoryNoteRepository, func(repo *InMemoryNoteRepository) {
+		note = mustCreate(t, ctx, repo, Note{Title: "Kept", UserID: owner, Tags: []Tag{{Name: "x"}}, RemindAt: &remindAt})
+		repo.Publish(ctx, note.ID.String())
+		repo.AssignSlug(ctx, owner, note.ID, "kept")
+		repo.Complete(ctx, ScheduledAction{NoteID: note.ID, Kind: ScheduleRemind, At: remindAt})
+		if err := repo.Snapshot(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// Changes after the snapshot only live in the log
+		gone = mustCreate(t, ctx, repo, Note{Title: "Gone", UserID: owner})
+		repo.Delete(ctx, gone.ID.String())
+		lock := NoteLock{NoteID: note.ID, UserID: owner, Token: "t", ExpiresAt: time.Now().Add(time.Hour)}
+		if err := repo.PutLock(ctx, &lock, time.Now()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	got, err := reopened.GetByID(ctx, note.ID.String())
+	if err != nil || got.Published == nil || got.Published.Slug != "kept" || tagNames(got) != "x" || !got.CreatedAt.Equal(note.CreatedAt) {
+		t.Fatalf("reopened = %+v, %v", got, err)
+	}
+	if _, err := reopened.GetByID(ctx, gone.ID.String()); err == nil {
+		t.Fatal("deleted note came back")
+	}
+	if held, err := reopened.GetLock(ctx, note.ID, time.Now()); err != nil || held.Token != "t" {
+		t.Fatalf("lock = %+v, %v", held, err)
+	}
+	if due, _ := reopened.ClaimDue(ctx, time.Now(), time.Minute, 10); len(due) != 0 {
+		t.Fatalf("delivered reminder is due again: %+v", due)
+	}
+
+	// What callers get back is theirs to change
+	got.Tags[0].Name = "changed"
+	got.Published.Title = "changed"
+	again, _ := reopened.GetByID(ctx, note.ID.String())
+	if tagNames(again) != "x" || again.Published.Title != "Kept" {
+		t.Fatalf("stored note changed through a returned copy: %+v", again)
+	}
+}
+
+func TestGitRepositoryReloadsAndCommits(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	ctx := context.Background()
+	dir := t.TempDir()
+	authors := func(ctx context.Context, userID string) (Author, error) {
+		return Author{Name: "Ada", Email: "ada@example.com"}, nil
+	}
+	repo, err := NewGitNoteRepository(dir, authors)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	owner := uuid.New()
+	userCtx := context.WithValue(ctx, middleware.UserIDKey, owner.String())
+	remindAt := time.Now().Add(-time.Minute)
+	note := mustCreate(t, userCtx, repo, Note{Title: "Kept", Content: "---\nstatus: draft\n---\nBody", UserID: owner, Tags: []Tag{{Name: "x"}}, RemindAt: &remindAt})
+	gone := mustCreate(t, ctx, repo, Note{Title: "Gone", UserID: owner})
+	repo.Publish(ctx, note.ID.String())
+	repo.AssignSlug(ctx, owner, note.ID, "kept")
+	repo.AssignSlug(ctx, owner, gone.ID, "gone")
+	repo.Complete(ctx, ScheduledAction{NoteID: note.ID, Kind: ScheduleRemind, At: remindAt})
+	repo.Delete(ctx, gone.ID.String())
+
+	reopened, err := NewGitNoteRepository(dir, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := reopened.GetByID(ctx, note.ID.String())
+	if err != nil || got.Content != note.Content || got.Published == nil || tagNames(got) != "x" || got.Published.Slug != "kept" {
+		t.Fatalf("reloaded = %+v, %v", got, err)
+	}
+	if _, err := reopened.GetByID(ctx, gone.ID.String()); err == nil {
+		t.Fatal("deleted note came back")
+	}
+	if slug, err := reopened.AssignSlug(ctx, owner, note.ID, "gone"); err != nil || slug != "gone-2" {
+		t.Fatalf("slug of the deleted note = %q, %v, want it reserved", slug, err)
+	}
+	if _, err := os.Stat(fmt.Sprintf("%s/%s/%s.md", dir, owner, gone.ID)); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("content of the deleted note kept: %v", err)
+	}
+	if due, _ := reopened.ClaimDue(ctx, time.Now(), time.Minute, 10); len(due) != 0 {
+		t.Fatalf("delivered reminder is due again: %+v", due)
+	}
+
+	content, _ := os.ReadFile(fmt.Sprintf("%s/%s/%s.md", dir, owner, note.ID))
+	if string(content) != note.Content {
+		t.Fatalf("markdown file = %q", content)
+	}
+	out, err := exec.Command("git", "-C", dir, "log", "--format=%an <%ae>|%s", "--", owner.String()+"/"+note.ID.String()+".md").Output()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	log := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(log) != 1 || log[0] != "Ada <ada@example.com>|Create note "+note.ID.String() {
+		t.Fatalf("content history = %q", log)
+	}
+}
+
+func TestRepositoryScheduleSurvivesReschedule(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ctx context.Context, b backend) {
+		now := time.Now().UTC().Truncate(time.Second)
+		publishAt := now.Add(-time.Minute)
+		note := mustCreate(t, ctx, b.repo, Note{Title: "Launch", Content: "Draft", UserID: b.newUser(t), PublishAt: &publishAt})
+		scheduler := NewScheduler(b.repo, b.repo, notifications.NewCenter(notifications.NewInMemoryNotificationRepository()), time.Minute)
+
+		actions, err := b.repo.ClaimDue(ctx, now, time.Minute, 100)
+		if err != nil || len(actions) != 1 || !actions[0].At.Equal(publishAt) {
+			t.Fatalf("ClaimDue = %+v, %v", actions, err)
+		}
+
+		// The owner moves the launch while the action is in flight
+		draft, _ := b.repo.GetByID(ctx, note.ID.String())
+		later := now.Add(time.Hour)
+		draft.PublishAt = &later
+		if err := b.repo.Update(ctx, draft); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if again, _ := b.repo.ClaimDue(ctx, now, time.Minute, 100); len(again) != 0 {
+			t.Fatalf("edit released the lease: %+v", again)
+		}
+
+		if err := scheduler.run(ctx, actions[0]); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := b.repo.Complete(ctx, actions[0]); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, _ := b.repo.GetByID(ctx, note.ID.String())
+		if got.Published != nil || got.PublishAt == nil || !got.PublishAt.Equal(later) {
+			t.Fatalf("rescheduled note = published %+v, publish_at %v", got.Published, got.PublishAt)
+		}
+	})
+}
+
+func TestRepositoryRemindsOncePerTime(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, ctx context.Context, b backend) {
+		now := time.Now().UTC().Truncate(time.Second)
+		remindAt := now.Add(-time.Minute)
+		owner := b.newUser(t)
+		note := mustCreate(t, ctx, b.repo, Note{Title: "Call", UserID: owner, RemindAt: &remindAt})
+		notificationRepo := notifications.NewInMemoryNotificationRepository()
+		scheduler := NewScheduler(b.repo, b.repo, notifications.NewCenter(notificationRepo), time.Minute)
+		reminders := func() int {
+			_, total, err := notificationRepo.ListByUser(ctx, owner, false, 10, 0)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			return total
+		}
+
+		if err := scheduler.RunOnce(ctx, now); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		list, total, _ := notificationRepo.ListByUser(ctx, owner, false, 10, 0)
+		if total != 1 || list[0].Type != notifications.TypeReminder || *list[0].NoteID != note.ID {
+			t.Fatalf("notifications = %+v", list)
+		}
+		// The reminder stays on the note, for the calendar, but is not due again
+		got, _ := b.repo.GetByID(ctx, note.ID.String())
+		if got.RemindAt == nil || !got.RemindAt.Equal(remindAt) {
+			t.Fatalf("remind_at = %v", got.RemindAt)
+		}
+		if err := scheduler.RunOnce(ctx, now.Add(2*time.Minute)); err != nil || reminders() != 1 {
+			t.Fatalf("reminded again: %d, %v", reminders(), err)
+		}
+
+		// Moving the reminder makes it due again
+		later := now.Add(time.Hour)
+		got.RemindAt = &later
+		if err := b.repo.Update(ctx, got); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := scheduler.RunOnce(ctx, later); err != nil || reminders() != 2 {
+			t.Fatalf("moved reminder: %d, %v", reminders(), err)
+		}
+	})
+}
+
+func TestSavedSearchesAndPropertiesSurviveRestart(t *testing.T) {
+	type stores struct {
+		searches   SavedSearchRepository
+		properties PropertyDefinitionRepository
+		newUser    func(t *testing.T) uuid.UUID
+		close      func()
+	}
+	opens := map[string]func(t *testing.T, dir string) stores{
+		"persisted memory": func(t *testing.T, dir string) stores {
+			searches, err := OpenInMemorySavedSearchRepository(filepath.Join(dir, "saved_searches"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			properties, err := OpenInMemoryPropertyDefinitionRepository(filepath.Join(dir, "properties"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// Left open, as after a crash
+			return stores{searches: searches, properties: properties, newUser: randomUser, close: func() {}}
+		},
+		"sqlite": func(t *testing.T, dir string) stores {
+			db := sqlitetest.OpenFile(t, filepath.Join(dir, "grimoire.db"))
+			return stores{
+				searches:   NewSQLiteSavedSearchRepository(db),
+				properties: NewSQLitePropertyDefinitionRepository(db),
+				newUser:    func(t *testing.T) uuid.UUID { return sqlitetest.NewUser(t, db) },
+				close:      func() { db.Close() },
+			}
+		},
+	}
+	for name, open := range opens {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+
+			s := open(t, dir)
+			owner := s.newUser(t)
+			kept := SavedSearch{UserID: owner, Name: "Work", Filter: Filter{Tags: []string{"work"}, Properties: map[string]string{"status": "open"}}}
+			if err := s.searches.Create(ctx, &kept); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			gone := SavedSearch{UserID: owner, Name: "Gone"}
+			if err := s.searches.Create(ctx, &gone); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := s.searches.Delete(ctx, gone.ID); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			kept.Name = "Work, open"
+			if err := s.searches.Update(ctx, &kept); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			status := PropertyDefinition{UserID: owner, Name: "status", Type: PropertyText}
+			if err := s.properties.Save(ctx, &status); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			status.Type, status.Options = PropertySelect, []string{"open", "done"}
+			if err := s.properties.Save(ctx, &status); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			due := PropertyDefinition{UserID: owner, Name: "due", Type: PropertyDate}
+			if err := s.properties.Save(ctx, &due); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := s.properties.Delete(ctx, owner, "due"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			s.close()
+
+			s = open(t, dir)
+			defer s.close()
+			searches, err := s.searches.GetByUserID(ctx, owner)
+			if err != nil || len(searches) != 1 || searches[0].Name != "Work, open" || searches[0].Filter.Properties["status"] != "open" {
+				t.Fatalf("searches = %+v, %v", searches, err)
+			}
+			defs, err := s.properties.GetByUserID(ctx, owner)
+			if err != nil || len(defs) != 1 || defs[0].Type != PropertySelect || strings.Join(defs[0].Options, ",") != "open,done" {
+				t.Fatalf("properties = %+v, %v", defs, err)
+			}
+			if !defs[0].CreatedAt.Equal(status.CreatedAt) {
+				t.Fatalf("created at %v, saved at %v", defs[0].CreatedAt, status.CreatedAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package notes
+
+import "errors"
+
+var (
+	// ErrNoDailyNote is returned by GetDaily when the user has no daily note
+	// for the date.
+	ErrNoDailyNote = errors.New("daily note not found")
+	// ErrDailyNoteExists is returned by Create for a second daily note on
+	// the same date.
+	ErrDailyNoteExists = errors.New("daily note already exists")
+)
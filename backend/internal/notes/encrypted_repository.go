@@ -0,0 +1,210 @@
+package notes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// ContentCipher encrypts note fields at rest for users who opted in. Fields
+// of users who did not are passed through unchanged.
+type ContentCipher interface {
+	Encrypt(ctx context.Context, userID uuid.UUID, plaintext string) (string, error)
+	Decrypt(ctx context.Context, userID uuid.UUID, stored string) (string, error)
+	Enabled(ctx context.Context, userID uuid.UUID) (bool, error)
+}
+
+// EncryptedNoteRepository wraps another NoteRepository and encrypts note
+// titles and contents before they reach storage. Tags stay in plaintext so
+// tag filtering keeps working.
+type EncryptedNoteRepository struct {
+	NoteRepository
+	cipher ContentCipher
+}
+
+// NewEncryptedNoteRepository creates a new instance of EncryptedNoteRepository.
+func NewEncryptedNoteRepository(repo NoteRepository, cipher ContentCipher) *EncryptedNoteRepository {
+	return &EncryptedNoteRepository{NoteRepository: repo, cipher: cipher}
+}
+
+// seal encrypts the note in place and returns a func restoring the plaintext.
+func (r *EncryptedNoteRepository) seal(ctx context.Context, note *Note) (func(), error) {
+	title, content := note.Title, note.Content
+	sealedTitle, err := r.cipher.Encrypt(ctx, note.UserID, title)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt note title: %w", err)
+	}
+	sealedContent := content
+	if !note.Encrypted { // end-to-end encrypted notes carry no plaintext content
+		sealedContent, err = r.cipher.Encrypt(ctx, note.UserID, content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt note content: %w", err)
+		}
+	}
+	note.Title, note.Content = sealedTitle, sealedContent
+	return func() { note.Title, note.Content = title, content }, nil
+}
+
+func (r *EncryptedNoteRepository) open(ctx context.Context, note *Note) error {
+	title, err := r.cipher.Decrypt(ctx, note.UserID, note.Title)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt note %s: %w", note.ID, err)
+	}
+	content, err := r.cipher.Decrypt(ctx, note.UserID, note.Content)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt note %s: %w", note.ID, err)
+	}
+	note.Title, note.Content = title, content
+
+	if note.Published != nil {
+		published := *note.Published
+		if published.Title, err = r.cipher.Decrypt(ctx, note.UserID, published.Title); err != nil {
+			return fmt.Errorf("failed to decrypt note %s: %w", note.ID, err)
+		}
+		if published.Content, err = r.cipher.Decrypt(ctx, note.UserID, published.Content); err != nil {
+			return fmt.Errorf("failed to decrypt note %s: %w", note.ID, err)
+		}
+		note.Published = &published
+	}
+	return nil
+}
+
+func (r *EncryptedNoteRepository) openAll(ctx context.Context, notes []Note) ([]Note, error) {
+	for i := range notes {
+		if err := r.open(ctx, &notes[i]); err != nil {
+			return nil, err
+		}
+	}
+	return notes, nil
+}
+
+func (r *EncryptedNoteRepository) Create(ctx context.Context, note *Note) error {
+	restore, err := r.seal(ctx, note)
+	if err != nil {
+		return err
+	}
+	defer restore()
+	return r.NoteRepository.Create(ctx, note)
+}
+
+func (r *EncryptedNoteRepository) Update(ctx context.Context, note *Note) error {
+	restore, err := r.seal(ctx, note)
+	if err != nil {
+		return err
+	}
+	defer restore()
+	return r.NoteRepository.Update(ctx, note)
+}
+
+func (r *EncryptedNoteRepository) GetAll(ctx context.Context) ([]Note, error) {
+	notes, err := r.NoteRepository.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.openAll(ctx, notes)
+}
+
+func (r *EncryptedNoteRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]Note, error) {
+	notes, err := r.NoteRepository.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return r.openAll(ctx, notes)
+}
+
+func (r *EncryptedNoteRepository) GetByTags(ctx context.Context, tags []string) ([]Note, error) {
+	notes, err := r.NoteRepository.GetByTags(ctx, tags)
+	if err != nil {
+		return nil, err
+	}
+	return r.openAll(ctx, notes)
+}
+
+func (r *EncryptedNoteRepository) GetByID(ctx context.Context, id string) (*Note, error) {
+	note, err := r.NoteRepository.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.open(ctx, note); err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// Stats cannot be aggregated by storage for users whose content is
+// encrypted, so it is computed from the decrypted notes instead.
+func (r *EncryptedNoteRepository) Stats(ctx context.Context, userID uuid.UUID) (*Stats, error) {
+	enabled, err := r.cipher.Enabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return r.NoteRepository.Stats(ctx, userID)
+	}
+	notes, err := r.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return ComputeStats(notes), nil
+}
+
+// SearchTitles falls back to in-process matching for users whose titles are
+// encrypted, since the database only sees ciphertext.
+func (r *EncryptedNoteRepository) SearchTitles(ctx context.Context, userID uuid.UUID, query string, limit int) ([]TitleMatch, error) {
+	enabled, err := r.cipher.Enabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return r.NoteRepository.SearchTitles(ctx, userID, query, limit)
+	}
+	notes, err := r.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return MatchTitles(notes, query, limit), nil
+}
+
+// Find filters in process for users whose content is encrypted, since text
+// matching cannot run against ciphertext.
+func (r *EncryptedNoteRepository) Find(ctx context.Context, userID uuid.UUID, filter *Filter) ([]Note, error) {
+	enabled, err := r.cipher.Enabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		notes, err := r.NoteRepository.Find(ctx, userID, filter)
+		if err != nil {
+			return nil, err
+		}
+		return r.openAll(ctx, notes)
+	}
+	notes, err := r.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(notes, func(i, j int) bool { return notes[i].UpdatedAt.After(notes[j].UpdatedAt) })
+	return FilterNotes(notes, filter), nil
+}
+
+func (r *EncryptedNoteRepository) GetPublished(ctx context.Context, limit, offset int) ([]Note, int, error) {
+	notes, total, err := r.NoteRepository.GetPublished(ctx, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	notes, err = r.openAll(ctx, notes)
+	return notes, total, err
+}
+
+func (r *EncryptedNoteRepository) GetDaily(ctx context.Context, userID uuid.UUID, date string) (*Note, error) {
+	note, err := r.NoteRepository.GetDaily(ctx, userID, date)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.open(ctx, note); err != nil {
+		return nil, err
+	}
+	return note, nil
+}
@@ -0,0 +1,123 @@
+package notes
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// Envelope holds the content of an end-to-end encrypted note. The server
+// never sees the key; it only stores the ciphertext and the metadata the
+// client needs to derive the key again from the user's passphrase.
+type Envelope struct {
+	Algorithm  string    `json:"algorithm"`
+	Ciphertext string    `json:"ciphertext"` // base64
+	Nonce      string    `json:"nonce"`      // base64
+	Salt       string    `json:"salt"`       // base64
+	KDF        string    `json:"kdf"`
+	KDFParams  KDFParams `json:"kdf_params"`
+}
+
+// KDFParams are the key-derivation parameters. Memory and Parallelism only
+// apply to argon2id.
+type KDFParams struct {
+	Iterations  int `json:"iterations"`
+	Memory      int `json:"memory,omitempty"` // KiB
+	Parallelism int `json:"parallelism,omitempty"`
+}
+
+// Nonce sizes of the supported algorithms.
+var envelopeAlgorithms = map[string]int{
+	"AES-256-GCM":        12,
+	"XChaCha20-Poly1305": 24,
+}
+
+const (
+	minSaltSize         = 16
+	minCiphertextSize   = 16 // the authentication tag alone
+	minPBKDF2Iterations = 100000
+	minArgon2Memory     = 19456 // KiB
+)
+
+// ErrEncryptedNote is returned by features that need the note plaintext.
+var ErrEncryptedNote = errors.New("operation not supported on end-to-end encrypted notes")
+
+// ValidateEnvelope checks that an envelope is well formed. It cannot check
+// that the ciphertext decrypts, only that it could.
+func ValidateEnvelope(e *Envelope) error {
+	if e == nil {
+		return errors.New("envelope is required")
+	}
+
+	nonceSize, ok := envelopeAlgorithms[e.Algorithm]
+	if !ok {
+		return fmt.Errorf("unsupported algorithm %q", e.Algorithm)
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(e.Ciphertext)
+	if err != nil {
+		return errors.New("ciphertext is not valid base64")
+	}
+	if len(ciphertext) < minCiphertextSize {
+		return errors.New("ciphertext is too short")
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(e.Nonce)
+	if err != nil {
+		return errors.New("nonce is not valid base64")
+	}
+	if len(nonce) != nonceSize {
+		return fmt.Errorf("nonce for %s must be %d bytes", e.Algorithm, nonceSize)
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(e.Salt)
+	if err != nil {
+		return errors.New("salt is not valid base64")
+	}
+	if len(salt) < minSaltSize {
+		return fmt.Errorf("salt must be at least %d bytes", minSaltSize)
+	}
+
+	p := e.KDFParams
+	switch e.KDF {
+	case "argon2id":
+		if p.Iterations < 1 || p.Parallelism < 1 || p.Memory < minArgon2Memory {
+			return fmt.Errorf("argon2id needs iterations >= 1, parallelism >= 1 and memory >= %d KiB", minArgon2Memory)
+		}
+	case "pbkdf2-sha256":
+		if p.Iterations < minPBKDF2Iterations {
+			return fmt.Errorf("pbkdf2-sha256 needs at least %d iterations", minPBKDF2Iterations)
+		}
+		if p.Memory != 0 || p.Parallelism != 0 {
+			return errors.New("pbkdf2-sha256 does not take memory or parallelism parameters")
+		}
+	default:
+		return fmt.Errorf("unsupported kdf %q", e.KDF)
+	}
+
+	return nil
+}
+
+// ValidateEncryption checks that the note's encrypted flag, envelope and
+// content agree with each other.
+func ValidateEncryption(note *Note) error {
+	if !note.Encrypted {
+		if note.Envelope != nil {
+			return errors.New("envelope given for a note that is not encrypted")
+		}
+		return nil
+	}
+	if note.Content != "" {
+		return errors.New("encrypted notes must not carry plaintext content")
+	}
+	return ValidateEnvelope(note.Envelope)
+}
+
+// envelopeOrNil returns the envelope to store, dropping it from notes that
+// are not flagged as encrypted.
+func (n *Note) envelopeOrNil() *Envelope {
+	if !n.Encrypted {
+		return nil
+	}
+	return n.Envelope
+}
@@ -0,0 +1,194 @@
+package notes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Filter narrows a user's notes. Zero fields do not filter. A note must carry
+// every tag in Tags; Text is matched case-insensitively against the title and
+// content. Date bounds are inclusive. Properties must all equal their values.
+// Sort names a property to order by, prefixed with "-" for descending.
+type Filter struct {
+	Tags        []string          `json:"tags,omitempty"`
+	Text        string            `json:"text,omitempty"`
+	CreatedFrom *time.Time        `json:"created_from,omitempty"`
+	CreatedTo   *time.Time        `json:"created_to,omitempty"`
+	UpdatedFrom *time.Time        `json:"updated_from,omitempty"`
+	UpdatedTo   *time.Time        `json:"updated_to,omitempty"`
+	Properties  map[string]string `json:"properties,omitempty"`
+	Sort        string            `json:"sort,omitempty"`
+}
+
+// Matches reports whether the note passes the filter.
+func (f *Filter) Matches(n *Note) bool {
+	if len(f.Tags) > 0 {
+		has := make(map[string]bool, len(n.Tags))
+		for _, tag := range n.Tags {
+			has[strings.ToLower(tag.Name)] = true
+		}
+		for _, tag := range f.Tags {
+			if !has[strings.ToLower(tag)] {
+				return false
+			}
+		}
+	}
+	if f.Text != "" {
+		text := strings.ToLower(f.Text)
+		if !strings.Contains(strings.ToLower(n.Title), text) && !strings.Contains(strings.ToLower(n.Content), text) {
+			return false
+		}
+	}
+	if f.CreatedFrom != nil && n.CreatedAt.Before(*f.CreatedFrom) {
+		return false
+	}
+	if f.CreatedTo != nil && n.CreatedAt.After(*f.CreatedTo) {
+		return false
+	}
+	if f.UpdatedFrom != nil && n.UpdatedAt.Before(*f.UpdatedFrom) {
+		return false
+	}
+	if f.UpdatedTo != nil && n.UpdatedAt.After(*f.UpdatedTo) {
+		return false
+	}
+	for name, value := range f.Properties {
+		if v, ok := n.Properties[name]; !ok || propertyString(v) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// SortProperty returns the property to sort by and the direction.
+func (f *Filter) SortProperty() (name string, descending bool) {
+	if strings.HasPrefix(f.Sort, "-") {
+		return f.Sort[1:], true
+	}
+	return f.Sort, false
+}
+
+// IsEmpty reports whether the filter lets every note through.
+func (f *Filter) IsEmpty() bool {
+	return len(f.Tags) == 0 && f.Text == "" && f.CreatedFrom == nil && f.CreatedTo == nil &&
+		f.UpdatedFrom == nil && f.UpdatedTo == nil && len(f.Properties) == 0 && f.Sort == ""
+}
+
+// FilterNotes keeps the notes that pass the filter, in order unless the
+// filter sorts by a property. It is the in-process equivalent of
+// PgNoteRepository.Find.
+func FilterNotes(notes []Note, f *Filter) []Note {
+	var result []Note
+	for i := range notes {
+		if f.Matches(&notes[i]) {
+			result = append(result, notes[i])
+		}
+	}
+	if name, desc := f.SortProperty(); name != "" {
+		sortByProperty(result, name, desc)
+	}
+	return result
+}
+
+// parseFilterTime accepts RFC 3339 timestamps and plain dates. Plain dates
+// used as an upper bound cover the whole day.
+func parseFilterTime(value string, upper bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q", value)
+	}
+	if upper {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return &t, nil
+}
+
+// ErrSavedSearchNotFound is returned when a request names a saved search the
+// user does not have.
+var ErrSavedSearchNotFound = errors.New("saved search not found")
+
+// FilterFromRequest builds a Filter from the query string. `search` names a
+// saved search to start from; `tag`, `q`, the date parameters and
+// `prop.<name>=<value>` narrow it further, and `sort=prop.<name>` (or
+// `sort=-prop.<name>`) orders by a property. searches may be nil when saved searches are not available.
+func FilterFromRequest(ctx context.Context, r *http.Request, userID uuid.UUID, searches SavedSearchRepository) (*Filter, error) {
+	query := r.URL.Query()
+	filter := &Filter{}
+
+	if id := query.Get("search"); id != "" {
+		searchID, err := uuid.Parse(id)
+		if err != nil || searches == nil {
+			return nil, ErrSavedSearchNotFound
+		}
+		saved, err := searches.GetByID(ctx, searchID)
+		if err != nil || saved.UserID != userID {
+			return nil, ErrSavedSearchNotFound
+		}
+		*filter = saved.Filter
+		filter.Properties = maps.Clone(saved.Filter.Properties)
+	}
+
+	filter.Tags = append(filter.Tags, query["tag"]...)
+	if text := query.Get("q"); text != "" {
+		filter.Text = text
+	}
+
+	for key, values := range query {
+		name, ok := strings.CutPrefix(key, "prop.")
+		if !ok || len(values) == 0 {
+			continue
+		}
+		if !propertyNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid property name %q", name)
+		}
+		if filter.Properties == nil {
+			filter.Properties = make(map[string]string)
+		}
+		filter.Properties[name] = values[0]
+	}
+	if sort := query.Get("sort"); sort != "" {
+		desc := strings.HasPrefix(sort, "-")
+		name, ok := strings.CutPrefix(strings.TrimPrefix(sort, "-"), "prop.")
+		if !ok || !propertyNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid sort %q", sort)
+		}
+		filter.Sort = name
+		if desc {
+			filter.Sort = "-" + name
+		}
+	}
+
+	bounds := []struct {
+		param string
+		upper bool
+		dest  **time.Time
+	}{
+		{"created_from", false, &filter.CreatedFrom},
+		{"created_to", true, &filter.CreatedTo},
+		{"updated_from", false, &filter.UpdatedFrom},
+		{"updated_to", true, &filter.UpdatedTo},
+	}
+	for _, b := range bounds {
+		t, err := parseFilterTime(query.Get(b.param), b.upper)
+		if err != nil {
+			return nil, err
+		}
+		if t != nil {
+			*b.dest = t
+		}
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,201 @@
+package notes
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const frontMatterDelimiter = "---"
+
+// parseFrontMatter separates a leading YAML front matter block from the
+// Markdown body and returns the block as a mapping node. ok is false when
+// the content has none: no block, an unterminated one, or one that does not
+// hold a mapping, such as text between two horizontal rules.
+func parseFrontMatter(content string) (block *yaml.Node, body string, ok bool) {
+	normalized := strings.ReplaceAll(content, "\r\n", "\n")
+	if !strings.HasPrefix(normalized, frontMatterDelimiter+"\n") {
+		return nil, content, false
+	}
+
+	rest := normalized[len(frontMatterDelimiter)+1:]
+	var raw string
+	switch {
+	case strings.HasPrefix(rest, frontMatterDelimiter+"\n"), rest == frontMatterDelimiter:
+		raw, body = "", strings.TrimPrefix(strings.TrimPrefix(rest, frontMatterDelimiter), "\n")
+	default:
+		end := strings.Index(rest, "\n"+frontMatterDelimiter+"\n")
+		if end < 0 {
+			if !strings.HasSuffix(rest, "\n"+frontMatterDelimiter) {
+				return nil, content, false // an unterminated block is just Markdown
+			}
+			end = len(rest) - len(frontMatterDelimiter) - 1
+		}
+		raw = rest[:end]
+		body = strings.TrimPrefix(rest[end+1+len(frontMatterDelimiter):], "\n")
+	}
+
+	if strings.TrimSpace(raw) == "" {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, body, true
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(raw), &doc); err != nil || len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, content, false
+	}
+	return doc.Content[0], body, true
+}
+
+// SplitFrontMatter separates a leading YAML front matter block from the
+// Markdown body. ok is false when the content has none.
+func SplitFrontMatter(content string) (props map[string]any, body string, ok bool) {
+	block, body, ok := parseFrontMatter(content)
+	if !ok {
+		return nil, content, false
+	}
+	props = make(map[string]any)
+	if err := block.Decode(&props); err != nil {
+		return nil, content, false
+	}
+	return props, body, true
+}
+
+// splitDefined sorts the keys of a front matter block into the properties
+// defs declare and the key and value nodes of the others, in written order.
+func splitDefined(block *yaml.Node, defs []PropertyDefinition) (props map[string]any, others []*yaml.Node, err error) {
+	defined := make(map[string]bool, len(defs))
+	for _, d := range defs {
+		defined[d.Name] = true
+	}
+
+	props = make(map[string]any)
+	for i := 0; i+1 < len(block.Content); i += 2 {
+		key, value := block.Content[i], block.Content[i+1]
+		if !defined[key.Value] {
+			others = append(others, key, value)
+			continue
+		}
+		var v any
+		if err := value.Decode(&v); err != nil {
+			return nil, nil, fmt.Errorf("invalid front matter: property %q: %w", key.Value, err)
+		}
+		props[key.Value] = v
+	}
+	return props, others, nil
+}
+
+// RenderFrontMatter puts props in a YAML front matter block on top of body.
+// Without properties the body is returned unchanged.
+func RenderFrontMatter(props map[string]any, body string) (string, error) {
+	return renderFrontMatter(props, nil, body)
+}
+
+// renderFrontMatter is RenderFrontMatter keeping the key and value nodes in
+// others, as written, after the properties.
+func renderFrontMatter(props map[string]any, others []*yaml.Node, body string) (string, error) {
+	if len(props) == 0 && len(others) == 0 {
+		return body, nil
+	}
+
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names) // keeps output stable
+
+	block := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, name := range names {
+		var value yaml.Node
+		if err := value.Encode(props[name]); err != nil {
+			return "", fmt.Errorf("failed to render front matter: %w", err)
+		}
+		block.Content = append(block.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}, &value)
+	}
+	block.Content = append(block.Content, others...)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(block); err != nil {
+		return "", fmt.Errorf("failed to render front matter: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return "", fmt.Errorf("failed to render front matter: %w", err)
+	}
+
+	return frontMatterDelimiter + "\n" + buf.String() + frontMatterDelimiter + "\n" + body, nil
+}
+
+// SyncProperties reconciles a note's properties with the front matter of its
+// content before it is saved. Keys with a definition are the properties:
+// front matter written in raw Markdown wins, otherwise the properties are
+// written into the content. Keys without one stay in the front matter as
+// written. Encrypted notes have no content to sync with.
+func SyncProperties(note *Note, defs []PropertyDefinition) error {
+	return syncProperties(note, defs, true)
+}
+
+// SetProperties is SyncProperties for a change to the properties alone: they
+// replace the defined keys of the front matter instead of giving way to them.
+func SetProperties(note *Note, defs []PropertyDefinition) error {
+	return syncProperties(note, defs, false)
+}
+
+func syncProperties(note *Note, defs []PropertyDefinition, contentWins bool) error {
+	body := note.Content
+	var others []*yaml.Node
+	if !note.Encrypted {
+		if block, rest, ok := parseFrontMatter(note.Content); ok {
+			defined, undefined, err := splitDefined(block, defs)
+			if err != nil {
+				return err
+			}
+			if contentWins {
+				note.Properties = defined
+			}
+			body, others = rest, undefined
+		}
+	}
+
+	props, err := ValidateProperties(defs, note.Properties)
+	if err != nil {
+		return err
+	}
+	note.Properties = props
+
+	if note.Encrypted {
+		return nil
+	}
+	note.Content, err = renderFrontMatter(props, others, body)
+	return err
+}
+
+// AdoptProperties is SyncProperties for a note changing hands, as in a fork:
+// properties the new owner defines differently are dropped instead of
+// failing validation, and those they do not define stay in the front matter.
+func AdoptProperties(note *Note, defs []PropertyDefinition) error {
+	var others []*yaml.Node
+	if block, body, ok := parseFrontMatter(note.Content); ok {
+		defined, undefined, err := splitDefined(block, defs)
+		if err != nil {
+			return err
+		}
+		note.Properties, note.Content, others = defined, body, undefined
+	}
+
+	kept := make(map[string]any, len(note.Properties))
+	for name, value := range note.Properties {
+		if v, err := ValidateProperties(defs, map[string]any{name: value}); err == nil {
+			if normalized, ok := v[name]; ok {
+				kept[name] = normalized
+			}
+		}
+	}
+	note.Properties = kept
+
+	var err error
+	note.Content, err = renderFrontMatter(kept, others, note.Content)
+	return err
+}
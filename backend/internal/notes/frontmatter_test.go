@@ -0,0 +1,93 @@
+package notes
+
+import "testing"
+
+var testDefinitions = []PropertyDefinition{
+	{Name: "status", Type: PropertySelect, Options: []string{"open", "done"}},
+	{Name: "priority", Type: PropertyNumber},
+	{Name: "due", Type: PropertyDate},
+}
+
+func TestSyncPropertiesReadsFrontMatter(t *testing.T) {
+	note := Note{Content: "---\nstatus: open\npriority: 2\ndue: 2024-05-01\n---\n# Plan\n"}
+
+	if err := SyncProperties(&note, testDefinitions); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if note.Properties["status"] != "open" || note.Properties["priority"] != 2.0 || note.Properties["due"] != "2024-05-01" {
+		t.Fatalf("unexpected properties %#v", note.Properties)
+	}
+
+	want := "---\ndue: \"2024-05-01\"\npriority: 2\nstatus: open\n---\n# Plan\n"
+	if note.Content != want {
+		t.Errorf("expected content %q, got %q", want, note.Content)
+	}
+}
+
+func TestSyncPropertiesWritesFrontMatter(t *testing.T) {
+	note := Note{Content: "# Plan\n", Properties: map[string]any{"status": "done"}}
+
+	if err := SyncProperties(&note, testDefinitions); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "---\nstatus: done\n---\n# Plan\n"
+	if note.Content != want {
+		t.Errorf("expected content %q, got %q", want, note.Content)
+	}
+}
+
+func TestSyncPropertiesRejectsInvalidValues(t *testing.T) {
+	cases := []string{
+		"---\nstatus: blocked\n---\n",
+		"---\npriority: high\n---\n",
+	}
+	for _, content := range cases {
+		note := Note{Content: content}
+		if err := SyncProperties(&note, testDefinitions); err == nil {
+			t.Errorf("expected %q to be rejected", content)
+		}
+	}
+}
+
+func TestSyncPropertiesKeepsUndefinedKeys(t *testing.T) {
+	note := Note{Content: "---\nstatus: open\naliases: [plan, roadmap]\nowner: me\n---\n# Plan\n"}
+
+	if err := SyncProperties(&note, testDefinitions); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := note.Properties["owner"]; ok || note.Properties["status"] != "open" {
+		t.Fatalf("unexpected properties %#v", note.Properties)
+	}
+
+	want := "---\nstatus: open\naliases: [plan, roadmap]\nowner: me\n---\n# Plan\n"
+	if note.Content != want {
+		t.Errorf("expected content %q, got %q", want, note.Content)
+	}
+}
+
+func TestSyncPropertiesIgnoresNonMappingFrontMatter(t *testing.T) {
+	for _, content := range []string{"---\ntext\n---\n", "---\n- a\n- b\n---\nbody\n"} {
+		note := Note{Content: content}
+		if err := SyncProperties(&note, testDefinitions); err != nil {
+			t.Fatalf("unexpected error for %q: %v", content, err)
+		}
+		if note.Content != content || len(note.Properties) != 0 {
+			t.Errorf("expected %q to stay plain Markdown, got %q with %#v", content, note.Content, note.Properties)
+		}
+	}
+}
+
+func TestSetPropertiesReplacesFrontMatter(t *testing.T) {
+	note := Note{
+		Content:    "---\nstatus: open\nowner: me\n---\n# Plan\n",
+		Properties: map[string]any{"status": "done"},
+	}
+
+	if err := SetProperties(&note, testDefinitions); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "---\nstatus: done\nowner: me\n---\n# Plan\n"
+	if note.Content != want {
+		t.Errorf("expected content %q, got %q", want, note.Content)
+	}
+}
@@ -0,0 +1,179 @@
+package notes
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// Thresholds mirror the pg_trgm defaults so both repositories agree on what
+// counts as a match.
+const (
+	similarityThreshold     = 0.3
+	wordSimilarityThreshold = 0.6
+)
+
+// TitleMatch is a note whose title fuzzily matches a lookup query.
+type TitleMatch struct {
+	ID        uuid.UUID `json:"id"`
+	Title     string    `json:"title"`
+	Score     float64   `json:"score"`
+	Positions []Range   `json:"positions"` // matched words of the title, for highlighting
+}
+
+// Range is a half-open range of rune offsets.
+type Range struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+type word struct {
+	text       string
+	start, end int // rune offsets in the original string
+}
+
+// words splits s into lowercase alphanumeric words, keeping their positions.
+func words(s string) []word {
+	var result []word
+	var current []rune
+	start := 0
+	i := 0
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if len(current) == 0 {
+				start = i
+			}
+			current = append(current, unicode.ToLower(r))
+		} else if len(current) > 0 {
+			result = append(result, word{text: string(current), start: start, end: i})
+			current = nil
+		}
+		i++
+	}
+	if len(current) > 0 {
+		result = append(result, word{text: string(current), start: start, end: i})
+	}
+	return result
+}
+
+// wordTrigrams adds the trigrams of a word, padded the way pg_trgm pads it.
+func wordTrigrams(w string, set map[string]bool) {
+	padded := []rune("  " + w + " ")
+	for i := 0; i+3 <= len(padded); i++ {
+		set[string(padded[i:i+3])] = true
+	}
+}
+
+func trigrams(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range words(s) {
+		wordTrigrams(w.text, set)
+	}
+	return set
+}
+
+func overlap(a, b map[string]bool) int {
+	n := 0
+	for t := range a {
+		if b[t] {
+			n++
+		}
+	}
+	return n
+}
+
+// similarity is the share of trigrams two strings have in common, like
+// pg_trgm's similarity().
+func similarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	common := overlap(a, b)
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+// wordSimilarity is the share of the query trigrams found in the title. It
+// favours short queries against long titles, like pg_trgm's word_similarity().
+func wordSimilarity(query, title map[string]bool) float64 {
+	if len(query) == 0 {
+		return 0
+	}
+	return float64(overlap(query, title)) / float64(len(query))
+}
+
+// fuzzyScore returns how well title matches query and whether it is a match at all.
+func fuzzyScore(query, title string) (float64, bool) {
+	q, t := trigrams(query), trigrams(title)
+	sim, wordSim := similarity(q, t), wordSimilarity(q, t)
+	return max(sim, wordSim), sim >= similarityThreshold || wordSim >= wordSimilarityThreshold
+}
+
+// matchPositions finds, for each query word, the title word it most likely
+// refers to. Typos are tolerated through trigram similarity and prefixes match.
+func matchPositions(title, query string) []Range {
+	titleWords := words(title)
+	titleSets := make([]map[string]bool, len(titleWords))
+	for i, w := range titleWords {
+		titleSets[i] = make(map[string]bool)
+		wordTrigrams(w.text, titleSets[i])
+	}
+
+	matched := make(map[int]bool)
+	for _, qw := range words(query) {
+		qSet := make(map[string]bool)
+		wordTrigrams(qw.text, qSet)
+		best, bestScore := -1, similarityThreshold
+		for i, tw := range titleWords {
+			score := similarity(qSet, titleSets[i])
+			if strings.HasPrefix(tw.text, qw.text) {
+				score = 1
+			}
+			if score >= bestScore && !matched[i] {
+				best, bestScore = i, score
+			}
+		}
+		if best >= 0 {
+			matched[best] = true
+		}
+	}
+
+	positions := []Range{}
+	for i, w := range titleWords {
+		if matched[i] {
+			positions = append(positions, Range{Start: w.start, End: w.end})
+		}
+	}
+	return positions
+}
+
+// MatchTitles is the in-process fuzzy title lookup used when the database
+// cannot do it: ranks notes by trigram similarity of their title to query.
+func MatchTitles(notes []Note, query string, limit int) []TitleMatch {
+	matches := []TitleMatch{}
+	for _, n := range notes {
+		score, ok := fuzzyScore(query, n.Title)
+		if !ok {
+			continue
+		}
+		matches = append(matches, TitleMatch{ID: n.ID, Title: n.Title, Score: score})
+	}
+	sortTitleMatches(matches)
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	for i := range matches {
+		matches[i].Positions = matchPositions(matches[i].Title, query)
+	}
+	return matches
+}
+
+func sortTitleMatches(matches []TitleMatch) {
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Title < matches[j].Title
+	})
+}
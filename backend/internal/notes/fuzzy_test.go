@@ -0,0 +1,49 @@
+package notes
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestMatchTitlesToleratesTypos(t *testing.T) {
+	notes := []Note{
+		{ID: uuid.New(), Title: "Shopping list"},
+		{ID: uuid.New(), Title: "Grimoire roadmap"},
+		{ID: uuid.New(), Title: "Road trip to Granada"},
+	}
+
+	matches := MatchTitles(notes, "grimiore rodmap", 5)
+	if len(matches) == 0 {
+		t.Fatal("expected at least one match")
+	}
+	if matches[0].Title != "Grimoire roadmap" {
+		t.Fatalf("expected %q first, got %q", "Grimoire roadmap", matches[0].Title)
+	}
+
+	want := []Range{{Start: 0, End: 8}, {Start: 9, End: 16}}
+	got := matches[0].Positions
+	if len(got) != len(want) {
+		t.Fatalf("expected positions %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected positions %v, got %v", want, got)
+		}
+	}
+}
+
+func TestMatchTitlesPrefix(t *testing.T) {
+	notes := []Note{
+		{ID: uuid.New(), Title: "Grimoire roadmap"},
+		{ID: uuid.New(), Title: "Shopping list"},
+	}
+
+	matches := MatchTitles(notes, "grim", 5)
+	if len(matches) != 1 || matches[0].Title != "Grimoire roadmap" {
+		t.Fatalf("expected only the roadmap to match, got %+v", matches)
+	}
+	if len(matches[0].Positions) != 1 || matches[0].Positions[0] != (Range{Start: 0, End: 8}) {
+		t.Errorf("expected the first word highlighted, got %v", matches[0].Positions)
+	}
+}
@@ -0,0 +1,334 @@
+package notes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/middleware"
+)
+
+// Author is who a commit is attributed to.
+type Author struct {
+	Name  string
+	Email string
+}
+
+// AuthorLookup resolves the author of the writes a user makes.
+type AuthorLookup func(ctx context.Context, userID string) (Author, error)
+
+// systemAuthor commits the writes no user is behind, such as scheduled
+// publishing, and is the committer of every commit.
+var systemAuthor = Author{Name: "Grimoire", Email: "grimoire@localhost"}
+
+// GitNoteRepository keeps notes as plain files in a git working tree and
+// commits every write, so a note's history is its git log:
+//
+//	<user-id>/<note-id>.md    the note's content, front matter included
+//	<user-id>/<note-id>.json  everything else: title, tags, timestamps...
+//
+// A deleted note keeps only its .json, which reserves its slugs.
+//
+// The files are the source of truth. They are loaded into an
+// InMemoryNoteRepository at startup, which then serves every read. Locks
+// and scheduler leases are not persisted. Only one process may use a
+// repository at a time.
+type GitNoteRepository struct {
+	*InMemoryNoteRepository
+	dir     string
+	authors AuthorLookup
+	writeMu sync.Mutex // one write, and so one commit, at a time
+}
+
+// gitNoteMeta is the .json half of a note. Content lives in the .md file
+// and ForkCount is derived, so both are left out.
+type gitNoteMeta struct {
+	Note
+	Content   string     `json:"content,omitempty"`
+	ForkCount int        `json:"fork_count,omitempty"`
+	Slugs     []string   `json:"slugs,omitempty"`
+	Reminded  *time.Time `json:"reminded,omitempty"` // remind_at already delivered
+}
+
+// NewGitNoteRepository opens the repository in dir, creating it if needed.
+// authors may be nil, in which case commits are attributed to user IDs.
+func NewGitNoteRepository(dir string, authors AuthorLookup) (*GitNoteRepository, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("git storage needs the git binary: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create notes directory: %w", err)
+	}
+	r := &GitNoteRepository{InMemoryNoteRepository: NewInMemoryNoteRepository(), dir: dir, authors: authors}
+
+	if _, err := os.Stat(filepath.Join(dir, ".git")); errors.Is(err, os.ErrNotExist) {
+		if err := r.git(nil, "init", "-q"); err != nil {
+			return nil, err
+		}
+		// Give HEAD a commit so rollbacks always have something to go back to
+		if err := r.git(nil, "commit", "-q", "--allow-empty", "-m", "Initialize notes repository"); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// load reads every note in the working tree into memory.
+func (r *GitNoteRepository) load() error {
+	users, err := os.ReadDir(r.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read notes directory: %w", err)
+	}
+	for _, user := range users {
+		if !user.IsDir() || uuid.Validate(user.Name()) != nil {
+			continue
+		}
+		files, err := filepath.Glob(filepath.Join(r.dir, user.Name(), "*.json"))
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			noteID, err := uuid.Parse(filepath.Base(file[:len(file)-len(".json")]))
+			if err != nil {
+				continue
+			}
+			if err := r.reload(user.Name(), noteID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *GitNoteRepository) paths(userID string, noteID uuid.UUID) (md, meta string) {
+	base := filepath.Join(userID, noteID.String())
+	return base + ".md", base + ".json"
+}
+
+// reload reads one note back from the working tree, forgetting it if its
+// files are gone.
+func (r *GitNoteRepository) reload(userID string, noteID uuid.UUID) error {
+	mdPath, metaPath := r.paths(userID, noteID)
+	raw, err := os.ReadFile(filepath.Join(r.dir, metaPath))
+	if errors.Is(err, os.ErrNotExist) {
+		r.forget(noteID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read note %s: %w", noteID, err)
+	}
+	var meta gitNoteMeta
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return fmt.Errorf("failed to parse note %s: %w", noteID, err)
+	}
+	content, err := os.ReadFile(filepath.Join(r.dir, mdPath))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read note %s: %w", noteID, err)
+	}
+	meta.Note.Content = string(content)
+	r.restore(noteRecord{Note: meta.Note, Slugs: meta.Slugs, Reminded: meta.Reminded})
+	return nil
+}
+
+// persist writes the note as held in memory to the working tree and commits
+// it. On failure the working tree and memory are put back to the last
+// commit.
+func (r *GitNoteRepository) persist(ctx context.Context, noteID uuid.UUID, verb string) error {
+	rec, ok := r.stored(noteID)
+	if !ok {
+		return errors.New("note not found")
+	}
+	note := rec.Note
+	userID := note.UserID.String()
+	mdPath, metaPath := r.paths(userID, noteID)
+
+	err := r.write(rec, mdPath, metaPath)
+	if err == nil {
+		author := r.author(ctx)
+		message := fmt.Sprintf("%s note %s\n\n%s", verb, noteID, note.Title)
+		err = r.commit(&author, message, mdPath, metaPath)
+	}
+	if err != nil {
+		r.git(nil, "reset", "-q", "--", mdPath, metaPath)
+		for _, p := range []string{mdPath, metaPath} {
+			if r.git(nil, "checkout", "-q", "HEAD", "--", p) != nil {
+				os.Remove(filepath.Join(r.dir, p)) // not committed yet
+			}
+		}
+		if reloadErr := r.reload(userID, noteID); reloadErr != nil {
+			return errors.Join(err, reloadErr)
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *GitNoteRepository) write(rec noteRecord, mdPath, metaPath string) error {
+	note := rec.Note
+	meta, err := json.MarshalIndent(gitNoteMeta{Note: note, Slugs: rec.Slugs, Reminded: rec.Reminded}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode note: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(r.dir, note.UserID.String()), 0o750); err != nil {
+		return fmt.Errorf("failed to create user directory: %w", err)
+	}
+	// A deleted note loses its content but keeps its .json as a tombstone,
+	// so its slugs stay reserved
+	if note.DeletedAt != nil {
+		if err := os.Remove(filepath.Join(r.dir, mdPath)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove note file: %w", err)
+		}
+	} else if err := writeFileAtomic(filepath.Join(r.dir, mdPath), []byte(note.Content)); err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(r.dir, metaPath), append(meta, '\n'))
+}
+
+// writeFileAtomic replaces the file so readers never see half of it.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// commit stages and commits the paths, if anything changed.
+func (r *GitNoteRepository) commit(author *Author, message string, paths ...string) error {
+	if err := r.git(nil, append([]string{"add", "-A", "--"}, paths...)...); err != nil {
+		return err
+	}
+	if r.git(nil, append([]string{"diff", "--cached", "--quiet", "--"}, paths...)...) == nil {
+		return nil // nothing to commit
+	}
+	return r.git(author, append([]string{"commit", "-q", "-m", message, "--"}, paths...)...)
+}
+
+// git runs a git command in the repository. Commands are not tied to the
+// request context: a commit cut short would leave the tree half written.
+func (r *GitNoteRepository) git(author *Author, args ...string) error {
+	cmd := exec.Command("git", append([]string{
+		"-c", "user.name=" + systemAuthor.Name,
+		"-c", "user.email=" + systemAuthor.Email,
+		"-c", "commit.gpgsign=false",
+	}, args...)...)
+	cmd.Dir = r.dir
+	if author != nil {
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME="+author.Name, "GIT_AUTHOR_EMAIL="+author.Email)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %s: %w: %s", args[0], err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// author attributes a write to the user of the request's JWT, or to
+// Grimoire itself when there is none.
+func (r *GitNoteRepository) author(ctx context.Context) Author {
+	userID, ok := ctx.Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		return systemAuthor
+	}
+	if r.authors != nil {
+		if author, err := r.authors(ctx, userID); err == nil {
+			return author
+		}
+	}
+	return Author{Name: userID, Email: userID + "@users.grimoire"}
+}
+
+func (r *GitNoteRepository) Create(ctx context.Context, note *Note) error {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	if err := r.InMemoryNoteRepository.Create(ctx, note); err != nil {
+		return err
+	}
+	return r.persist(ctx, note.ID, "Create")
+}
+
+func (r *GitNoteRepository) Update(ctx context.Context, note *Note) error {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	if err := r.InMemoryNoteRepository.Update(ctx, note); err != nil {
+		return err
+	}
+	return r.persist(ctx, note.ID, "Update")
+}
+
+func (r *GitNoteRepository) Delete(ctx context.Context, id string) error {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	if err := r.InMemoryNoteRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	return r.persist(ctx, uuid.MustParse(id), "Delete")
+}
+
+func (r *GitNoteRepository) Publish(ctx context.Context, id string) error {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	if err := r.InMemoryNoteRepository.Publish(ctx, id); err != nil {
+		return err
+	}
+	return r.persist(ctx, uuid.MustParse(id), "Publish")
+}
+
+func (r *GitNoteRepository) Unpublish(ctx context.Context, id string) error {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	if err := r.InMemoryNoteRepository.Unpublish(ctx, id); err != nil {
+		return err
+	}
+	return r.persist(ctx, uuid.MustParse(id), "Unpublish")
+}
+
+func (r *GitNoteRepository) Complete(ctx context.Context, action ScheduledAction) error {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	if err := r.InMemoryNoteRepository.Complete(ctx, action); err != nil {
+		return err
+	}
+	return r.persist(ctx, action.NoteID, "Complete schedule of")
+}
+
+func (r *GitNoteRepository) AssignSlug(ctx context.Context, userID, noteID uuid.UUID, base string) (string, error) {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	slug, err := r.InMemoryNoteRepository.AssignSlug(ctx, userID, noteID, base)
+	if err != nil {
+		return "", err
+	}
+	if err := r.persist(ctx, noteID, "Assign slug to"); err != nil {
+		return "", err
+	}
+	return slug, nil
+}
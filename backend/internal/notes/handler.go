@@ -3,8 +3,12 @@ package notes
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jehufrayle/grimoire/internal/users"
@@ -13,11 +17,30 @@ import (
 )
 
 type Handler struct {
-	repo NoteRepository
+	repo       NoteRepository
+	searches   SavedSearchRepository
+	properties PropertyDefinitionRepository
 }
 
-func NewHandler(repo NoteRepository) *Handler {
-	return &Handler{repo: repo}
+func NewHandler(repo NoteRepository, searches SavedSearchRepository, properties PropertyDefinitionRepository) *Handler {
+	return &Handler{repo: repo, searches: searches, properties: properties}
+}
+
+// syncProperties validates the note's properties against its owner's
+// definitions and mirrors them in the front matter with sync, SyncProperties
+// or SetProperties. It writes the error response itself and returns false
+// when the request should stop.
+func (h *Handler) syncProperties(w http.ResponseWriter, r *http.Request, note *Note, sync func(*Note, []PropertyDefinition) error) bool {
+	defs, err := h.properties.GetByUserID(r.Context(), note.UserID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve properties", http.StatusInternalServerError)
+		return false
+	}
+	if err := sync(note, defs); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
 }
 
 func (h *Handler) GetAllNotes(w http.ResponseWriter, r *http.Request) {
@@ -58,7 +81,24 @@ func (h *Handler) GetUserNotes(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
 		return
 	}
-	notes, err := repo.GetByUserID(r.Context(), userID)
+
+	// Listings accept a saved search and ad-hoc filters
+	filter, err := FilterFromRequest(r.Context(), r, userID, h.searches)
+	if err != nil {
+		if errors.Is(err, ErrSavedSearchNotFound) {
+			http.Error(w, "Saved search not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var notes []Note
+	if filter.IsEmpty() {
+		notes, err = repo.GetByUserID(r.Context(), userID)
+	} else {
+		notes, err = repo.Find(r.Context(), userID, filter)
+	}
 	if err != nil {
 		http.Error(w, "Failed to retrieve notes for user", http.StatusInternalServerError)
 		return
@@ -136,10 +176,17 @@ func (h *Handler) CreateNote(w http.ResponseWriter, r *http.Request) {
 	// Create a new note
 	repo := h.repo
 	type req struct {
-		Title    string   `json:"title"`
-		Content  string   `json:"content"`
-		Tags     []string `json:"tags"`
-		IsPublic bool     `json:"is_public"`
+		Title        string         `json:"title"`
+		Content      string         `json:"content"`
+		Tags         []string       `json:"tags"`
+		IsPublic     bool           `json:"is_public"`
+		Encrypted    bool           `json:"encrypted"`
+		Envelope     *Envelope      `json:"envelope"`
+		Properties   map[string]any `json:"properties"`
+		PublishAt    *time.Time     `json:"publish_at"`
+		ExpiresAt    *time.Time     `json:"expires_at"`
+		ExpireAction ExpireAction   `json:"expire_action"`
+		RemindAt     *time.Time     `json:"remind_at"`
 	}
 	var note Note
 	var requestBody req
@@ -167,11 +214,29 @@ func (h *Handler) CreateNote(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	note = Note{
-		Title:    requestBody.Title,
-		Content:  requestBody.Content,
-		UserID:   userID, // Assuming user_id is passed in the path
-		IsPublic: requestBody.IsPublic,
-		Tags:     tags,
+		Title:        requestBody.Title,
+		Content:      requestBody.Content,
+		UserID:       userID, // Assuming user_id is passed in the path
+		IsPublic:     requestBody.IsPublic,
+		Tags:         tags,
+		Encrypted:    requestBody.Encrypted,
+		Envelope:     requestBody.Envelope,
+		Properties:   requestBody.Properties,
+		PublishAt:    requestBody.PublishAt,
+		ExpiresAt:    requestBody.ExpiresAt,
+		ExpireAction: requestBody.ExpireAction,
+		RemindAt:     requestBody.RemindAt,
+	}
+	if err := ValidateEncryption(&note); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := ValidateSchedule(&note); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !h.syncProperties(w, r, &note, SyncProperties) {
+		return
 	}
 	if err := repo.Create(r.Context(), &note); err != nil {
 		http.Error(w, "Failed to create note", http.StatusInternalServerError)
@@ -192,25 +257,67 @@ func (h *Handler) UpdateNote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	uid, err := uuid.Parse(id)
-	if err != nil {
+	if _, err := uuid.Parse(id); err != nil {
 		http.Error(w, "Invalid note ID format", http.StatusBadRequest)
 		return
 	}
+	userIDstr, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	userID, err := uuid.Parse(userIDstr)
+	if err != nil {
+		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
 
-	var note Note
-	if err := json.NewDecoder(r.Body).Decode(&note); err != nil {
+	var patch NotePatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
+	existing, err := repo.GetByID(r.Context(), id)
+	if err != nil || existing == nil {
+		http.Error(w, "Note not found", http.StatusNotFound)
+		return
+	}
+	if existing.UserID != userID {
+		http.Error(w, "Unauthorized access to this note", http.StatusForbidden)
+		return
+	}
+
+	// Fields left out of the body keep their stored values
+	note := *existing
+	patch.Apply(&note)
+	if err := ValidateEncryption(&note); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := ValidateSchedule(&note); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Properties sent on their own replace the stored front matter, which
+	// would otherwise win over them
+	sync := SyncProperties
+	if patch.SetsPropertiesOnly() {
+		sync = SetProperties
+	}
+	if !h.syncProperties(w, r, &note, sync) {
+		return
+	}
+
 	if err := repo.Update(r.Context(), &note); err != nil {
+		if errors.Is(err, ErrVersionConflict) {
+			http.Error(w, "Note was modified since it was loaded", http.StatusConflict)
+			return
+		}
 		http.Error(w, "Failed to update note", http.StatusInternalServerError)
 		return
 	}
 
-	note.ID = uid
-
 	utils.JSONResponse(w, note, http.StatusOK)
 }
 
@@ -223,6 +330,26 @@ func (h *Handler) DeleteNote(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Note ID is required", http.StatusBadRequest)
 		return
 	}
+	userIDstr, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	userID, err := uuid.Parse(userIDstr)
+	if err != nil {
+		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	existing, err := repo.GetByID(r.Context(), id)
+	if err != nil || existing == nil {
+		http.Error(w, "Note not found", http.StatusNotFound)
+		return
+	}
+	if existing.UserID != userID {
+		http.Error(w, "Unauthorized access to this note", http.StatusForbidden)
+		return
+	}
 
 	if err := repo.Delete(r.Context(), id); err != nil {
 		http.Error(w, "Failed to delete note", http.StatusInternalServerError)
@@ -232,6 +359,120 @@ func (h *Handler) DeleteNote(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent) // 204 No Content
 }
 
+func (h *Handler) ForkNote(w http.ResponseWriter, r *http.Request) {
+	// Copy a readable note into the caller's own collection
+	repo := h.repo
+	id := r.PathValue("id")
+
+	if id == "" {
+		http.Error(w, "Note ID is required", http.StatusBadRequest)
+		return
+	}
+
+	userIDstr, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := uuid.Parse(userIDstr)
+	if err != nil {
+		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	source, err := repo.GetByID(r.Context(), id)
+	if err != nil || source == nil {
+		http.Error(w, "Note not found", http.StatusNotFound)
+		return
+	}
+
+	// Readers who only have public access fork the published snapshot
+	source, err = ReadableVersion(r.Context(), repo, source, userID)
+	if err != nil {
+		http.Error(w, "Failed to check note access", http.StatusInternalServerError)
+		return
+	}
+	if source == nil {
+		http.Error(w, "Unauthorized access to this note", http.StatusForbidden)
+		return
+	}
+	if source.Encrypted {
+		http.Error(w, ErrEncryptedNote.Error(), http.StatusConflict)
+		return
+	}
+
+	tags := make([]Tag, len(source.Tags))
+	for i, tag := range source.Tags {
+		tags[i] = Tag{
+			Name: tag.Name,
+		}
+	}
+	fork := Note{
+		Title:            source.Title,
+		Content:          source.Content,
+		UserID:           userID,
+		IsPublic:         false, // forks start private regardless of the source
+		Tags:             tags,
+		ForkedFromID:     &source.ID,
+		ForkedFromUserID: &source.UserID,
+	}
+	// Properties only carry over where the forker defines them compatibly
+	defs, err := h.properties.GetByUserID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve properties", http.StatusInternalServerError)
+		return
+	}
+	if err := AdoptProperties(&fork, defs); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := repo.Create(r.Context(), &fork); err != nil {
+		http.Error(w, "Failed to fork note", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, fork, http.StatusCreated)
+}
+
+func (h *Handler) LookupTitles(w http.ResponseWriter, r *http.Request) {
+	// Fuzzy title lookup for the quick switcher
+	repo := h.repo
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "Query parameter q is required", http.StatusBadRequest)
+		return
+	}
+
+	limit := 10
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 && v <= 50 {
+		limit = v
+	}
+
+	userIDstr, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := uuid.Parse(userIDstr)
+	if err != nil {
+		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	matches, err := repo.SearchTitles(r.Context(), userID, query, limit)
+	if err != nil {
+		http.Error(w, "Failed to look up notes", http.StatusInternalServerError)
+		return
+	}
+	if matches == nil {
+		matches = []TitleMatch{}
+	}
+
+	utils.JSONResponse(w, matches, http.StatusOK)
+}
+
 func (h *Handler) GetNotesByTags(w http.ResponseWriter, r *http.Request) {
 	// Get notes by tags
 	repo := h.repo
@@ -0,0 +1,43 @@
+package notes
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrVersionConflict is returned by Update when the note's Version does
+	// not match the stored one, i.e. it changed since the caller read it.
+	ErrVersionConflict = errors.New("note was modified concurrently")
+	// ErrLocked is returned by PutLock when another live lock holds the note.
+	ErrLocked = errors.New("note is locked")
+	// ErrNotLocked is returned by GetLock when no live lock holds the note.
+	ErrNotLocked = errors.New("note is not locked")
+)
+
+// NoteLock is an exclusive write lock on a note, as taken by WebDAV clients.
+// Version is the note version the holder last wrote or saw: writes under
+// the lock are conditional on it, so changes made elsewhere while the lock
+// is held are not silently overwritten.
+type NoteLock struct {
+	NoteID    uuid.UUID
+	UserID    uuid.UUID
+	Token     string
+	Owner     string // client-supplied owner XML, echoed back on discovery
+	Version   int
+	ExpiresAt time.Time
+}
+
+// LockStore keeps note locks. Expired locks are ignored and replaced.
+type LockStore interface {
+	// GetLock returns the live lock on the note, or ErrNotLocked.
+	GetLock(ctx context.Context, noteID uuid.UUID, now time.Time) (*NoteLock, error)
+	// PutLock takes the lock or, when the token already holds it, renews and
+	// updates it. It fails with ErrLocked if another live lock holds the note.
+	PutLock(ctx context.Context, lock *NoteLock, now time.Time) error
+	// DeleteLock releases the lock held by token, or returns ErrNotLocked.
+	DeleteLock(ctx context.Context, noteID uuid.UUID, token string) error
+}
@@ -3,29 +3,101 @@ package notes
 import (
 	"context"
 	"errors"
+	"maps"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/journal"
 )
 
+// InMemoryNoteRepository keeps notes in memory and hands out copies, never
+// the notes it holds. Opened with OpenInMemoryNoteRepository it also
+// persists them to disk.
 type InMemoryNoteRepository struct {
-	mu    sync.RWMutex
-	notes map[string]*Note
+	mu     sync.RWMutex
+	notes  map[string]*Note
+	shares map[uuid.UUID]map[uuid.UUID]bool   // note ID -> user ID -> can edit
+	leases map[uuid.UUID]time.Time            // note ID -> scheduler lease expiry
+	slugs  map[uuid.UUID]map[string]uuid.UUID // user ID -> slug -> note ID
+	// note ID -> slugs the note has had, current one last
+	slugHistory map[uuid.UUID][]string
+	reminded    map[uuid.UUID]time.Time // note ID -> remind_at delivered
+	locks       map[uuid.UUID]NoteLock
+	journal     *journal.Store[noteSnapshot, noteEntry] // nil when not persisted
 }
 
 func NewInMemoryNoteRepository() *InMemoryNoteRepository {
 	return &InMemoryNoteRepository{
-		notes: make(map[string]*Note),
+		notes:  make(map[string]*Note),
+		shares: make(map[uuid.UUID]map[uuid.UUID]bool),
+		leases: make(map[uuid.UUID]time.Time),
+		slugs:  make(map[uuid.UUID]map[string]uuid.UUID),
+
+		slugHistory: make(map[uuid.UUID][]string),
+		reminded:    make(map[uuid.UUID]time.Time),
+		locks:       make(map[uuid.UUID]NoteLock),
+	}
+}
+
+// clone returns a deep copy of the note. Property values are scalars, so
+// copying the map is enough.
+func (n *Note) clone() Note {
+	note := *n
+	note.Tags = slices.Clone(n.Tags)
+	note.Properties = maps.Clone(n.Properties)
+	note.DeletedAt = clonePtr(n.DeletedAt)
+	note.ForkedFromID = clonePtr(n.ForkedFromID)
+	note.ForkedFromUserID = clonePtr(n.ForkedFromUserID)
+	note.Envelope = clonePtr(n.Envelope)
+	note.Published = clonePtr(n.Published)
+	if note.Published != nil {
+		note.Published.Tags = slices.Clone(n.Published.Tags)
+	}
+	note.PublishAt = clonePtr(n.PublishAt)
+	note.ExpiresAt = clonePtr(n.ExpiresAt)
+	note.RemindAt = clonePtr(n.RemindAt)
+	return note
+}
+
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
 	}
+	v := *p
+	return &v
+}
+
+// withForkCount returns a copy of the note with ForkCount and the current
+// slug filled in.
+// Callers must hold at least a read lock.
+func (r *InMemoryNoteRepository) withForkCount(n *Note) Note {
+	note := n.clone()
+	if history := r.slugHistory[n.ID]; note.Published != nil && len(history) > 0 {
+		note.Published.Slug = history[len(history)-1]
+	}
+	note.ForkCount = 0
+	for _, other := range r.notes {
+		if other.DeletedAt == nil && other.ForkedFromID != nil && *other.ForkedFromID == n.ID {
+			note.ForkCount++
+		}
+	}
+	return note
 }
 
 func (r *InMemoryNoteRepository) Create(ctx context.Context, note *Note) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	if note.DailyDate != "" && r.daily(note.UserID, note.DailyDate) != nil {
+		return ErrDailyNoteExists
+	}
+
 	note.ID = uuid.New()
+	note.Version = 1
 	now := time.Now()
 	note.CreatedAt = now
 	note.UpdatedAt = now
@@ -37,8 +109,10 @@ func (r *InMemoryNoteRepository) Create(ctx context.Context, note *Note) error {
 		}
 	}
 
-	r.notes[note.ID.String()] = note
-	return nil
+	undo := r.undo(note.ID)
+	stored := note.clone()
+	r.notes[note.ID.String()] = &stored
+	return r.logNote(note.ID, undo)
 }
 
 func (r *InMemoryNoteRepository) GetAll(ctx context.Context) ([]Note, error) {
@@ -48,7 +122,7 @@ func (r *InMemoryNoteRepository) GetAll(ctx context.Context) ([]Note, error) {
 	var result []Note
 	for _, n := range r.notes {
 		if n.DeletedAt == nil {
-			result = append(result, *n)
+			result = append(result, r.withForkCount(n))
 		}
 	}
 	return result, nil
@@ -61,21 +135,54 @@ func (r *InMemoryNoteRepository) GetByUserID(ctx context.Context, userID uuid.UU
 
 	for _, n := range r.notes {
 		if n.DeletedAt == nil && n.UserID == userID {
-			result = append(result, *n)
+			result = append(result, r.withForkCount(n))
 		}
 	}
 	return result, nil
 }
 
+func (r *InMemoryNoteRepository) Find(ctx context.Context, userID uuid.UUID, filter *Filter) ([]Note, error) {
+	notes, err := r.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(notes, func(i, j int) bool { return notes[i].UpdatedAt.After(notes[j].UpdatedAt) })
+	return FilterNotes(notes, filter), nil
+}
+
 func (r *InMemoryNoteRepository) GetByID(ctx context.Context, id string) (*Note, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	note, ok := r.notes[id]
 	if !ok || note.DeletedAt != nil {
-		return nil, errors.New("note not found")
+		return nil, ErrNoteNotFound
 	}
-	return note, nil
+	result := r.withForkCount(note)
+	return &result, nil
+}
+
+// daily finds the user's live daily note for date.
+// Callers must hold at least a read lock.
+func (r *InMemoryNoteRepository) daily(userID uuid.UUID, date string) *Note {
+	for _, n := range r.notes {
+		if n.DeletedAt == nil && n.UserID == userID && n.DailyDate == date {
+			return n
+		}
+	}
+	return nil
+}
+
+func (r *InMemoryNoteRepository) GetDaily(ctx context.Context, userID uuid.UUID, date string) (*Note, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	note := r.daily(userID, date)
+	if note == nil {
+		return nil, ErrNoDailyNote
+	}
+	result := r.withForkCount(note)
+	return &result, nil
 }
 
 func (r *InMemoryNoteRepository) GetByTags(ctx context.Context, tags []string) ([]Note, error) {
@@ -94,7 +201,7 @@ func (r *InMemoryNoteRepository) GetByTags(ctx context.Context, tags []string) (
 		}
 		for _, tag := range n.Tags {
 			if _, ok := tagSet[strings.ToLower(tag.Name)]; ok {
-				result = append(result, *n)
+				result = append(result, r.withForkCount(n))
 				break
 			}
 		}
@@ -110,10 +217,186 @@ func (r *InMemoryNoteRepository) Update(ctx context.Context, note *Note) error {
 	if !ok || existing.DeletedAt != nil {
 		return errors.New("note not found")
 	}
+	if note.Version != 0 && note.Version != existing.Version {
+		return ErrVersionConflict
+	}
 
+	undo := r.undo(note.ID)
+	note.Version = existing.Version + 1
 	note.UpdatedAt = time.Now()
-	r.notes[note.ID.String()] = note
-	return nil
+	note.Published = clonePtr(existing.Published) // only Publish touches the snapshot
+	note.DailyDate = existing.DailyDate
+	stored := note.clone()
+	r.notes[note.ID.String()] = &stored
+	return r.logNote(note.ID, undo)
+}
+
+func (r *InMemoryNoteRepository) Publish(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	note, ok := r.notes[id]
+	if !ok || note.DeletedAt != nil {
+		return errors.New("note not found or already deleted")
+	}
+	undo := r.undo(note.ID)
+	note.Published = &Snapshot{Title: note.Title, Content: note.Content, Tags: slices.Clone(note.Tags), PublishedAt: time.Now()}
+	note.IsPublic = true
+	return r.logNote(note.ID, undo)
+}
+
+func (r *InMemoryNoteRepository) Unpublish(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	note, ok := r.notes[id]
+	if !ok || note.DeletedAt != nil {
+		return errors.New("note not found or already deleted")
+	}
+	undo := r.undo(note.ID)
+	note.Published = nil
+	note.IsPublic = false
+	return r.logNote(note.ID, undo)
+}
+
+func (r *InMemoryNoteRepository) GetPublished(ctx context.Context, limit, offset int) ([]Note, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []Note
+	for _, n := range r.notes {
+		if n.DeletedAt == nil && n.IsPublic && n.Published != nil {
+			result = append(result, r.withForkCount(n))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Published.PublishedAt.After(result[j].Published.PublishedAt) })
+
+	total := len(result)
+	if offset >= total {
+		return []Note{}, total, nil
+	}
+	return result[offset:min(offset+limit, total)], total, nil
+}
+
+func (r *InMemoryNoteRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]ScheduledAction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var actions []ScheduledAction
+	claimed := 0
+	for _, n := range r.notes {
+		if claimed == limit {
+			break
+		}
+		if n.DeletedAt != nil || r.leases[n.ID].After(now) {
+			continue
+		}
+		publish := n.PublishAt != nil && !n.PublishAt.After(now)
+		expire := n.ExpiresAt != nil && !n.ExpiresAt.After(now)
+		remind := reminderDue(n.RemindAt, r.remindedAt(n.ID), now)
+		if !publish && !expire && !remind {
+			continue
+		}
+		r.leases[n.ID] = now.Add(lease)
+		claimed++
+		if publish {
+			actions = append(actions, ScheduledAction{NoteID: n.ID, Kind: SchedulePublish, At: *n.PublishAt})
+		}
+		if expire {
+			actions = append(actions, ScheduledAction{NoteID: n.ID, Kind: ScheduleExpire, Expire: n.ExpireAction, At: *n.ExpiresAt})
+		}
+		if remind {
+			actions = append(actions, ScheduledAction{NoteID: n.ID, Kind: ScheduleRemind, At: *n.RemindAt})
+		}
+	}
+	return actions, nil
+}
+
+func (r *InMemoryNoteRepository) Complete(ctx context.Context, action ScheduledAction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	note, ok := r.notes[action.NoteID.String()]
+	if !ok {
+		return errors.New("note not found")
+	}
+	if due := note.scheduled(action.Kind); due == nil || !due.Equal(action.At) {
+		return nil // rescheduled since the claim
+	}
+	undo := r.undo(note.ID)
+	switch action.Kind {
+	case SchedulePublish:
+		note.PublishAt = nil
+	case ScheduleRemind:
+		r.reminded[note.ID] = action.At
+	default:
+		note.ExpiresAt = nil
+		note.ExpireAction = ""
+	}
+	return r.logNote(note.ID, undo)
+}
+
+func (r *InMemoryNoteRepository) AssignSlug(ctx context.Context, userID, noteID uuid.UUID, base string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	history := r.slugHistory[noteID]
+	if len(history) > 0 && derivesFrom(history[len(history)-1], base) {
+		return history[len(history)-1], nil
+	}
+	if r.slugs[userID] == nil {
+		r.slugs[userID] = make(map[string]uuid.UUID)
+	}
+	undo := r.undo(noteID)
+	for n := 1; ; n++ {
+		slug := slugFor(base, n)
+		owner, taken := r.slugs[userID][slug]
+		if taken && owner != noteID {
+			continue
+		}
+		r.slugs[userID][slug] = noteID
+		r.slugHistory[noteID] = append(slices.Clone(history), slug)
+		if err := r.logNote(noteID, undo); err != nil {
+			return "", err
+		}
+		return slug, nil
+	}
+}
+
+func (r *InMemoryNoteRepository) ResolveSlug(ctx context.Context, userID uuid.UUID, slug string) (uuid.UUID, string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	noteID, ok := r.slugs[userID][slug]
+	if !ok {
+		return uuid.Nil, "", errors.New("slug not found")
+	}
+	history := r.slugHistory[noteID]
+	return noteID, history[len(history)-1], nil
+}
+
+func (r *InMemoryNoteRepository) Stats(ctx context.Context, userID uuid.UUID) (*Stats, error) {
+	notes, err := r.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return ComputeStats(notes), nil
+}
+
+func (r *InMemoryNoteRepository) SearchTitles(ctx context.Context, userID uuid.UUID, query string, limit int) ([]TitleMatch, error) {
+	notes, err := r.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return MatchTitles(notes, query, limit), nil
+}
+
+func (r *InMemoryNoteRepository) IsSharedWith(ctx context.Context, noteID, userID uuid.UUID) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.shares[noteID][userID]
+	return ok, nil
 }
 
 func (r *InMemoryNoteRepository) Delete(ctx context.Context, id string) error {
@@ -125,8 +408,259 @@ func (r *InMemoryNoteRepository) Delete(ctx context.Context, id string) error {
 		return errors.New("note not found or already deleted")
 	}
 
+	undo := r.undo(note.ID)
 	now := time.Now()
 	note.DeletedAt = &now
 	note.UpdatedAt = now
+	return r.logNote(note.ID, undo)
+}
+
+func (r *InMemoryNoteRepository) GetLock(ctx context.Context, noteID uuid.UUID, now time.Time) (*NoteLock, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	lock, ok := r.locks[noteID]
+	if !ok || !lock.ExpiresAt.After(now) {
+		return nil, ErrNotLocked
+	}
+	return &lock, nil
+}
+
+func (r *InMemoryNoteRepository) PutLock(ctx context.Context, lock *NoteLock, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	held, ok := r.locks[lock.NoteID]
+	if ok && held.Token != lock.Token && held.ExpiresAt.After(now) {
+		return ErrLocked
+	}
+	r.locks[lock.NoteID] = *lock
+	if err := r.journal.Log(noteEntry{Lock: lock}); err != nil {
+		r.putLock(lock.NoteID, held, ok)
+		return err
+	}
+	return nil
+}
+
+func (r *InMemoryNoteRepository) DeleteLock(ctx context.Context, noteID uuid.UUID, token string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	held, ok := r.locks[noteID]
+	if !ok || held.Token != token {
+		return ErrNotLocked
+	}
+	delete(r.locks, noteID)
+	if err := r.journal.Log(noteEntry{Unlock: &noteID}); err != nil {
+		r.putLock(noteID, held, true)
+		return err
+	}
+	return nil
+}
+
+// putLock sets or clears the lock held on a note.
+// Callers must hold the write lock.
+func (r *InMemoryNoteRepository) putLock(noteID uuid.UUID, lock NoteLock, held bool) {
+	if held {
+		r.locks[noteID] = lock
+	} else {
+		delete(r.locks, noteID)
+	}
+}
+
+// noteRecord is a note as persisted: deleted or not, with its slug history.
+type noteRecord struct {
+	Note     Note       `json:"note"`
+	Slugs    []string   `json:"slugs,omitempty"`
+	Reminded *time.Time `json:"reminded,omitempty"` // see remindedAt
+}
+
+// record returns a copy of what is held for a note.
+// Callers must hold at least a read lock.
+func (r *InMemoryNoteRepository) record(id uuid.UUID) (noteRecord, bool) {
+	note, ok := r.notes[id.String()]
+	if !ok {
+		return noteRecord{}, false
+	}
+	return noteRecord{Note: note.clone(), Slugs: slices.Clone(r.slugHistory[id]), Reminded: r.remindedAt(id)}, true
+}
+
+// remindedAt returns the remind_at whose reminder was delivered, if any.
+// Callers must hold at least a read lock.
+func (r *InMemoryNoteRepository) remindedAt(id uuid.UUID) *time.Time {
+	if reminded, ok := r.reminded[id]; ok {
+		return &reminded
+	}
+	return nil
+}
+
+// put replaces whatever is held for the record's note.
+// Callers must hold the write lock.
+func (r *InMemoryNoteRepository) put(rec noteRecord) {
+	note := rec.Note.clone()
+	r.dropSlugs(note.ID)
+	r.notes[note.ID.String()] = &note
+	if rec.Reminded != nil {
+		r.reminded[note.ID] = *rec.Reminded
+	} else {
+		delete(r.reminded, note.ID)
+	}
+	if len(rec.Slugs) > 0 {
+		if r.slugs[note.UserID] == nil {
+			r.slugs[note.UserID] = make(map[string]uuid.UUID)
+		}
+		for _, slug := range rec.Slugs {
+			r.slugs[note.UserID][slug] = note.ID
+		}
+		r.slugHistory[note.ID] = slices.Clone(rec.Slugs)
+	}
+}
+
+// drop removes every trace of a note.
+// Callers must hold the write lock.
+func (r *InMemoryNoteRepository) drop(id uuid.UUID) {
+	r.dropSlugs(id)
+	delete(r.notes, id.String())
+	delete(r.leases, id)
+	delete(r.reminded, id)
+	delete(r.locks, id)
+}
+
+// Callers must hold the write lock.
+func (r *InMemoryNoteRepository) dropSlugs(id uuid.UUID) {
+	for userID, slugs := range r.slugs {
+		for slug, noteID := range slugs {
+			if noteID == id {
+				delete(r.slugs[userID], slug)
+			}
+		}
+	}
+	delete(r.slugHistory, id)
+}
+
+// stored returns a copy of the note as stored, deleted or not, with its slug
+// history and delivered reminder. It lets backends built on this one
+// persist what it holds.
+func (r *InMemoryNoteRepository) stored(id uuid.UUID) (noteRecord, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.record(id)
+}
+
+// restore puts a note back exactly as it was persisted, ID, timestamps,
+// slug history and delivered reminder included, replacing whatever is held
+// for its ID.
+func (r *InMemoryNoteRepository) restore(rec noteRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.put(rec)
+}
+
+// forget drops every trace of a note.
+func (r *InMemoryNoteRepository) forget(id uuid.UUID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.drop(id)
+}
+
+// noteEntry is one change in the journal's log.
+type noteEntry struct {
+	Note   *noteRecord `json:"note,omitempty"`
+	Lock   *NoteLock   `json:"lock,omitempty"`
+	Unlock *uuid.UUID  `json:"unlock,omitempty"`
+}
+
+// noteSnapshot is the whole repository as the journal's snapshot holds it.
+// Scheduler leases are left out; they only guard against double runs.
+type noteSnapshot struct {
+	Notes []noteRecord `json:"notes"`
+	Locks []NoteLock   `json:"locks"`
+}
+
+// OpenInMemoryNoteRepository returns an InMemoryNoteRepository persisted in
+// dir: it starts from what dir holds and logs every write there before
+// returning. Snapshot keeps the log short and Close takes a last snapshot.
+func OpenInMemoryNoteRepository(dir string) (*InMemoryNoteRepository, error) {
+	r := NewInMemoryNoteRepository()
+	j, err := journal.OpenStore(dir, &r.mu, r.snapshot,
+		func(snapshot noteSnapshot) {
+			for _, rec := range snapshot.Notes {
+				r.put(rec)
+			}
+			for _, lock := range snapshot.Locks {
+				r.locks[lock.NoteID] = lock
+			}
+		},
+		func(entry noteEntry) {
+			switch {
+			case entry.Note != nil:
+				r.put(*entry.Note)
+			case entry.Lock != nil:
+				r.locks[entry.Lock.NoteID] = *entry.Lock
+			case entry.Unlock != nil:
+				delete(r.locks, *entry.Unlock)
+			}
+		})
+	if err != nil {
+		return nil, err
+	}
+	r.journal = j
+	return r, nil
+}
+
+// undo returns a func that puts the note back as it is now, for when a
+// change to it cannot be logged. It is nil when nothing is logged.
+// Callers must hold the write lock.
+func (r *InMemoryNoteRepository) undo(id uuid.UUID) func() {
+	if r.journal == nil {
+		return nil
+	}
+	rec, ok := r.record(id)
+	if !ok {
+		return func() { r.drop(id) }
+	}
+	return func() { r.put(rec) }
+}
+
+// logNote logs the note as it now is, calling undo if that fails so memory
+// never holds a change the disk does not.
+// Callers must hold the write lock.
+func (r *InMemoryNoteRepository) logNote(id uuid.UUID, undo func()) error {
+	rec, ok := r.record(id)
+	if !ok {
+		return nil
+	}
+	if err := r.journal.Log(noteEntry{Note: &rec}); err != nil {
+		undo()
+		return err
+	}
 	return nil
 }
+
+// snapshot returns everything held, as the journal's snapshot holds it.
+// Callers must hold at least a read lock.
+func (r *InMemoryNoteRepository) snapshot() noteSnapshot {
+	snapshot := noteSnapshot{Notes: make([]noteRecord, 0, len(r.notes)), Locks: make([]NoteLock, 0, len(r.locks))}
+	for _, note := range r.notes {
+		rec, _ := r.record(note.ID)
+		snapshot.Notes = append(snapshot.Notes, rec)
+	}
+	for _, lock := range r.locks {
+		snapshot.Locks = append(snapshot.Locks, lock)
+	}
+	return snapshot
+}
+
+// Snapshot writes everything held to disk and empties the log. It is a
+// no-op when the repository is not persisted.
+func (r *InMemoryNoteRepository) Snapshot() error {
+	return r.journal.Snapshot()
+}
+
+// Close takes a last snapshot and closes the journal.
+func (r *InMemoryNoteRepository) Close() error {
+	return r.journal.Close()
+}
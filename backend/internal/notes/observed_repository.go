@@ -0,0 +1,104 @@
+package notes
+
+import (
+	"context"
+	"sync"
+)
+
+// NoteObserver is told about every successful write made through an
+// ObservedNoteRepository. Observers run synchronously after the write and
+// must not call back into the repository's write methods.
+type NoteObserver interface {
+	NoteSaved(ctx context.Context, note *Note)
+	NoteDeleted(ctx context.Context, note *Note)
+}
+
+// ObservedNoteRepository wraps another NoteRepository and notifies observers
+// of creates, updates and deletes, so caches and indexes can stay current.
+type ObservedNoteRepository struct {
+	NoteRepository
+	mu        sync.RWMutex
+	observers []NoteObserver
+}
+
+// NewObservedNoteRepository creates a new instance of ObservedNoteRepository.
+func NewObservedNoteRepository(repo NoteRepository, observers ...NoteObserver) *ObservedNoteRepository {
+	return &ObservedNoteRepository{NoteRepository: repo, observers: observers}
+}
+
+// Observe registers another observer.
+func (r *ObservedNoteRepository) Observe(observer NoteObserver) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.observers = append(r.observers, observer)
+}
+
+func (r *ObservedNoteRepository) saved(ctx context.Context, note *Note) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, o := range r.observers {
+		o.NoteSaved(ctx, note)
+	}
+}
+
+func (r *ObservedNoteRepository) deleted(ctx context.Context, note *Note) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, o := range r.observers {
+		o.NoteDeleted(ctx, note)
+	}
+}
+
+func (r *ObservedNoteRepository) Create(ctx context.Context, note *Note) error {
+	if err := r.NoteRepository.Create(ctx, note); err != nil {
+		return err
+	}
+	r.saved(ctx, note)
+	return nil
+}
+
+func (r *ObservedNoteRepository) Update(ctx context.Context, note *Note) error {
+	if err := r.NoteRepository.Update(ctx, note); err != nil {
+		return err
+	}
+	r.saved(ctx, note)
+	return nil
+}
+
+func (r *ObservedNoteRepository) Delete(ctx context.Context, id string) error {
+	// Load the note first so observers know whose note went away
+	note, err := r.NoteRepository.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := r.NoteRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	r.deleted(ctx, note)
+	return nil
+}
+
+func (r *ObservedNoteRepository) Publish(ctx context.Context, id string) error {
+	if err := r.NoteRepository.Publish(ctx, id); err != nil {
+		return err
+	}
+	return r.reloaded(ctx, id)
+}
+
+func (r *ObservedNoteRepository) Unpublish(ctx context.Context, id string) error {
+	if err := r.NoteRepository.Unpublish(ctx, id); err != nil {
+		return err
+	}
+	return r.reloaded(ctx, id)
+}
+
+// reloaded notifies observers of a note changed in storage without the
+// caller holding the new version.
+func (r *ObservedNoteRepository) reloaded(ctx context.Context, id string) error {
+	note, err := r.NoteRepository.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	r.saved(ctx, note)
+	return nil
+}
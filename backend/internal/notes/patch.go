@@ -0,0 +1,102 @@
+package notes
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Nullable is a field of a partial update that can be left out, set, or
+// cleared with an explicit null.
+type Nullable[T any] struct {
+	Set   bool // present in the body, possibly as null
+	Value *T
+}
+
+func (n *Nullable[T]) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	if string(data) == "null" {
+		n.Value = nil
+		return nil
+	}
+	n.Value = new(T)
+	return json.Unmarshal(data, n.Value)
+}
+
+// NotePatch is the body of a note update. Fields left out keep their stored
+// value.
+type NotePatch struct {
+	Title        *string                  `json:"title"`
+	Content      *string                  `json:"content"`
+	Tags         *[]Tag                   `json:"tags"`
+	Properties   Nullable[map[string]any] `json:"properties"`
+	IsPublic     *bool                    `json:"is_public"`
+	Encrypted    *bool                    `json:"encrypted"`
+	Envelope     Nullable[Envelope]       `json:"envelope"`
+	PublishAt    Nullable[time.Time]      `json:"publish_at"`
+	ExpiresAt    Nullable[time.Time]      `json:"expires_at"`
+	ExpireAction *ExpireAction            `json:"expire_action"`
+	RemindAt     Nullable[time.Time]      `json:"remind_at"`
+	Version      *int                     `json:"version"`
+}
+
+// Apply merges the patch onto note.
+func (p *NotePatch) Apply(note *Note) {
+	if p.Title != nil {
+		note.Title = *p.Title
+	}
+	if p.Content != nil {
+		note.Content = *p.Content
+	}
+	if p.Tags != nil {
+		note.Tags = *p.Tags
+	}
+	if p.Properties.Set {
+		note.Properties = nil
+		if p.Properties.Value != nil {
+			note.Properties = *p.Properties.Value
+		}
+	}
+	if p.IsPublic != nil {
+		note.IsPublic = *p.IsPublic
+	}
+
+	if p.Encrypted != nil && *p.Encrypted != note.Encrypted {
+		// Switching modes moves the body between content and envelope
+		note.Encrypted = *p.Encrypted
+		if note.Encrypted && p.Content == nil {
+			note.Content = ""
+		}
+		if !note.Encrypted && !p.Envelope.Set {
+			note.Envelope = nil
+		}
+	}
+	if p.Envelope.Set {
+		note.Envelope = p.Envelope.Value
+	}
+
+	if p.PublishAt.Set {
+		note.PublishAt = p.PublishAt.Value
+	}
+	if p.ExpiresAt.Set {
+		note.ExpiresAt = p.ExpiresAt.Value
+		if note.ExpiresAt == nil {
+			note.ExpireAction = ""
+		}
+	}
+	if p.ExpireAction != nil {
+		note.ExpireAction = *p.ExpireAction
+	}
+	if p.RemindAt.Set {
+		note.RemindAt = p.RemindAt.Value
+	}
+
+	if p.Version != nil {
+		note.Version = *p.Version
+	}
+}
+
+// SetsPropertiesOnly reports whether the patch changes the properties without
+// bringing content whose front matter would say otherwise.
+func (p *NotePatch) SetsPropertiesOnly() bool {
+	return p.Properties.Set && p.Content == nil
+}
@@ -0,0 +1,103 @@
+package notes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/middleware"
+)
+
+func TestUpdateNote(t *testing.T) {
+	ctx := context.Background()
+	owner, stranger := uuid.New(), uuid.New()
+	repo := NewInMemoryNoteRepository()
+	props := NewInMemoryPropertyDefinitionRepository()
+	h := NewHandler(repo, NewInMemorySavedSearchRepository(), props)
+	if err := props.Save(ctx, &PropertyDefinition{UserID: owner, Name: "status", Type: PropertyText}); err != nil {
+		t.Fatal(err)
+	}
+
+	publishAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	remindAt := publishAt.Add(24 * time.Hour)
+	note := Note{UserID: owner, Title: "Launch", Content: "Draft", IsPublic: true, PublishAt: &publishAt, RemindAt: &remindAt}
+	if err := repo.Create(ctx, &note); err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(method string, userID uuid.UUID, body string) int {
+		r := httptest.NewRequest(method, "/api/notes/"+note.ID.String(), strings.NewReader(body))
+		r.SetPathValue("id", note.ID.String())
+		r = r.WithContext(context.WithValue(r.Context(), middleware.UserIDKey, userID.String()))
+		w := httptest.NewRecorder()
+		if method == http.MethodDelete {
+			h.DeleteNote(w, r)
+		} else {
+			h.UpdateNote(w, r)
+		}
+		return w.Code
+	}
+
+	if code := send(http.MethodPatch, stranger, `{"title": "Mine now"}`); code != http.StatusForbidden {
+		t.Errorf("stranger PATCH: %d", code)
+	}
+	if code := send(http.MethodDelete, stranger, ``); code != http.StatusForbidden {
+		t.Errorf("stranger DELETE: %d", code)
+	}
+
+	// Fields left out keep their values
+	if code := send(http.MethodPatch, owner, `{"content": "Final"}`); code != http.StatusOK {
+		t.Fatalf("owner PATCH: %d", code)
+	}
+	stored, _ := repo.GetByID(ctx, note.ID.String())
+	if stored.Title != "Launch" || stored.Content != "Final" || !stored.IsPublic || stored.UserID != owner {
+		t.Errorf("after PATCH: %q, %q, public %v by %v", stored.Title, stored.Content, stored.IsPublic, stored.UserID)
+	}
+	if stored.PublishAt == nil || !stored.PublishAt.Equal(publishAt) || stored.RemindAt == nil || !stored.RemindAt.Equal(remindAt) {
+		t.Errorf("PATCH without a schedule changed it: publish_at %v, remind_at %v", stored.PublishAt, stored.RemindAt)
+	}
+
+	// Properties survive a PATCH that leaves them out, and an explicit null
+	// clears them along with their front matter
+	if code := send(http.MethodPatch, owner, `{"properties": {"status": "draft"}}`); code != http.StatusOK {
+		t.Fatalf("setting properties: %d", code)
+	}
+	if code := send(http.MethodPatch, owner, `{"title": "Launch day"}`); code != http.StatusOK {
+		t.Fatalf("renaming: %d", code)
+	}
+	stored, _ = repo.GetByID(ctx, note.ID.String())
+	if stored.Properties["status"] != "draft" || !strings.HasPrefix(stored.Content, "---\n") {
+		t.Errorf("PATCH without properties changed them: %v in %q", stored.Properties, stored.Content)
+	}
+	if code := send(http.MethodPatch, owner, `{"properties": null}`); code != http.StatusOK {
+		t.Fatalf("clearing properties: %d", code)
+	}
+	stored, _ = repo.GetByID(ctx, note.ID.String())
+	if len(stored.Properties) != 0 || stored.Content != "Final" {
+		t.Errorf("after clearing properties: %v in %q", stored.Properties, stored.Content)
+	}
+
+	if code := send(http.MethodPatch, owner, `{"publish_at": null}`); code != http.StatusOK {
+		t.Fatalf("clearing publish_at: %d", code)
+	}
+	stored, _ = repo.GetByID(ctx, note.ID.String())
+	if stored.PublishAt != nil {
+		t.Errorf("after clearing publish_at: %v", stored.PublishAt)
+	}
+
+	if code := send(http.MethodPatch, owner, `{"remind_at": null}`); code != http.StatusOK {
+		t.Fatalf("clearing remind_at: %d", code)
+	}
+	stored, _ = repo.GetByID(ctx, note.ID.String())
+	if stored.RemindAt != nil {
+		t.Errorf("after clearing remind_at: %v", stored.RemindAt)
+	}
+
+	if code := send(http.MethodDelete, owner, ``); code != http.StatusNoContent {
+		t.Errorf("owner DELETE: %d", code)
+	}
+}
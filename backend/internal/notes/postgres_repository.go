@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -22,7 +23,23 @@ SELECT
     n.updated_at,
     n.user_id,
     n.is_public,
-    COALESCE(jsonb_agg(jsonb_build_object('id', t.id, 'name', t.name)) FILTER (WHERE t.id IS NOT NULL), '[]') AS tags
+    COALESCE(jsonb_agg(jsonb_build_object('id', t.id, 'name', t.name)) FILTER (WHERE t.id IS NOT NULL), '[]') AS tags,
+    n.forked_from_note_id,
+    n.forked_from_user_id,
+    (SELECT COUNT(*) FROM active_notes f WHERE f.forked_from_note_id = n.id) AS fork_count,
+    n.envelope,
+    n.properties,
+    n.published_title,
+    n.published_content,
+    n.published_at,
+    n.published_tags,
+    (SELECT s.slug FROM note_slugs s WHERE s.note_id = n.id ORDER BY s.assigned_at DESC LIMIT 1) AS slug,
+    n.publish_at,
+    n.expires_at,
+    COALESCE(n.expire_action, ''),
+    COALESCE(to_char(n.daily_date, 'YYYY-MM-DD'), ''),
+    n.remind_at,
+    n.version
 FROM
     active_notes n
 LEFT JOIN
@@ -31,7 +48,62 @@ LEFT JOIN
     tags t ON nt.tag_id = t.id
 `
 
-const groupByClause = " GROUP BY n.id, n.title, n.content, n.created_at, n.updated_at, n.user_id, n.is_public"
+const groupByClause = " GROUP BY n.id, n.title, n.content, n.created_at, n.updated_at, n.user_id, n.is_public, n.forked_from_note_id, n.forked_from_user_id, n.envelope, n.properties, n.published_title, n.published_content, n.published_at, n.published_tags, n.publish_at, n.expires_at, n.expire_action, n.daily_date, n.remind_at, n.version"
+
+// rowScanner is satisfied by both pgx.Row and pgx.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanNote reads a row produced by selectNoteWithTagsQuery into a Note.
+func scanNote(row rowScanner) (Note, error) {
+	var note Note
+	var tagsJSON, publishedTagsJSON []byte
+	var publishedTitle, publishedContent, slug *string
+	var publishedAt *time.Time
+	err := row.Scan(&note.ID, &note.Title, &note.Content, &note.CreatedAt, &note.UpdatedAt, &note.UserID, &note.IsPublic, &tagsJSON,
+		&note.ForkedFromID, &note.ForkedFromUserID, &note.ForkCount, &note.Envelope, &note.Properties,
+		&publishedTitle, &publishedContent, &publishedAt, &publishedTagsJSON, &slug, &note.PublishAt, &note.ExpiresAt, &note.ExpireAction, &note.DailyDate, &note.RemindAt, &note.Version)
+	if err != nil {
+		return note, err
+	}
+	note.Encrypted = note.Envelope != nil
+	if publishedAt != nil {
+		note.Published = &Snapshot{Title: *publishedTitle, Content: *publishedContent, PublishedAt: *publishedAt}
+		if slug != nil {
+			note.Published.Slug = *slug
+		}
+		if publishedTagsJSON != nil {
+			if err := json.Unmarshal(publishedTagsJSON, &note.Published.Tags); err != nil {
+				return note, fmt.Errorf("failed to unmarshal published tags for note %s: %w", note.ID, err)
+			}
+		}
+	}
+	if err := json.Unmarshal(tagsJSON, &note.Tags); err != nil {
+		return note, fmt.Errorf("failed to unmarshal tags for note %s: %w", note.ID, err)
+	}
+	return note, nil
+}
+
+// collectNotes drains rows produced by selectNoteWithTagsQuery.
+func collectNotes(rows pgx.Rows) ([]Note, error) {
+	defer rows.Close()
+
+	var notes []Note
+	for rows.Next() {
+		note, err := scanNote(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan note row: %w", err)
+		}
+		notes = append(notes, note)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return notes, nil
+}
 
 // PgNoteRepository implements the Repository interface for PostgreSQL.
 type PgNoteRepository struct {
@@ -53,16 +125,21 @@ func (r *PgNoteRepository) Create(ctx context.Context, note *Note) error {
 
 	// Insert the note
 	noteQuery := `
-        INSERT INTO notes (user_id, title, content, is_public)
-        VALUES ($1, $2, $3, $4)
-        RETURNING id, created_at, updated_at`
-	err = tx.QueryRow(ctx, noteQuery, note.UserID, note.Title, note.Content, note.IsPublic).
-		Scan(&note.ID, &note.CreatedAt, &note.UpdatedAt)
+        INSERT INTO notes (user_id, title, content, is_public, forked_from_note_id, forked_from_user_id, envelope, properties,
+                           publish_at, expires_at, expire_action, daily_date, remind_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NULLIF($12, '')::date, $13)
+        RETURNING id, created_at, updated_at, version`
+	err = tx.QueryRow(ctx, noteQuery, note.UserID, note.Title, note.Content, note.IsPublic, note.ForkedFromID, note.ForkedFromUserID, note.envelopeOrNil(), note.propertiesOrEmpty(),
+		note.PublishAt, note.ExpiresAt, note.expireActionOrNil(), note.DailyDate, note.RemindAt).
+		Scan(&note.ID, &note.CreatedAt, &note.UpdatedAt, &note.Version)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign key violation
 			return fmt.Errorf("user not found: %w", err)
 		}
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "notes_user_id_daily_date_idx" {
+			return ErrDailyNoteExists
+		}
 		return fmt.Errorf("failed to insert note: %w", err)
 	}
 
@@ -104,26 +181,8 @@ func (r *PgNoteRepository) GetAll(ctx context.Context) ([]Note, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to query notes: %w", err)
 	}
-	defer rows.Close()
-
-	var notes []Note
-	for rows.Next() {
-		var note Note
-		var tagsJSON []byte
-		if err := rows.Scan(&note.ID, &note.Title, &note.Content, &note.CreatedAt, &note.UpdatedAt, &note.UserID, &note.IsPublic, &tagsJSON); err != nil {
-			return nil, fmt.Errorf("failed to scan note row: %w", err)
-		}
-		if err := json.Unmarshal(tagsJSON, &note.Tags); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal tags for note %s: %w", note.ID, err)
-		}
-		notes = append(notes, note)
-	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("row iteration error: %w", err)
-	}
-
-	return notes, nil
+	return collectNotes(rows)
 }
 
 // GetByUserID retrieves all notes for a specific user.
@@ -133,26 +192,90 @@ func (r *PgNoteRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([
 	if err != nil {
 		return nil, fmt.Errorf("failed to query notes by user ID: %w", err)
 	}
-	defer rows.Close()
 
-	var notes []Note
-	for rows.Next() {
-		var note Note
-		var tagsJSON []byte
-		if err := rows.Scan(&note.ID, &note.Title, &note.Content, &note.CreatedAt, &note.UpdatedAt, &note.UserID, &note.IsPublic, &tagsJSON); err != nil {
-			return nil, fmt.Errorf("failed to scan note row: %w", err)
+	return collectNotes(rows)
+}
+
+// Find retrieves the user's notes that pass the filter, most recently updated
+// first unless the filter sorts by a property.
+func (r *PgNoteRepository) Find(ctx context.Context, userID uuid.UUID, filter *Filter) ([]Note, error) {
+	args := []any{userID}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	where := " WHERE n.user_id = $1"
+	if len(filter.Tags) > 0 {
+		lowerTags := make([]string, len(filter.Tags))
+		for i, t := range filter.Tags {
+			lowerTags[i] = strings.ToLower(t)
 		}
-		if err := json.Unmarshal(tagsJSON, &note.Tags); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal tags for note %s: %w", note.ID, err)
+		where += `
+        AND (
+            SELECT COUNT(DISTINCT t_sub.name)
+            FROM note_tags nt_sub
+            JOIN tags t_sub ON nt_sub.tag_id = t_sub.id
+            WHERE nt_sub.note_id = n.id AND t_sub.name = ANY(` + arg(lowerTags) + `)
+        ) = ` + arg(len(uniqueStrings(lowerTags)))
+	}
+	if filter.Text != "" {
+		pattern := "%" + likeEscaper.Replace(filter.Text) + "%"
+		p := arg(pattern)
+		where += " AND (n.title ILIKE " + p + " OR n.content ILIKE " + p + ")"
+	}
+	if filter.CreatedFrom != nil {
+		where += " AND n.created_at >= " + arg(*filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		where += " AND n.created_at <= " + arg(*filter.CreatedTo)
+	}
+	if filter.UpdatedFrom != nil {
+		where += " AND n.updated_at >= " + arg(*filter.UpdatedFrom)
+	}
+	if filter.UpdatedTo != nil {
+		where += " AND n.updated_at <= " + arg(*filter.UpdatedTo)
+	}
+
+	for name, value := range filter.Properties {
+		where += " AND n.properties->>" + arg(name) + " = " + arg(value)
+	}
+
+	orderBy := " ORDER BY n.updated_at DESC"
+	if name, desc := filter.SortProperty(); name != "" {
+		dir := "ASC"
+		if desc {
+			dir = "DESC"
 		}
-		notes = append(notes, note)
+		// Numbers sort numerically, everything else as text; notes without
+		// the property go last either way.
+		p := arg(name)
+		orderBy = " ORDER BY CASE WHEN jsonb_typeof(n.properties->" + p + ") = 'number' THEN (n.properties->>" + p + ")::numeric END " + dir + " NULLS LAST, " +
+			"n.properties->>" + p + " " + dir + " NULLS LAST, n.updated_at DESC"
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("row iteration error: %w", err)
+	query := selectNoteWithTagsQuery + where + groupByClause + orderBy
+	rows, err := r.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query filtered notes: %w", err)
 	}
 
-	return notes, nil
+	return collectNotes(rows)
+}
+
+// likeEscaper escapes the LIKE wildcards in user supplied text.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var result []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
 }
 
 // GetByID retrieves a single note by its ID.
@@ -165,20 +288,27 @@ func (r *PgNoteRepository) GetByID(ctx context.Context, id string) (*Note, error
 	query := selectNoteWithTagsQuery + " WHERE n.id = $1" + groupByClause
 	row := r.DB.QueryRow(ctx, query, noteID)
 
-	var note Note
-	var tagsJSON []byte
-	err = row.Scan(&note.ID, &note.Title, &note.Content, &note.CreatedAt, &note.UpdatedAt, &note.UserID, &note.IsPublic, &tagsJSON)
+	note, err := scanNote(row)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, fmt.Errorf("note not found")
+			return nil, ErrNoteNotFound
 		}
 		return nil, fmt.Errorf("failed to scan note: %w", err)
 	}
 
-	if err := json.Unmarshal(tagsJSON, &note.Tags); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal tags for note %s: %w", note.ID, err)
-	}
+	return &note, nil
+}
 
+// GetDaily retrieves the user's daily note for the given date.
+func (r *PgNoteRepository) GetDaily(ctx context.Context, userID uuid.UUID, date string) (*Note, error) {
+	query := selectNoteWithTagsQuery + " WHERE n.user_id = $1 AND n.daily_date = $2::date" + groupByClause
+	note, err := scanNote(r.DB.QueryRow(ctx, query, userID, date))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNoDailyNote
+		}
+		return nil, fmt.Errorf("failed to scan daily note: %w", err)
+	}
 	return &note, nil
 }
 
@@ -202,26 +332,8 @@ func (r *PgNoteRepository) GetByTags(ctx context.Context, tags []string) ([]Note
 	if err != nil {
 		return nil, fmt.Errorf("failed to query notes by tags: %w", err)
 	}
-	defer rows.Close()
-
-	var notes []Note
-	for rows.Next() {
-		var note Note
-		var tagsJSON []byte
-		if err := rows.Scan(&note.ID, &note.Title, &note.Content, &note.CreatedAt, &note.UpdatedAt, &note.UserID, &note.IsPublic, &tagsJSON); err != nil {
-			return nil, fmt.Errorf("failed to scan note row: %w", err)
-		}
-		if err := json.Unmarshal(tagsJSON, &note.Tags); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal tags for note %s: %w", note.ID, err)
-		}
-		notes = append(notes, note)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("row iteration error: %w", err)
-	}
 
-	return notes, nil
+	return collectNotes(rows)
 }
 
 // Update handles the modification of a note's details and its tags.
@@ -234,12 +346,19 @@ func (r *PgNoteRepository) Update(ctx context.Context, note *Note) error {
 
 	updateQuery := `
         UPDATE notes
-        SET title = $1, content = $2, is_public = $3, updated_at = now()
-        WHERE id = $4 AND deleted_at IS NULL
-        RETURNING updated_at`
-	err = tx.QueryRow(ctx, updateQuery, note.Title, note.Content, note.IsPublic, note.ID).Scan(&note.UpdatedAt)
+        SET title = $1, content = $2, is_public = $3, envelope = $4, properties = $5,
+            publish_at = $6, expires_at = $7, expire_action = $8, remind_at = $9,
+            version = version + 1, updated_at = now()
+        WHERE id = $10 AND deleted_at IS NULL AND ($11 = 0 OR version = $11)
+        RETURNING updated_at, version`
+	err = tx.QueryRow(ctx, updateQuery, note.Title, note.Content, note.IsPublic, note.envelopeOrNil(), note.propertiesOrEmpty(),
+		note.PublishAt, note.ExpiresAt, note.expireActionOrNil(), note.RemindAt, note.ID, note.Version).Scan(&note.UpdatedAt, &note.Version)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
+			var exists bool
+			if note.Version != 0 && tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM active_notes WHERE id = $1)", note.ID).Scan(&exists) == nil && exists {
+				return ErrVersionConflict
+			}
 			return fmt.Errorf("note not found or already deleted")
 		}
 		return fmt.Errorf("failed to update note: %w", err)
@@ -280,6 +399,289 @@ func (r *PgNoteRepository) Update(ctx context.Context, note *Note) error {
 	return tx.Commit(ctx)
 }
 
+// Publish copies the draft title, content and tags into the published
+// snapshot. Fields encrypted at rest are copied sealed.
+func (r *PgNoteRepository) Publish(ctx context.Context, id string) error {
+	query := `
+        UPDATE notes
+        SET published_title = title, published_content = content, published_at = now(), is_public = true,
+            published_tags = (
+                SELECT COALESCE(jsonb_agg(jsonb_build_object('id', t.id, 'name', t.name) ORDER BY t.name), '[]')
+                FROM note_tags nt JOIN tags t ON nt.tag_id = t.id WHERE nt.note_id = notes.id)
+        WHERE id = $1 AND deleted_at IS NULL`
+	result, err := r.DB.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to publish note: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("note not found or already deleted")
+	}
+	return nil
+}
+
+// Unpublish drops the published snapshot and makes the note private.
+func (r *PgNoteRepository) Unpublish(ctx context.Context, id string) error {
+	query := `
+        UPDATE notes
+        SET published_title = NULL, published_content = NULL, published_at = NULL, published_tags = NULL, is_public = false
+        WHERE id = $1 AND deleted_at IS NULL`
+	result, err := r.DB.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to unpublish note: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("note not found or already deleted")
+	}
+	return nil
+}
+
+// GetPublished retrieves a page of published notes, most recently published
+// first, along with the total count.
+func (r *PgNoteRepository) GetPublished(ctx context.Context, limit, offset int) ([]Note, int, error) {
+	where := " WHERE n.is_public AND n.published_at IS NOT NULL"
+
+	var total int
+	if err := r.DB.QueryRow(ctx, "SELECT COUNT(*) FROM active_notes n"+where).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count published notes: %w", err)
+	}
+
+	query := selectNoteWithTagsQuery + where + groupByClause + " ORDER BY n.published_at DESC LIMIT $1 OFFSET $2"
+	rows, err := r.DB.Query(ctx, query, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query published notes: %w", err)
+	}
+	notes, err := collectNotes(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return notes, total, nil
+}
+
+// ClaimDue leases the notes with a publish or expiry time at or before now,
+// or a reminder due and not yet delivered. SKIP LOCKED keeps concurrent
+// schedulers from claiming the same notes.
+func (r *PgNoteRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]ScheduledAction, error) {
+	query := `
+        WITH due AS (
+            SELECT id FROM notes
+            WHERE deleted_at IS NULL
+              AND (publish_at <= $1 OR expires_at <= $1
+                   OR (remind_at <= $1 AND remind_at IS DISTINCT FROM reminded_at))
+              AND (schedule_lease_until IS NULL OR schedule_lease_until < $1)
+            ORDER BY LEAST(publish_at, expires_at, remind_at)
+            LIMIT $3
+            FOR UPDATE SKIP LOCKED
+        )
+        UPDATE notes n
+        SET schedule_lease_until = $2
+        FROM due
+        WHERE n.id = due.id
+        RETURNING n.id, n.publish_at, n.expires_at, COALESCE(n.expire_action, ''), n.remind_at, n.reminded_at`
+	rows, err := r.DB.Query(ctx, query, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim scheduled notes: %w", err)
+	}
+	defer rows.Close()
+
+	var actions []ScheduledAction
+	for rows.Next() {
+		var id uuid.UUID
+		var publishAt, expiresAt, remindAt, remindedAt *time.Time
+		var action ExpireAction
+		if err := rows.Scan(&id, &publishAt, &expiresAt, &action, &remindAt, &remindedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled note: %w", err)
+		}
+		if publishAt != nil && !publishAt.After(now) {
+			actions = append(actions, ScheduledAction{NoteID: id, Kind: SchedulePublish, At: *publishAt})
+		}
+		if expiresAt != nil && !expiresAt.After(now) {
+			actions = append(actions, ScheduledAction{NoteID: id, Kind: ScheduleExpire, Expire: action, At: *expiresAt})
+		}
+		if reminderDue(remindAt, remindedAt, now) {
+			actions = append(actions, ScheduledAction{NoteID: id, Kind: ScheduleRemind, At: *remindAt})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return actions, nil
+}
+
+// Complete clears a carried out action from the note's schedule, or marks
+// a reminder delivered, unless it was rescheduled since the claim. It also
+// applies to notes the action just soft-deleted.
+func (r *PgNoteRepository) Complete(ctx context.Context, action ScheduledAction) error {
+	var query string
+	switch action.Kind {
+	case SchedulePublish:
+		query = "UPDATE notes SET publish_at = NULL WHERE id = $1 AND publish_at = $2"
+	case ScheduleRemind:
+		query = "UPDATE notes SET reminded_at = remind_at WHERE id = $1 AND remind_at = $2"
+	default:
+		query = "UPDATE notes SET expires_at = NULL, expire_action = NULL WHERE id = $1 AND expires_at = $2"
+	}
+	if _, err := r.DB.Exec(ctx, query, action.NoteID, action.At); err != nil {
+		return fmt.Errorf("failed to complete scheduled action: %w", err)
+	}
+	return nil
+}
+
+// AssignSlug gives the note its current slug, reusing one it held before
+// when possible. Candidates are claimed with ON CONFLICT so two notes racing
+// for the same slug cannot both get it.
+func (r *PgNoteRepository) AssignSlug(ctx context.Context, userID, noteID uuid.UUID, base string) (string, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var current string
+	err = tx.QueryRow(ctx, "SELECT slug FROM note_slugs WHERE note_id = $1 ORDER BY assigned_at DESC LIMIT 1", noteID).Scan(&current)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("failed to look up current slug: %w", err)
+	}
+	if current != "" && derivesFrom(current, base) {
+		return current, nil
+	}
+
+	for n := 1; ; n++ {
+		slug := slugFor(base, n)
+		result, err := tx.Exec(ctx, `
+            INSERT INTO note_slugs (user_id, slug, note_id) VALUES ($1, $2, $3)
+            ON CONFLICT (user_id, slug) DO UPDATE SET assigned_at = now()
+            WHERE note_slugs.note_id = EXCLUDED.note_id`, userID, slug, noteID)
+		if err != nil {
+			return "", fmt.Errorf("failed to assign slug: %w", err)
+		}
+		if result.RowsAffected() == 1 { // new, or an old slug of this note made current again
+			return slug, tx.Commit(ctx)
+		}
+	}
+}
+
+// ResolveSlug finds the note behind one of a user's slugs.
+func (r *PgNoteRepository) ResolveSlug(ctx context.Context, userID uuid.UUID, slug string) (uuid.UUID, string, error) {
+	query := `
+        SELECT s.note_id, (SELECT c.slug FROM note_slugs c WHERE c.note_id = s.note_id ORDER BY c.assigned_at DESC LIMIT 1)
+        FROM note_slugs s
+        WHERE s.user_id = $1 AND s.slug = $2`
+	var noteID uuid.UUID
+	var current string
+	if err := r.DB.QueryRow(ctx, query, userID, slug).Scan(&noteID, &current); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, "", fmt.Errorf("slug not found")
+		}
+		return uuid.Nil, "", fmt.Errorf("failed to resolve slug: %w", err)
+	}
+	return noteID, current, nil
+}
+
+// Stats aggregates a user's writing statistics over active_notes and note_tags.
+func (r *PgNoteRepository) Stats(ctx context.Context, userID uuid.UUID) (*Stats, error) {
+	stats := &Stats{}
+
+	totalsQuery := `
+        SELECT
+            COUNT(*),
+            COUNT(*) FILTER (WHERE is_public),
+            COUNT(*) FILTER (WHERE envelope IS NOT NULL),
+            COALESCE(SUM(array_length(regexp_split_to_array(btrim(content), '\s+'), 1)) FILTER (WHERE btrim(content) <> ''), 0)
+        FROM active_notes
+        WHERE user_id = $1`
+	err := r.DB.QueryRow(ctx, totalsQuery, userID).
+		Scan(&stats.TotalNotes, &stats.PublicNotes, &stats.EncryptedNotes, &stats.Words)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate note totals: %w", err)
+	}
+
+	tagsQuery := `
+        SELECT t.name, COUNT(*)
+        FROM active_notes n
+        JOIN note_tags nt ON n.id = nt.note_id
+        JOIN tags t ON nt.tag_id = t.id
+        WHERE n.user_id = $1
+        GROUP BY t.name`
+	rows, err := r.DB.Query(ctx, tagsQuery, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate tags: %w", err)
+	}
+	stats.Tags, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (TagCount, error) {
+		var tc TagCount
+		err := row.Scan(&tc.Name, &tc.Count)
+		return tc, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan tag counts: %w", err)
+	}
+	sortTagCounts(stats.Tags)
+
+	activityQuery := `
+        SELECT to_char(day, 'YYYY-MM-DD'), SUM(created)::int, SUM(updated)::int
+        FROM (
+            SELECT (created_at AT TIME ZONE 'UTC')::date AS day, 1 AS created, 0 AS updated
+            FROM active_notes WHERE user_id = $1
+            UNION ALL
+            SELECT (updated_at AT TIME ZONE 'UTC')::date, 0, 1
+            FROM active_notes WHERE user_id = $1 AND updated_at > created_at
+        ) activity
+        GROUP BY day
+        ORDER BY day`
+	rows, err = r.DB.Query(ctx, activityQuery, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate activity: %w", err)
+	}
+	stats.Activity, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (DayActivity, error) {
+		var d DayActivity
+		err := row.Scan(&d.Date, &d.Created, &d.Updated)
+		return d, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan activity: %w", err)
+	}
+
+	stats.finish()
+	return stats, nil
+}
+
+// SearchTitles finds the user's notes whose titles fuzzily match query using
+// pg_trgm similarity. Match positions are worked out in Go.
+func (r *PgNoteRepository) SearchTitles(ctx context.Context, userID uuid.UUID, query string, limit int) ([]TitleMatch, error) {
+	sqlQuery := `
+        SELECT id, title, GREATEST(similarity(title, $2), word_similarity($2, title)) AS score
+        FROM active_notes
+        WHERE user_id = $1 AND (title % $2 OR $2 <% title)
+        ORDER BY score DESC, title
+        LIMIT $3`
+	rows, err := r.DB.Query(ctx, sqlQuery, userID, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search note titles: %w", err)
+	}
+	matches, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (TitleMatch, error) {
+		var m TitleMatch
+		err := row.Scan(&m.ID, &m.Title, &m.Score)
+		return m, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan title match: %w", err)
+	}
+
+	for i := range matches {
+		matches[i].Positions = matchPositions(matches[i].Title, query)
+	}
+	return matches, nil
+}
+
+// IsSharedWith reports whether the note has been shared with the given user.
+func (r *PgNoteRepository) IsSharedWith(ctx context.Context, noteID, userID uuid.UUID) (bool, error) {
+	var shared bool
+	query := `SELECT EXISTS (SELECT 1 FROM shared_notes WHERE note_id = $1 AND shared_with_user_id = $2)`
+	if err := r.DB.QueryRow(ctx, query, noteID, userID).Scan(&shared); err != nil {
+		return false, fmt.Errorf("failed to check note share: %w", err)
+	}
+	return shared, nil
+}
+
 // Delete performs a soft delete on a note.
 func (r *PgNoteRepository) Delete(ctx context.Context, id string) error {
 	noteID, err := uuid.Parse(id)
@@ -303,3 +705,51 @@ func (r *PgNoteRepository) Delete(ctx context.Context, id string) error {
 
 	return nil
 }
+
+// GetLock retrieves the live lock on the note.
+func (r *PgNoteRepository) GetLock(ctx context.Context, noteID uuid.UUID, now time.Time) (*NoteLock, error) {
+	lock := NoteLock{NoteID: noteID}
+	err := r.DB.QueryRow(ctx, `
+        SELECT user_id, token, owner, version, expires_at
+        FROM note_locks WHERE note_id = $1 AND expires_at > $2`, noteID, now).
+		Scan(&lock.UserID, &lock.Token, &lock.Owner, &lock.Version, &lock.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotLocked
+		}
+		return nil, fmt.Errorf("failed to get note lock: %w", err)
+	}
+	return &lock, nil
+}
+
+// PutLock takes, renews or updates the lock in one statement, so two
+// clients racing for a note cannot both win.
+func (r *PgNoteRepository) PutLock(ctx context.Context, lock *NoteLock, now time.Time) error {
+	query := `
+        INSERT INTO note_locks (note_id, user_id, token, owner, version, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        ON CONFLICT (note_id) DO UPDATE
+        SET user_id = EXCLUDED.user_id, token = EXCLUDED.token, owner = EXCLUDED.owner,
+            version = EXCLUDED.version, expires_at = EXCLUDED.expires_at
+        WHERE note_locks.token = EXCLUDED.token OR note_locks.expires_at <= $7`
+	result, err := r.DB.Exec(ctx, query, lock.NoteID, lock.UserID, lock.Token, lock.Owner, lock.Version, lock.ExpiresAt, now)
+	if err != nil {
+		return fmt.Errorf("failed to put note lock: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrLocked
+	}
+	return nil
+}
+
+// DeleteLock releases the lock held by token.
+func (r *PgNoteRepository) DeleteLock(ctx context.Context, noteID uuid.UUID, token string) error {
+	result, err := r.DB.Exec(ctx, "DELETE FROM note_locks WHERE note_id = $1 AND token = $2", noteID, token)
+	if err != nil {
+		return fmt.Errorf("failed to delete note lock: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotLocked
+	}
+	return nil
+}
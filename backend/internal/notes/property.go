@@ -0,0 +1,208 @@
+package notes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PropertyType is the type of a custom note property.
+type PropertyType string
+
+const (
+	PropertyText   PropertyType = "text"
+	PropertySelect PropertyType = "select" // one of Options, e.g. status or priority
+	PropertyNumber PropertyType = "number"
+	PropertyDate   PropertyType = "date" // stored as YYYY-MM-DD
+	PropertyURL    PropertyType = "url"
+)
+
+var propertyTypes = map[PropertyType]bool{
+	PropertyText: true, PropertySelect: true, PropertyNumber: true, PropertyDate: true, PropertyURL: true,
+}
+
+// propertyNamePattern keeps names usable as query parameters and YAML keys.
+var propertyNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// PropertyDefinition declares a property a user can set on their notes.
+type PropertyDefinition struct {
+	UserID    uuid.UUID    `json:"user_id"`
+	Name      string       `json:"name"`
+	Type      PropertyType `json:"type"`
+	Options   []string     `json:"options,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// Interface
+type PropertyDefinitionRepository interface {
+	GetByUserID(ctx context.Context, userID uuid.UUID) ([]PropertyDefinition, error)
+	// Save creates the definition or replaces the one with the same name.
+	Save(ctx context.Context, def *PropertyDefinition) error
+	Delete(ctx context.Context, userID uuid.UUID, name string) error
+}
+
+// Validate checks that a definition is well formed.
+func (d *PropertyDefinition) Validate() error {
+	if !propertyNamePattern.MatchString(d.Name) {
+		return errors.New("property names must be lowercase letters, digits and underscores, starting with a letter")
+	}
+	if !propertyTypes[d.Type] {
+		return fmt.Errorf("unknown property type %q", d.Type)
+	}
+	if d.Type == PropertySelect && len(d.Options) == 0 {
+		return errors.New("select properties need at least one option")
+	}
+	if d.Type != PropertySelect && len(d.Options) > 0 {
+		return errors.New("only select properties take options")
+	}
+	return nil
+}
+
+// ValidateProperties checks props against the user's definitions and returns
+// them normalized: numbers as float64, dates as YYYY-MM-DD strings.
+func ValidateProperties(defs []PropertyDefinition, props map[string]any) (map[string]any, error) {
+	byName := make(map[string]PropertyDefinition, len(defs))
+	for _, d := range defs {
+		byName[d.Name] = d
+	}
+
+	normalized := make(map[string]any, len(props))
+	for name, value := range props {
+		def, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown property %q", name)
+		}
+		if value == nil {
+			continue // clearing a property
+		}
+		v, err := normalizeProperty(def, value)
+		if err != nil {
+			return nil, fmt.Errorf("property %q: %w", name, err)
+		}
+		normalized[name] = v
+	}
+	return normalized, nil
+}
+
+func normalizeProperty(def PropertyDefinition, value any) (any, error) {
+	switch def.Type {
+	case PropertyNumber:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int:
+			return float64(v), nil
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, errors.New("must be a number")
+			}
+			return f, nil
+		}
+		return nil, errors.New("must be a number")
+
+	case PropertyDate:
+		switch v := value.(type) {
+		case time.Time:
+			return v.Format("2006-01-02"), nil
+		case string:
+			t, err := time.Parse("2006-01-02", strings.TrimSpace(v))
+			if err != nil {
+				return nil, errors.New("must be a date in YYYY-MM-DD format")
+			}
+			return t.Format("2006-01-02"), nil
+		}
+		return nil, errors.New("must be a date in YYYY-MM-DD format")
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return nil, errors.New("must be a string")
+	}
+	s = strings.TrimSpace(s)
+	switch def.Type {
+	case PropertySelect:
+		for _, option := range def.Options {
+			if s == option {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("must be one of %s", strings.Join(def.Options, ", "))
+	case PropertyURL:
+		u, err := url.Parse(s)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, errors.New("must be an absolute http or https URL")
+		}
+	}
+	return s, nil
+}
+
+// propertyString is the form property values are compared in when filtering.
+func propertyString(value any) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// comparePropertyValues orders two property values: numbers numerically,
+// everything else as text, missing values last.
+func comparePropertyValues(a, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	fa, aNum := a.(float64)
+	fb, bNum := b.(float64)
+	if aNum && bNum {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(propertyString(a), propertyString(b))
+}
+
+// sortByProperty sorts notes by a property. Notes without it go last
+// whatever the direction.
+func sortByProperty(notes []Note, name string, descending bool) {
+	sort.SliceStable(notes, func(i, j int) bool {
+		a, b := notes[i].Properties[name], notes[j].Properties[name]
+		if a == nil || b == nil {
+			return a != nil
+		}
+		c := comparePropertyValues(a, b)
+		if descending {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+// propertiesOrEmpty is what gets stored in the NOT NULL properties column.
+func (n *Note) propertiesOrEmpty() map[string]any {
+	if n.Properties == nil {
+		return map[string]any{}
+	}
+	return n.Properties
+}
@@ -0,0 +1,97 @@
+package notes
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/middleware"
+	"github.com/jehufrayle/grimoire/utils"
+)
+
+type PropertyHandler struct {
+	repo PropertyDefinitionRepository
+}
+
+func NewPropertyHandler(repo PropertyDefinitionRepository) *PropertyHandler {
+	return &PropertyHandler{repo: repo}
+}
+
+func (h *PropertyHandler) GetProperties(w http.ResponseWriter, r *http.Request) {
+	userIDstr, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	userID, err := uuid.Parse(userIDstr)
+	if err != nil {
+		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	defs, err := h.repo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve properties", http.StatusInternalServerError)
+		return
+	}
+	if defs == nil {
+		defs = []PropertyDefinition{}
+	}
+
+	utils.JSONResponse(w, defs, http.StatusOK)
+}
+
+// SaveProperty creates or redefines the property named in the path.
+func (h *PropertyHandler) SaveProperty(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Type    PropertyType `json:"type"`
+		Options []string     `json:"options"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userIDstr, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	userID, err := uuid.Parse(userIDstr)
+	if err != nil {
+		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	def := PropertyDefinition{UserID: userID, Name: r.PathValue("name"), Type: req.Type, Options: req.Options}
+	if err := def.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.repo.Save(r.Context(), &def); err != nil {
+		http.Error(w, "Failed to save property", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, def, http.StatusOK)
+}
+
+func (h *PropertyHandler) DeleteProperty(w http.ResponseWriter, r *http.Request) {
+	userIDstr, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	userID, err := uuid.Parse(userIDstr)
+	if err != nil {
+		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.Delete(r.Context(), userID, r.PathValue("name")); err != nil {
+		http.Error(w, "Property not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
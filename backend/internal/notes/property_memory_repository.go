@@ -0,0 +1,151 @@
+package notes
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/journal"
+)
+
+// InMemoryPropertyDefinitionRepository keeps property definitions in
+// memory. Opened with OpenInMemoryPropertyDefinitionRepository it also
+// persists them to disk.
+type InMemoryPropertyDefinitionRepository struct {
+	mu      sync.RWMutex
+	defs    map[uuid.UUID]map[string]PropertyDefinition         // user ID -> name -> definition
+	journal *journal.Store[[]PropertyDefinition, propertyEntry] // nil when not persisted
+}
+
+func NewInMemoryPropertyDefinitionRepository() *InMemoryPropertyDefinitionRepository {
+	return &InMemoryPropertyDefinitionRepository{
+		defs: make(map[uuid.UUID]map[string]PropertyDefinition),
+	}
+}
+
+func (r *InMemoryPropertyDefinitionRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]PropertyDefinition, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []PropertyDefinition
+	for _, d := range r.defs[userID] {
+		result = append(result, d)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+func (r *InMemoryPropertyDefinitionRepository) Save(ctx context.Context, def *PropertyDefinition) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, existed := r.defs[def.UserID][def.Name]
+	if existed {
+		def.CreatedAt = existing.CreatedAt
+	} else {
+		def.CreatedAt = time.Now()
+	}
+	stored := *def
+	stored.Options = slices.Clone(def.Options)
+	r.put(stored)
+	if err := r.journal.Log(propertyEntry{Definition: &stored}); err != nil {
+		if existed {
+			r.put(existing)
+		} else {
+			delete(r.defs[def.UserID], def.Name)
+		}
+		return err
+	}
+	return nil
+}
+
+// put stores a definition, replacing the one with the same name.
+// Callers must hold the write lock.
+func (r *InMemoryPropertyDefinitionRepository) put(def PropertyDefinition) {
+	byName, ok := r.defs[def.UserID]
+	if !ok {
+		byName = make(map[string]PropertyDefinition)
+		r.defs[def.UserID] = byName
+	}
+	byName[def.Name] = def
+}
+
+func (r *InMemoryPropertyDefinitionRepository) Delete(ctx context.Context, userID uuid.UUID, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.defs[userID][name]
+	if !ok {
+		return errors.New("property not found")
+	}
+	delete(r.defs[userID], name)
+	if err := r.journal.Log(propertyEntry{Deleted: &propertyKey{UserID: userID, Name: name}}); err != nil {
+		r.put(existing)
+		return err
+	}
+	return nil
+}
+
+// propertyKey names a definition in the journal's log.
+type propertyKey struct {
+	UserID uuid.UUID `json:"user_id"`
+	Name   string    `json:"name"`
+}
+
+// propertyEntry is one change in the journal's log.
+type propertyEntry struct {
+	Definition *PropertyDefinition `json:"definition,omitempty"`
+	Deleted    *propertyKey        `json:"deleted,omitempty"`
+}
+
+// OpenInMemoryPropertyDefinitionRepository returns an
+// InMemoryPropertyDefinitionRepository persisted in dir. It starts from what
+// dir holds and logs every write there before returning. Snapshot keeps the
+// log short and Close takes a last snapshot.
+func OpenInMemoryPropertyDefinitionRepository(dir string) (*InMemoryPropertyDefinitionRepository, error) {
+	r := NewInMemoryPropertyDefinitionRepository()
+	j, err := journal.OpenStore(dir, &r.mu, r.all,
+		func(defs []PropertyDefinition) {
+			for _, d := range defs {
+				r.put(d)
+			}
+		},
+		func(entry propertyEntry) {
+			if entry.Definition != nil {
+				r.put(*entry.Definition)
+			} else if entry.Deleted != nil {
+				delete(r.defs[entry.Deleted.UserID], entry.Deleted.Name)
+			}
+		})
+	if err != nil {
+		return nil, err
+	}
+	r.journal = j
+	return r, nil
+}
+
+// Callers must hold at least a read lock.
+func (r *InMemoryPropertyDefinitionRepository) all() []PropertyDefinition {
+	var defs []PropertyDefinition
+	for _, byName := range r.defs {
+		for _, d := range byName {
+			defs = append(defs, d)
+		}
+	}
+	return defs
+}
+
+// Snapshot writes every definition to disk and empties the log. It is a
+// no-op when the repository is not persisted.
+func (r *InMemoryPropertyDefinitionRepository) Snapshot() error {
+	return r.journal.Snapshot()
+}
+
+// Close takes a last snapshot and closes the journal.
+func (r *InMemoryPropertyDefinitionRepository) Close() error {
+	return r.journal.Close()
+}
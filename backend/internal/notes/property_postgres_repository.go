@@ -0,0 +1,73 @@
+package notes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgPropertyDefinitionRepository implements the PropertyDefinitionRepository interface for PostgreSQL.
+type PgPropertyDefinitionRepository struct {
+	DB *pgxpool.Pool
+}
+
+// NewPgPropertyDefinitionRepository creates a new instance of PgPropertyDefinitionRepository.
+func NewPgPropertyDefinitionRepository(db *pgxpool.Pool) *PgPropertyDefinitionRepository {
+	return &PgPropertyDefinitionRepository{DB: db}
+}
+
+// GetByUserID retrieves all property definitions of a user, by name.
+func (r *PgPropertyDefinitionRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]PropertyDefinition, error) {
+	query := `
+        SELECT user_id, name, type, options, created_at
+        FROM property_definitions
+        WHERE user_id = $1
+        ORDER BY name`
+	rows, err := r.DB.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query property definitions: %w", err)
+	}
+	defs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (PropertyDefinition, error) {
+		var d PropertyDefinition
+		err := row.Scan(&d.UserID, &d.Name, &d.Type, &d.Options, &d.CreatedAt)
+		return d, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan property definition row: %w", err)
+	}
+	return defs, nil
+}
+
+// Save creates a property definition or replaces its type and options.
+func (r *PgPropertyDefinitionRepository) Save(ctx context.Context, def *PropertyDefinition) error {
+	options := def.Options
+	if options == nil {
+		options = []string{}
+	}
+	query := `
+        INSERT INTO property_definitions (user_id, name, type, options)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (user_id, name) DO UPDATE SET type = EXCLUDED.type, options = EXCLUDED.options
+        RETURNING created_at`
+	err := r.DB.QueryRow(ctx, query, def.UserID, def.Name, def.Type, options).Scan(&def.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save property definition: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a property definition. Values already stored on notes are
+// left alone; the note has to drop them before it validates again.
+func (r *PgPropertyDefinitionRepository) Delete(ctx context.Context, userID uuid.UUID, name string) error {
+	result, err := r.DB.Exec(ctx, "DELETE FROM property_definitions WHERE user_id = $1 AND name = $2", userID, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete property definition: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("property not found")
+	}
+	return nil
+}
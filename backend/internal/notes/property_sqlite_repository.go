@@ -0,0 +1,90 @@
+package notes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/database"
+)
+
+// SQLitePropertyDefinitionRepository implements the
+// PropertyDefinitionRepository interface for the embedded SQLite backend.
+type SQLitePropertyDefinitionRepository struct {
+	DB *sql.DB
+}
+
+// NewSQLitePropertyDefinitionRepository creates a new instance of SQLitePropertyDefinitionRepository.
+func NewSQLitePropertyDefinitionRepository(db *sql.DB) *SQLitePropertyDefinitionRepository {
+	return &SQLitePropertyDefinitionRepository{DB: db}
+}
+
+// GetByUserID retrieves all property definitions of a user, by name.
+func (r *SQLitePropertyDefinitionRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]PropertyDefinition, error) {
+	query := `
+        SELECT user_id, name, type, options, created_at
+        FROM property_definitions
+        WHERE user_id = ?
+        ORDER BY name`
+	rows, err := r.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query property definitions: %w", err)
+	}
+	defer rows.Close()
+
+	var defs []PropertyDefinition
+	for rows.Next() {
+		var d PropertyDefinition
+		var options string
+		if err := rows.Scan(&d.UserID, &d.Name, &d.Type, &options, database.ScanTime{T: &d.CreatedAt}); err != nil {
+			return nil, fmt.Errorf("failed to scan property definition row: %w", err)
+		}
+		if err := json.Unmarshal([]byte(options), &d.Options); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal options of property %s: %w", d.Name, err)
+		}
+		defs = append(defs, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return defs, nil
+}
+
+// Save creates a property definition or replaces its type and options.
+func (r *SQLitePropertyDefinitionRepository) Save(ctx context.Context, def *PropertyDefinition) error {
+	options := def.Options
+	if options == nil {
+		options = []string{}
+	}
+	optionsJSON, err := json.Marshal(options)
+	if err != nil {
+		return fmt.Errorf("failed to encode options: %w", err)
+	}
+	query := `
+        INSERT INTO property_definitions (user_id, name, type, options, created_at)
+        VALUES (?, ?, ?, ?, ?)
+        ON CONFLICT (user_id, name) DO UPDATE SET type = excluded.type, options = excluded.options
+        RETURNING created_at`
+	err = r.DB.QueryRowContext(ctx, query, def.UserID, def.Name, def.Type, string(optionsJSON), database.Timestamp(time.Now())).
+		Scan(database.ScanTime{T: &def.CreatedAt})
+	if err != nil {
+		return fmt.Errorf("failed to save property definition: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a property definition. Values already stored on notes are
+// left alone; the note has to drop them before it validates again.
+func (r *SQLitePropertyDefinitionRepository) Delete(ctx context.Context, userID uuid.UUID, name string) error {
+	result, err := r.DB.ExecContext(ctx, "DELETE FROM property_definitions WHERE user_id = ? AND name = ?", userID, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete property definition: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return fmt.Errorf("property not found")
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package notes
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/middleware"
+	"github.com/jehufrayle/grimoire/utils"
+)
+
+// NotePage is a page of notes with the total across all pages.
+type NotePage struct {
+	Notes   []Note `json:"notes"`
+	Page    int    `json:"page"`
+	PerPage int    `json:"per_page"`
+	Total   int    `json:"total"`
+}
+
+type PublishHandler struct {
+	repo NoteRepository
+}
+
+func NewPublishHandler(repo NoteRepository) *PublishHandler {
+	return &PublishHandler{repo: repo}
+}
+
+// ownedNote loads a note of the current user. It writes the error response
+// itself and returns nil when the request should stop.
+func (h *PublishHandler) ownedNote(w http.ResponseWriter, r *http.Request) *Note {
+	userIDstr, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return nil
+	}
+	userID, err := uuid.Parse(userIDstr)
+	if err != nil {
+		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		return nil
+	}
+
+	note, err := h.repo.GetByID(r.Context(), r.PathValue("id"))
+	if err != nil || note == nil {
+		http.Error(w, "Note not found", http.StatusNotFound)
+		return nil
+	}
+	if note.UserID != userID {
+		http.Error(w, "Unauthorized access to this note", http.StatusForbidden)
+		return nil
+	}
+	return note
+}
+
+// PublishNote makes the current draft the public version of the note.
+func (h *PublishHandler) PublishNote(w http.ResponseWriter, r *http.Request) {
+	note := h.ownedNote(w, r)
+	if note == nil {
+		return
+	}
+	if note.Encrypted {
+		http.Error(w, ErrEncryptedNote.Error(), http.StatusConflict)
+		return
+	}
+
+	id := note.ID.String()
+	if err := h.repo.Publish(r.Context(), id); err != nil {
+		http.Error(w, "Failed to publish note", http.StatusInternalServerError)
+		return
+	}
+	note, err := h.repo.GetByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Failed to retrieve note", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, note, http.StatusOK)
+}
+
+// UnpublishNote takes the note out of public view. The draft is untouched.
+func (h *PublishHandler) UnpublishNote(w http.ResponseWriter, r *http.Request) {
+	note := h.ownedNote(w, r)
+	if note == nil {
+		return
+	}
+
+	id := note.ID.String()
+	if err := h.repo.Unpublish(r.Context(), id); err != nil {
+		http.Error(w, "Failed to unpublish note", http.StatusInternalServerError)
+		return
+	}
+	note, err := h.repo.GetByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Failed to retrieve note", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, note, http.StatusOK)
+}
+
+// GetPublicNotes is the public feed. It only ever serves published snapshots.
+func (h *PublishHandler) GetPublicNotes(w http.ResponseWriter, r *http.Request) {
+	page, perPage := utils.Pagination(r, 20, 100)
+	published, total, err := h.repo.GetPublished(r.Context(), perPage, (page-1)*perPage)
+	if err != nil {
+		http.Error(w, "Failed to retrieve public notes", http.StatusInternalServerError)
+		return
+	}
+
+	notes := make([]Note, 0, len(published))
+	for i := range published {
+		if version := published[i].PublishedVersion(); version != nil {
+			notes = append(notes, *version)
+		}
+	}
+
+	utils.JSONResponse(w, NotePage{Notes: notes, Page: page, PerPage: perPage, Total: total}, http.StatusOK)
+}
+
+// GetPublicNote serves the published snapshot behind a share link.
+func (h *PublishHandler) GetPublicNote(w http.ResponseWriter, r *http.Request) {
+	note, err := h.repo.GetByID(r.Context(), r.PathValue("id"))
+	if err != nil || note == nil {
+		http.Error(w, "Note not found", http.StatusNotFound)
+		return
+	}
+	published := note.PublishedVersion()
+	if published == nil {
+		http.Error(w, "Note not found", http.StatusNotFound)
+		return
+	}
+
+	utils.JSONResponse(w, published, http.StatusOK)
+}
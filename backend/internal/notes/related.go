@@ -0,0 +1,213 @@
+package notes
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// Field weights: a term in the title or tags says more about a note than
+// one in the body.
+const (
+	titleWeight = 2
+	tagWeight   = 3
+)
+
+// stopWords are skipped when indexing. Notes are written in English and Spanish.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"for": true, "from": true, "has": true, "in": true, "is": true, "it": true, "its": true, "of": true,
+	"on": true, "or": true, "that": true, "the": true, "this": true, "to": true, "was": true, "were": true,
+	"will": true, "with": true,
+	"de": true, "del": true, "el": true, "en": true, "es": true, "la": true, "las": true, "lo": true,
+	"los": true, "para": true, "por": true, "que": true, "se": true, "un": true, "una": true, "y": true,
+}
+
+// tokenize lowercases text and splits it into indexable terms.
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := fields[:0]
+	for _, f := range fields {
+		if len([]rune(f)) > 1 && !stopWords[f] {
+			terms = append(terms, f)
+		}
+	}
+	return terms
+}
+
+// noteTerms counts the weighted term frequencies of a note.
+func noteTerms(note *Note) map[string]int {
+	tf := make(map[string]int)
+	for _, t := range tokenize(note.Title) {
+		tf[t] += titleWeight
+	}
+	for _, tag := range note.Tags {
+		for _, t := range tokenize(tag.Name) {
+			tf[t] += tagWeight
+		}
+	}
+	for _, t := range tokenize(note.Content) {
+		tf[t]++
+	}
+	return tf
+}
+
+// RelatedNote is a note similar to the one being looked at.
+type RelatedNote struct {
+	ID    uuid.UUID `json:"id"`
+	Title string    `json:"title"`
+	Tags  []Tag     `json:"tags"`
+	Score float64   `json:"score"`
+}
+
+type indexedNote struct {
+	title string
+	tags  []Tag
+	tf    map[string]int
+}
+
+// termIndex holds the documents and document frequencies of one user.
+type termIndex struct {
+	docs map[uuid.UUID]indexedNote
+	df   map[string]int
+}
+
+func (ix *termIndex) add(note *Note) {
+	ix.remove(note.ID)
+	if note.Encrypted {
+		return // nothing the server can read
+	}
+	tf := noteTerms(note)
+	ix.docs[note.ID] = indexedNote{title: note.Title, tags: note.Tags, tf: tf}
+	for t := range tf {
+		ix.df[t]++
+	}
+}
+
+func (ix *termIndex) remove(id uuid.UUID) {
+	doc, ok := ix.docs[id]
+	if !ok {
+		return
+	}
+	for t := range doc.tf {
+		if ix.df[t]--; ix.df[t] <= 0 {
+			delete(ix.df, t)
+		}
+	}
+	delete(ix.docs, id)
+}
+
+// weights turns term frequencies into a unit-length TF-IDF vector.
+func (ix *termIndex) weights(tf map[string]int) map[string]float64 {
+	n := float64(len(ix.docs))
+	vec := make(map[string]float64, len(tf))
+	var norm float64
+	for t, f := range tf {
+		df := float64(ix.df[t])
+		if df == 0 {
+			continue // unknown to the collection, cannot match anything
+		}
+		w := (1 + math.Log(float64(f))) * math.Log(1+n/df)
+		vec[t] = w
+		norm += w * w
+	}
+	norm = math.Sqrt(norm)
+	for t := range vec {
+		vec[t] /= norm
+	}
+	return vec
+}
+
+// RelatedIndex is an in-process TF-IDF index over each user's notes, used to
+// find similar notes. A user's index is built from the repository on first
+// use and then kept current as a NoteObserver.
+type RelatedIndex struct {
+	mu      sync.Mutex
+	repo    NoteRepository
+	indexes map[uuid.UUID]*termIndex
+}
+
+func NewRelatedIndex(repo NoteRepository) *RelatedIndex {
+	return &RelatedIndex{
+		repo:    repo,
+		indexes: make(map[uuid.UUID]*termIndex),
+	}
+}
+
+// load returns the user's index, building it if needed. Callers must hold mu.
+func (ri *RelatedIndex) load(ctx context.Context, userID uuid.UUID) (*termIndex, error) {
+	if ix, ok := ri.indexes[userID]; ok {
+		return ix, nil
+	}
+	notes, err := ri.repo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	ix := &termIndex{docs: make(map[uuid.UUID]indexedNote), df: make(map[string]int)}
+	for i := range notes {
+		ix.add(&notes[i])
+	}
+	ri.indexes[userID] = ix
+	return ix, nil
+}
+
+// Related returns up to limit notes of userID most similar to note, best first.
+func (ri *RelatedIndex) Related(ctx context.Context, userID uuid.UUID, note *Note, limit int) ([]RelatedNote, error) {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+
+	ix, err := ri.load(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := ix.weights(noteTerms(note))
+	results := []RelatedNote{}
+	for id, doc := range ix.docs {
+		if id == note.ID {
+			continue
+		}
+		var score float64
+		for t, w := range ix.weights(doc.tf) {
+			score += w * query[t]
+		}
+		if score > 0 {
+			results = append(results, RelatedNote{ID: id, Title: doc.title, Tags: doc.tags, Score: score})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID.String() < results[j].ID.String()
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func (ri *RelatedIndex) NoteSaved(ctx context.Context, note *Note) {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+	// Users whose index is not loaded yet will pick the note up when it is
+	if ix, ok := ri.indexes[note.UserID]; ok {
+		ix.add(note)
+	}
+}
+
+func (ri *RelatedIndex) NoteDeleted(ctx context.Context, note *Note) {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+	if ix, ok := ri.indexes[note.UserID]; ok {
+		ix.remove(note.ID)
+	}
+}
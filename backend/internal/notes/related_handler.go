@@ -0,0 +1,68 @@
+package notes
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/middleware"
+	"github.com/jehufrayle/grimoire/utils"
+)
+
+const defaultRelatedLimit = 5
+
+type RelatedHandler struct {
+	repo  NoteRepository
+	index *RelatedIndex
+}
+
+func NewRelatedHandler(repo NoteRepository, index *RelatedIndex) *RelatedHandler {
+	return &RelatedHandler{repo: repo, index: index}
+}
+
+// GetRelatedNotes lists the caller's notes most similar to the given one.
+func (h *RelatedHandler) GetRelatedNotes(w http.ResponseWriter, r *http.Request) {
+	userIDstr, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	userID, err := uuid.Parse(userIDstr)
+	if err != nil {
+		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	note, err := h.repo.GetByID(r.Context(), r.PathValue("id"))
+	if err != nil || note == nil {
+		http.Error(w, "Note not found", http.StatusNotFound)
+		return
+	}
+	// Strangers get neighbours of the published text, not of the draft
+	readable, err := ReadableVersion(r.Context(), h.repo, note, userID)
+	if err != nil {
+		http.Error(w, "Failed to check note access", http.StatusInternalServerError)
+		return
+	}
+	if readable == nil {
+		http.Error(w, "Unauthorized access to this note", http.StatusForbidden)
+		return
+	}
+	if readable.Encrypted {
+		http.Error(w, ErrEncryptedNote.Error(), http.StatusConflict)
+		return
+	}
+
+	limit := defaultRelatedLimit
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 && v <= 50 {
+		limit = v
+	}
+
+	related, err := h.index.Related(r.Context(), userID, readable, limit)
+	if err != nil {
+		http.Error(w, "Failed to find related notes", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, related, http.StatusOK)
+}
@@ -0,0 +1,112 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/middleware"
+)
+
+func TestRelatedIndex(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryNoteRepository()
+	observed := NewObservedNoteRepository(repo)
+	index := NewRelatedIndex(observed)
+	observed.Observe(index)
+	userID := uuid.New()
+
+	create := func(title, content string, tags ...string) *Note {
+		note := &Note{Title: title, Content: content, UserID: userID}
+		for _, tag := range tags {
+			note.Tags = append(note.Tags, Tag{Name: tag})
+		}
+		if err := observed.Create(ctx, note); err != nil {
+			t.Fatalf("failed to create note: %v", err)
+		}
+		return note
+	}
+
+	dragons := create("Dragon taming", "How to calm an angry dragon with fire-resistant gloves", "dragons")
+	lair := create("Dragon lair map", "The dragon sleeps in the northern caves", "dragons", "maps")
+	create("Potion of healing", "Mix mandrake root and moonwater")
+
+	related, err := index.Related(ctx, userID, dragons, 5)
+	if err != nil {
+		t.Fatalf("failed to get related notes: %v", err)
+	}
+	if len(related) == 0 || related[0].ID != lair.ID {
+		t.Fatalf("expected the lair note first, got %+v", related)
+	}
+	for _, rn := range related {
+		if rn.ID == dragons.ID {
+			t.Error("a note should not be related to itself")
+		}
+	}
+
+	// The index follows writes once loaded
+	if err := observed.Delete(ctx, lair.ID.String()); err != nil {
+		t.Fatalf("failed to delete note: %v", err)
+	}
+	eggs := create("Dragon eggs", "Keep dragon eggs warm", "dragons")
+
+	related, err = index.Related(ctx, userID, dragons, 5)
+	if err != nil {
+		t.Fatalf("failed to get related notes: %v", err)
+	}
+	if len(related) == 0 || related[0].ID != eggs.ID {
+		t.Fatalf("expected the eggs note first, got %+v", related)
+	}
+	for _, rn := range related {
+		if rn.ID == lair.ID {
+			t.Error("deleted note is still indexed")
+		}
+	}
+}
+
+func TestGetRelatedNotesUsesReadableVersion(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryNoteRepository()
+	h := NewRelatedHandler(repo, NewRelatedIndex(repo))
+	owner, reader := uuid.New(), uuid.New()
+
+	note := Note{UserID: owner, Title: "Dragon lair", Content: "The dragon sleeps in the northern caves", IsPublic: true}
+	if err := repo.Create(ctx, &note); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.Publish(ctx, note.ID.String()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The draft moves on to something only the owner may see
+	note.Content = "Secret treasure vault under the lair"
+	if err := repo.Update(ctx, &note); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, n := range []Note{
+		{UserID: reader, Title: "Cave survey", Content: "Northern caves and where the dragon sleeps"},
+		{UserID: reader, Title: "Vault plans", Content: "Secret treasure vault blueprints"},
+	} {
+		if err := repo.Create(ctx, &n); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/notes/"+note.ID.String()+"/related", nil)
+	r.SetPathValue("id", note.ID.String())
+	r = r.WithContext(context.WithValue(r.Context(), middleware.UserIDKey, reader.String()))
+	w := httptest.NewRecorder()
+	h.GetRelatedNotes(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	var related []RelatedNote
+	if err := json.NewDecoder(w.Body).Decode(&related); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(related) != 1 || related[0].Title != "Cave survey" {
+		t.Fatalf("related = %+v", related)
+	}
+}
@@ -0,0 +1,93 @@
+package notes
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/markdown"
+	"github.com/jehufrayle/grimoire/middleware"
+	"github.com/jehufrayle/grimoire/utils"
+)
+
+// RenderedNote is a note as HTML, embeds included.
+type RenderedNote struct {
+	ID    uuid.UUID `json:"id"`
+	Title string    `json:"title"`
+	HTML  string    `json:"html"`
+}
+
+type RenderHandler struct {
+	repo        NoteRepository
+	transcluder *Transcluder
+}
+
+func NewRenderHandler(repo NoteRepository, transcluder *Transcluder) *RenderHandler {
+	return &RenderHandler{repo: repo, transcluder: transcluder}
+}
+
+// GetRenderedNote renders a note the caller can read.
+func (h *RenderHandler) GetRenderedNote(w http.ResponseWriter, r *http.Request) {
+	userIDstr, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	userID, err := uuid.Parse(userIDstr)
+	if err != nil {
+		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	note, err := h.repo.GetByID(r.Context(), r.PathValue("id"))
+	if err != nil || note == nil {
+		http.Error(w, "Note not found", http.StatusNotFound)
+		return
+	}
+	version, err := ReadableVersion(r.Context(), h.repo, note, userID)
+	if err != nil {
+		http.Error(w, "Failed to check note access", http.StatusInternalServerError)
+		return
+	}
+	if version == nil {
+		http.Error(w, "Unauthorized access to this note", http.StatusForbidden)
+		return
+	}
+
+	h.render(w, r, version, userID)
+}
+
+// GetPublicRenderedNote renders the published snapshot behind a share link.
+func (h *RenderHandler) GetPublicRenderedNote(w http.ResponseWriter, r *http.Request) {
+	note, err := h.repo.GetByID(r.Context(), r.PathValue("id"))
+	if err != nil || note == nil {
+		http.Error(w, "Note not found", http.StatusNotFound)
+		return
+	}
+	published := note.PublishedVersion()
+	if published == nil {
+		http.Error(w, "Note not found", http.StatusNotFound)
+		return
+	}
+
+	h.render(w, r, published, uuid.Nil)
+}
+
+func (h *RenderHandler) render(w http.ResponseWriter, r *http.Request, note *Note, readerID uuid.UUID) {
+	if note.Encrypted {
+		http.Error(w, ErrEncryptedNote.Error(), http.StatusConflict)
+		return
+	}
+
+	expanded, err := h.transcluder.Expand(r.Context(), note, readerID)
+	if err != nil {
+		http.Error(w, "Failed to resolve embedded notes", http.StatusInternalServerError)
+		return
+	}
+	html, err := markdown.Render(expanded)
+	if err != nil {
+		http.Error(w, "Failed to render note", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, RenderedNote{ID: note.ID, Title: note.Title, HTML: html}, http.StatusOK)
+}
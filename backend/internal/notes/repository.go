@@ -2,17 +2,34 @@ package notes
 
 import (
 	"context"
+	"errors"
 
 	"github.com/google/uuid"
 )
 
+// ErrNoteNotFound is returned by GetByID when there is no live note with the
+// given ID.
+var ErrNoteNotFound = errors.New("note not found")
+
 // Interface
 type NoteRepository interface {
 	GetAll(ctx context.Context) ([]Note, error) // Get all users
 	GetByID(ctx context.Context, id string) (*Note, error)
 	GetByUserID(ctx context.Context, userID uuid.UUID) ([]Note, error)
 	GetByTags(ctx context.Context, tags []string) ([]Note, error)
+	Find(ctx context.Context, userID uuid.UUID, filter *Filter) ([]Note, error)
 	Create(ctx context.Context, note *Note) error
 	Update(ctx context.Context, note *Note) error
 	Delete(ctx context.Context, id string) error
+	// Publish snapshots the current draft as the public version; Unpublish
+	// drops the snapshot and makes the note private again.
+	Publish(ctx context.Context, id string) error
+	Unpublish(ctx context.Context, id string) error
+	GetPublished(ctx context.Context, limit, offset int) ([]Note, int, error)
+	// GetDaily returns the user's daily note for date (YYYY-MM-DD), or
+	// ErrNoDailyNote.
+	GetDaily(ctx context.Context, userID uuid.UUID, date string) (*Note, error)
+	IsSharedWith(ctx context.Context, noteID, userID uuid.UUID) (bool, error)
+	Stats(ctx context.Context, userID uuid.UUID) (*Stats, error)
+	SearchTitles(ctx context.Context, userID uuid.UUID, query string, limit int) ([]TitleMatch, error)
 }
@@ -0,0 +1,41 @@
+package notes
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SavedSearch is a named Filter, listed as a smart notebook in the sidebar.
+type SavedSearch struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Name      string    `json:"name"`
+	Filter    Filter    `json:"filter"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SavedSearchSummary is a saved search with what it currently matches.
+type SavedSearchSummary struct {
+	SavedSearch
+	MatchCount int          `json:"match_count"`
+	Newest     *NoteSummary `json:"newest,omitempty"`
+}
+
+// NoteSummary is the small view of a note shown in listings.
+type NoteSummary struct {
+	ID        uuid.UUID `json:"id"`
+	Title     string    `json:"title"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Interface
+type SavedSearchRepository interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*SavedSearch, error)
+	GetByUserID(ctx context.Context, userID uuid.UUID) ([]SavedSearch, error)
+	Create(ctx context.Context, search *SavedSearch) error
+	Update(ctx context.Context, search *SavedSearch) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
@@ -0,0 +1,193 @@
+package notes
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/middleware"
+	"github.com/jehufrayle/grimoire/utils"
+)
+
+type SavedSearchHandler struct {
+	repo  SavedSearchRepository
+	notes NoteRepository
+}
+
+func NewSavedSearchHandler(repo SavedSearchRepository, noteRepo NoteRepository) *SavedSearchHandler {
+	return &SavedSearchHandler{repo: repo, notes: noteRepo}
+}
+
+// ownedSearch loads a saved search of the current user. It writes the error
+// response itself and returns nil when the request should stop.
+func (h *SavedSearchHandler) ownedSearch(w http.ResponseWriter, r *http.Request) *SavedSearch {
+	userIDstr, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return nil
+	}
+	userID, err := uuid.Parse(userIDstr)
+	if err != nil {
+		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		return nil
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid saved search ID format", http.StatusBadRequest)
+		return nil
+	}
+
+	search, err := h.repo.GetByID(r.Context(), id)
+	if err != nil || search.UserID != userID {
+		http.Error(w, "Saved search not found", http.StatusNotFound)
+		return nil
+	}
+	return search
+}
+
+// GetSavedSearches lists the user's saved searches with their current match
+// count and newest matching note, for the sidebar.
+func (h *SavedSearchHandler) GetSavedSearches(w http.ResponseWriter, r *http.Request) {
+	userIDstr, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	userID, err := uuid.Parse(userIDstr)
+	if err != nil {
+		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	searches, err := h.repo.GetByUserID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve saved searches", http.StatusInternalServerError)
+		return
+	}
+
+	// One load serves every search; the sidebar asks on every page
+	userNotes, err := h.notes.GetByUserID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to run saved search", http.StatusInternalServerError)
+		return
+	}
+
+	summaries := make([]SavedSearchSummary, 0, len(searches))
+	for _, search := range searches {
+		summary := SavedSearchSummary{SavedSearch: search}
+		for i := range userNotes {
+			m := &userNotes[i]
+			if !search.Filter.Matches(m) {
+				continue
+			}
+			summary.MatchCount++
+			if summary.Newest == nil || m.UpdatedAt.After(summary.Newest.UpdatedAt) {
+				summary.Newest = &NoteSummary{ID: m.ID, Title: m.Title, UpdatedAt: m.UpdatedAt}
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+
+	utils.JSONResponse(w, summaries, http.StatusOK)
+}
+
+func (h *SavedSearchHandler) CreateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name   string `json:"name"`
+		Filter Filter `json:"filter"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	userIDstr, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	userID, err := uuid.Parse(userIDstr)
+	if err != nil {
+		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	search := SavedSearch{UserID: userID, Name: strings.TrimSpace(req.Name), Filter: req.Filter}
+	if err := h.repo.Create(r.Context(), &search); err != nil {
+		http.Error(w, "Failed to create saved search", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, search, http.StatusCreated)
+}
+
+func (h *SavedSearchHandler) UpdateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name   *string `json:"name"`
+		Filter *Filter `json:"filter"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	search := h.ownedSearch(w, r)
+	if search == nil {
+		return
+	}
+	if req.Name != nil {
+		if strings.TrimSpace(*req.Name) == "" {
+			http.Error(w, "Name is required", http.StatusBadRequest)
+			return
+		}
+		search.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Filter != nil {
+		search.Filter = *req.Filter
+	}
+
+	if err := h.repo.Update(r.Context(), search); err != nil {
+		http.Error(w, "Failed to update saved search", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, search, http.StatusOK)
+}
+
+func (h *SavedSearchHandler) DeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	search := h.ownedSearch(w, r)
+	if search == nil {
+		return
+	}
+
+	if err := h.repo.Delete(r.Context(), search.ID); err != nil {
+		http.Error(w, "Failed to delete saved search", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSavedSearchNotes lists the notes currently matching a saved search.
+func (h *SavedSearchHandler) GetSavedSearchNotes(w http.ResponseWriter, r *http.Request) {
+	search := h.ownedSearch(w, r)
+	if search == nil {
+		return
+	}
+
+	notes, err := h.notes.Find(r.Context(), search.UserID, &search.Filter)
+	if err != nil {
+		http.Error(w, "Failed to run saved search", http.StatusInternalServerError)
+		return
+	}
+	if notes == nil {
+		notes = []Note{}
+	}
+
+	utils.JSONResponse(w, notes, http.StatusOK)
+}
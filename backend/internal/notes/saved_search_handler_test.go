@@ -0,0 +1,61 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/middleware"
+)
+
+func TestGetSavedSearchesSummarizesMatches(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryNoteRepository()
+	searches := NewInMemorySavedSearchRepository()
+	h := NewSavedSearchHandler(searches, repo)
+	userID := uuid.New()
+
+	for _, n := range []Note{
+		{UserID: userID, Title: "Quest log", Tags: []Tag{{Name: "quests"}}},
+		{UserID: userID, Title: "Side quest", Tags: []Tag{{Name: "quests"}}},
+		{UserID: userID, Title: "Recipes"},
+		{UserID: uuid.New(), Title: "Someone else's quest", Tags: []Tag{{Name: "quests"}}},
+	} {
+		if err := repo.Create(ctx, &n); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	for _, s := range []SavedSearch{
+		{UserID: userID, Name: "Quests", Filter: Filter{Tags: []string{"quests"}}},
+		{UserID: userID, Name: "Dragons", Filter: Filter{Text: "dragon"}},
+	} {
+		if err := searches.Create(ctx, &s); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/searches", nil)
+	r = r.WithContext(context.WithValue(r.Context(), middleware.UserIDKey, userID.String()))
+	w := httptest.NewRecorder()
+	h.GetSavedSearches(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	var summaries []SavedSearchSummary
+	if err := json.NewDecoder(w.Body).Decode(&summaries); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	byName := make(map[string]SavedSearchSummary)
+	for _, s := range summaries {
+		byName[s.Name] = s
+	}
+	if quests := byName["Quests"]; quests.MatchCount != 2 || quests.Newest == nil || quests.Newest.Title != "Side quest" {
+		t.Fatalf("quests = %+v", quests)
+	}
+	if dragons := byName["Dragons"]; dragons.MatchCount != 0 || dragons.Newest != nil {
+		t.Fatalf("dragons = %+v", dragons)
+	}
+}
@@ -0,0 +1,156 @@
+package notes
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/journal"
+)
+
+// InMemorySavedSearchRepository keeps saved searches in memory. Opened with
+// OpenInMemorySavedSearchRepository it also persists them to disk.
+type InMemorySavedSearchRepository struct {
+	mu       sync.RWMutex
+	searches map[uuid.UUID]SavedSearch
+	journal  *journal.Store[[]SavedSearch, savedSearchEntry] // nil when not persisted
+}
+
+func NewInMemorySavedSearchRepository() *InMemorySavedSearchRepository {
+	return &InMemorySavedSearchRepository{
+		searches: make(map[uuid.UUID]SavedSearch),
+	}
+}
+
+func (r *InMemorySavedSearchRepository) GetByID(ctx context.Context, id uuid.UUID) (*SavedSearch, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	search, ok := r.searches[id]
+	if !ok {
+		return nil, errors.New("saved search not found")
+	}
+	return &search, nil
+}
+
+func (r *InMemorySavedSearchRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]SavedSearch, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []SavedSearch
+	for _, s := range r.searches {
+		if s.UserID == userID {
+			result = append(result, s)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+func (r *InMemorySavedSearchRepository) Create(ctx context.Context, search *SavedSearch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	search.ID = uuid.New()
+	now := time.Now()
+	search.CreatedAt = now
+	search.UpdatedAt = now
+	r.searches[search.ID] = *search
+	if err := r.journal.Log(savedSearchEntry{Search: search}); err != nil {
+		delete(r.searches, search.ID)
+		return err
+	}
+	return nil
+}
+
+func (r *InMemorySavedSearchRepository) Update(ctx context.Context, search *SavedSearch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.searches[search.ID]
+	if !ok {
+		return errors.New("saved search not found")
+	}
+	updated := existing
+	updated.Name = search.Name
+	updated.Filter = search.Filter
+	updated.UpdatedAt = time.Now()
+	r.searches[search.ID] = updated
+	if err := r.journal.Log(savedSearchEntry{Search: &updated}); err != nil {
+		r.searches[search.ID] = existing
+		return err
+	}
+	*search = updated
+	return nil
+}
+
+func (r *InMemorySavedSearchRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.searches[id]
+	if !ok {
+		return errors.New("saved search not found")
+	}
+	delete(r.searches, id)
+	if err := r.journal.Log(savedSearchEntry{Deleted: &id}); err != nil {
+		r.searches[id] = existing
+		return err
+	}
+	return nil
+}
+
+// savedSearchEntry is one change in the journal's log.
+type savedSearchEntry struct {
+	Search  *SavedSearch `json:"search,omitempty"`
+	Deleted *uuid.UUID   `json:"deleted,omitempty"`
+}
+
+// OpenInMemorySavedSearchRepository returns an InMemorySavedSearchRepository
+// persisted in dir. It starts from what dir holds and logs every write there
+// before returning. Snapshot keeps the log short and Close takes a last
+// snapshot.
+func OpenInMemorySavedSearchRepository(dir string) (*InMemorySavedSearchRepository, error) {
+	r := NewInMemorySavedSearchRepository()
+	j, err := journal.OpenStore(dir, &r.mu, r.all,
+		func(searches []SavedSearch) {
+			for _, s := range searches {
+				r.searches[s.ID] = s
+			}
+		},
+		func(entry savedSearchEntry) {
+			if entry.Search != nil {
+				r.searches[entry.Search.ID] = *entry.Search
+			} else if entry.Deleted != nil {
+				delete(r.searches, *entry.Deleted)
+			}
+		})
+	if err != nil {
+		return nil, err
+	}
+	r.journal = j
+	return r, nil
+}
+
+// Callers must hold at least a read lock.
+func (r *InMemorySavedSearchRepository) all() []SavedSearch {
+	searches := make([]SavedSearch, 0, len(r.searches))
+	for _, s := range r.searches {
+		searches = append(searches, s)
+	}
+	return searches
+}
+
+// Snapshot writes every saved search to disk and empties the log. It is a
+// no-op when the repository is not persisted.
+func (r *InMemorySavedSearchRepository) Snapshot() error {
+	return r.journal.Snapshot()
+}
+
+// Close takes a last snapshot and closes the journal.
+func (r *InMemorySavedSearchRepository) Close() error {
+	return r.journal.Close()
+}
@@ -0,0 +1,99 @@
+package notes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgSavedSearchRepository implements the SavedSearchRepository interface for PostgreSQL.
+type PgSavedSearchRepository struct {
+	DB *pgxpool.Pool
+}
+
+// NewPgSavedSearchRepository creates a new instance of PgSavedSearchRepository.
+func NewPgSavedSearchRepository(db *pgxpool.Pool) *PgSavedSearchRepository {
+	return &PgSavedSearchRepository{DB: db}
+}
+
+const selectSavedSearchQuery = `SELECT id, user_id, name, filter, created_at, updated_at FROM saved_searches`
+
+func scanSavedSearch(row rowScanner) (SavedSearch, error) {
+	var s SavedSearch
+	err := row.Scan(&s.ID, &s.UserID, &s.Name, &s.Filter, &s.CreatedAt, &s.UpdatedAt)
+	return s, err
+}
+
+// GetByID retrieves a single saved search.
+func (r *PgSavedSearchRepository) GetByID(ctx context.Context, id uuid.UUID) (*SavedSearch, error) {
+	s, err := scanSavedSearch(r.DB.QueryRow(ctx, selectSavedSearchQuery+" WHERE id = $1", id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("saved search not found")
+		}
+		return nil, fmt.Errorf("failed to scan saved search: %w", err)
+	}
+	return &s, nil
+}
+
+// GetByUserID retrieves all saved searches of a user, by name.
+func (r *PgSavedSearchRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]SavedSearch, error) {
+	rows, err := r.DB.Query(ctx, selectSavedSearchQuery+" WHERE user_id = $1 ORDER BY name", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query saved searches: %w", err)
+	}
+	searches, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (SavedSearch, error) {
+		return scanSavedSearch(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan saved search row: %w", err)
+	}
+	return searches, nil
+}
+
+// Create inserts a saved search.
+func (r *PgSavedSearchRepository) Create(ctx context.Context, search *SavedSearch) error {
+	query := `
+        INSERT INTO saved_searches (user_id, name, filter)
+        VALUES ($1, $2, $3)
+        RETURNING id, created_at, updated_at`
+	err := r.DB.QueryRow(ctx, query, search.UserID, search.Name, search.Filter).
+		Scan(&search.ID, &search.CreatedAt, &search.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert saved search: %w", err)
+	}
+	return nil
+}
+
+// Update renames a saved search or changes its filter.
+func (r *PgSavedSearchRepository) Update(ctx context.Context, search *SavedSearch) error {
+	query := `
+        UPDATE saved_searches
+        SET name = $1, filter = $2, updated_at = now()
+        WHERE id = $3
+        RETURNING updated_at`
+	err := r.DB.QueryRow(ctx, query, search.Name, search.Filter, search.ID).Scan(&search.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("saved search not found")
+		}
+		return fmt.Errorf("failed to update saved search: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a saved search.
+func (r *PgSavedSearchRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.DB.Exec(ctx, "DELETE FROM saved_searches WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete saved search: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("saved search not found")
+	}
+	return nil
+}
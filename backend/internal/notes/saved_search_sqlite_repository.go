@@ -0,0 +1,125 @@
+package notes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/database"
+)
+
+// SQLiteSavedSearchRepository implements the SavedSearchRepository
+// interface for the embedded SQLite backend.
+type SQLiteSavedSearchRepository struct {
+	DB *sql.DB
+}
+
+// NewSQLiteSavedSearchRepository creates a new instance of SQLiteSavedSearchRepository.
+func NewSQLiteSavedSearchRepository(db *sql.DB) *SQLiteSavedSearchRepository {
+	return &SQLiteSavedSearchRepository{DB: db}
+}
+
+const selectSQLiteSavedSearchQuery = `SELECT id, user_id, name, filter, created_at, updated_at FROM saved_searches`
+
+func scanSQLiteSavedSearch(row rowScanner) (SavedSearch, error) {
+	var s SavedSearch
+	var filter string
+	err := row.Scan(&s.ID, &s.UserID, &s.Name, &filter, database.ScanTime{T: &s.CreatedAt}, database.ScanTime{T: &s.UpdatedAt})
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal([]byte(filter), &s.Filter); err != nil {
+		return s, fmt.Errorf("failed to unmarshal filter of saved search %s: %w", s.ID, err)
+	}
+	return s, nil
+}
+
+// GetByID retrieves a single saved search.
+func (r *SQLiteSavedSearchRepository) GetByID(ctx context.Context, id uuid.UUID) (*SavedSearch, error) {
+	s, err := scanSQLiteSavedSearch(r.DB.QueryRowContext(ctx, selectSQLiteSavedSearchQuery+" WHERE id = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("saved search not found")
+		}
+		return nil, fmt.Errorf("failed to scan saved search: %w", err)
+	}
+	return &s, nil
+}
+
+// GetByUserID retrieves all saved searches of a user, by name.
+func (r *SQLiteSavedSearchRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]SavedSearch, error) {
+	rows, err := r.DB.QueryContext(ctx, selectSQLiteSavedSearchQuery+" WHERE user_id = ? ORDER BY name", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query saved searches: %w", err)
+	}
+	defer rows.Close()
+
+	var searches []SavedSearch
+	for rows.Next() {
+		s, err := scanSQLiteSavedSearch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan saved search row: %w", err)
+		}
+		searches = append(searches, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return searches, nil
+}
+
+// Create inserts a saved search.
+func (r *SQLiteSavedSearchRepository) Create(ctx context.Context, search *SavedSearch) error {
+	filter, err := json.Marshal(search.Filter)
+	if err != nil {
+		return fmt.Errorf("failed to encode filter: %w", err)
+	}
+	id := uuid.New()
+	created := time.Now().UTC()
+	query := `
+        INSERT INTO saved_searches (id, user_id, name, filter, created_at, updated_at)
+        VALUES (?, ?, ?, ?, ?, ?)`
+	now := database.Timestamp(created)
+	if _, err := r.DB.ExecContext(ctx, query, id, search.UserID, search.Name, string(filter), now, now); err != nil {
+		return fmt.Errorf("failed to insert saved search: %w", err)
+	}
+	search.ID = id
+	search.CreatedAt = created
+	search.UpdatedAt = created
+	return nil
+}
+
+// Update renames a saved search or changes its filter.
+func (r *SQLiteSavedSearchRepository) Update(ctx context.Context, search *SavedSearch) error {
+	filter, err := json.Marshal(search.Filter)
+	if err != nil {
+		return fmt.Errorf("failed to encode filter: %w", err)
+	}
+	updated := time.Now().UTC()
+	result, err := r.DB.ExecContext(ctx, "UPDATE saved_searches SET name = ?, filter = ?, updated_at = ? WHERE id = ?",
+		search.Name, string(filter), database.Timestamp(updated), search.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update saved search: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return fmt.Errorf("saved search not found")
+	}
+	search.UpdatedAt = updated
+	return nil
+}
+
+// Delete removes a saved search.
+func (r *SQLiteSavedSearchRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.DB.ExecContext(ctx, "DELETE FROM saved_searches WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete saved search: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return fmt.Errorf("saved search not found")
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package notes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/notifications"
+)
+
+// ExpireAction is what happens to a note when it expires.
+type ExpireAction string
+
+const (
+	ExpireDelete    ExpireAction = "delete"    // soft-delete the note
+	ExpireUnpublish ExpireAction = "unpublish" // only take it out of public view
+)
+
+// ValidateSchedule checks a note's publish and expiry times and fills in the
+// default expire action.
+func ValidateSchedule(note *Note) error {
+	if note.ExpiresAt == nil {
+		if note.ExpireAction != "" {
+			return errors.New("expire_action needs expires_at")
+		}
+	} else {
+		switch note.ExpireAction {
+		case "":
+			note.ExpireAction = ExpireDelete
+		case ExpireDelete, ExpireUnpublish:
+		default:
+			return fmt.Errorf("unknown expire_action %q", note.ExpireAction)
+		}
+	}
+	if note.PublishAt != nil && note.Encrypted {
+		return ErrEncryptedNote
+	}
+	if note.PublishAt != nil && note.ExpiresAt != nil && !note.ExpiresAt.After(*note.PublishAt) {
+		return errors.New("expires_at must be after publish_at")
+	}
+	return nil
+}
+
+// expireActionOrNil is what gets stored in the nullable expire_action column.
+func (n *Note) expireActionOrNil() any {
+	if n.ExpireAction == "" {
+		return nil
+	}
+	return string(n.ExpireAction)
+}
+
+// ScheduledKind says what a scheduled action does.
+type ScheduledKind string
+
+const (
+	SchedulePublish ScheduledKind = "publish" // at publish_at
+	ScheduleExpire  ScheduledKind = "expire"  // at expires_at
+	ScheduleRemind  ScheduledKind = "remind"  // at remind_at, once per time
+)
+
+// ScheduledAction is a due publish, expiry or reminder claimed by one
+// scheduler.
+type ScheduledAction struct {
+	NoteID uuid.UUID
+	Kind   ScheduledKind
+	Expire ExpireAction // set for ScheduleExpire
+	At     time.Time    // the publish_at, expires_at or remind_at that was due
+}
+
+// ScheduleStore hands out due scheduled actions. The schedule lives on the
+// notes themselves so it survives restarts. A claimed action is leased to a
+// single caller; if the caller dies, the lease runs out and another
+// scheduler picks it up. Edits to the note leave the lease alone.
+//
+// Complete clears a publish or expiry time from the note only if it still
+// is action.At, so a schedule changed in the meantime is kept. Reminders
+// keep their time, which the calendar feed still shows; Complete marks the
+// reminder delivered instead, and one moved to a new time is due again.
+// The lease is left to run out.
+type ScheduleStore interface {
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]ScheduledAction, error)
+	Complete(ctx context.Context, action ScheduledAction) error
+}
+
+const (
+	scheduleLease = time.Minute
+	scheduleBatch = 100
+)
+
+// Scheduler carries out due scheduled actions through the regular note
+// repository, so publishing and expiry look like any other write to
+// observers and expired notes take the usual soft-delete path. Due
+// reminders notify the owner.
+type Scheduler struct {
+	store         ScheduleStore
+	repo          NoteRepository
+	notifications notifications.Emitter
+	interval      time.Duration
+}
+
+// NewScheduler creates a new instance of Scheduler.
+func NewScheduler(store ScheduleStore, repo NoteRepository, emitter notifications.Emitter, interval time.Duration) *Scheduler {
+	return &Scheduler{store: store, repo: repo, notifications: emitter, interval: interval}
+}
+
+// Run polls for due actions until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RunOnce(ctx, time.Now()); err != nil {
+			log.Printf("scheduler: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce carries out the actions due at now. Actions that fail are left
+// leased and retried once the lease runs out.
+func (s *Scheduler) RunOnce(ctx context.Context, now time.Time) error {
+	actions, err := s.store.ClaimDue(ctx, now, scheduleLease, scheduleBatch)
+	if err != nil {
+		return fmt.Errorf("failed to claim scheduled actions: %w", err)
+	}
+	for _, action := range actions {
+		if err := s.run(ctx, action); err != nil {
+			log.Printf("scheduler: note %s: %v", action.NoteID, err)
+			continue
+		}
+		if err := s.store.Complete(ctx, action); err != nil {
+			log.Printf("scheduler: note %s: failed to complete: %v", action.NoteID, err)
+		}
+	}
+	return nil
+}
+
+func (s *Scheduler) run(ctx context.Context, action ScheduledAction) error {
+	id := action.NoteID.String()
+	note, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrNoteNotFound) {
+			return nil // deleted in the meantime; nothing left to do
+		}
+		return err
+	}
+
+	// Rescheduled since the claim; the new time gets its own claim
+	if due := note.scheduled(action.Kind); due == nil || !due.Equal(action.At) {
+		return nil
+	}
+
+	switch {
+	case action.Kind == ScheduleRemind:
+		return s.notifications.Emit(ctx, &notifications.Notification{
+			UserID: note.UserID,
+			Type:   notifications.TypeReminder,
+			NoteID: &note.ID,
+		})
+	case action.Kind == SchedulePublish:
+		if note.Encrypted {
+			return nil
+		}
+		return s.repo.Publish(ctx, id)
+	case note.ExpireAction == ExpireUnpublish:
+		return s.repo.Unpublish(ctx, id)
+	default:
+		return s.repo.Delete(ctx, id)
+	}
+}
+
+// reminderDue reports whether a reminder at remindAt is due at now and not
+// yet delivered, reminded being the remind_at last delivered.
+func reminderDue(remindAt, reminded *time.Time, now time.Time) bool {
+	return remindAt != nil && !remindAt.After(now) && (reminded == nil || !reminded.Equal(*remindAt))
+}
+
+// scheduled returns the note's time for actions of kind.
+func (n *Note) scheduled(kind ScheduledKind) *time.Time {
+	switch kind {
+	case SchedulePublish:
+		return n.PublishAt
+	case ScheduleRemind:
+		return n.RemindAt
+	default:
+		return n.ExpiresAt
+	}
+}
@@ -0,0 +1,75 @@
+package notes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/notifications"
+)
+
+func TestSchedulerPublishesAndExpires(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryNoteRepository()
+	scheduler := NewScheduler(repo, repo, notifications.NewCenter(notifications.NewInMemoryNotificationRepository()), time.Minute)
+
+	now := time.Now()
+	publishAt, expiresAt := now.Add(time.Hour), now.Add(2*time.Hour)
+	note := Note{Title: "Launch", Content: "Draft", UserID: uuid.New(), PublishAt: &publishAt, ExpiresAt: &expiresAt}
+	if err := ValidateSchedule(&note); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.Create(ctx, &note); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	id := note.ID.String()
+
+	if err := scheduler.RunOnce(ctx, now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, _ := repo.GetByID(ctx, id); got.Published != nil {
+		t.Fatal("note was published before publish_at")
+	}
+
+	if err := scheduler.RunOnce(ctx, publishAt); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := repo.GetByID(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Published == nil || !got.IsPublic || got.PublishAt != nil {
+		t.Fatalf("expected note to be published once, got %+v", got)
+	}
+
+	if err := scheduler.RunOnce(ctx, expiresAt); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := repo.GetByID(ctx, id); err == nil {
+		t.Fatal("expected expired note to be deleted")
+	}
+}
+
+func TestScheduleLeaseKeepsActionsWithOneScheduler(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryNoteRepository()
+
+	publishAt := time.Now()
+	note := Note{Title: "Launch", UserID: uuid.New(), PublishAt: &publishAt}
+	if err := repo.Create(ctx, &note); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	first, _ := repo.ClaimDue(ctx, publishAt, time.Minute, 10)
+	second, _ := repo.ClaimDue(ctx, publishAt, time.Minute, 10)
+	if len(first) != 1 || len(second) != 0 {
+		t.Fatalf("expected the action to be claimed once, got %d and %d", len(first), len(second))
+	}
+
+	// An abandoned lease runs out and the action is handed out again
+	again, _ := repo.ClaimDue(ctx, publishAt.Add(2*time.Minute), time.Minute, 10)
+	if len(again) != 1 {
+		t.Fatalf("expected the expired lease to be reclaimed, got %d", len(again))
+	}
+}
@@ -0,0 +1,105 @@
+package notes
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+const maxSlugLength = 80
+
+// SlugRepository keeps the per-user slugs of published notes. A note keeps
+// every slug it ever had so old URLs can redirect to the current one.
+type SlugRepository interface {
+	// AssignSlug makes base, or base-N when base is taken by another of the
+	// user's notes, the note's current slug. A note whose current slug
+	// already derives from base keeps it.
+	AssignSlug(ctx context.Context, userID, noteID uuid.UUID, base string) (string, error)
+	// ResolveSlug finds the note a slug belongs to and that note's current slug.
+	ResolveSlug(ctx context.Context, userID uuid.UUID, slug string) (noteID uuid.UUID, current string, err error)
+}
+
+var stripMarks = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+// Slugify turns a title into a URL path segment: lowercase ASCII letters and
+// digits separated by single hyphens. Accents are dropped ("Año" -> "ano").
+func Slugify(title string) string {
+	folded, _, err := transform.String(stripMarks, strings.ToLower(title))
+	if err != nil {
+		folded = strings.ToLower(title)
+	}
+
+	var b strings.Builder
+	hyphen := false
+	for _, r := range folded {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			hyphen = false
+			if b.Len() >= maxSlugLength {
+				break
+			}
+			b.WriteRune(r)
+		} else {
+			hyphen = true
+		}
+	}
+
+	if b.Len() == 0 {
+		return "note"
+	}
+	return b.String()
+}
+
+// slugFor is the n-th candidate slug for base: base, base-2, base-3...
+func slugFor(base string, n int) string {
+	if n == 1 {
+		return base
+	}
+	return base + "-" + strconv.Itoa(n)
+}
+
+// derivesFrom reports whether slug is base or base-N.
+func derivesFrom(slug, base string) bool {
+	if slug == base {
+		return true
+	}
+	suffix, ok := strings.CutPrefix(slug, base+"-")
+	if !ok {
+		return false
+	}
+	n, err := strconv.Atoi(suffix)
+	return err == nil && n > 1
+}
+
+// SlugIndex gives notes a slug when they are published. Slugs follow the
+// published title, not the draft, and only change when a new title is
+// published.
+type SlugIndex struct {
+	repo SlugRepository
+}
+
+// NewSlugIndex creates a new instance of SlugIndex.
+func NewSlugIndex(repo SlugRepository) *SlugIndex {
+	return &SlugIndex{repo: repo}
+}
+
+func (s *SlugIndex) NoteSaved(ctx context.Context, note *Note) {
+	if note.Published == nil {
+		return
+	}
+	if _, err := s.repo.AssignSlug(ctx, note.UserID, note.ID, Slugify(note.Published.Title)); err != nil {
+		log.Printf("failed to assign slug to note %s: %v", note.ID, err)
+	}
+}
+
+// NoteDeleted keeps the slugs: they stay reserved and resolve to nothing.
+func (s *SlugIndex) NoteDeleted(ctx context.Context, note *Note) {}
@@ -0,0 +1,50 @@
+package notes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSlugify(t *testing.T) {
+	cases := map[string]string{
+		"Grimoire roadmap":          "grimoire-roadmap",
+		"  Año nuevo, ¡vida nueva!": "ano-nuevo-vida-nueva",
+		"C++ & Go: 2024 notes":      "c-go-2024-notes",
+		"!!!":                       "note",
+	}
+	for title, want := range cases {
+		if got := Slugify(title); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", title, got, want)
+		}
+	}
+}
+
+func TestAssignSlugKeepsOldSlugsAsRedirects(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryNoteRepository()
+	userID, first, second := uuid.New(), uuid.New(), uuid.New()
+
+	if slug, _ := repo.AssignSlug(ctx, userID, first, "roadmap"); slug != "roadmap" {
+		t.Fatalf("expected %q, got %q", "roadmap", slug)
+	}
+	if slug, _ := repo.AssignSlug(ctx, userID, second, "roadmap"); slug != "roadmap-2" {
+		t.Fatalf("expected %q, got %q", "roadmap-2", slug)
+	}
+	// Republishing under the same title keeps the slug stable
+	if slug, _ := repo.AssignSlug(ctx, userID, second, "roadmap"); slug != "roadmap-2" {
+		t.Fatalf("expected %q to be kept, got %q", "roadmap-2", slug)
+	}
+
+	if slug, _ := repo.AssignSlug(ctx, userID, first, "roadmap-2025"); slug != "roadmap-2025" {
+		t.Fatalf("expected %q, got %q", "roadmap-2025", slug)
+	}
+	noteID, current, err := repo.ResolveSlug(ctx, userID, "roadmap")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if noteID != first || current != "roadmap-2025" {
+		t.Fatalf("expected old slug to point at %q, got %s %q", "roadmap-2025", noteID, current)
+	}
+}
@@ -0,0 +1,680 @@
+package notes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/database"
+)
+
+const selectSQLiteNoteQuery = `
+SELECT
+    n.id,
+    n.title,
+    n.content,
+    n.created_at,
+    n.updated_at,
+    n.user_id,
+    n.is_public,
+    (SELECT json_group_array(json_object('id', t.id, 'name', t.name))
+     FROM note_tags nt JOIN tags t ON nt.tag_id = t.id WHERE nt.note_id = n.id) AS tags,
+    n.forked_from_note_id,
+    n.forked_from_user_id,
+    (SELECT COUNT(*) FROM active_notes f WHERE f.forked_from_note_id = n.id) AS fork_count,
+    n.envelope,
+    n.properties,
+    n.published_title,
+    n.published_content,
+    n.published_at,
+    n.published_tags,
+    (SELECT s.slug FROM note_slugs s WHERE s.note_id = n.id ORDER BY s.assigned_at DESC LIMIT 1) AS slug,
+    n.publish_at,
+    n.expires_at,
+    COALESCE(n.expire_action, ''),
+    COALESCE(n.daily_date, ''),
+    n.remind_at,
+    n.version
+FROM
+    active_notes n
+`
+
+// scanSQLiteNote reads a row produced by selectSQLiteNoteQuery into a Note.
+func scanSQLiteNote(row rowScanner) (Note, error) {
+	var note Note
+	var tagsJSON, propertiesJSON string
+	var envelopeJSON, publishedTitle, publishedContent, publishedTagsJSON, slug *string
+	var publishedAt *time.Time
+	err := row.Scan(&note.ID, &note.Title, &note.Content, database.ScanTime{T: &note.CreatedAt}, database.ScanTime{T: &note.UpdatedAt}, &note.UserID, &note.IsPublic, &tagsJSON,
+		&note.ForkedFromID, &note.ForkedFromUserID, &note.ForkCount, &envelopeJSON, &propertiesJSON,
+		&publishedTitle, &publishedContent, database.ScanNullTime{T: &publishedAt}, &publishedTagsJSON, &slug, database.ScanNullTime{T: &note.PublishAt}, database.ScanNullTime{T: &note.ExpiresAt},
+		&note.ExpireAction, &note.DailyDate, database.ScanNullTime{T: &note.RemindAt}, &note.Version)
+	if err != nil {
+		return note, err
+	}
+	if envelopeJSON != nil {
+		if err := json.Unmarshal([]byte(*envelopeJSON), &note.Envelope); err != nil {
+			return note, fmt.Errorf("failed to unmarshal envelope for note %s: %w", note.ID, err)
+		}
+	}
+	note.Encrypted = note.Envelope != nil
+	if publishedAt != nil {
+		note.Published = &Snapshot{Title: *publishedTitle, Content: *publishedContent, PublishedAt: *publishedAt}
+		if slug != nil {
+			note.Published.Slug = *slug
+		}
+		if publishedTagsJSON != nil {
+			if err := json.Unmarshal([]byte(*publishedTagsJSON), &note.Published.Tags); err != nil {
+				return note, fmt.Errorf("failed to unmarshal published tags for note %s: %w", note.ID, err)
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(tagsJSON), &note.Tags); err != nil {
+		return note, fmt.Errorf("failed to unmarshal tags for note %s: %w", note.ID, err)
+	}
+	if err := json.Unmarshal([]byte(propertiesJSON), &note.Properties); err != nil {
+		return note, fmt.Errorf("failed to unmarshal properties for note %s: %w", note.ID, err)
+	}
+	return note, nil
+}
+
+// collectSQLiteNotes drains rows produced by selectSQLiteNoteQuery.
+func collectSQLiteNotes(rows *sql.Rows) ([]Note, error) {
+	defer rows.Close()
+
+	var notes []Note
+	for rows.Next() {
+		note, err := scanSQLiteNote(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan note row: %w", err)
+		}
+		notes = append(notes, note)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return notes, nil
+}
+
+// SQLiteNoteRepository implements the Repository interface for the embedded
+// SQLite backend. Text search goes through the notes_fts full-text index.
+type SQLiteNoteRepository struct {
+	DB *sql.DB
+}
+
+// NewSQLiteNoteRepository creates a new instance of SQLiteNoteRepository.
+func NewSQLiteNoteRepository(db *sql.DB) *SQLiteNoteRepository {
+	return &SQLiteNoteRepository{DB: db}
+}
+
+// jsonOrNil encodes v as JSON text, or nil when v is nil.
+func jsonOrNil[T any](v *T) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Create handles the creation of a new note and its associated tags.
+func (r *SQLiteNoteRepository) Create(ctx context.Context, note *Note) error {
+	envelope, err := jsonOrNil(note.envelopeOrNil())
+	if err != nil {
+		return fmt.Errorf("failed to encode envelope: %w", err)
+	}
+	properties, err := json.Marshal(note.propertiesOrEmpty())
+	if err != nil {
+		return fmt.Errorf("failed to encode properties: %w", err)
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback is a no-op if tx has been committed.
+
+	id := uuid.New()
+	created := time.Now().UTC()
+	now := database.Timestamp(created)
+	noteQuery := `
+        INSERT INTO notes (id, user_id, title, content, is_public, forked_from_note_id, forked_from_user_id, envelope, properties,
+                           publish_at, expires_at, expire_action, daily_date, remind_at, created_at, updated_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?, ?)`
+	_, err = tx.ExecContext(ctx, noteQuery, id, note.UserID, note.Title, note.Content, note.IsPublic, note.ForkedFromID, note.ForkedFromUserID, envelope, string(properties),
+		database.NullTimestamp(note.PublishAt), database.NullTimestamp(note.ExpiresAt), note.expireActionOrNil(), note.DailyDate, database.NullTimestamp(note.RemindAt), now, now)
+	if err != nil {
+		if database.IsSQLiteConstraint(err, "FOREIGN KEY constraint failed") {
+			return fmt.Errorf("user not found: %w", err)
+		}
+		if database.IsSQLiteConstraint(err, "UNIQUE constraint failed: notes.user_id, notes.daily_date") {
+			return ErrDailyNoteExists
+		}
+		return fmt.Errorf("failed to insert note: %w", err)
+	}
+
+	if err := linkSQLiteTags(ctx, tx, id, note.Tags); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit note: %w", err)
+	}
+
+	note.ID = id
+	note.CreatedAt = created
+	note.UpdatedAt = created
+	note.Version = 1
+	return nil
+}
+
+// linkSQLiteTags upserts the tags, lowercased, and links them to the note.
+func linkSQLiteTags(ctx context.Context, tx *sql.Tx, noteID uuid.UUID, tags []Tag) error {
+	for i, tag := range tags {
+		tagName := strings.ToLower(tag.Name)
+		tagQuery := `
+            INSERT INTO tags (id, name) VALUES (?, ?)
+            ON CONFLICT (name) DO UPDATE SET name = excluded.name
+            RETURNING id`
+		var tagID uuid.UUID
+		if err := tx.QueryRowContext(ctx, tagQuery, uuid.New(), tagName).Scan(&tagID); err != nil {
+			return fmt.Errorf("failed to upsert tag '%s': %w", tagName, err)
+		}
+		tags[i].ID = tagID
+		tags[i].Name = tagName
+
+		_, err := tx.ExecContext(ctx, "INSERT INTO note_tags (note_id, tag_id) VALUES (?, ?) ON CONFLICT DO NOTHING", noteID, tagID)
+		if err != nil {
+			return fmt.Errorf("failed to link tag to note: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetAll retrieves all non-deleted notes, along with their tags.
+func (r *SQLiteNoteRepository) GetAll(ctx context.Context) ([]Note, error) {
+	rows, err := r.DB.QueryContext(ctx, selectSQLiteNoteQuery+" ORDER BY n.created_at DESC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notes: %w", err)
+	}
+
+	return collectSQLiteNotes(rows)
+}
+
+// GetByUserID retrieves all notes for a specific user.
+func (r *SQLiteNoteRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]Note, error) {
+	rows, err := r.DB.QueryContext(ctx, selectSQLiteNoteQuery+" WHERE n.user_id = ? ORDER BY n.created_at DESC", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notes by user ID: %w", err)
+	}
+
+	return collectSQLiteNotes(rows)
+}
+
+// Find retrieves the user's notes that pass the filter, most recently updated
+// first unless the filter sorts by a property. Text of three or more
+// characters is looked up in the trigram index; shorter text, which the
+// index cannot match, falls back to LIKE. Properties are matched and sorted
+// in Go, the same way as in memory.
+func (r *SQLiteNoteRepository) Find(ctx context.Context, userID uuid.UUID, filter *Filter) ([]Note, error) {
+	args := []any{userID}
+	where := " WHERE n.user_id = ?"
+
+	if len(filter.Tags) > 0 {
+		lowerTags := make([]string, len(filter.Tags))
+		for i, t := range filter.Tags {
+			lowerTags[i] = strings.ToLower(t)
+		}
+		lowerTags = uniqueStrings(lowerTags)
+		where += `
+        AND (
+            SELECT COUNT(*)
+            FROM note_tags nt_sub
+            JOIN tags t_sub ON nt_sub.tag_id = t_sub.id
+            WHERE nt_sub.note_id = n.id AND t_sub.name IN (` + placeholders(len(lowerTags)) + `)
+        ) = ?`
+		for _, t := range lowerTags {
+			args = append(args, t)
+		}
+		args = append(args, len(lowerTags))
+	}
+	if filter.Text != "" {
+		if utf8.RuneCountInString(filter.Text) >= 3 {
+			where += " AND n.seq IN (SELECT rowid FROM notes_fts WHERE notes_fts MATCH ?)"
+			args = append(args, `"`+strings.ReplaceAll(filter.Text, `"`, `""`)+`"`)
+		} else {
+			pattern := "%" + likeEscaper.Replace(filter.Text) + "%"
+			where += ` AND (n.title LIKE ? ESCAPE '\' OR n.content LIKE ? ESCAPE '\')`
+			args = append(args, pattern, pattern)
+		}
+	}
+	bounds := []struct {
+		clause string
+		t      *time.Time
+	}{
+		{" AND n.created_at >= ?", filter.CreatedFrom},
+		{" AND n.created_at <= ?", filter.CreatedTo},
+		{" AND n.updated_at >= ?", filter.UpdatedFrom},
+		{" AND n.updated_at <= ?", filter.UpdatedTo},
+	}
+	for _, b := range bounds {
+		if b.t != nil {
+			where += b.clause
+			args = append(args, database.Timestamp(*b.t))
+		}
+	}
+
+	rows, err := r.DB.QueryContext(ctx, selectSQLiteNoteQuery+where+" ORDER BY n.updated_at DESC", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query filtered notes: %w", err)
+	}
+	notes, err := collectSQLiteNotes(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return FilterNotes(notes, &Filter{Properties: filter.Properties, Sort: filter.Sort}), nil
+}
+
+// placeholders returns n comma-separated parameter placeholders.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// GetByID retrieves a single note by its ID.
+func (r *SQLiteNoteRepository) GetByID(ctx context.Context, id string) (*Note, error) {
+	noteID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid note ID format: %w", err)
+	}
+
+	note, err := scanSQLiteNote(r.DB.QueryRowContext(ctx, selectSQLiteNoteQuery+" WHERE n.id = ?", noteID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoteNotFound
+		}
+		return nil, fmt.Errorf("failed to scan note: %w", err)
+	}
+
+	return &note, nil
+}
+
+// GetDaily retrieves the user's daily note for the given date.
+func (r *SQLiteNoteRepository) GetDaily(ctx context.Context, userID uuid.UUID, date string) (*Note, error) {
+	query := selectSQLiteNoteQuery + " WHERE n.user_id = ? AND n.daily_date = ?"
+	note, err := scanSQLiteNote(r.DB.QueryRowContext(ctx, query, userID, date))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoDailyNote
+		}
+		return nil, fmt.Errorf("failed to scan daily note: %w", err)
+	}
+	return &note, nil
+}
+
+// GetByTags retrieves all notes that have at least one of the specified tags.
+func (r *SQLiteNoteRepository) GetByTags(ctx context.Context, tags []string) ([]Note, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	args := make([]any, len(tags))
+	for i, t := range tags {
+		args[i] = strings.ToLower(t)
+	}
+
+	query := selectSQLiteNoteQuery + `
+        WHERE EXISTS (
+            SELECT 1
+            FROM note_tags nt_sub
+            JOIN tags t_sub ON nt_sub.tag_id = t_sub.id
+            WHERE nt_sub.note_id = n.id AND t_sub.name IN (` + placeholders(len(tags)) + `)
+        )
+        ORDER BY n.created_at DESC`
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notes by tags: %w", err)
+	}
+
+	return collectSQLiteNotes(rows)
+}
+
+// Update handles the modification of a note's details and its tags.
+func (r *SQLiteNoteRepository) Update(ctx context.Context, note *Note) error {
+	envelope, err := jsonOrNil(note.envelopeOrNil())
+	if err != nil {
+		return fmt.Errorf("failed to encode envelope: %w", err)
+	}
+	properties, err := json.Marshal(note.propertiesOrEmpty())
+	if err != nil {
+		return fmt.Errorf("failed to encode properties: %w", err)
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	updateQuery := `
+        UPDATE notes
+        SET title = ?, content = ?, is_public = ?, envelope = ?, properties = ?,
+            publish_at = ?, expires_at = ?, expire_action = ?, remind_at = ?,
+            version = version + 1, updated_at = ?
+        WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)
+        RETURNING updated_at, version`
+	err = tx.QueryRowContext(ctx, updateQuery, note.Title, note.Content, note.IsPublic, envelope, string(properties),
+		database.NullTimestamp(note.PublishAt), database.NullTimestamp(note.ExpiresAt), note.expireActionOrNil(), database.NullTimestamp(note.RemindAt),
+		database.Timestamp(time.Now()), note.ID, note.Version, note.Version).Scan(database.ScanTime{T: &note.UpdatedAt}, &note.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			var exists bool
+			if note.Version != 0 && tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM active_notes WHERE id = ?)", note.ID).Scan(&exists) == nil && exists {
+				return ErrVersionConflict
+			}
+			return fmt.Errorf("note not found or already deleted")
+		}
+		return fmt.Errorf("failed to update note: %w", err)
+	}
+
+	// Replace the note's tags
+	if _, err := tx.ExecContext(ctx, "DELETE FROM note_tags WHERE note_id = ?", note.ID); err != nil {
+		return fmt.Errorf("failed to clear existing tags: %w", err)
+	}
+	if err := linkSQLiteTags(ctx, tx, note.ID, note.Tags); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Publish copies the draft title, content and tags into the published
+// snapshot. Fields encrypted at rest are copied sealed.
+func (r *SQLiteNoteRepository) Publish(ctx context.Context, id string) error {
+	query := `
+        UPDATE notes
+        SET published_title = title, published_content = content, published_at = ?, is_public = 1,
+            published_tags = (
+                SELECT json_group_array(json_object('id', t.id, 'name', t.name))
+                FROM (SELECT t.id, t.name FROM note_tags nt JOIN tags t ON nt.tag_id = t.id
+                      WHERE nt.note_id = notes.id ORDER BY t.name) t)
+        WHERE id = ? AND deleted_at IS NULL`
+	return r.execOnLiveNote(ctx, "publish note", query, database.Timestamp(time.Now()), id)
+}
+
+// Unpublish drops the published snapshot and makes the note private.
+func (r *SQLiteNoteRepository) Unpublish(ctx context.Context, id string) error {
+	query := `
+        UPDATE notes
+        SET published_title = NULL, published_content = NULL, published_at = NULL, published_tags = NULL, is_public = 0
+        WHERE id = ? AND deleted_at IS NULL`
+	return r.execOnLiveNote(ctx, "unpublish note", query, id)
+}
+
+// execOnLiveNote runs a statement that must change one live note.
+func (r *SQLiteNoteRepository) execOnLiveNote(ctx context.Context, what, query string, args ...any) error {
+	result, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to %s: %w", what, err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return fmt.Errorf("note not found or already deleted")
+	}
+	return nil
+}
+
+// GetPublished retrieves a page of published notes, most recently published
+// first, along with the total count.
+func (r *SQLiteNoteRepository) GetPublished(ctx context.Context, limit, offset int) ([]Note, int, error) {
+	where := " WHERE n.is_public AND n.published_at IS NOT NULL"
+
+	var total int
+	if err := r.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM active_notes n"+where).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count published notes: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, selectSQLiteNoteQuery+where+" ORDER BY n.published_at DESC LIMIT ? OFFSET ?", limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query published notes: %w", err)
+	}
+	notes, err := collectSQLiteNotes(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return notes, total, nil
+}
+
+// ClaimDue leases the notes with a publish or expiry time at or before now,
+// or a reminder due and not yet delivered. Claiming is one UPDATE, so two
+// processes sharing the database file cannot claim the same notes.
+func (r *SQLiteNoteRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]ScheduledAction, error) {
+	query := `
+        UPDATE notes
+        SET schedule_lease_until = ?2
+        WHERE id IN (
+            SELECT id FROM notes
+            WHERE deleted_at IS NULL
+              AND (publish_at <= ?1 OR expires_at <= ?1
+                   OR (remind_at <= ?1 AND remind_at IS NOT reminded_at))
+              AND (schedule_lease_until IS NULL OR schedule_lease_until < ?1)
+            ORDER BY MIN(COALESCE(publish_at, expires_at, remind_at),
+                         COALESCE(expires_at, remind_at, publish_at),
+                         COALESCE(remind_at, publish_at, expires_at))
+            LIMIT ?3
+        )
+        RETURNING id, publish_at, expires_at, COALESCE(expire_action, ''), remind_at, reminded_at`
+	rows, err := r.DB.QueryContext(ctx, query, database.Timestamp(now), database.Timestamp(now.Add(lease)), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim scheduled notes: %w", err)
+	}
+	defer rows.Close()
+
+	var actions []ScheduledAction
+	for rows.Next() {
+		var id uuid.UUID
+		var publishAt, expiresAt, remindAt, remindedAt *time.Time
+		var action ExpireAction
+		err := rows.Scan(&id, database.ScanNullTime{T: &publishAt}, database.ScanNullTime{T: &expiresAt}, &action,
+			database.ScanNullTime{T: &remindAt}, database.ScanNullTime{T: &remindedAt})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled note: %w", err)
+		}
+		if publishAt != nil && !publishAt.After(now) {
+			actions = append(actions, ScheduledAction{NoteID: id, Kind: SchedulePublish, At: *publishAt})
+		}
+		if expiresAt != nil && !expiresAt.After(now) {
+			actions = append(actions, ScheduledAction{NoteID: id, Kind: ScheduleExpire, Expire: action, At: *expiresAt})
+		}
+		if reminderDue(remindAt, remindedAt, now) {
+			actions = append(actions, ScheduledAction{NoteID: id, Kind: ScheduleRemind, At: *remindAt})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return actions, nil
+}
+
+// Complete clears a carried out action from the note's schedule, or marks
+// a reminder delivered, unless it was rescheduled since the claim. It also
+// applies to notes the action just soft-deleted.
+func (r *SQLiteNoteRepository) Complete(ctx context.Context, action ScheduledAction) error {
+	var query string
+	switch action.Kind {
+	case SchedulePublish:
+		query = "UPDATE notes SET publish_at = NULL WHERE id = ? AND publish_at = ?"
+	case ScheduleRemind:
+		query = "UPDATE notes SET reminded_at = remind_at WHERE id = ? AND remind_at = ?"
+	default:
+		query = "UPDATE notes SET expires_at = NULL, expire_action = NULL WHERE id = ? AND expires_at = ?"
+	}
+	if _, err := r.DB.ExecContext(ctx, query, action.NoteID, database.Timestamp(action.At)); err != nil {
+		return fmt.Errorf("failed to complete scheduled action: %w", err)
+	}
+	return nil
+}
+
+// AssignSlug gives the note its current slug, reusing one it held before
+// when possible.
+func (r *SQLiteNoteRepository) AssignSlug(ctx context.Context, userID, noteID uuid.UUID, base string) (string, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var current string
+	err = tx.QueryRowContext(ctx, "SELECT slug FROM note_slugs WHERE note_id = ? ORDER BY assigned_at DESC LIMIT 1", noteID).Scan(&current)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("failed to look up current slug: %w", err)
+	}
+	if current != "" && derivesFrom(current, base) {
+		return current, nil
+	}
+
+	now := database.Timestamp(time.Now())
+	for n := 1; ; n++ {
+		slug := slugFor(base, n)
+		result, err := tx.ExecContext(ctx, `
+            INSERT INTO note_slugs (user_id, slug, note_id, assigned_at) VALUES (?1, ?2, ?3, ?4)
+            ON CONFLICT (user_id, slug) DO UPDATE SET assigned_at = ?4
+            WHERE note_slugs.note_id = excluded.note_id`, userID, slug, noteID, now)
+		if err != nil {
+			return "", fmt.Errorf("failed to assign slug: %w", err)
+		}
+		if affected, _ := result.RowsAffected(); affected == 1 { // new, or an old slug of this note made current again
+			return slug, tx.Commit()
+		}
+	}
+}
+
+// ResolveSlug finds the note behind one of a user's slugs.
+func (r *SQLiteNoteRepository) ResolveSlug(ctx context.Context, userID uuid.UUID, slug string) (uuid.UUID, string, error) {
+	query := `
+        SELECT s.note_id, (SELECT c.slug FROM note_slugs c WHERE c.note_id = s.note_id ORDER BY c.assigned_at DESC LIMIT 1)
+        FROM note_slugs s
+        WHERE s.user_id = ? AND s.slug = ?`
+	var noteID uuid.UUID
+	var current string
+	if err := r.DB.QueryRowContext(ctx, query, userID, slug).Scan(&noteID, &current); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, "", fmt.Errorf("slug not found")
+		}
+		return uuid.Nil, "", fmt.Errorf("failed to resolve slug: %w", err)
+	}
+	return noteID, current, nil
+}
+
+// Stats computes a user's writing statistics with ComputeStats.
+func (r *SQLiteNoteRepository) Stats(ctx context.Context, userID uuid.UUID) (*Stats, error) {
+	notes, err := r.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return ComputeStats(notes), nil
+}
+
+// SearchTitles fuzzily matches the user's note titles with MatchTitles.
+func (r *SQLiteNoteRepository) SearchTitles(ctx context.Context, userID uuid.UUID, query string, limit int) ([]TitleMatch, error) {
+	rows, err := r.DB.QueryContext(ctx, "SELECT id, title FROM active_notes WHERE user_id = ?", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query note titles: %w", err)
+	}
+	defer rows.Close()
+
+	var notes []Note
+	for rows.Next() {
+		var note Note
+		if err := rows.Scan(&note.ID, &note.Title); err != nil {
+			return nil, fmt.Errorf("failed to scan note title: %w", err)
+		}
+		notes = append(notes, note)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return MatchTitles(notes, query, limit), nil
+}
+
+// IsSharedWith reports whether the note has been shared with the given user.
+func (r *SQLiteNoteRepository) IsSharedWith(ctx context.Context, noteID, userID uuid.UUID) (bool, error) {
+	var shared bool
+	query := `SELECT EXISTS (SELECT 1 FROM shared_notes WHERE note_id = ? AND shared_with_user_id = ?)`
+	if err := r.DB.QueryRowContext(ctx, query, noteID, userID).Scan(&shared); err != nil {
+		return false, fmt.Errorf("failed to check note share: %w", err)
+	}
+	return shared, nil
+}
+
+// Delete performs a soft delete on a note.
+func (r *SQLiteNoteRepository) Delete(ctx context.Context, id string) error {
+	noteID, err := uuid.Parse(id)
+	if err != nil {
+		return fmt.Errorf("invalid note ID format: %w", err)
+	}
+
+	now := database.Timestamp(time.Now())
+	query := `
+        UPDATE notes
+        SET deleted_at = ?, updated_at = ?
+        WHERE id = ? AND deleted_at IS NULL`
+	return r.execOnLiveNote(ctx, "soft delete note", query, now, now, noteID)
+}
+
+// GetLock retrieves the live lock on the note.
+func (r *SQLiteNoteRepository) GetLock(ctx context.Context, noteID uuid.UUID, now time.Time) (*NoteLock, error) {
+	lock := NoteLock{NoteID: noteID}
+	err := r.DB.QueryRowContext(ctx, `
+        SELECT user_id, token, owner, version, expires_at
+        FROM note_locks WHERE note_id = ? AND expires_at > ?`, noteID, database.Timestamp(now)).
+		Scan(&lock.UserID, &lock.Token, &lock.Owner, &lock.Version, database.ScanTime{T: &lock.ExpiresAt})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotLocked
+		}
+		return nil, fmt.Errorf("failed to get note lock: %w", err)
+	}
+	return &lock, nil
+}
+
+// PutLock takes, renews or updates the lock in one statement, so two
+// clients racing for a note cannot both win.
+func (r *SQLiteNoteRepository) PutLock(ctx context.Context, lock *NoteLock, now time.Time) error {
+	query := `
+        INSERT INTO note_locks (note_id, user_id, token, owner, version, expires_at)
+        VALUES (?, ?, ?, ?, ?, ?)
+        ON CONFLICT (note_id) DO UPDATE
+        SET user_id = excluded.user_id, token = excluded.token, owner = excluded.owner,
+            version = excluded.version, expires_at = excluded.expires_at
+        WHERE note_locks.token = excluded.token OR note_locks.expires_at <= ?`
+	result, err := r.DB.ExecContext(ctx, query, lock.NoteID, lock.UserID, lock.Token, lock.Owner, lock.Version,
+		database.Timestamp(lock.ExpiresAt), database.Timestamp(now))
+	if err != nil {
+		return fmt.Errorf("failed to put note lock: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrLocked
+	}
+	return nil
+}
+
+// DeleteLock releases the lock held by token.
+func (r *SQLiteNoteRepository) DeleteLock(ctx context.Context, noteID uuid.UUID, token string) error {
+	result, err := r.DB.ExecContext(ctx, "DELETE FROM note_locks WHERE note_id = ? AND token = ?", noteID, token)
+	if err != nil {
+		return fmt.Errorf("failed to delete note lock: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotLocked
+	}
+	return nil
+}
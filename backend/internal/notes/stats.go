@@ -0,0 +1,175 @@
+package notes
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// wordsPerMinute is the reading speed used for ReadingMinutes.
+const wordsPerMinute = 200
+
+// dayLayout formats the days of the activity heatmap.
+const dayLayout = "2006-01-02"
+
+// Stats summarizes a user's writing.
+type Stats struct {
+	TotalNotes     int           `json:"total_notes"`
+	PublicNotes    int           `json:"public_notes"`
+	EncryptedNotes int           `json:"encrypted_notes"`
+	Words          int           `json:"words"`
+	ReadingMinutes int           `json:"reading_minutes"`
+	Tags           []TagCount    `json:"tags"`
+	Activity       []DayActivity `json:"activity"` // only days with activity, oldest first
+	LongestStreak  int           `json:"longest_streak"`
+	GeneratedAt    time.Time     `json:"generated_at"`
+}
+
+type TagCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// DayActivity counts notes created and last updated on a day (UTC).
+type DayActivity struct {
+	Date    string `json:"date"`
+	Created int    `json:"created"`
+	Updated int    `json:"updated"`
+}
+
+// ComputeStats builds Stats from a user's active notes. It is the in-process
+// equivalent of the aggregate queries run by PgNoteRepository.Stats.
+func ComputeStats(notes []Note) *Stats {
+	stats := &Stats{}
+	tagCounts := make(map[string]int)
+	days := make(map[string]*DayActivity)
+	day := func(t time.Time) *DayActivity {
+		key := t.UTC().Format(dayLayout)
+		if days[key] == nil {
+			days[key] = &DayActivity{Date: key}
+		}
+		return days[key]
+	}
+
+	for _, n := range notes {
+		stats.TotalNotes++
+		if n.IsPublic {
+			stats.PublicNotes++
+		}
+		if n.Encrypted {
+			stats.EncryptedNotes++
+		}
+		stats.Words += len(strings.Fields(n.Content))
+		for _, tag := range n.Tags {
+			tagCounts[tag.Name]++
+		}
+		day(n.CreatedAt).Created++
+		if n.UpdatedAt.After(n.CreatedAt) {
+			day(n.UpdatedAt).Updated++
+		}
+	}
+
+	stats.Tags = make([]TagCount, 0, len(tagCounts))
+	for name, count := range tagCounts {
+		stats.Tags = append(stats.Tags, TagCount{Name: name, Count: count})
+	}
+	sortTagCounts(stats.Tags)
+
+	stats.Activity = make([]DayActivity, 0, len(days))
+	for _, d := range days {
+		stats.Activity = append(stats.Activity, *d)
+	}
+	sort.Slice(stats.Activity, func(i, j int) bool { return stats.Activity[i].Date < stats.Activity[j].Date })
+
+	stats.finish()
+	return stats
+}
+
+// finish fills in the fields derived from the raw aggregates.
+func (s *Stats) finish() {
+	s.ReadingMinutes = (s.Words + wordsPerMinute - 1) / wordsPerMinute
+	s.LongestStreak = longestStreak(s.Activity)
+	s.GeneratedAt = time.Now().UTC()
+}
+
+func sortTagCounts(tags []TagCount) {
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Name < tags[j].Name
+	})
+}
+
+// longestStreak returns the longest run of consecutive days with activity.
+// days must be sorted oldest first.
+func longestStreak(days []DayActivity) int {
+	longest, current := 0, 0
+	var previous time.Time
+	for _, d := range days {
+		date, err := time.Parse(dayLayout, d.Date)
+		if err != nil {
+			continue
+		}
+		if current > 0 && date.Sub(previous) == 24*time.Hour {
+			current++
+		} else {
+			current = 1
+		}
+		previous = date
+		if current > longest {
+			longest = current
+		}
+	}
+	return longest
+}
+
+// StatsCache keeps computed Stats per user until one of their notes changes
+// or the entry expires. It is a NoteObserver.
+type StatsCache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	entries map[uuid.UUID]*Stats
+}
+
+func NewStatsCache(ttl time.Duration) *StatsCache {
+	return &StatsCache{
+		ttl:     ttl,
+		entries: make(map[uuid.UUID]*Stats),
+	}
+}
+
+func (c *StatsCache) Get(userID uuid.UUID) (*Stats, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	stats, ok := c.entries[userID]
+	if !ok || time.Since(stats.GeneratedAt) > c.ttl {
+		return nil, false
+	}
+	return stats, true
+}
+
+func (c *StatsCache) Put(userID uuid.UUID, stats *Stats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[userID] = stats
+}
+
+func (c *StatsCache) Invalidate(userID uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, userID)
+}
+
+func (c *StatsCache) NoteSaved(ctx context.Context, note *Note) {
+	c.Invalidate(note.UserID)
+}
+
+func (c *StatsCache) NoteDeleted(ctx context.Context, note *Note) {
+	c.Invalidate(note.UserID)
+}
@@ -0,0 +1,63 @@
+package notes
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/middleware"
+	"github.com/jehufrayle/grimoire/utils"
+)
+
+type StatsHandler struct {
+	repo  NoteRepository
+	cache *StatsCache
+}
+
+// NewStatsHandler expects repo to notify cache of writes, e.g. through an
+// ObservedNoteRepository, so cached stats never outlive a change.
+func NewStatsHandler(repo NoteRepository, cache *StatsCache) *StatsHandler {
+	return &StatsHandler{repo: repo, cache: cache}
+}
+
+func (h *StatsHandler) GetMyStats(w http.ResponseWriter, r *http.Request) {
+	userIDstr, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	userID, err := uuid.Parse(userIDstr)
+	if err != nil {
+		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	stats, ok := h.cache.Get(userID)
+	if !ok {
+		stats, err = h.repo.Stats(r.Context(), userID)
+		if err != nil {
+			http.Error(w, "Failed to compute stats", http.StatusInternalServerError)
+			return
+		}
+		h.cache.Put(userID, stats)
+	}
+
+	// The ETag covers the cached entry, so clients revalidate cheaply until a note changes
+	body, err := json.Marshal(stats)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to serialize response"}`, http.StatusInternalServerError)
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	utils.JSONResponse(w, stats, http.StatusOK)
+}
@@ -0,0 +1,19 @@
+package notes
+
+// Storage is a complete note storage backend: the repository itself plus
+// the side stores the scheduler, slugs and WebDAV locks rely on.
+// PgNoteRepository, SQLiteNoteRepository, InMemoryNoteRepository and
+// GitNoteRepository are all Storages.
+type Storage interface {
+	NoteRepository
+	ScheduleStore
+	SlugRepository
+	LockStore
+}
+
+var (
+	_ Storage = (*PgNoteRepository)(nil)
+	_ Storage = (*SQLiteNoteRepository)(nil)
+	_ Storage = (*InMemoryNoteRepository)(nil)
+	_ Storage = (*GitNoteRepository)(nil)
+)
@@ -0,0 +1,258 @@
+package notes
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// maxEmbedDepth caps how deeply embeds may nest.
+const maxEmbedDepth = 5
+
+// maxEmbeds and maxEmbeddedBytes cap how much one Expand call embeds, so a
+// few notes embedding each other several times cannot fan out into an
+// exponentially large page.
+const (
+	maxEmbeds        = 200
+	maxEmbeddedBytes = 1 << 20
+)
+
+// embedPattern matches ![[Title]] and ![[Title#Heading]].
+var embedPattern = regexp.MustCompile(`!\[\[([^\[\]#|]+)(?:#([^\[\]|]+))?\]\]`)
+
+var headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+
+// Transcluder expands ![[Title]] embeds with the content of other notes of
+// the same owner. Every embedded note is checked against the reader, so a
+// note the reader cannot open never shows up inside one they can.
+type Transcluder struct {
+	repo NoteRepository
+}
+
+// NewTranscluder creates a new instance of Transcluder.
+func NewTranscluder(repo NoteRepository) *Transcluder {
+	return &Transcluder{repo: repo}
+}
+
+// Expand returns the note's Markdown body with embeds resolved as readerID
+// sees them. Anonymous readers pass uuid.Nil and only ever see published
+// snapshots. note itself must already be the version the reader may see.
+func (t *Transcluder) Expand(ctx context.Context, note *Note, readerID uuid.UUID) (string, error) {
+	e := &expansion{
+		repo:     t.repo,
+		reader:   readerID,
+		owners:   make(map[uuid.UUID][]Note),
+		readable: make(map[uuid.UUID]*Note),
+		expanded: make(map[embedKey]string),
+	}
+	return e.expand(ctx, note.UserID, stripFrontMatter(note.Content), []uuid.UUID{note.ID})
+}
+
+// expansion holds the state of one Expand call.
+type expansion struct {
+	repo     NoteRepository
+	reader   uuid.UUID
+	owners   map[uuid.UUID][]Note // notes per owner, loaded once
+	readable map[uuid.UUID]*Note  // the version the reader may see, nil if none
+	expanded map[embedKey]string  // each embed expanded once
+	embeds   int                  // embeds made so far
+	size     int                  // bytes embedded so far
+	cuts     int                  // embeds cut for a cycle or for depth
+}
+
+// embedKey identifies an embed by the note and heading it takes in.
+type embedKey struct {
+	noteID  uuid.UUID
+	heading string
+}
+
+func (e *expansion) expand(ctx context.Context, ownerID uuid.UUID, text string, stack []uuid.UUID) (string, error) {
+	lines := strings.Split(text, "\n")
+	fenced := false
+	for i, line := range lines {
+		if isFence(line) {
+			fenced = !fenced
+			continue
+		}
+		if fenced || !strings.Contains(line, "![[") {
+			continue
+		}
+
+		var err error
+		lines[i] = embedPattern.ReplaceAllStringFunc(line, func(match string) string {
+			if err != nil {
+				return match
+			}
+			groups := embedPattern.FindStringSubmatch(match)
+			var embedded string
+			embedded, err = e.embed(ctx, ownerID, strings.TrimSpace(groups[1]), strings.TrimSpace(groups[2]), stack)
+			return embedded
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (e *expansion) embed(ctx context.Context, ownerID uuid.UUID, title, heading string, stack []uuid.UUID) (string, error) {
+	label := title
+	if heading != "" {
+		label += "#" + heading
+	}
+
+	target, err := e.find(ctx, ownerID, title)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case target == nil: // missing and forbidden look the same, so nothing leaks
+		return placeholder(label, "not found"), nil
+	case slices.Contains(stack, target.ID):
+		e.cuts++
+		return placeholder(label, "embeds itself"), nil
+	case len(stack) > maxEmbedDepth:
+		e.cuts++
+		return placeholder(label, "is nested too deeply"), nil
+	case target.Encrypted:
+		return placeholder(label, "is encrypted"), nil
+	}
+
+	key := embedKey{noteID: target.ID, heading: strings.ToLower(heading)}
+	if embedded, ok := e.expanded[key]; ok {
+		if !e.spend(len(embedded)) {
+			return placeholder(label, "was left out, the page embeds too much"), nil
+		}
+		return embedded, nil
+	}
+
+	body := stripFrontMatter(target.Content)
+	if heading != "" {
+		section, ok := headingSection(body, heading)
+		if !ok {
+			return placeholder(label, "not found"), nil
+		}
+		body = section
+	}
+	// Nested embeds spend their own share, so only this note's text counts
+	if !e.spend(len(body)) {
+		return placeholder(label, "was left out, the page embeds too much"), nil
+	}
+
+	cuts := e.cuts
+	expanded, err := e.expand(ctx, target.UserID, body, append(stack, target.ID))
+	if err != nil {
+		return "", err
+	}
+	embedded := "\n\n" + strings.TrimSpace(expanded) + "\n\n"
+	// A cut depends on where the embed sits, so such an expansion is not reused
+	if e.cuts == cuts {
+		e.expanded[key] = embedded
+	}
+	return embedded, nil
+}
+
+// spend counts an embed of size bytes against the budget of the Expand call
+// and reports whether it fits.
+func (e *expansion) spend(size int) bool {
+	if e.embeds >= maxEmbeds || e.size+size > maxEmbeddedBytes {
+		return false
+	}
+	e.embeds++
+	e.size += size
+	return true
+}
+
+// find returns the version of the owner's note titled title that the reader
+// may see, or nil.
+func (e *expansion) find(ctx context.Context, ownerID uuid.UUID, title string) (*Note, error) {
+	candidates, ok := e.owners[ownerID]
+	if !ok {
+		var err error
+		candidates, err = e.repo.GetByUserID(ctx, ownerID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load notes to embed: %w", err)
+		}
+		e.owners[ownerID] = candidates
+	}
+
+	for i := range candidates {
+		n := &candidates[i]
+		if !strings.EqualFold(n.Title, title) && (n.Published == nil || !strings.EqualFold(n.Published.Title, title)) {
+			continue
+		}
+		version, err := e.readableVersion(ctx, n)
+		if err != nil {
+			return nil, err
+		}
+		if version != nil && strings.EqualFold(version.Title, title) {
+			return version, nil
+		}
+	}
+	return nil, nil
+}
+
+// readableVersion is ReadableVersion, decided once per note.
+func (e *expansion) readableVersion(ctx context.Context, n *Note) (*Note, error) {
+	if version, ok := e.readable[n.ID]; ok {
+		return version, nil
+	}
+	version, err := ReadableVersion(ctx, e.repo, n, e.reader)
+	if err != nil {
+		return nil, err
+	}
+	e.readable[n.ID] = version
+	return version, nil
+}
+
+func placeholder(label, reason string) string {
+	return fmt.Sprintf("> *Embedded note “%s” %s.*", label, reason)
+}
+
+func stripFrontMatter(content string) string {
+	if _, body, ok := SplitFrontMatter(content); ok {
+		return body
+	}
+	return content
+}
+
+func isFence(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")
+}
+
+// headingSection returns the heading matching name and everything under it
+// up to the next heading of the same or a higher level.
+func headingSection(body, name string) (string, bool) {
+	lines := strings.Split(body, "\n")
+	start, level := -1, 0
+	fenced := false
+	for i, line := range lines {
+		if isFence(line) {
+			fenced = !fenced
+			continue
+		}
+		if fenced {
+			continue
+		}
+		m := headingPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		if start < 0 {
+			if strings.EqualFold(m[2], name) {
+				start, level = i, len(m[1])
+			}
+		} else if len(m[1]) <= level {
+			return strings.Join(lines[start:i], "\n"), true
+		}
+	}
+	if start < 0 {
+		return "", false
+	}
+	return strings.Join(lines[start:], "\n"), true
+}
@@ -0,0 +1,125 @@
+package notes
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func createNote(t *testing.T, repo *InMemoryNoteRepository, userID uuid.UUID, title, content string) *Note {
+	t.Helper()
+	note := &Note{Title: title, Content: content, UserID: userID}
+	if err := repo.Create(context.Background(), note); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return note
+}
+
+func TestExpandEmbedsHeadingSection(t *testing.T) {
+	repo := NewInMemoryNoteRepository()
+	owner := uuid.New()
+	createNote(t, repo, owner, "Recipes", "# Recipes\n\n## Bread\nFlour and water.\n\n## Soup\nStock.")
+	root := createNote(t, repo, owner, "Menu", "Today:\n![[recipes#Bread]]")
+
+	got, err := NewTranscluder(repo).Expand(context.Background(), root, owner)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(got, "## Bread\nFlour and water.") || strings.Contains(got, "Stock.") {
+		t.Fatalf("expected only the Bread section, got %q", got)
+	}
+}
+
+func TestExpandDetectsCycles(t *testing.T) {
+	repo := NewInMemoryNoteRepository()
+	owner := uuid.New()
+	a := createNote(t, repo, owner, "A", "A embeds ![[B]]")
+	createNote(t, repo, owner, "B", "B embeds ![[A]]")
+
+	got, err := NewTranscluder(repo).Expand(context.Background(), a, owner)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(got, "B embeds") || !strings.Contains(got, "“A” embeds itself") {
+		t.Fatalf("expected the cycle to be cut, got %q", got)
+	}
+}
+
+func TestExpandHidesNotesTheReaderCannotOpen(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryNoteRepository()
+	owner := uuid.New()
+	createNote(t, repo, owner, "Diary", "secret")
+	public := createNote(t, repo, owner, "Blog", "Hello ![[Diary]]")
+	if err := repo.Publish(ctx, public.ID.String()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	published, _ := repo.GetByID(ctx, public.ID.String())
+
+	got, err := NewTranscluder(repo).Expand(ctx, published.PublishedVersion(), uuid.Nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(got, "secret") || !strings.Contains(got, "“Diary” not found") {
+		t.Fatalf("expected the private note to stay hidden, got %q", got)
+	}
+}
+
+// sharingCounter counts the access checks made against the repository.
+type sharingCounter struct {
+	*InMemoryNoteRepository
+	checks int
+}
+
+func (r *sharingCounter) IsSharedWith(ctx context.Context, noteID, userID uuid.UUID) (bool, error) {
+	r.checks++
+	return r.InMemoryNoteRepository.IsSharedWith(ctx, noteID, userID)
+}
+
+func TestExpandReusesRepeatedEmbeds(t *testing.T) {
+	ctx := context.Background()
+	repo := &sharingCounter{InMemoryNoteRepository: NewInMemoryNoteRepository()}
+	owner := uuid.New()
+	root := createNote(t, repo.InMemoryNoteRepository, owner, "A", "![[B]] ![[B]]")
+	createNote(t, repo.InMemoryNoteRepository, owner, "B", "![[C]] ![[C]]")
+	createNote(t, repo.InMemoryNoteRepository, owner, "C", "leaf")
+
+	got, err := NewTranscluder(repo).Expand(ctx, root, uuid.New())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Count(got, "not found") != 2 {
+		t.Fatalf("expected both embeds of the private B to be hidden, got %q", got)
+	}
+	if repo.checks != 1 {
+		t.Errorf("expected one access check for B, got %d", repo.checks)
+	}
+
+	got, err = NewTranscluder(repo).Expand(ctx, root, owner)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Count(got, "leaf") != 4 {
+		t.Fatalf("expected C four times, got %q", got)
+	}
+}
+
+func TestExpandStopsAtTheEmbedBudget(t *testing.T) {
+	repo := NewInMemoryNoteRepository()
+	owner := uuid.New()
+	createNote(t, repo, owner, "Big", strings.Repeat("x", maxEmbeddedBytes/4-100))
+	root := createNote(t, repo, owner, "Page", strings.Repeat("![[Big]]\n", 6))
+
+	got, err := NewTranscluder(repo).Expand(context.Background(), root, owner)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := strings.Count(got, "“Big” was left out"); n != 2 {
+		t.Fatalf("expected the last two embeds to be left out, got %d", n)
+	}
+	if len(got) > maxEmbeddedBytes+1024 {
+		t.Errorf("expected the page to stay within the budget, got %d bytes", len(got))
+	}
+}
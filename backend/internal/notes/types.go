@@ -16,6 +16,52 @@ type Note struct {
 	UserID    uuid.UUID  `json:"user_id"`
 	IsPublic  bool       `json:"is_public"`
 	Tags      []Tag      `json:"tags"`
+
+	// Fork metadata. ForkedFromID and ForkedFromUserID point at the note this
+	// one was copied from; ForkCount is how many active notes were forked from it.
+	ForkedFromID     *uuid.UUID `json:"forked_from_id,omitempty"`
+	ForkedFromUserID *uuid.UUID `json:"forked_from_user_id,omitempty"`
+	ForkCount        int        `json:"fork_count"`
+
+	// Encrypted notes are end-to-end encrypted by the client: Content is
+	// empty and the ciphertext lives in Envelope.
+	Encrypted bool      `json:"encrypted"`
+	Envelope  *Envelope `json:"envelope,omitempty"`
+
+	// Properties are typed custom fields validated against the owner's
+	// PropertyDefinitions and mirrored in the content's YAML front matter.
+	Properties map[string]any `json:"properties,omitempty"`
+
+	// Published is the snapshot public readers see. Title and Content above
+	// are the owner's working draft.
+	Published *Snapshot `json:"published,omitempty"`
+
+	// Scheduling. At PublishAt the draft is published; at ExpiresAt the
+	// note is deleted or unpublished, as ExpireAction says.
+	PublishAt    *time.Time   `json:"publish_at,omitempty"`
+	ExpiresAt    *time.Time   `json:"expires_at,omitempty"`
+	ExpireAction ExpireAction `json:"expire_action,omitempty"`
+
+	// DailyDate is set on daily journal notes to the day, YYYY-MM-DD in the
+	// owner's time zone, the note is for. It is fixed at creation.
+	DailyDate string `json:"daily_date,omitempty"`
+
+	// RemindAt is when the owner wants to be reminded of the note. Due dates
+	// are a date property named "due"; both show up in the calendar feed.
+	RemindAt *time.Time `json:"remind_at,omitempty"`
+
+	// Version counts updates. An Update carrying a non-zero Version only
+	// applies if the stored note is still at that version.
+	Version int `json:"version"`
+}
+
+// Snapshot is the published version of a note.
+type Snapshot struct {
+	Title       string    `json:"title"`
+	Content     string    `json:"content"`
+	Tags        []Tag     `json:"tags,omitempty"`
+	PublishedAt time.Time `json:"published_at"`
+	Slug        string    `json:"slug,omitempty"` // current slug, see SlugRepository
 }
 
 type Tag struct {
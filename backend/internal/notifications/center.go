@@ -0,0 +1,99 @@
+package notifications
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// subscriptionBuffer is how many notifications a slow live subscriber can
+// fall behind before it misses some. Missed ones are still stored, and the
+// unread count catches the client up.
+const subscriptionBuffer = 16
+
+// Center stores notifications the user wants and pushes them to the user's
+// live subscribers. It is the Emitter the rest of the backend uses.
+type Center struct {
+	repo NotificationRepository
+
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[chan Notification]struct{}
+	closed      bool
+}
+
+var _ Emitter = (*Center)(nil)
+
+// NewCenter creates a new instance of Center.
+func NewCenter(repo NotificationRepository) *Center {
+	return &Center{repo: repo, subscribers: make(map[uuid.UUID]map[chan Notification]struct{})}
+}
+
+// Emit stores the notification, unless the user turned its type off, and
+// pushes it to the user's subscribers.
+func (c *Center) Emit(ctx context.Context, notification *Notification) error {
+	prefs, err := c.repo.GetPreferences(ctx, notification.UserID)
+	if err != nil {
+		return err
+	}
+	if !prefs.Wants(notification.Type) {
+		return nil
+	}
+	if err := c.repo.Create(ctx, notification); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for ch := range c.subscribers[notification.UserID] {
+		select {
+		case ch <- *notification:
+		default: // the subscriber is not keeping up
+		}
+	}
+	return nil
+}
+
+// Subscribe returns a channel receiving the user's new notifications until
+// cancel is called or the center is closed, which close the channel.
+func (c *Center) Subscribe(userID uuid.UUID) (notifications <-chan Notification, cancel func()) {
+	ch := make(chan Notification, subscriptionBuffer)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if c.subscribers[userID] == nil {
+		c.subscribers[userID] = make(map[chan Notification]struct{})
+	}
+	c.subscribers[userID][ch] = struct{}{}
+
+	return ch, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if _, ok := c.subscribers[userID][ch]; !ok {
+			return // already closed
+		}
+		delete(c.subscribers[userID], ch)
+		if len(c.subscribers[userID]) == 0 {
+			delete(c.subscribers, userID)
+		}
+		close(ch)
+	}
+}
+
+// Close ends every subscription, so that live streams let the server shut
+// down.
+func (c *Center) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	for userID, chans := range c.subscribers {
+		for ch := range chans {
+			close(ch)
+		}
+		delete(c.subscribers, userID)
+	}
+}
@@ -0,0 +1,127 @@
+package notifications
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/middleware"
+)
+
+func TestCenterEmit(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryNotificationRepository()
+	center := NewCenter(repo)
+	userID := uuid.New()
+
+	incoming, cancel := center.Subscribe(userID)
+	defer cancel()
+
+	if err := repo.SetPreferences(ctx, userID, Preferences{TypeComment: false}); err != nil {
+		t.Fatal(err)
+	}
+	for _, typ := range []Type{TypeComment, TypeMention} {
+		if err := center.Emit(ctx, &Notification{UserID: userID, Type: typ}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the mention gets through the preferences
+	list, total, err := repo.ListByUser(ctx, userID, true, 10, 0)
+	if err != nil || total != 1 || list[0].Type != TypeMention {
+		t.Fatalf("unread = %v, %d, %v", list, total, err)
+	}
+	select {
+	case n := <-incoming:
+		if n.ID != list[0].ID {
+			t.Errorf("pushed %v, want %v", n.ID, list[0].ID)
+		}
+	default:
+		t.Fatal("the mention was not pushed")
+	}
+
+	if err := repo.MarkRead(ctx, uuid.New(), list[0].ID); err != ErrNotFound {
+		t.Errorf("marking someone else's notification read: %v", err)
+	}
+	if err := repo.MarkRead(ctx, userID, list[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if unread, _ := repo.CountUnread(ctx, userID); unread != 0 {
+		t.Errorf("unread = %d after marking read", unread)
+	}
+
+	center.Close()
+	if _, ok := <-incoming; ok {
+		t.Error("subscription still open after Close")
+	}
+}
+
+func TestStream(t *testing.T) {
+	repo := NewInMemoryNotificationRepository()
+	center := NewCenter(repo)
+	handler := NewHandler(repo, center)
+	userID := uuid.New()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), middleware.UserIDKey, userID.String())
+		handler.Stream(w, r.WithContext(ctx))
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	events := bufio.NewReader(resp.Body)
+	readEvent := func() string {
+		var lines []string
+		for {
+			line, err := events.ReadString('\n')
+			if err != nil {
+				t.Fatalf("reading stream: %v", err)
+			}
+			if line == "\n" {
+				return strings.Join(lines, "")
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	if got := readEvent(); got != "event: unread\ndata: {\"unread\":0}\n" {
+		t.Fatalf("first event = %q", got)
+	}
+
+	// The subscription is in place once the first event has been sent
+	note := uuid.New()
+	if err := center.Emit(context.Background(), &Notification{UserID: userID, Type: TypeReminder, NoteID: &note}); err != nil {
+		t.Fatal(err)
+	}
+	got := readEvent()
+	if !strings.Contains(got, "event: notification\n") || !strings.Contains(got, `"note_id":"`+note.String()+`"`) {
+		t.Fatalf("notification event = %q", got)
+	}
+
+	// Closing the center ends the stream
+	center.Close()
+	deadline := time.After(5 * time.Second)
+	ended := make(chan struct{})
+	go func() {
+		events.ReadString(0)
+		close(ended)
+	}()
+	select {
+	case <-ended:
+	case <-deadline:
+		t.Fatal("stream still open after Close")
+	}
+}
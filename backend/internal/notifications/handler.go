@@ -0,0 +1,232 @@
+package notifications
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/middleware"
+	"github.com/jehufrayle/grimoire/utils"
+)
+
+// keepAlive is how often an idle stream gets a comment, so proxies do not
+// close it.
+const keepAlive = 30 * time.Second
+
+type Handler struct {
+	repo   NotificationRepository
+	center *Center
+}
+
+func NewHandler(repo NotificationRepository, center *Center) *Handler {
+	return &Handler{repo: repo, center: center}
+}
+
+func currentUserID(r *http.Request) (uuid.UUID, bool) {
+	userIDstr, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(userIDstr)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// GetNotifications lists the current user's notifications, newest first.
+// ?unread=true leaves out the ones already read.
+func (h *Handler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+	page, perPage := utils.Pagination(r, 20, 100)
+	list, total, err := h.repo.ListByUser(r.Context(), userID, unreadOnly, perPage, (page-1)*perPage)
+	if err != nil {
+		http.Error(w, "Failed to retrieve notifications", http.StatusInternalServerError)
+		return
+	}
+	unread, err := h.repo.CountUnread(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to count unread notifications", http.StatusInternalServerError)
+		return
+	}
+	if list == nil {
+		list = []Notification{}
+	}
+
+	utils.JSONResponse(w, Page{Notifications: list, Page: page, PerPage: perPage, Total: total, Unread: unread}, http.StatusOK)
+}
+
+func (h *Handler) GetUnreadCount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	unread, err := h.repo.CountUnread(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to count unread notifications", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, map[string]int{"unread": unread}, http.StatusOK)
+}
+
+func (h *Handler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid notification ID format", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.MarkRead(r.Context(), userID, id); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			http.Error(w, "Notification not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to mark notification read", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.repo.MarkAllRead(r.Context(), userID); err != nil {
+		http.Error(w, "Failed to mark notifications read", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetPreferences returns whether each type of notification is on.
+func (h *Handler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	prefs, err := h.repo.GetPreferences(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve notification preferences", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, prefs.complete(), http.StatusOK)
+}
+
+// UpdatePreferences turns the types in the body, such as {"comment": false},
+// on or off and leaves the others as they are.
+func (h *Handler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var prefs Preferences
+	if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := prefs.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.repo.SetPreferences(r.Context(), userID, prefs); err != nil {
+		http.Error(w, "Failed to save notification preferences", http.StatusInternalServerError)
+		return
+	}
+
+	h.GetPreferences(w, r)
+}
+
+// Stream pushes the current user's new notifications as server-sent events.
+// It starts with an "unread" event holding the unread count, then sends a
+// "notification" event for each notification as it is emitted.
+//
+// Like the rest of the API it needs the Authorization header, so clients
+// read it with fetch rather than EventSource.
+func (h *Handler) Stream(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	unread, err := h.repo.CountUnread(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to count unread notifications", http.StatusInternalServerError)
+		return
+	}
+
+	// Subscribe before the first write so nothing emitted meanwhile is missed
+	incoming, cancel := h.center.Subscribe(userID)
+	defer cancel()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // keep nginx from buffering events
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event, id string, data any) bool {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			log.Printf("❌ Failed to encode %s event: %v", event, err)
+			return false
+		}
+		if id != "" {
+			fmt.Fprintf(w, "id: %s\n", id)
+		}
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+		return rc.Flush() == nil
+	}
+	if !send("unread", "", map[string]int{"unread": unread}) {
+		return
+	}
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case n, ok := <-incoming:
+			if !ok {
+				return // shutting down
+			}
+			if !send("notification", n.ID.String(), n) {
+				return
+			}
+		case <-ticker.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			if rc.Flush() != nil {
+				return
+			}
+		}
+	}
+}
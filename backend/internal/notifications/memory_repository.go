@@ -0,0 +1,216 @@
+package notifications
+
+import (
+	"context"
+	"maps"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/journal"
+)
+
+// InMemoryNotificationRepository keeps notifications and preferences in
+// memory. Opened with OpenInMemoryNotificationRepository it also persists
+// them to disk.
+type InMemoryNotificationRepository struct {
+	mu            sync.RWMutex
+	notifications map[uuid.UUID]*Notification
+	preferences   map[uuid.UUID]Preferences
+	journal       *journal.Store[notificationSnapshot, notificationEntry] // nil when not persisted
+}
+
+func NewInMemoryNotificationRepository() *InMemoryNotificationRepository {
+	return &InMemoryNotificationRepository{
+		notifications: make(map[uuid.UUID]*Notification),
+		preferences:   make(map[uuid.UUID]Preferences),
+	}
+}
+
+func (r *InMemoryNotificationRepository) Create(ctx context.Context, notification *Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	notification.ID = uuid.New()
+	notification.CreatedAt = time.Now()
+	notification.ReadAt = nil
+
+	stored := *notification
+	r.notifications[notification.ID] = &stored
+	if err := r.journal.Log(notificationEntry{Notifications: []Notification{stored}}); err != nil {
+		delete(r.notifications, notification.ID)
+		return err
+	}
+	return nil
+}
+
+func (r *InMemoryNotificationRepository) ListByUser(ctx context.Context, userID uuid.UUID, unreadOnly bool, limit, offset int) ([]Notification, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []Notification
+	for _, n := range r.notifications {
+		if n.UserID == userID && (!unreadOnly || n.ReadAt == nil) {
+			result = append(result, *n)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.After(result[j].CreatedAt) })
+
+	total := len(result)
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return result[offset:end], total, nil
+}
+
+func (r *InMemoryNotificationRepository) CountUnread(ctx context.Context, userID uuid.UUID) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int
+	for _, n := range r.notifications {
+		if n.UserID == userID && n.ReadAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *InMemoryNotificationRepository) MarkRead(ctx context.Context, userID, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n, ok := r.notifications[id]
+	if !ok || n.UserID != userID {
+		return ErrNotFound
+	}
+	if n.ReadAt == nil {
+		now := time.Now()
+		n.ReadAt = &now
+		if err := r.journal.Log(notificationEntry{Notifications: []Notification{*n}}); err != nil {
+			n.ReadAt = nil
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *InMemoryNotificationRepository) MarkAllRead(ctx context.Context, userID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var read []*Notification
+	var entry notificationEntry
+	for _, n := range r.notifications {
+		if n.UserID == userID && n.ReadAt == nil {
+			n.ReadAt = &now
+			read = append(read, n)
+			entry.Notifications = append(entry.Notifications, *n)
+		}
+	}
+	if len(read) == 0 {
+		return nil
+	}
+	if err := r.journal.Log(entry); err != nil {
+		for _, n := range read {
+			n.ReadAt = nil
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *InMemoryNotificationRepository) GetPreferences(ctx context.Context, userID uuid.UUID) (Preferences, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return maps.Clone(r.preferences[userID]), nil
+}
+
+func (r *InMemoryNotificationRepository) SetPreferences(ctx context.Context, userID uuid.UUID, prefs Preferences) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := maps.Clone(r.preferences[userID])
+	if current == nil {
+		current = make(Preferences)
+	}
+	maps.Copy(current, prefs)
+	if err := r.journal.Log(notificationEntry{UserID: &userID, Preferences: current}); err != nil {
+		return err
+	}
+	r.preferences[userID] = current
+	return nil
+}
+
+// notificationEntry is one change in the journal's log: notifications as
+// they now are, or a user's preferences as they now are.
+type notificationEntry struct {
+	Notifications []Notification `json:"notifications,omitempty"`
+	UserID        *uuid.UUID     `json:"user_id,omitempty"`
+	Preferences   Preferences    `json:"preferences,omitempty"`
+}
+
+// notificationSnapshot is everything the repository holds.
+type notificationSnapshot struct {
+	Notifications []Notification            `json:"notifications"`
+	Preferences   map[uuid.UUID]Preferences `json:"preferences"`
+}
+
+// OpenInMemoryNotificationRepository returns an
+// InMemoryNotificationRepository persisted in dir. It starts from what dir
+// holds and logs every write there before returning. Snapshot keeps the log
+// short and Close takes a last snapshot.
+func OpenInMemoryNotificationRepository(dir string) (*InMemoryNotificationRepository, error) {
+	r := NewInMemoryNotificationRepository()
+	apply := func(entry notificationEntry) {
+		for i := range entry.Notifications {
+			r.notifications[entry.Notifications[i].ID] = &entry.Notifications[i]
+		}
+		if entry.UserID != nil {
+			r.preferences[*entry.UserID] = entry.Preferences
+		}
+	}
+	j, err := journal.OpenStore(dir, &r.mu, r.snapshot,
+		func(snapshot notificationSnapshot) {
+			apply(notificationEntry{Notifications: snapshot.Notifications})
+			for userID, prefs := range snapshot.Preferences {
+				r.preferences[userID] = prefs
+			}
+		},
+		apply)
+	if err != nil {
+		return nil, err
+	}
+	r.journal = j
+	return r, nil
+}
+
+// Callers must hold at least a read lock.
+func (r *InMemoryNotificationRepository) snapshot() notificationSnapshot {
+	snapshot := notificationSnapshot{
+		Notifications: make([]Notification, 0, len(r.notifications)),
+		Preferences:   r.preferences,
+	}
+	for _, n := range r.notifications {
+		snapshot.Notifications = append(snapshot.Notifications, *n)
+	}
+	return snapshot
+}
+
+// Snapshot writes every notification and preference to disk and empties the
+// log. It is a no-op when the repository is not persisted.
+func (r *InMemoryNotificationRepository) Snapshot() error {
+	return r.journal.Snapshot()
+}
+
+// Close takes a last snapshot and closes the journal.
+func (r *InMemoryNotificationRepository) Close() error {
+	return r.journal.Close()
+}
@@ -0,0 +1,155 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgNotificationRepository implements the NotificationRepository interface
+// for PostgreSQL.
+type PgNotificationRepository struct {
+	DB *pgxpool.Pool
+}
+
+// NewPgNotificationRepository creates a new instance of PgNotificationRepository.
+func NewPgNotificationRepository(db *pgxpool.Pool) *PgNotificationRepository {
+	return &PgNotificationRepository{DB: db}
+}
+
+// Create inserts a notification.
+func (r *PgNotificationRepository) Create(ctx context.Context, notification *Notification) error {
+	notification.ID = uuid.New()
+	notification.ReadAt = nil
+	query := `
+        INSERT INTO notifications (id, user_id, type, actor_id, note_id, comment_id)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING created_at`
+	err := r.DB.QueryRow(ctx, query, notification.ID, notification.UserID, notification.Type,
+		notification.ActorID, notification.NoteID, notification.CommentID).Scan(&notification.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign key violation
+			return fmt.Errorf("user, note or comment not found: %w", err)
+		}
+		return fmt.Errorf("failed to insert notification: %w", err)
+	}
+	return nil
+}
+
+// ListByUser retrieves a page of the user's notifications, newest first.
+func (r *PgNotificationRepository) ListByUser(ctx context.Context, userID uuid.UUID, unreadOnly bool, limit, offset int) ([]Notification, int, error) {
+	where := ` WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL)`
+
+	var total int
+	if err := r.DB.QueryRow(ctx, `SELECT COUNT(*) FROM notifications`+where, userID, unreadOnly).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count notifications: %w", err)
+	}
+
+	query := `
+        SELECT id, user_id, type, actor_id, note_id, comment_id, created_at, read_at
+        FROM notifications` + where + `
+        ORDER BY created_at DESC
+        LIMIT $3 OFFSET $4`
+	rows, err := r.DB.Query(ctx, query, userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var result []Notification
+	for rows.Next() {
+		var n Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.ActorID, &n.NoteID, &n.CommentID, &n.CreatedAt, &n.ReadAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan notification row: %w", err)
+		}
+		result = append(result, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("row iteration error: %w", err)
+	}
+	return result, total, nil
+}
+
+// CountUnread counts the user's unread notifications.
+func (r *PgNotificationRepository) CountUnread(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`
+	if err := r.DB.QueryRow(ctx, query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+	return count, nil
+}
+
+// MarkRead marks a notification read, keeping the time it was first read.
+func (r *PgNotificationRepository) MarkRead(ctx context.Context, userID, id uuid.UUID) error {
+	query := `UPDATE notifications SET read_at = COALESCE(read_at, now()) WHERE id = $1 AND user_id = $2`
+	result, err := r.DB.Exec(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to mark notification read: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// MarkAllRead marks every unread notification of the user read.
+func (r *PgNotificationRepository) MarkAllRead(ctx context.Context, userID uuid.UUID) error {
+	query := `UPDATE notifications SET read_at = now() WHERE user_id = $1 AND read_at IS NULL`
+	if _, err := r.DB.Exec(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return nil
+}
+
+// GetPreferences loads the types the user has turned on or off.
+func (r *PgNotificationRepository) GetPreferences(ctx context.Context, userID uuid.UUID) (Preferences, error) {
+	rows, err := r.DB.Query(ctx, `SELECT type, enabled FROM notification_preferences WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notification preferences: %w", err)
+	}
+	defer rows.Close()
+
+	prefs := make(Preferences)
+	for rows.Next() {
+		var t Type
+		var enabled bool
+		if err := rows.Scan(&t, &enabled); err != nil {
+			return nil, fmt.Errorf("failed to scan notification preference row: %w", err)
+		}
+		prefs[t] = enabled
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return prefs, nil
+}
+
+// SetPreferences upserts the given types.
+func (r *PgNotificationRepository) SetPreferences(ctx context.Context, userID uuid.UUID, prefs Preferences) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx) // Rollback is a no-op if tx has been committed.
+
+	query := `
+        INSERT INTO notification_preferences (user_id, type, enabled)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (user_id, type) DO UPDATE SET enabled = EXCLUDED.enabled`
+	for t, enabled := range prefs {
+		if _, err := tx.Exec(ctx, query, userID, t, enabled); err != nil {
+			return fmt.Errorf("failed to save notification preference: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
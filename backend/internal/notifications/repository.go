@@ -0,0 +1,36 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned for notifications that do not exist or belong to
+// someone else.
+var ErrNotFound = errors.New("notification not found")
+
+// Interface
+type NotificationRepository interface {
+	Create(ctx context.Context, notification *Notification) error
+	// ListByUser returns the user's notifications, or only the unread ones,
+	// newest first, and how many there are in all.
+	ListByUser(ctx context.Context, userID uuid.UUID, unreadOnly bool, limit, offset int) ([]Notification, int, error)
+	CountUnread(ctx context.Context, userID uuid.UUID) (int, error)
+	// MarkRead marks one of the user's notifications read.
+	MarkRead(ctx context.Context, userID, id uuid.UUID) error
+	// MarkAllRead marks all the user's notifications read.
+	MarkAllRead(ctx context.Context, userID uuid.UUID) error
+
+	// GetPreferences returns the preferences the user has set.
+	GetPreferences(ctx context.Context, userID uuid.UUID) (Preferences, error)
+	// SetPreferences changes the types given and leaves the others alone.
+	SetPreferences(ctx context.Context, userID uuid.UUID, prefs Preferences) error
+}
+
+var (
+	_ NotificationRepository = (*PgNotificationRepository)(nil)
+	_ NotificationRepository = (*InMemoryNotificationRepository)(nil)
+	_ NotificationRepository = (*SQLiteNotificationRepository)(nil)
+)
@@ -0,0 +1,94 @@
+package notifications
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/database/sqlitetest"
+	"github.com/jehufrayle/grimoire/internal/journal/journaltest"
+)
+
+func TestPersistedMemoryRepositoryReopens(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	reopened := journaltest.Reopen(t, OpenInMemoryNotificationRepository, func(repo *InMemoryNotificationRepository) {
+		if err := repo.SetPreferences(ctx, userID, Preferences{TypeComment: false}); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 3; i++ {
+			if err := repo.Create(ctx, &Notification{UserID: userID, Type: TypeMention}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := repo.Snapshot(); err != nil {
+			t.Fatal(err)
+		}
+		// Changes after the snapshot only live in the log
+		if err := repo.MarkAllRead(ctx, userID); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Create(ctx, &Notification{UserID: userID, Type: TypeComment}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if list, total, err := reopened.ListByUser(ctx, userID, false, 10, 0); err != nil || total != 4 || list[0].Type != TypeComment {
+		t.Fatalf("list = %+v, %d, %v", list, total, err)
+	}
+	if unread, err := reopened.CountUnread(ctx, userID); err != nil || unread != 1 {
+		t.Fatalf("unread = %d, %v", unread, err)
+	}
+	if prefs, err := reopened.GetPreferences(ctx, userID); err != nil || prefs.Wants(TypeComment) {
+		t.Fatalf("preferences = %v, %v", prefs, err)
+	}
+}
+
+func TestSQLiteRepository(t *testing.T) {
+	ctx := context.Background()
+	db := sqlitetest.Open(t)
+	userID := sqlitetest.NewUser(t, db)
+
+	repo := NewSQLiteNotificationRepository(db)
+	first := Notification{UserID: userID, Type: TypeMention}
+	if err := repo.Create(ctx, &first); err != nil {
+		t.Fatal(err)
+	}
+	second := Notification{UserID: userID, Type: TypeComment, ActorID: &userID}
+	if err := repo.Create(ctx, &second); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Create(ctx, &Notification{UserID: uuid.New(), Type: TypeMention}); err == nil {
+		t.Fatal("notification for a missing user was stored")
+	}
+
+	if err := repo.MarkRead(ctx, userID, first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.MarkRead(ctx, uuid.New(), second.ID); err != ErrNotFound {
+		t.Fatalf("marking someone else's notification: %v", err)
+	}
+	list, total, err := repo.ListByUser(ctx, userID, true, 10, 0)
+	if err != nil || total != 1 || list[0].ID != second.ID || *list[0].ActorID != userID {
+		t.Fatalf("unread = %+v, %d, %v", list, total, err)
+	}
+	if list, total, err := repo.ListByUser(ctx, userID, false, 1, 1); err != nil || total != 2 || len(list) != 1 || list[0].ReadAt == nil {
+		t.Fatalf("second page = %+v, %d, %v", list, total, err)
+	}
+	if err := repo.MarkAllRead(ctx, userID); err != nil {
+		t.Fatal(err)
+	}
+	if unread, err := repo.CountUnread(ctx, userID); err != nil || unread != 0 {
+		t.Fatalf("unread = %d, %v", unread, err)
+	}
+
+	if err := repo.SetPreferences(ctx, userID, Preferences{TypeComment: false, TypeMention: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetPreferences(ctx, userID, Preferences{TypeComment: true}); err != nil {
+		t.Fatal(err)
+	}
+	if prefs, err := repo.GetPreferences(ctx, userID); err != nil || len(prefs) != 2 || !prefs.Wants(TypeComment) {
+		t.Fatalf("preferences = %v, %v", prefs, err)
+	}
+}
@@ -0,0 +1,158 @@
+package notifications
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/database"
+)
+
+// SQLiteNotificationRepository implements the NotificationRepository
+// interface for the embedded SQLite backend.
+type SQLiteNotificationRepository struct {
+	DB *sql.DB
+}
+
+// NewSQLiteNotificationRepository creates a new instance of SQLiteNotificationRepository.
+func NewSQLiteNotificationRepository(db *sql.DB) *SQLiteNotificationRepository {
+	return &SQLiteNotificationRepository{DB: db}
+}
+
+// Create inserts a notification.
+func (r *SQLiteNotificationRepository) Create(ctx context.Context, notification *Notification) error {
+	id := uuid.New()
+	created := time.Now().UTC()
+	query := `
+        INSERT INTO notifications (id, user_id, type, actor_id, note_id, comment_id, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err := r.DB.ExecContext(ctx, query, id, notification.UserID, notification.Type,
+		notification.ActorID, notification.NoteID, notification.CommentID, database.Timestamp(created))
+	if err != nil {
+		if database.IsSQLiteConstraint(err, "FOREIGN KEY constraint failed") {
+			return fmt.Errorf("user, note or comment not found: %w", err)
+		}
+		return fmt.Errorf("failed to insert notification: %w", err)
+	}
+	notification.ID = id
+	notification.CreatedAt = created
+	notification.ReadAt = nil
+	return nil
+}
+
+// ListByUser retrieves a page of the user's notifications, newest first.
+func (r *SQLiteNotificationRepository) ListByUser(ctx context.Context, userID uuid.UUID, unreadOnly bool, limit, offset int) ([]Notification, int, error) {
+	where := ` WHERE user_id = ?1 AND (NOT ?2 OR read_at IS NULL)`
+
+	var total int
+	if err := r.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM notifications`+where, userID, unreadOnly).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count notifications: %w", err)
+	}
+
+	query := `
+        SELECT id, user_id, type, actor_id, note_id, comment_id, created_at, read_at
+        FROM notifications` + where + `
+        ORDER BY created_at DESC
+        LIMIT ?3 OFFSET ?4`
+	rows, err := r.DB.QueryContext(ctx, query, userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var result []Notification
+	for rows.Next() {
+		var n Notification
+		err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.ActorID, &n.NoteID, &n.CommentID,
+			database.ScanTime{T: &n.CreatedAt}, database.ScanNullTime{T: &n.ReadAt})
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan notification row: %w", err)
+		}
+		result = append(result, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("row iteration error: %w", err)
+	}
+	return result, total, nil
+}
+
+// CountUnread counts the user's unread notifications.
+func (r *SQLiteNotificationRepository) CountUnread(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM notifications WHERE user_id = ? AND read_at IS NULL`
+	if err := r.DB.QueryRowContext(ctx, query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+	return count, nil
+}
+
+// MarkRead marks a notification read, keeping the time it was first read.
+func (r *SQLiteNotificationRepository) MarkRead(ctx context.Context, userID, id uuid.UUID) error {
+	query := `UPDATE notifications SET read_at = COALESCE(read_at, ?) WHERE id = ? AND user_id = ?`
+	result, err := r.DB.ExecContext(ctx, query, database.Timestamp(time.Now()), id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to mark notification read: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// MarkAllRead marks every unread notification of the user read.
+func (r *SQLiteNotificationRepository) MarkAllRead(ctx context.Context, userID uuid.UUID) error {
+	query := `UPDATE notifications SET read_at = ? WHERE user_id = ? AND read_at IS NULL`
+	if _, err := r.DB.ExecContext(ctx, query, database.Timestamp(time.Now()), userID); err != nil {
+		return fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return nil
+}
+
+// GetPreferences loads the types the user has turned on or off.
+func (r *SQLiteNotificationRepository) GetPreferences(ctx context.Context, userID uuid.UUID) (Preferences, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT type, enabled FROM notification_preferences WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notification preferences: %w", err)
+	}
+	defer rows.Close()
+
+	prefs := make(Preferences)
+	for rows.Next() {
+		var t Type
+		var enabled bool
+		if err := rows.Scan(&t, &enabled); err != nil {
+			return nil, fmt.Errorf("failed to scan notification preference row: %w", err)
+		}
+		prefs[t] = enabled
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return prefs, nil
+}
+
+// SetPreferences upserts the given types.
+func (r *SQLiteNotificationRepository) SetPreferences(ctx context.Context, userID uuid.UUID, prefs Preferences) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback is a no-op if tx has been committed.
+
+	query := `
+        INSERT INTO notification_preferences (user_id, type, enabled)
+        VALUES (?, ?, ?)
+        ON CONFLICT (user_id, type) DO UPDATE SET enabled = excluded.enabled`
+	for t, enabled := range prefs {
+		if _, err := tx.ExecContext(ctx, query, userID, t, enabled); err != nil {
+			return fmt.Errorf("failed to save notification preference: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
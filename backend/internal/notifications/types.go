@@ -0,0 +1,97 @@
+// Package notifications is the notification center: what users should find
+// out about, such as being mentioned in a note or a new comment on one,
+// kept until they read it and pushed to them live while they are online.
+//
+// Other packages produce notifications through an Emitter and need not know
+// how they are stored or delivered.
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Type says what a notification is about.
+type Type string
+
+const (
+	// TypeMention: ActorID's note NoteID mentions the user.
+	TypeMention Type = "mention"
+	// TypeComment: ActorID commented on NoteID, the user's note, or replied
+	// to the user's comment on it.
+	TypeComment Type = "comment"
+	// TypeShare: ActorID shared NoteID with the user.
+	TypeShare Type = "share"
+	// TypeReminder: the reminder set on NoteID is due.
+	TypeReminder Type = "reminder"
+)
+
+// Types lists every notification type.
+var Types = []Type{TypeMention, TypeComment, TypeShare, TypeReminder}
+
+func (t Type) valid() bool {
+	for _, known := range Types {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+type Notification struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Type      Type       `json:"type"`
+	ActorID   *uuid.UUID `json:"actor_id,omitempty"`
+	NoteID    *uuid.UUID `json:"note_id,omitempty"`
+	CommentID *uuid.UUID `json:"comment_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+}
+
+// Page is a page of notifications with the user's unread count.
+type Page struct {
+	Notifications []Notification `json:"notifications"`
+	Page          int            `json:"page"`
+	PerPage       int            `json:"per_page"`
+	Total         int            `json:"total"`
+	Unread        int            `json:"unread"`
+}
+
+// Preferences says which types of notification a user wants. Types missing
+// from it are on.
+type Preferences map[Type]bool
+
+// Wants reports whether the user wants notifications of type t.
+func (p Preferences) Wants(t Type) bool {
+	enabled, ok := p[t]
+	return !ok || enabled
+}
+
+// complete returns the preferences with every type filled in.
+func (p Preferences) complete() Preferences {
+	all := make(Preferences, len(Types))
+	for _, t := range Types {
+		all[t] = p.Wants(t)
+	}
+	return all
+}
+
+// validate rejects types that do not exist.
+func (p Preferences) validate() error {
+	for t := range p {
+		if !t.valid() {
+			return fmt.Errorf("unknown notification type %q", t)
+		}
+	}
+	return nil
+}
+
+// Emitter delivers notifications to users. It is the only part of this
+// package other packages need.
+type Emitter interface {
+	Emit(ctx context.Context, notification *Notification) error
+}
@@ -0,0 +1,131 @@
+// Package profiles serves users' public pages: their profile and their
+// published notes under human-readable URLs.
+package profiles
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/notes"
+	"github.com/jehufrayle/grimoire/internal/users"
+	"github.com/jehufrayle/grimoire/utils"
+)
+
+type Handler struct {
+	users users.UserRepository
+	notes notes.NoteRepository
+	slugs notes.SlugRepository
+}
+
+func NewHandler(userRepo users.UserRepository, noteRepo notes.NoteRepository, slugs notes.SlugRepository) *Handler {
+	return &Handler{users: userRepo, notes: noteRepo, slugs: slugs}
+}
+
+// activeUser looks up a user who may have a public page. Inactive and
+// deleted users do not. It writes the error response itself and returns
+// nil when the request should stop.
+func (h *Handler) activeUser(w http.ResponseWriter, r *http.Request, username string) (*users.User, uuid.UUID) {
+	user, err := h.users.GetByUsername(r.Context(), username)
+	if err != nil || user == nil || !user.Active {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return nil, uuid.Nil
+	}
+	userID, err := uuid.Parse(user.ID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return nil, uuid.Nil
+	}
+	return user, userID
+}
+
+// GetProfile lists a user's profile and published notes, newest first.
+func (h *Handler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	user, userID := h.activeUser(w, r, r.PathValue("username"))
+	if user == nil {
+		return
+	}
+
+	profile, err := h.users.GetProfile(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve profile", http.StatusInternalServerError)
+		return
+	}
+	userNotes, err := h.notes.GetByUserID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve notes", http.StatusInternalServerError)
+		return
+	}
+
+	public := PublicProfile{
+		Username:  user.Username,
+		Bio:       profile.Bio,
+		AvatarURL: profile.AvatarURL,
+		Links:     []users.Link{},
+		Notes:     []PublicNote{},
+	}
+	for _, link := range profile.Links {
+		if link.Active {
+			public.Links = append(public.Links, link)
+		}
+	}
+	for i := range userNotes {
+		published := userNotes[i].PublishedVersion()
+		if published == nil || published.Published.Slug == "" {
+			continue
+		}
+		tags := make([]string, len(published.Tags))
+		for j, tag := range published.Tags {
+			tags[j] = tag.Name
+		}
+		public.Notes = append(public.Notes, PublicNote{
+			ID:          published.ID,
+			Title:       published.Title,
+			Slug:        published.Published.Slug,
+			URL:         NotePath(user.Username, published.Published.Slug),
+			Tags:        tags,
+			PublishedAt: published.Published.PublishedAt,
+		})
+	}
+	sort.Slice(public.Notes, func(i, j int) bool { return public.Notes[i].PublishedAt.After(public.Notes[j].PublishedAt) })
+
+	utils.JSONResponse(w, public, http.StatusOK)
+}
+
+// GetNoteBySlug serves /@username/slug. Old slugs redirect to the current one.
+func (h *Handler) GetNoteBySlug(w http.ResponseWriter, r *http.Request) {
+	username, ok := strings.CutPrefix(r.PathValue("handle"), "@")
+	if !ok || username == "" {
+		http.NotFound(w, r)
+		return
+	}
+	user, userID := h.activeUser(w, r, username)
+	if user == nil {
+		return
+	}
+
+	slug := r.PathValue("slug")
+	noteID, current, err := h.slugs.ResolveSlug(r.Context(), userID, slug)
+	if err != nil {
+		http.Error(w, "Note not found", http.StatusNotFound)
+		return
+	}
+	if current != slug {
+		http.Redirect(w, r, NotePath(user.Username, current), http.StatusMovedPermanently)
+		return
+	}
+
+	note, err := h.notes.GetByID(r.Context(), noteID.String())
+	if err != nil || note == nil {
+		http.Error(w, "Note not found", http.StatusNotFound)
+		return
+	}
+	published := note.PublishedVersion()
+	if published == nil {
+		http.Error(w, "Note not found", http.StatusNotFound)
+		return
+	}
+
+	utils.JSONResponse(w, published, http.StatusOK)
+}
@@ -0,0 +1,32 @@
+package profiles
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/users"
+)
+
+// PublicProfile is what anyone can see of a user.
+type PublicProfile struct {
+	Username  string       `json:"username"`
+	Bio       string       `json:"bio"`
+	AvatarURL string       `json:"avatar_url"`
+	Links     []users.Link `json:"links"` // active links only
+	Notes     []PublicNote `json:"notes"`
+}
+
+// PublicNote lists a published note on a profile.
+type PublicNote struct {
+	ID          uuid.UUID `json:"id"`
+	Title       string    `json:"title"`
+	Slug        string    `json:"slug"`
+	URL         string    `json:"url"`
+	Tags        []string  `json:"tags"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// NotePath is the public path of a published note: /@username/slug.
+func NotePath(username, slug string) string {
+	return "/@" + username + "/" + slug
+}
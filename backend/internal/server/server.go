@@ -6,11 +6,25 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"time"
 
+	"github.com/jehufrayle/grimoire/internal/attachments"
 	"github.com/jehufrayle/grimoire/internal/auth"
-	"github.com/jehufrayle/grimoire/internal/database"
+	"github.com/jehufrayle/grimoire/internal/calendar"
+	"github.com/jehufrayle/grimoire/internal/clipper"
+	"github.com/jehufrayle/grimoire/internal/comments"
+	"github.com/jehufrayle/grimoire/internal/dav"
+	"github.com/jehufrayle/grimoire/internal/digest"
+	"github.com/jehufrayle/grimoire/internal/encryption"
+	"github.com/jehufrayle/grimoire/internal/export"
+	"github.com/jehufrayle/grimoire/internal/feeds"
+	"github.com/jehufrayle/grimoire/internal/inbox"
+	"github.com/jehufrayle/grimoire/internal/mailer"
+	"github.com/jehufrayle/grimoire/internal/mentions"
 	"github.com/jehufrayle/grimoire/internal/notes"
+	"github.com/jehufrayle/grimoire/internal/notifications"
+	"github.com/jehufrayle/grimoire/internal/profiles"
 	"github.com/jehufrayle/grimoire/internal/users"
 	"github.com/jehufrayle/grimoire/middleware"
 	"github.com/rs/cors"
@@ -24,8 +38,15 @@ func StartServer(ctx context.Context, addr string) {
 	})
 	mux.HandleFunc("GET /hello", helloHandler)
 
+	// Repositories of the backend picked by GRIMOIRE_STORAGE
+	storage, err := openStores(ctx)
+	if err != nil {
+		log.Fatalf("❌ Failed to open storage: %v", err)
+	}
+	defer storage.close()
+
 	// User-related endpoints
-	userRepo := users.NewPgUserRepository(database.DB) // change this to change between memory and database
+	userRepo := storage.users
 	userHandler := users.NewHandler(userRepo)
 	mux.HandleFunc("GET /api/users", userHandler.GetAllUsers)
 	mux.HandleFunc("POST /api/users", userHandler.CreateUser)
@@ -39,16 +60,186 @@ func StartServer(ctx context.Context, addr string) {
 	mux.HandleFunc("POST /api/auth/signup", authHandler.SignupHandler)
 	mux.HandleFunc("GET /api/token", tokenValidatorHandler)
 
+	// Notification center; other packages emit through it
+	notificationRepo := storage.notifications
+	notificationCenter := notifications.NewCenter(notificationRepo)
+	notificationHandler := notifications.NewHandler(notificationRepo, notificationCenter)
+	mux.HandleFunc("GET /api/notifications", notificationHandler.GetNotifications)
+	mux.HandleFunc("GET /api/notifications/unread-count", notificationHandler.GetUnreadCount)
+	mux.HandleFunc("GET /api/notifications/stream", notificationHandler.Stream)
+	mux.HandleFunc("POST /api/notifications/{id}/read", notificationHandler.MarkRead)
+	mux.HandleFunc("POST /api/notifications/read-all", notificationHandler.MarkAllRead)
+	mux.HandleFunc("GET /api/users/me/notification-preferences", notificationHandler.GetPreferences)
+	mux.HandleFunc("PATCH /api/users/me/notification-preferences", notificationHandler.UpdatePreferences)
+
 	// Notes related endpoints
-	noteRepo := notes.NewPgNoteRepository(database.DB) // change this to change between memory and()
-	noteHandler := notes.NewHandler(noteRepo)
+	noteRepo := storage.notes
+	var noteStore notes.NoteRepository = noteRepo
+
+	// Encryption at rest is only available when a master key is configured
+	masterKey, err := encryption.LoadMasterKey()
+	if err != nil {
+		log.Fatalf("❌ Failed to load master key: %v", err)
+	}
+	var keyring *encryption.Keyring
+	if masterKey != nil {
+		if storage.keys == nil {
+			log.Fatalf("❌ Encryption at rest needs the %s storage backend", StoragePostgres)
+		}
+		keyring = encryption.NewKeyring(masterKey, storage.keys)
+		noteStore = notes.NewEncryptedNoteRepository(noteStore, keyring)
+	}
+
+	// Keep derived data in sync with note writes
+	statsCache := notes.NewStatsCache(10 * time.Minute)
+	observedNotes := notes.NewObservedNoteRepository(noteStore, statsCache)
+	relatedIndex := notes.NewRelatedIndex(noteStore)
+	observedNotes.Observe(relatedIndex)
+	observedNotes.Observe(notes.NewSlugIndex(noteRepo))
+	mentionRepo := storage.mentions
+	observedNotes.Observe(mentions.NewTracker(mentionRepo, userRepo, noteRepo, notificationCenter))
+	noteStore = observedNotes
+
+	if keyring != nil {
+		encryptionHandler := encryption.NewHandler(keyring, noteStore)
+		mux.HandleFunc("GET /api/users/me/encryption", encryptionHandler.GetStatus)
+		mux.HandleFunc("POST /api/users/me/encryption", encryptionHandler.Enable)
+	}
+
+	savedSearchRepo := storage.savedSearches
+	propertyRepo := storage.properties
+	noteHandler := notes.NewHandler(noteStore, savedSearchRepo, propertyRepo)
 	mux.HandleFunc("GET /api/notes", noteHandler.GetUserNotes)
+	mux.HandleFunc("GET /api/notes/lookup", noteHandler.LookupTitles)
 	mux.HandleFunc("GET /api/admin/notes", noteHandler.GetAllNotes)
 	mux.HandleFunc("GET /api/notes/{id}", noteHandler.GetUserNoteByID)
 	mux.HandleFunc("GET /api/admin/notes/{id}", noteHandler.GetNoteByID)
 	mux.HandleFunc("POST /api/notes", noteHandler.CreateNote)
 	mux.HandleFunc("PATCH /api/notes/{id}", noteHandler.UpdateNote)
 	mux.HandleFunc("DELETE /api/notes/{id}", noteHandler.DeleteNote)
+	mux.HandleFunc("POST /api/notes/{id}/fork", noteHandler.ForkNote)
+
+	// Scheduled publishing and expiry run against the decorated store so
+	// observers see them like any other write; due reminders notify owners
+	scheduler := notes.NewScheduler(noteRepo, noteStore, notificationCenter, time.Minute)
+	go scheduler.Run(ctx)
+
+	// Weekly digest emails, when mail is configured
+	mail, err := mailer.FromEnv()
+	if err != nil {
+		log.Fatalf("❌ Failed to configure mail: %v", err)
+	}
+	if mail != nil {
+		appURL := os.Getenv("GRIMOIRE_APP_URL")
+		if appURL == "" {
+			appURL = os.Getenv("GRIMOIRE_BASE_URL")
+		}
+		digestJob := digest.NewJob(userRepo, noteStore, notificationRepo, mail, appURL, 15*time.Minute)
+		go digestJob.Run(ctx)
+	}
+
+	// Published snapshots; the feed and share links are public
+	publishHandler := notes.NewPublishHandler(noteStore)
+	mux.HandleFunc("POST /api/notes/{id}/publish", publishHandler.PublishNote)
+	mux.HandleFunc("DELETE /api/notes/{id}/publish", publishHandler.UnpublishNote)
+	mux.HandleFunc("GET /api/notes/public", publishHandler.GetPublicNotes)
+	mux.HandleFunc("GET /api/public/notes/{id}", publishHandler.GetPublicNote)
+
+	// Rendered HTML with ![[Title]] embeds resolved for the reader
+	transcluder := notes.NewTranscluder(noteStore)
+	renderHandler := notes.NewRenderHandler(noteStore, transcluder)
+	mux.HandleFunc("GET /api/notes/{id}/rendered", renderHandler.GetRenderedNote)
+	mux.HandleFunc("GET /api/public/notes/{id}/rendered", renderHandler.GetPublicRenderedNote)
+
+	// Public profiles and /@username/slug note URLs
+	profileHandler := profiles.NewHandler(userRepo, noteStore, noteRepo)
+	mux.HandleFunc("GET /api/public/users/{username}", profileHandler.GetProfile)
+	mux.HandleFunc("GET /{handle}/{slug}", profileHandler.GetNoteBySlug)
+
+	// Feeds of a user's published notes, for feed readers
+	feedHandler := feeds.NewHandler(userRepo, noteStore, transcluder)
+	mux.HandleFunc("GET /api/public/users/{username}/rss", feedHandler.GetRSS)
+	mux.HandleFunc("GET /api/public/users/{username}/atom", feedHandler.GetAtom)
+
+	statsHandler := notes.NewStatsHandler(noteStore, statsCache)
+	mux.HandleFunc("GET /api/users/me/stats", statsHandler.GetMyStats)
+
+	mentionHandler := mentions.NewHandler(mentionRepo, noteStore)
+	mux.HandleFunc("GET /api/users/me/mentions", mentionHandler.GetMyMentions)
+
+	savedSearchHandler := notes.NewSavedSearchHandler(savedSearchRepo, noteStore)
+	mux.HandleFunc("GET /api/searches", savedSearchHandler.GetSavedSearches)
+	mux.HandleFunc("POST /api/searches", savedSearchHandler.CreateSavedSearch)
+	mux.HandleFunc("PATCH /api/searches/{id}", savedSearchHandler.UpdateSavedSearch)
+	mux.HandleFunc("DELETE /api/searches/{id}", savedSearchHandler.DeleteSavedSearch)
+	mux.HandleFunc("GET /api/searches/{id}/notes", savedSearchHandler.GetSavedSearchNotes)
+
+	propertyHandler := notes.NewPropertyHandler(propertyRepo)
+	mux.HandleFunc("GET /api/properties", propertyHandler.GetProperties)
+	mux.HandleFunc("PUT /api/properties/{name}", propertyHandler.SaveProperty)
+	mux.HandleFunc("DELETE /api/properties/{name}", propertyHandler.DeleteProperty)
+
+	relatedHandler := notes.NewRelatedHandler(noteStore, relatedIndex)
+	mux.HandleFunc("GET /api/notes/{id}/related", relatedHandler.GetRelatedNotes)
+
+	// Account export and import
+	exportHandler := export.NewHandler(noteStore, savedSearchRepo, propertyRepo)
+	mux.HandleFunc("GET /api/account/export", exportHandler.Export)
+	mux.HandleFunc("POST /api/account/import", exportHandler.Import)
+
+	// Web clipper for the browser extension
+	clipHandler := clipper.NewHandler(noteStore, propertyRepo)
+	mux.HandleFunc("POST /api/notes/clip", clipHandler.ClipNote)
+
+	// Comment related endpoints
+	commentRepo := storage.comments
+	commentHandler := comments.NewHandler(commentRepo, noteStore, notificationCenter)
+	mux.HandleFunc("GET /api/notes/{id}/comments", commentHandler.GetNoteComments)
+	mux.HandleFunc("POST /api/notes/{id}/comments", commentHandler.CreateComment)
+	mux.HandleFunc("PATCH /api/comments/{id}", commentHandler.UpdateComment)
+	mux.HandleFunc("DELETE /api/comments/{id}", commentHandler.DeleteComment)
+
+	// Note attachments
+	attachmentRepo := storage.attachments
+	attachmentHandler := attachments.NewHandler(attachmentRepo, noteStore)
+	mux.HandleFunc("GET /api/notes/{id}/attachments", attachmentHandler.GetNoteAttachments)
+	mux.HandleFunc("GET /api/attachments/{id}", attachmentHandler.GetAttachment)
+	mux.HandleFunc("DELETE /api/attachments/{id}", attachmentHandler.DeleteAttachment)
+
+	// Email inbox, when GRIMOIRE_SMTP_ADDR is set: mail for a user's secret
+	// address becomes a note in their account
+	if smtpAddr := os.Getenv("GRIMOIRE_SMTP_ADDR"); smtpAddr != "" {
+		domain := os.Getenv("GRIMOIRE_INBOX_DOMAIN")
+		if domain == "" {
+			domain = "grimoire.local"
+		}
+		inboxHandler := inbox.NewHandler(userRepo, domain)
+		mux.HandleFunc("POST /api/users/me/inbox-token", inboxHandler.CreateAddress)
+		mux.HandleFunc("DELETE /api/users/me/inbox-token", inboxHandler.DeleteAddress)
+
+		inboxServer := inbox.NewServer(smtpAddr, domain, userRepo, noteStore, attachmentRepo)
+		go func() {
+			log.Printf("📬 Inbox receiving mail for %s on %s", domain, smtpAddr)
+			if err := inboxServer.ListenAndServe(); err != nil {
+				log.Fatalf("❌ Inbox error: %v", err)
+			}
+		}()
+		defer inboxServer.Close()
+	}
+
+	// Daily notes, the calendar and the settings they follow. The .ics feed
+	// is authenticated by the token in its URL
+	calendarHandler := calendar.NewHandler(userRepo, noteStore, propertyRepo)
+	mux.HandleFunc("GET /api/users/me/settings", calendarHandler.GetSettings)
+	mux.HandleFunc("PATCH /api/users/me/settings", calendarHandler.UpdateSettings)
+	mux.HandleFunc("GET /api/daily/{date}", calendarHandler.GetDailyNote)
+	mux.HandleFunc("GET /api/notes/calendar", calendarHandler.GetCalendar)
+	mux.HandleFunc("POST /api/users/me/calendar-token", calendarHandler.CreateFeedToken)
+	mux.HandleFunc("DELETE /api/users/me/calendar-token", calendarHandler.DeleteFeedToken)
+	mux.HandleFunc("GET /api/public/calendar/{file}", calendarHandler.GetFeed)
+
+	// WebDAV, with its own methods and authentication
+	davHandler := dav.NewHandler(userRepo, noteStore, noteRepo, propertyRepo)
 
 	// Create the HTTP server
 	middlewares := middleware.CreateStack(middleware.Logging, middleware.Authentication, middleware.Authorization)
@@ -58,12 +249,18 @@ func StartServer(ctx context.Context, addr string) {
 		AllowedHeaders:   []string{"Authorization", "Content-Type"},
 		AllowCredentials: true,
 	})
-	handler := c.Handler(middlewares(mux))
+	// WebDAV is served beside the API mux rather than in it: a catch-all
+	// /dav/ pattern would conflict with GET /{handle}/{slug}
+	root := http.NewServeMux()
+	root.Handle(dav.Prefix+"/", middleware.Logging(davHandler))
+	root.Handle("/", c.Handler(middlewares(mux)))
 
 	server := &http.Server{
 		Addr:    addr,
-		Handler: handler,
+		Handler: root,
 	}
+	// Live notification streams never finish on their own
+	server.RegisterOnShutdown(notificationCenter.Close)
 
 	// Channel to stop the server when necessary
 	serverStopped := make(chan struct{})
@@ -0,0 +1,217 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jehufrayle/grimoire/internal/attachments"
+	"github.com/jehufrayle/grimoire/internal/comments"
+	"github.com/jehufrayle/grimoire/internal/database"
+	"github.com/jehufrayle/grimoire/internal/encryption"
+	"github.com/jehufrayle/grimoire/internal/journal"
+	"github.com/jehufrayle/grimoire/internal/mentions"
+	"github.com/jehufrayle/grimoire/internal/notes"
+	"github.com/jehufrayle/grimoire/internal/notifications"
+	"github.com/jehufrayle/grimoire/internal/users"
+)
+
+// Storage backends, chosen with GRIMOIRE_STORAGE.
+const (
+	StoragePostgres = "postgres" // the default
+	StorageSQLite   = "sqlite"   // a single file at GRIMOIRE_SQLITE_PATH
+	StorageMemory   = "memory"   // persisted to GRIMOIRE_MEMORY_DIR when set
+)
+
+// snapshotInterval is how often persisted in-memory stores are snapshotted,
+// keeping the log replayed on startup short.
+const snapshotInterval = 5 * time.Minute
+
+// stores are the repositories of the configured backend.
+type stores struct {
+	users         users.UserRepository
+	notes         notes.Storage
+	savedSearches notes.SavedSearchRepository
+	properties    notes.PropertyDefinitionRepository
+	comments      comments.CommentRepository
+	attachments   attachments.AttachmentRepository
+	mentions      mentions.MentionRepository
+	notifications notifications.NotificationRepository
+	keys          encryption.KeyStore // nil when data keys cannot be persisted
+	close         func()
+}
+
+// openStores opens the backend named by GRIMOIRE_STORAGE. Every backend
+// holds every store, but encryption at rest is only available with
+// PostgreSQL.
+// GRIMOIRE_NOTES_GIT_DIR keeps notes in a git repository instead. It needs
+// the memory backend: the PostgreSQL and SQLite tables reference notes(id).
+func openStores(ctx context.Context) (*stores, error) {
+	gitDir := os.Getenv("GRIMOIRE_NOTES_GIT_DIR")
+	backend := os.Getenv("GRIMOIRE_STORAGE")
+	if gitDir != "" && backend != StorageMemory {
+		return nil, fmt.Errorf("GRIMOIRE_NOTES_GIT_DIR needs GRIMOIRE_STORAGE=%s", StorageMemory)
+	}
+
+	var s *stores
+	switch backend {
+	case "", StoragePostgres:
+		database.Connect()
+		s = &stores{
+			users:         users.NewPgUserRepository(database.DB),
+			notes:         notes.NewPgNoteRepository(database.DB),
+			savedSearches: notes.NewPgSavedSearchRepository(database.DB),
+			properties:    notes.NewPgPropertyDefinitionRepository(database.DB),
+			comments:      comments.NewPgCommentRepository(database.DB),
+			attachments:   attachments.NewPgAttachmentRepository(database.DB),
+			mentions:      mentions.NewPgMentionRepository(database.DB),
+			notifications: notifications.NewPgNotificationRepository(database.DB),
+			keys:          encryption.NewPgKeyStore(database.DB),
+			close:         database.Close,
+		}
+	case StorageSQLite:
+		path := os.Getenv("GRIMOIRE_SQLITE_PATH")
+		if path == "" {
+			path = "grimoire.db"
+		}
+		db, err := database.OpenSQLite(path)
+		if err != nil {
+			return nil, err
+		}
+		s = &stores{
+			users:         users.NewSQLiteUserRepository(db),
+			notes:         notes.NewSQLiteNoteRepository(db),
+			savedSearches: notes.NewSQLiteSavedSearchRepository(db),
+			properties:    notes.NewSQLitePropertyDefinitionRepository(db),
+			comments:      comments.NewSQLiteCommentRepository(db),
+			attachments:   attachments.NewSQLiteAttachmentRepository(db),
+			mentions:      mentions.NewSQLiteMentionRepository(db),
+			notifications: notifications.NewSQLiteNotificationRepository(db),
+			close:         func() { db.Close() },
+		}
+	case StorageMemory:
+		dir := os.Getenv("GRIMOIRE_MEMORY_DIR")
+		if dir == "" {
+			s = inMemoryStores()
+			log.Println("⚠️ Using in-memory storage; data is lost on restart")
+			break
+		}
+		var err error
+		// Notes kept in git are not opened here as well
+		if s, err = persistedMemoryStores(ctx, dir, gitDir == ""); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown GRIMOIRE_STORAGE %q, want %s, %s or %s", backend, StoragePostgres, StorageSQLite, StorageMemory)
+	}
+
+	if gitDir != "" {
+		gitRepo, err := notes.NewGitNoteRepository(gitDir, gitAuthors(s.users))
+		if err != nil {
+			s.close()
+			return nil, fmt.Errorf("failed to open git note repository: %w", err)
+		}
+		s.notes = gitRepo
+	}
+	return s, nil
+}
+
+func inMemoryStores() *stores {
+	return &stores{
+		users:         users.NewMemUserRepository(),
+		notes:         notes.NewInMemoryNoteRepository(),
+		savedSearches: notes.NewInMemorySavedSearchRepository(),
+		properties:    notes.NewInMemoryPropertyDefinitionRepository(),
+		comments:      comments.NewInMemoryCommentRepository(),
+		attachments:   attachments.NewInMemoryAttachmentRepository(),
+		mentions:      mentions.NewInMemoryMentionRepository(),
+		notifications: notifications.NewInMemoryNotificationRepository(),
+		close:         func() {},
+	}
+}
+
+// persisted is an in-memory store kept on disk.
+type persisted interface {
+	Snapshot() error
+	Close() error
+}
+
+// openPersisted opens the store kept in dir/name and adds it to opened.
+func openPersisted[T persisted](opened *[]persisted, dir, name string, open func(dir string) (T, error)) (T, error) {
+	store, err := open(filepath.Join(dir, name))
+	if err != nil {
+		return store, fmt.Errorf("failed to open %s in %s: %w", name, dir, err)
+	}
+	*opened = append(*opened, store)
+	return store, nil
+}
+
+// persistedMemoryStores keeps every store in memory, each persisted in its
+// own directory under dir and snapshotted every snapshotInterval until ctx
+// is done. The notes store is left nil unless withNotes is set.
+func persistedMemoryStores(ctx context.Context, dir string, withNotes bool) (*stores, error) {
+	var opened []persisted
+	closeAll := func() {
+		for _, store := range opened {
+			if err := store.Close(); err != nil {
+				log.Printf("❌ Failed to close store in %s: %v", dir, err)
+			}
+		}
+	}
+
+	s := &stores{close: closeAll}
+	var err error
+	if s.users, err = openPersisted(&opened, dir, "users", users.OpenMemUserRepository); err != nil {
+		closeAll()
+		return nil, err
+	}
+	if withNotes {
+		if s.notes, err = openPersisted(&opened, dir, "notes", notes.OpenInMemoryNoteRepository); err != nil {
+			closeAll()
+			return nil, err
+		}
+	}
+	if s.savedSearches, err = openPersisted(&opened, dir, "saved_searches", notes.OpenInMemorySavedSearchRepository); err != nil {
+		closeAll()
+		return nil, err
+	}
+	if s.properties, err = openPersisted(&opened, dir, "properties", notes.OpenInMemoryPropertyDefinitionRepository); err != nil {
+		closeAll()
+		return nil, err
+	}
+	if s.comments, err = openPersisted(&opened, dir, "comments", comments.OpenInMemoryCommentRepository); err != nil {
+		closeAll()
+		return nil, err
+	}
+	if s.attachments, err = openPersisted(&opened, dir, "attachments", attachments.OpenInMemoryAttachmentRepository); err != nil {
+		closeAll()
+		return nil, err
+	}
+	if s.mentions, err = openPersisted(&opened, dir, "mentions", mentions.OpenInMemoryMentionRepository); err != nil {
+		closeAll()
+		return nil, err
+	}
+	if s.notifications, err = openPersisted(&opened, dir, "notifications", notifications.OpenInMemoryNotificationRepository); err != nil {
+		closeAll()
+		return nil, err
+	}
+
+	for _, store := range opened {
+		go journal.Every(ctx, snapshotInterval, store.Snapshot)
+	}
+	return s, nil
+}
+
+// gitAuthors attributes git commits to the user who made the change.
+func gitAuthors(userRepo users.UserRepository) notes.AuthorLookup {
+	return func(ctx context.Context, userID string) (notes.Author, error) {
+		user, err := userRepo.GetByID(ctx, userID)
+		if err != nil {
+			return notes.Author{}, err
+		}
+		return notes.Author{Name: user.Username, Email: user.Email}, nil
+	}
+}
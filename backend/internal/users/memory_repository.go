@@ -3,11 +3,27 @@ package users
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
+	"sync"
 	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/journal"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// MemUserRepository keeps users in memory and hands out copies, never the
+// users it holds. Opened with OpenMemUserRepository it also persists them to
+// disk.
 type MemUserRepository struct {
-	users map[string]User // In-memory storage for users
+	mu             sync.RWMutex
+	users          map[string]User   // In-memory storage for users
+	calendarTokens map[string]string // token hash -> user ID
+	inboxTokens    map[string]string // token hash -> user ID
+	digestWeeks    map[string]string // user ID -> week of the last digest claimed
+
+	journal *journal.Store[[]userRecord, userEntry] // nil when not persisted
 }
 
 var initialUsers = map[string]User{
@@ -42,50 +58,361 @@ var initialUsers = map[string]User{
 
 func NewMemUserRepository() *MemUserRepository {
 	return &MemUserRepository{
-		users: initialUsers,
+		users:          maps.Clone(initialUsers),
+		calendarTokens: make(map[string]string),
+		inboxTokens:    make(map[string]string),
+		digestWeeks:    make(map[string]string),
+	}
+}
+
+// clone returns a deep copy of the user.
+func (u User) clone() *User {
+	u.Profile.Links = slices.Clone(u.Profile.Links)
+	if u.DeletedAt != nil {
+		deletedAt := *u.DeletedAt
+		u.DeletedAt = &deletedAt
+	}
+	return &u
+}
+
+// userRecord is a user as persisted, with the hashes User keeps out of JSON.
+type userRecord struct {
+	User              User   `json:"user"`
+	PasswordHash      string `json:"password_hash"`
+	CalendarTokenHash string `json:"calendar_token_hash,omitempty"`
+	InboxTokenHash    string `json:"inbox_token_hash,omitempty"`
+	DigestWeek        string `json:"digest_week,omitempty"`
+}
+
+// userEntry is one change in the journal's log.
+type userEntry struct {
+	User    *userRecord `json:"user,omitempty"`
+	Deleted string      `json:"deleted,omitempty"`
+}
+
+// OpenMemUserRepository returns a MemUserRepository persisted in dir: it
+// starts from what dir holds, without the seed users, and logs every write
+// there before returning. Snapshot keeps the log short and Close takes a
+// last snapshot.
+func OpenMemUserRepository(dir string) (*MemUserRepository, error) {
+	r := &MemUserRepository{
+		users:          make(map[string]User),
+		calendarTokens: make(map[string]string),
+		inboxTokens:    make(map[string]string),
+		digestWeeks:    make(map[string]string),
+	}
+	j, err := journal.OpenStore(dir, &r.mu, r.records,
+		func(records []userRecord) {
+			for _, rec := range records {
+				r.put(rec)
+			}
+		},
+		func(entry userEntry) {
+			if entry.User != nil {
+				r.put(*entry.User)
+			} else if entry.Deleted != "" {
+				r.drop(entry.Deleted)
+			}
+		})
+	if err != nil {
+		return nil, err
+	}
+	r.journal = j
+	return r, nil
+}
+
+// record returns what is held for a user.
+// Callers must hold at least a read lock.
+func (r *MemUserRepository) record(id string) (userRecord, bool) {
+	user, exists := r.users[id]
+	if !exists {
+		return userRecord{}, false
+	}
+	rec := userRecord{
+		User:              *user.clone(),
+		PasswordHash:      user.PasswordHash,
+		CalendarTokenHash: tokenOf(r.calendarTokens, id),
+		InboxTokenHash:    tokenOf(r.inboxTokens, id),
+		DigestWeek:        r.digestWeeks[id],
+	}
+	return rec, true
+}
+
+// put replaces whatever is held for the record's user.
+// Callers must hold the write lock.
+func (r *MemUserRepository) put(rec userRecord) {
+	user := rec.User.clone()
+	user.PasswordHash = rec.PasswordHash
+	r.drop(user.ID)
+	r.users[user.ID] = *user
+	setToken(r.calendarTokens, user.ID, rec.CalendarTokenHash)
+	setToken(r.inboxTokens, user.ID, rec.InboxTokenHash)
+	if rec.DigestWeek != "" {
+		r.digestWeeks[user.ID] = rec.DigestWeek
+	}
+}
+
+// drop removes a user and their tokens.
+// Callers must hold the write lock.
+func (r *MemUserRepository) drop(id string) {
+	delete(r.users, id)
+	setToken(r.calendarTokens, id, "")
+	setToken(r.inboxTokens, id, "")
+	delete(r.digestWeeks, id)
+}
+
+// tokenOf returns the hash of the user's token in tokens, if any.
+func tokenOf(tokens map[string]string, id string) string {
+	for hash, userID := range tokens {
+		if userID == id {
+			return hash
+		}
 	}
+	return ""
 }
+
+// setToken replaces the user's token in tokens; an empty hash removes it.
+func setToken(tokens map[string]string, id, tokenHash string) {
+	for hash, userID := range tokens {
+		if userID == id {
+			delete(tokens, hash)
+		}
+	}
+	if tokenHash != "" {
+		tokens[tokenHash] = id
+	}
+}
+
+// change applies a change to one user, logging the user as they end up and
+// putting them back as they were if that fails.
+// Callers must hold the write lock.
+func (r *MemUserRepository) change(id string, apply func()) error {
+	before, existed := r.record(id)
+	apply()
+	if r.journal == nil {
+		return nil
+	}
+
+	var entry userEntry
+	if rec, exists := r.record(id); exists {
+		entry.User = &rec
+	} else {
+		entry.Deleted = id
+	}
+	if err := r.journal.Log(entry); err != nil {
+		if existed {
+			r.put(before)
+		} else {
+			r.drop(id)
+		}
+		return err
+	}
+	return nil
+}
+
+// records returns what is held for every user.
+// Callers must hold at least a read lock.
+func (r *MemUserRepository) records() []userRecord {
+	records := make([]userRecord, 0, len(r.users))
+	for id := range r.users {
+		rec, _ := r.record(id)
+		records = append(records, rec)
+	}
+	return records
+}
+
+// Snapshot writes every user to disk and empties the log. It is a no-op
+// when the repository is not persisted.
+func (r *MemUserRepository) Snapshot() error {
+	return r.journal.Snapshot()
+}
+
+// Close takes a last snapshot and closes the journal.
+func (r *MemUserRepository) Close() error {
+	return r.journal.Close()
+}
+
 func (r *MemUserRepository) GetAll(ctx context.Context) ([]User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	var userList []User
 	for _, user := range r.users {
-		userList = append(userList, user)
+		userList = append(userList, *user.clone())
 	}
 	return userList, nil
 }
 func (r *MemUserRepository) GetByID(ctx context.Context, id string) (*User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	user, exists := r.users[id]
 	if !exists {
 		return nil, fmt.Errorf("user with id %s not found", id)
 	}
-	return &user, nil
+	return user.clone(), nil
+}
+func (r *MemUserRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.Email == email {
+			return user.clone(), nil
+		}
+	}
+	return nil, fmt.Errorf("user with email %s not found", email)
+}
+func (r *MemUserRepository) GetByUsername(ctx context.Context, username string) (*User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.Username == username {
+			return user.clone(), nil
+		}
+	}
+	return nil, fmt.Errorf("user %s not found", username)
 }
-func (r *MemUserRepository) Create(ctx context.Context, user *User) error {
+func (r *MemUserRepository) GetProfile(ctx context.Context, id string) (*Profile, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, exists := r.users[id]
+	if !exists {
+		return nil, fmt.Errorf("user with id %s not found", id)
+	}
+	return &user.clone().Profile, nil
+}
+func (r *MemUserRepository) GetSettings(ctx context.Context, id string) (*Settings, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, exists := r.users[id]
+	if !exists {
+		return nil, fmt.Errorf("user with id %s not found", id)
+	}
+	return &user.Settings, nil
+}
+func (r *MemUserRepository) UpdateSettings(ctx context.Context, id string, settings *Settings) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, exists := r.users[id]
+	if !exists {
+		return fmt.Errorf("user with id %s not found", id)
+	}
+	return r.change(id, func() {
+		user.Settings = *settings
+		r.users[id] = user
+	})
+}
+func (r *MemUserRepository) SetCalendarToken(ctx context.Context, id string, tokenHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.users[id]; !exists {
+		return fmt.Errorf("user with id %s not found", id)
+	}
+	return r.change(id, func() { setToken(r.calendarTokens, id, tokenHash) })
+}
+func (r *MemUserRepository) GetByCalendarToken(ctx context.Context, tokenHash string) (*User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, exists := r.users[r.calendarTokens[tokenHash]]
+	if !exists {
+		return nil, fmt.Errorf("calendar token not found")
+	}
+	return user.clone(), nil
+}
+func (r *MemUserRepository) SetInboxToken(ctx context.Context, id string, tokenHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.users[id]; !exists {
+		return fmt.Errorf("user with id %s not found", id)
+	}
+	return r.change(id, func() { setToken(r.inboxTokens, id, tokenHash) })
+}
+func (r *MemUserRepository) GetByInboxToken(ctx context.Context, tokenHash string) (*User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, exists := r.users[r.inboxTokens[tokenHash]]
+	if !exists {
+		return nil, fmt.Errorf("inbox token not found")
+	}
+	return user.clone(), nil
+}
+func (r *MemUserRepository) ClaimDigest(ctx context.Context, id string, week string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.users[id]; !exists {
+		return false, fmt.Errorf("user with id %s not found", id)
+	}
+	if last, ok := r.digestWeeks[id]; ok && last >= week {
+		return false, nil
+	}
+	if err := r.change(id, func() { r.digestWeeks[id] = week }); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+func (r *MemUserRepository) ReleaseDigest(ctx context.Context, id string, week string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.digestWeeks[id] != week {
+		return nil
+	}
+	return r.change(id, func() { delete(r.digestWeeks, id) })
+}
+func (r *MemUserRepository) Create(ctx context.Context, user *User, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if _, exists := r.users[user.ID]; exists {
 		return fmt.Errorf("user with id %s already exists", user.ID)
 	}
+	for _, other := range r.users {
+		if other.Username == user.Username || other.Email == user.Email {
+			return fmt.Errorf("user already exists")
+		}
+	}
+	user.PasswordHash = string(hash)
 	user.CreatedAt = time.Now()
 	user.UpdatedAt = time.Now()
 	if user.ID == "" {
-		user.ID = fmt.Sprintf("u%d", len(r.users)+1) // Simple ID generation
+		user.ID = uuid.NewString() // notes refer to their owner by UUID
 	}
 	if user.Role == "" {
 		user.Role = RoleUser // Default role if not specified
 	}
 
-	r.users[user.ID] = *user
-	return nil
+	return r.change(user.ID, func() { r.users[user.ID] = *user.clone() })
 }
 func (r *MemUserRepository) Update(ctx context.Context, user *User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if _, exists := r.users[user.ID]; !exists {
 		return fmt.Errorf("user with id %s not found", user.ID)
 	}
-	r.users[user.ID] = *user
-	return nil
+	return r.change(user.ID, func() { r.users[user.ID] = *user.clone() })
 }
 func (r *MemUserRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if _, exists := r.users[id]; !exists {
 		return fmt.Errorf("user with id %s not found", id)
 	}
-	delete(r.users, id)
-	return nil
+	return r.change(id, func() { delete(r.users, id) })
 }
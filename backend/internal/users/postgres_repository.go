@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"golang.org/x/crypto/bcrypt"
@@ -60,6 +61,138 @@ func (r *PgUserRepository) GetByEmail(ctx context.Context, email string) (*User,
 	return &user, nil
 }
 
+func (r *PgUserRepository) GetByUsername(ctx context.Context, username string) (*User, error) {
+	row := r.DB.QueryRow(ctx, `SELECT id, username, email, created_at, updated_at, role, active FROM active_users WHERE username = $1`, username)
+	var user User
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt, &user.UpdatedAt, &user.Role, &user.Active)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by username: %w", err)
+	}
+	return &user, nil
+}
+
+// GetProfile loads the user's profile and links. Users without a profile row
+// get an empty profile.
+func (r *PgUserRepository) GetProfile(ctx context.Context, id string) (*Profile, error) {
+	var profile Profile
+	err := r.DB.QueryRow(ctx, `
+		SELECT COALESCE(first_name, ''), COALESCE(last_name, ''), COALESCE(bio, ''), COALESCE(avatar_url, '')
+		FROM profiles WHERE user_id = $1`, id).
+		Scan(&profile.FirstName, &profile.LastName, &profile.Bio, &profile.AvatarURL)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get profile: %w", err)
+	}
+
+	rows, err := r.DB.Query(ctx, `
+		SELECT url, COALESCE(title, ''), COALESCE(icon, ''), active
+		FROM links WHERE user_id = $1 ORDER BY id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query links: %w", err)
+	}
+	profile.Links, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (Link, error) {
+		var link Link
+		err := row.Scan(&link.URL, &link.Title, &link.Icon, &link.Active)
+		return link, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan link row: %w", err)
+	}
+	return &profile, nil
+}
+
+func (r *PgUserRepository) GetSettings(ctx context.Context, id string) (*Settings, error) {
+	var settings Settings
+	err := r.DB.QueryRow(ctx, `SELECT timezone, daily_template, weekly_digest FROM active_users WHERE id = $1`, id).
+		Scan(&settings.Timezone, &settings.DailyTemplate, &settings.WeeklyDigest)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to get settings: %w", err)
+	}
+	return &settings, nil
+}
+
+func (r *PgUserRepository) UpdateSettings(ctx context.Context, id string, settings *Settings) error {
+	query := `
+		UPDATE users
+		SET timezone = $1, daily_template = $2, weekly_digest = $3, updated_at = now()
+		WHERE id = $4 AND deleted_at IS NULL`
+	result, err := r.DB.Exec(ctx, query, settings.Timezone, settings.DailyTemplate, settings.WeeklyDigest, id)
+	if err != nil {
+		return fmt.Errorf("failed to update settings: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("user not found or already deleted")
+	}
+	return nil
+}
+
+func (r *PgUserRepository) SetCalendarToken(ctx context.Context, id string, tokenHash string) error {
+	result, err := r.DB.Exec(ctx, `UPDATE users SET calendar_token_hash = NULLIF($1, '') WHERE id = $2 AND deleted_at IS NULL`, tokenHash, id)
+	if err != nil {
+		return fmt.Errorf("failed to set calendar token: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("user not found or already deleted")
+	}
+	return nil
+}
+
+func (r *PgUserRepository) GetByCalendarToken(ctx context.Context, tokenHash string) (*User, error) {
+	row := r.DB.QueryRow(ctx, `
+		SELECT id, username, email, created_at, updated_at, role, active
+		FROM users WHERE calendar_token_hash = $1 AND deleted_at IS NULL`, tokenHash)
+	var user User
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt, &user.UpdatedAt, &user.Role, &user.Active)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by calendar token: %w", err)
+	}
+	return &user, nil
+}
+
+func (r *PgUserRepository) SetInboxToken(ctx context.Context, id string, tokenHash string) error {
+	result, err := r.DB.Exec(ctx, `UPDATE users SET inbox_token_hash = NULLIF($1, '') WHERE id = $2 AND deleted_at IS NULL`, tokenHash, id)
+	if err != nil {
+		return fmt.Errorf("failed to set inbox token: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("user not found or already deleted")
+	}
+	return nil
+}
+
+func (r *PgUserRepository) GetByInboxToken(ctx context.Context, tokenHash string) (*User, error) {
+	row := r.DB.QueryRow(ctx, `
+		SELECT id, username, email, created_at, updated_at, role, active
+		FROM users WHERE inbox_token_hash = $1 AND deleted_at IS NULL`, tokenHash)
+	var user User
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt, &user.UpdatedAt, &user.Role, &user.Active)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by inbox token: %w", err)
+	}
+	return &user, nil
+}
+
+func (r *PgUserRepository) ClaimDigest(ctx context.Context, id string, week string) (bool, error) {
+	query := `
+		UPDATE users SET digest_week = $1::date
+		WHERE id = $2 AND deleted_at IS NULL AND (digest_week IS NULL OR digest_week < $1::date)`
+	result, err := r.DB.Exec(ctx, query, week, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim digest: %w", err)
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+func (r *PgUserRepository) ReleaseDigest(ctx context.Context, id string, week string) error {
+	_, err := r.DB.Exec(ctx, `UPDATE users SET digest_week = NULL WHERE id = $1 AND digest_week = $2::date`, id, week)
+	if err != nil {
+		return fmt.Errorf("failed to release digest: %w", err)
+	}
+	return nil
+}
+
 func (r *PgUserRepository) Create(ctx context.Context, user *User, password string) error {
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	log.Print("Generated hash: ", hash)
@@ -9,7 +9,31 @@ type UserRepository interface {
 	GetAll(ctx context.Context) ([]User, error) // Get all users
 	GetByID(ctx context.Context, id string) (*User, error)
 	GetByEmail(ctx context.Context, email string) (*User, error)
+	GetByUsername(ctx context.Context, username string) (*User, error)
+	GetProfile(ctx context.Context, id string) (*Profile, error)
+	GetSettings(ctx context.Context, id string) (*Settings, error)
+	UpdateSettings(ctx context.Context, id string, settings *Settings) error
+	// SetCalendarToken stores the hash of the user's calendar feed token,
+	// replacing any previous one; an empty hash disables the feed.
+	SetCalendarToken(ctx context.Context, id string, tokenHash string) error
+	GetByCalendarToken(ctx context.Context, tokenHash string) (*User, error)
+	// SetInboxToken stores the hash of the token in the user's inbox email
+	// address, replacing any previous one; an empty hash disables the inbox.
+	SetInboxToken(ctx context.Context, id string, tokenHash string) error
+	GetByInboxToken(ctx context.Context, tokenHash string) (*User, error)
+	// ClaimDigest records that the weekly digest for the week starting on
+	// week, a YYYY-MM-DD date, goes out to the user. It returns false when
+	// that week's digest or a later one already did, so each is sent once.
+	ClaimDigest(ctx context.Context, id string, week string) (bool, error)
+	// ReleaseDigest takes back a claim whose digest could not be sent.
+	ReleaseDigest(ctx context.Context, id string, week string) error
 	Create(ctx context.Context, user *User, password string) error
 	Update(ctx context.Context, user *User) error
 	Delete(ctx context.Context, id string) error
 }
+
+var (
+	_ UserRepository = (*PgUserRepository)(nil)
+	_ UserRepository = (*SQLiteUserRepository)(nil)
+	_ UserRepository = (*MemUserRepository)(nil)
+)
@@ -0,0 +1,245 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jehufrayle/grimoire/internal/database"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// SQLiteUserRepository implements the UserRepository interface for the
+// embedded SQLite backend.
+type SQLiteUserRepository struct {
+	DB *sql.DB
+}
+
+func NewSQLiteUserRepository(db *sql.DB) *SQLiteUserRepository {
+	return &SQLiteUserRepository{DB: db}
+}
+
+const selectSQLiteUserQuery = "SELECT id, username, email, password_hash, created_at, updated_at, role, active FROM active_users"
+
+func scanSQLiteUser(row interface{ Scan(...any) error }) (*User, error) {
+	var user User
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash,
+		database.ScanTime{T: &user.CreatedAt}, database.ScanTime{T: &user.UpdatedAt}, &user.Role, &user.Active)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// getOne runs a query for a single user, reporting "user not found" when
+// there is none.
+func (r *SQLiteUserRepository) getOne(ctx context.Context, what, where string, arg any) (*User, error) {
+	user, err := scanSQLiteUser(r.DB.QueryRowContext(ctx, selectSQLiteUserQuery+" WHERE "+where, arg))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user by %s: %w", what, err)
+	}
+	return user, nil
+}
+
+func (r *SQLiteUserRepository) GetAll(ctx context.Context) ([]User, error) {
+	rows, err := r.DB.QueryContext(ctx, selectSQLiteUserQuery+" ORDER BY created_at DESC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		user, err := scanSQLiteUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user row: %w", err)
+		}
+		user.PasswordHash = ""
+		users = append(users, *user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return users, nil
+}
+
+func (r *SQLiteUserRepository) GetByID(ctx context.Context, id string) (*User, error) {
+	return r.getOne(ctx, "id", "id = ?", id)
+}
+
+func (r *SQLiteUserRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
+	return r.getOne(ctx, "email", "email = ?", email)
+}
+
+func (r *SQLiteUserRepository) GetByUsername(ctx context.Context, username string) (*User, error) {
+	return r.getOne(ctx, "username", "username = ?", username)
+}
+
+// GetProfile loads the user's profile and links. Users without a profile row
+// get an empty profile.
+func (r *SQLiteUserRepository) GetProfile(ctx context.Context, id string) (*Profile, error) {
+	var profile Profile
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT COALESCE(first_name, ''), COALESCE(last_name, ''), COALESCE(bio, ''), COALESCE(avatar_url, '')
+		FROM profiles WHERE user_id = ?`, id).
+		Scan(&profile.FirstName, &profile.LastName, &profile.Bio, &profile.AvatarURL)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get profile: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT url, COALESCE(title, ''), COALESCE(icon, ''), active
+		FROM links WHERE user_id = ? ORDER BY id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query links: %w", err)
+	}
+	defer rows.Close()
+
+	profile.Links = []Link{}
+	for rows.Next() {
+		var link Link
+		if err := rows.Scan(&link.URL, &link.Title, &link.Icon, &link.Active); err != nil {
+			return nil, fmt.Errorf("failed to scan link row: %w", err)
+		}
+		profile.Links = append(profile.Links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return &profile, nil
+}
+
+func (r *SQLiteUserRepository) GetSettings(ctx context.Context, id string) (*Settings, error) {
+	var settings Settings
+	err := r.DB.QueryRowContext(ctx, `SELECT timezone, daily_template, weekly_digest FROM active_users WHERE id = ?`, id).
+		Scan(&settings.Timezone, &settings.DailyTemplate, &settings.WeeklyDigest)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to get settings: %w", err)
+	}
+	return &settings, nil
+}
+
+func (r *SQLiteUserRepository) UpdateSettings(ctx context.Context, id string, settings *Settings) error {
+	query := `
+		UPDATE users
+		SET timezone = ?, daily_template = ?, weekly_digest = ?, updated_at = ?
+		WHERE id = ? AND deleted_at IS NULL`
+	return r.execOnLiveUser(ctx, "update settings", query, settings.Timezone, settings.DailyTemplate, settings.WeeklyDigest,
+		database.Timestamp(time.Now()), id)
+}
+
+func (r *SQLiteUserRepository) SetCalendarToken(ctx context.Context, id string, tokenHash string) error {
+	query := `UPDATE users SET calendar_token_hash = NULLIF(?, '') WHERE id = ? AND deleted_at IS NULL`
+	return r.execOnLiveUser(ctx, "set calendar token", query, tokenHash, id)
+}
+
+func (r *SQLiteUserRepository) GetByCalendarToken(ctx context.Context, tokenHash string) (*User, error) {
+	return r.getOne(ctx, "calendar token", "calendar_token_hash = ?", tokenHash)
+}
+
+func (r *SQLiteUserRepository) SetInboxToken(ctx context.Context, id string, tokenHash string) error {
+	query := `UPDATE users SET inbox_token_hash = NULLIF(?, '') WHERE id = ? AND deleted_at IS NULL`
+	return r.execOnLiveUser(ctx, "set inbox token", query, tokenHash, id)
+}
+
+func (r *SQLiteUserRepository) GetByInboxToken(ctx context.Context, tokenHash string) (*User, error) {
+	return r.getOne(ctx, "inbox token", "inbox_token_hash = ?", tokenHash)
+}
+
+// ClaimDigest compares weeks as text, which orders YYYY-MM-DD dates.
+func (r *SQLiteUserRepository) ClaimDigest(ctx context.Context, id string, week string) (bool, error) {
+	query := `
+		UPDATE users SET digest_week = ?
+		WHERE id = ? AND deleted_at IS NULL AND (digest_week IS NULL OR digest_week < ?)`
+	result, err := r.DB.ExecContext(ctx, query, week, id, week)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim digest: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim digest: %w", err)
+	}
+	return n == 1, nil
+}
+
+func (r *SQLiteUserRepository) ReleaseDigest(ctx context.Context, id string, week string) error {
+	_, err := r.DB.ExecContext(ctx, `UPDATE users SET digest_week = NULL WHERE id = ? AND digest_week = ?`, id, week)
+	if err != nil {
+		return fmt.Errorf("failed to release digest: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteUserRepository) Create(ctx context.Context, user *User, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	id := uuid.NewString()
+	now := time.Now().UTC()
+	query := `INSERT INTO users (id, username, email, password_hash, role, active, created_at, updated_at)
+              VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = r.DB.ExecContext(ctx, query, id, user.Username, user.Email, string(hash), user.Role, user.Active,
+		database.Timestamp(now), database.Timestamp(now))
+	if err != nil {
+		if database.IsSQLiteConstraint(err, "UNIQUE constraint failed") {
+			return fmt.Errorf("user already exists: %w", err)
+		}
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	user.ID = id
+	user.PasswordHash = string(hash)
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	return nil
+}
+
+func (r *SQLiteUserRepository) Update(ctx context.Context, user *User) error {
+	query := `
+		UPDATE users
+		SET username = ?, email = ?, role = ?, active = ?, updated_at = ?
+		WHERE id = ? AND deleted_at IS NULL`
+	now := time.Now().UTC()
+	if err := r.execOnLiveUser(ctx, "update user", query, user.Username, user.Email, user.Role, user.Active, database.Timestamp(now), user.ID); err != nil {
+		if database.IsSQLiteConstraint(err, "UNIQUE constraint failed") {
+			return fmt.Errorf("user already exists: %w", err)
+		}
+		return err
+	}
+	user.UpdatedAt = now
+	return nil
+}
+
+func (r *SQLiteUserRepository) Delete(ctx context.Context, id string) error {
+	now := database.Timestamp(time.Now())
+	query := `
+		UPDATE users
+		SET deleted_at = ?, updated_at = ?
+		WHERE id = ? AND deleted_at IS NULL`
+	return r.execOnLiveUser(ctx, "soft delete user", query, now, now, id)
+}
+
+// execOnLiveUser runs a statement that must change one user who has not been
+// deleted.
+func (r *SQLiteUserRepository) execOnLiveUser(ctx context.Context, what, query string, args ...any) error {
+	result, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to %s: %w", what, err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return fmt.Errorf("user not found or already deleted")
+	}
+	return nil
+}
@@ -19,7 +19,8 @@ type User struct {
 	Role         Role       `json:"role"`
 	Active       bool       `json:"active"`
 
-	Profile Profile
+	Profile  Profile
+	Settings Settings `json:"settings"`
 }
 
 func (u *User) VerifyPassword(password string) bool {
@@ -35,6 +36,25 @@ func (u *User) VerifyPassword(password string) bool {
 	return true
 }
 
+// Settings are a user's preferences.
+type Settings struct {
+	Timezone      string `json:"timezone"`       // IANA name such as "Europe/Madrid"
+	DailyTemplate string `json:"daily_template"` // text/template for new daily notes; empty uses the default
+	WeeklyDigest  bool   `json:"weekly_digest"`  // email a summary of the week every Monday
+}
+
+// Location returns the user's time zone, UTC when it is unset or unknown.
+func (s *Settings) Location() *time.Location {
+	if s.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
 type Profile struct {
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
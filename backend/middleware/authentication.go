@@ -24,10 +24,28 @@ var publicPaths = map[string]bool{
 	"/hello":            true,
 }
 
+// Everything under these prefixes is public too
+var publicPrefixes = []string{
+	"/api/public/",
+	"/@", // /@username/slug
+}
+
+func isPublicPath(path string) bool {
+	if publicPaths[path] {
+		return true
+	}
+	for _, prefix := range publicPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 func Authentication(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Skip auth if the route is public
-		if publicPaths[r.URL.Path] {
+		if isPublicPath(r.URL.Path) {
 			next.ServeHTTP(w, r)
 			return
 		}
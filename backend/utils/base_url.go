@@ -0,0 +1,21 @@
+package utils
+
+import (
+	"net/http"
+	"os"
+	"strings"
+)
+
+// BaseURL is where absolute links handed out by the API point.
+// GRIMOIRE_BASE_URL wins over what the request says, since proxies may
+// rewrite the host.
+func BaseURL(r *http.Request) string {
+	if base := os.Getenv("GRIMOIRE_BASE_URL"); base != "" {
+		return strings.TrimRight(base, "/")
+	}
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
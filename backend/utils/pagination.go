@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Pagination reads the `page` and `per_page` query parameters. Missing or
+// invalid values fall back to page 1 and defaultPerPage; per_page is capped at maxPerPage.
+func Pagination(r *http.Request, defaultPerPage, maxPerPage int) (page, perPage int) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	perPage, err = strconv.Atoi(r.URL.Query().Get("per_page"))
+	if err != nil || perPage < 1 {
+		perPage = defaultPerPage
+	}
+	if perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+	return page, perPage
+}
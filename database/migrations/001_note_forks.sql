@@ -0,0 +1,27 @@
+-- Track where a forked note was copied from.
+
+ALTER TABLE public.notes
+    ADD COLUMN forked_from_note_id uuid,
+    ADD COLUMN forked_from_user_id uuid;
+
+ALTER TABLE ONLY public.notes
+    ADD CONSTRAINT notes_forked_from_note_id_fkey FOREIGN KEY (forked_from_note_id) REFERENCES public.notes(id) ON DELETE SET NULL;
+
+ALTER TABLE ONLY public.notes
+    ADD CONSTRAINT notes_forked_from_user_id_fkey FOREIGN KEY (forked_from_user_id) REFERENCES public.users(id) ON DELETE SET NULL;
+
+CREATE INDEX notes_forked_from_note_id_idx ON public.notes USING btree (forked_from_note_id);
+
+CREATE OR REPLACE VIEW public.active_notes AS
+ SELECT notes.id,
+    notes.title,
+    notes.content,
+    notes.created_at,
+    notes.updated_at,
+    notes.user_id,
+    notes.is_public,
+    notes.deleted_at,
+    notes.forked_from_note_id,
+    notes.forked_from_user_id
+   FROM public.notes
+  WHERE (notes.deleted_at IS NULL);
@@ -0,0 +1,36 @@
+-- Threaded comments on notes. thread_id is the id of the top-level comment.
+
+CREATE TABLE public.comments (
+    id uuid DEFAULT public.uuid_generate_v4() NOT NULL,
+    note_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    parent_id uuid,
+    thread_id uuid NOT NULL,
+    body text NOT NULL,
+    anchor_quote text,
+    anchor_start integer,
+    anchor_end integer,
+    resolved boolean DEFAULT false NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL,
+    deleted_at timestamp with time zone,
+    CONSTRAINT comments_anchor_check CHECK (((anchor_start IS NULL) OR ((anchor_start >= 0) AND (anchor_end > anchor_start))))
+);
+
+ALTER TABLE public.comments OWNER TO grimoire_user;
+
+ALTER TABLE ONLY public.comments
+    ADD CONSTRAINT comments_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY public.comments
+    ADD CONSTRAINT comments_note_id_fkey FOREIGN KEY (note_id) REFERENCES public.notes(id) ON DELETE CASCADE;
+
+ALTER TABLE ONLY public.comments
+    ADD CONSTRAINT comments_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+ALTER TABLE ONLY public.comments
+    ADD CONSTRAINT comments_parent_id_fkey FOREIGN KEY (parent_id) REFERENCES public.comments(id) ON DELETE CASCADE;
+
+CREATE INDEX comments_note_id_idx ON public.comments USING btree (note_id, created_at);
+
+CREATE INDEX comments_thread_id_idx ON public.comments USING btree (thread_id);
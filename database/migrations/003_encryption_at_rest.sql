@@ -0,0 +1,40 @@
+-- Per-user data keys for encryption at rest, wrapped by the master key.
+
+CREATE TABLE public.user_data_keys (
+    user_id uuid NOT NULL,
+    wrapped_key bytea NOT NULL,
+    master_key_id character varying(16) NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    rotated_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+ALTER TABLE public.user_data_keys OWNER TO grimoire_user;
+
+ALTER TABLE ONLY public.user_data_keys
+    ADD CONSTRAINT user_data_keys_pkey PRIMARY KEY (user_id);
+
+ALTER TABLE ONLY public.user_data_keys
+    ADD CONSTRAINT user_data_keys_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+-- Encrypted titles no longer fit in 200 characters. The view depends on the
+-- column, so it has to be recreated around the type change.
+
+DROP VIEW public.active_notes;
+
+ALTER TABLE public.notes ALTER COLUMN title TYPE text;
+
+CREATE VIEW public.active_notes AS
+ SELECT notes.id,
+    notes.title,
+    notes.content,
+    notes.created_at,
+    notes.updated_at,
+    notes.user_id,
+    notes.is_public,
+    notes.deleted_at,
+    notes.forked_from_note_id,
+    notes.forked_from_user_id
+   FROM public.notes
+  WHERE (notes.deleted_at IS NULL);
+
+ALTER TABLE public.active_notes OWNER TO grimoire_user;
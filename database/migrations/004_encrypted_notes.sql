@@ -0,0 +1,21 @@
+-- End-to-end encrypted notes keep their ciphertext and key-derivation
+-- metadata in envelope; content stays empty for them.
+
+ALTER TABLE public.notes
+    ADD COLUMN envelope jsonb,
+    ADD CONSTRAINT notes_envelope_content_check CHECK (((envelope IS NULL) OR (content = ''::text)));
+
+CREATE OR REPLACE VIEW public.active_notes AS
+ SELECT notes.id,
+    notes.title,
+    notes.content,
+    notes.created_at,
+    notes.updated_at,
+    notes.user_id,
+    notes.is_public,
+    notes.deleted_at,
+    notes.forked_from_note_id,
+    notes.forked_from_user_id,
+    notes.envelope
+   FROM public.notes
+  WHERE (notes.deleted_at IS NULL);
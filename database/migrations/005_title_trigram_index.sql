@@ -0,0 +1,5 @@
+-- Fuzzy title lookup for the quick switcher.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm WITH SCHEMA public;
+
+CREATE INDEX notes_title_trgm_idx ON public.notes USING gin (title public.gin_trgm_ops) WHERE (deleted_at IS NULL);
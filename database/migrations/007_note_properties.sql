@@ -0,0 +1,40 @@
+-- Typed custom properties. Definitions are per user; the values live on the
+-- note as a flat JSON object keyed by property name.
+
+CREATE TABLE public.property_definitions (
+    user_id uuid NOT NULL,
+    name character varying(50) NOT NULL,
+    type character varying(10) NOT NULL,
+    options text[] DEFAULT '{}'::text[] NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT property_definitions_type_check CHECK (((type)::text = ANY ((ARRAY['text'::character varying, 'select'::character varying, 'number'::character varying, 'date'::character varying, 'url'::character varying])::text[])))
+);
+
+ALTER TABLE public.property_definitions OWNER TO grimoire_user;
+
+ALTER TABLE ONLY public.property_definitions
+    ADD CONSTRAINT property_definitions_pkey PRIMARY KEY (user_id, name);
+
+ALTER TABLE ONLY public.property_definitions
+    ADD CONSTRAINT property_definitions_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+ALTER TABLE public.notes
+    ADD COLUMN properties jsonb DEFAULT '{}'::jsonb NOT NULL;
+
+CREATE INDEX notes_properties_idx ON public.notes USING gin (properties jsonb_path_ops);
+
+CREATE OR REPLACE VIEW public.active_notes AS
+ SELECT notes.id,
+    notes.title,
+    notes.content,
+    notes.created_at,
+    notes.updated_at,
+    notes.user_id,
+    notes.is_public,
+    notes.deleted_at,
+    notes.forked_from_note_id,
+    notes.forked_from_user_id,
+    notes.envelope,
+    notes.properties
+   FROM public.notes
+  WHERE (notes.deleted_at IS NULL);
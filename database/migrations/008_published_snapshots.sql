@@ -0,0 +1,34 @@
+-- Public readers see the published snapshot; title and content stay the
+-- owner's working draft. published_at is set exactly when a snapshot exists.
+
+ALTER TABLE public.notes
+    ADD COLUMN published_title text,
+    ADD COLUMN published_content text,
+    ADD COLUMN published_at timestamp with time zone,
+    ADD CONSTRAINT notes_published_snapshot_check CHECK (((published_at IS NULL) = (published_content IS NULL)) AND ((published_at IS NULL) = (published_title IS NULL)));
+
+-- Notes already public are published as they stand
+UPDATE public.notes
+    SET published_title = title, published_content = content, published_at = updated_at
+    WHERE is_public AND envelope IS NULL;
+
+CREATE INDEX notes_published_at_idx ON public.notes USING btree (published_at DESC) WHERE (published_at IS NOT NULL);
+
+CREATE OR REPLACE VIEW public.active_notes AS
+ SELECT notes.id,
+    notes.title,
+    notes.content,
+    notes.created_at,
+    notes.updated_at,
+    notes.user_id,
+    notes.is_public,
+    notes.deleted_at,
+    notes.forked_from_note_id,
+    notes.forked_from_user_id,
+    notes.envelope,
+    notes.properties,
+    notes.published_title,
+    notes.published_content,
+    notes.published_at
+   FROM public.notes
+  WHERE (notes.deleted_at IS NULL);
@@ -0,0 +1,37 @@
+-- Scheduled publishing and expiry. The scheduler leases due notes through
+-- schedule_lease_until so only one instance acts on each, and a crashed
+-- instance's lease simply runs out.
+
+ALTER TABLE public.notes
+    ADD COLUMN publish_at timestamp with time zone,
+    ADD COLUMN expires_at timestamp with time zone,
+    ADD COLUMN expire_action character varying(10),
+    ADD COLUMN schedule_lease_until timestamp with time zone,
+    ADD CONSTRAINT notes_expire_action_check CHECK (((expire_action)::text = ANY ((ARRAY['delete'::character varying, 'unpublish'::character varying])::text[]))),
+    ADD CONSTRAINT notes_expire_action_required CHECK (((expires_at IS NULL) OR (expire_action IS NOT NULL)));
+
+CREATE INDEX notes_publish_at_idx ON public.notes USING btree (publish_at) WHERE ((publish_at IS NOT NULL) AND (deleted_at IS NULL));
+
+CREATE INDEX notes_expires_at_idx ON public.notes USING btree (expires_at) WHERE ((expires_at IS NOT NULL) AND (deleted_at IS NULL));
+
+CREATE OR REPLACE VIEW public.active_notes AS
+ SELECT notes.id,
+    notes.title,
+    notes.content,
+    notes.created_at,
+    notes.updated_at,
+    notes.user_id,
+    notes.is_public,
+    notes.deleted_at,
+    notes.forked_from_note_id,
+    notes.forked_from_user_id,
+    notes.envelope,
+    notes.properties,
+    notes.published_title,
+    notes.published_content,
+    notes.published_at,
+    notes.publish_at,
+    notes.expires_at,
+    notes.expire_action
+   FROM public.notes
+  WHERE (notes.deleted_at IS NULL);
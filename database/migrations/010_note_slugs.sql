@@ -0,0 +1,22 @@
+-- Per-user slugs of published notes. A note keeps all the slugs it has had;
+-- the most recently assigned one is current and the others redirect to it.
+
+CREATE TABLE public.note_slugs (
+    user_id uuid NOT NULL,
+    slug character varying(100) NOT NULL,
+    note_id uuid NOT NULL,
+    assigned_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+ALTER TABLE public.note_slugs OWNER TO grimoire_user;
+
+ALTER TABLE ONLY public.note_slugs
+    ADD CONSTRAINT note_slugs_pkey PRIMARY KEY (user_id, slug);
+
+ALTER TABLE ONLY public.note_slugs
+    ADD CONSTRAINT note_slugs_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+ALTER TABLE ONLY public.note_slugs
+    ADD CONSTRAINT note_slugs_note_id_fkey FOREIGN KEY (note_id) REFERENCES public.notes(id) ON DELETE CASCADE;
+
+CREATE INDEX note_slugs_note_id_idx ON public.note_slugs USING btree (note_id, assigned_at DESC);
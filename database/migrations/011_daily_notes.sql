@@ -0,0 +1,50 @@
+-- Daily journal notes. Each user has at most one live daily note per date;
+-- dates are calendar days in the user's own time zone.
+
+ALTER TABLE public.users
+    ADD COLUMN timezone character varying(64) DEFAULT 'UTC'::character varying NOT NULL,
+    ADD COLUMN daily_template text DEFAULT ''::text NOT NULL;
+
+CREATE OR REPLACE VIEW public.active_users AS
+ SELECT users.id,
+    users.username,
+    users.email,
+    users.created_at,
+    users.updated_at,
+    users.role,
+    users.active,
+    users.password_hash,
+    users.deleted_at,
+    users.last_login,
+    users.timezone,
+    users.daily_template
+   FROM public.users
+  WHERE (users.deleted_at IS NULL);
+
+ALTER TABLE public.notes
+    ADD COLUMN daily_date date;
+
+CREATE UNIQUE INDEX notes_user_id_daily_date_idx ON public.notes USING btree (user_id, daily_date) WHERE ((daily_date IS NOT NULL) AND (deleted_at IS NULL));
+
+CREATE OR REPLACE VIEW public.active_notes AS
+ SELECT notes.id,
+    notes.title,
+    notes.content,
+    notes.created_at,
+    notes.updated_at,
+    notes.user_id,
+    notes.is_public,
+    notes.deleted_at,
+    notes.forked_from_note_id,
+    notes.forked_from_user_id,
+    notes.envelope,
+    notes.properties,
+    notes.published_title,
+    notes.published_content,
+    notes.published_at,
+    notes.publish_at,
+    notes.expires_at,
+    notes.expire_action,
+    notes.daily_date
+   FROM public.notes
+  WHERE (notes.deleted_at IS NULL);
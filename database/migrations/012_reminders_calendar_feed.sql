@@ -0,0 +1,34 @@
+-- Note reminders and the per-user iCalendar subscription. Only a SHA-256
+-- hash of the feed token is stored; regenerating it revokes the old URL.
+
+ALTER TABLE public.notes
+    ADD COLUMN remind_at timestamp with time zone;
+
+ALTER TABLE public.users
+    ADD COLUMN calendar_token_hash character(64);
+
+CREATE UNIQUE INDEX users_calendar_token_hash_idx ON public.users USING btree (calendar_token_hash) WHERE (calendar_token_hash IS NOT NULL);
+
+CREATE OR REPLACE VIEW public.active_notes AS
+ SELECT notes.id,
+    notes.title,
+    notes.content,
+    notes.created_at,
+    notes.updated_at,
+    notes.user_id,
+    notes.is_public,
+    notes.deleted_at,
+    notes.forked_from_note_id,
+    notes.forked_from_user_id,
+    notes.envelope,
+    notes.properties,
+    notes.published_title,
+    notes.published_content,
+    notes.published_at,
+    notes.publish_at,
+    notes.expires_at,
+    notes.expire_action,
+    notes.daily_date,
+    notes.remind_at
+   FROM public.notes
+  WHERE (notes.deleted_at IS NULL);
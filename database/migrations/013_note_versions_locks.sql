@@ -0,0 +1,51 @@
+-- Note versions for optimistic concurrency, and write locks taken through
+-- WebDAV. A lock remembers the version its holder last saw; writes under
+-- it only succeed while the note is still at that version.
+
+ALTER TABLE public.notes
+    ADD COLUMN version integer DEFAULT 1 NOT NULL;
+
+CREATE TABLE public.note_locks (
+    note_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    token character varying(100) NOT NULL,
+    owner text DEFAULT ''::text NOT NULL,
+    version integer NOT NULL,
+    expires_at timestamp with time zone NOT NULL
+);
+
+ALTER TABLE public.note_locks OWNER TO grimoire_user;
+
+ALTER TABLE ONLY public.note_locks
+    ADD CONSTRAINT note_locks_pkey PRIMARY KEY (note_id);
+
+ALTER TABLE ONLY public.note_locks
+    ADD CONSTRAINT note_locks_note_id_fkey FOREIGN KEY (note_id) REFERENCES public.notes(id) ON DELETE CASCADE;
+
+ALTER TABLE ONLY public.note_locks
+    ADD CONSTRAINT note_locks_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+CREATE OR REPLACE VIEW public.active_notes AS
+ SELECT notes.id,
+    notes.title,
+    notes.content,
+    notes.created_at,
+    notes.updated_at,
+    notes.user_id,
+    notes.is_public,
+    notes.deleted_at,
+    notes.forked_from_note_id,
+    notes.forked_from_user_id,
+    notes.envelope,
+    notes.properties,
+    notes.published_title,
+    notes.published_content,
+    notes.published_at,
+    notes.publish_at,
+    notes.expires_at,
+    notes.expire_action,
+    notes.daily_date,
+    notes.remind_at,
+    notes.version
+   FROM public.notes
+  WHERE (notes.deleted_at IS NULL);
@@ -0,0 +1,32 @@
+-- Files attached to notes, and the per-user inbox address emails are
+-- forwarded to. Only a SHA-256 hash of the token in the address is stored;
+-- regenerating it retires the old address.
+
+ALTER TABLE public.users
+    ADD COLUMN inbox_token_hash character(64);
+
+CREATE UNIQUE INDEX users_inbox_token_hash_idx ON public.users USING btree (inbox_token_hash) WHERE (inbox_token_hash IS NOT NULL);
+
+CREATE TABLE public.attachments (
+    id uuid DEFAULT public.uuid_generate_v4() NOT NULL,
+    note_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    filename text NOT NULL,
+    content_type character varying(255) NOT NULL,
+    size integer NOT NULL,
+    data bytea NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+ALTER TABLE public.attachments OWNER TO grimoire_user;
+
+ALTER TABLE ONLY public.attachments
+    ADD CONSTRAINT attachments_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY public.attachments
+    ADD CONSTRAINT attachments_note_id_fkey FOREIGN KEY (note_id) REFERENCES public.notes(id) ON DELETE CASCADE;
+
+ALTER TABLE ONLY public.attachments
+    ADD CONSTRAINT attachments_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+CREATE INDEX attachments_note_id_idx ON public.attachments USING btree (note_id, created_at);
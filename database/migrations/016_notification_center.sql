@@ -0,0 +1,28 @@
+-- Who caused a notification and which comment it is about, and the types of
+-- notification each user has turned on or off. Types without a row are on.
+
+ALTER TABLE public.notifications
+    ADD COLUMN actor_id uuid,
+    ADD COLUMN comment_id uuid;
+
+ALTER TABLE ONLY public.notifications
+    ADD CONSTRAINT notifications_actor_id_fkey FOREIGN KEY (actor_id) REFERENCES public.users(id) ON DELETE SET NULL;
+
+ALTER TABLE ONLY public.notifications
+    ADD CONSTRAINT notifications_comment_id_fkey FOREIGN KEY (comment_id) REFERENCES public.comments(id) ON DELETE CASCADE;
+
+CREATE INDEX notifications_unread_idx ON public.notifications USING btree (user_id) WHERE (read_at IS NULL);
+
+CREATE TABLE public.notification_preferences (
+    user_id uuid NOT NULL,
+    type character varying(32) NOT NULL,
+    enabled boolean NOT NULL
+);
+
+ALTER TABLE public.notification_preferences OWNER TO grimoire_user;
+
+ALTER TABLE ONLY public.notification_preferences
+    ADD CONSTRAINT notification_preferences_pkey PRIMARY KEY (user_id, type);
+
+ALTER TABLE ONLY public.notification_preferences
+    ADD CONSTRAINT notification_preferences_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
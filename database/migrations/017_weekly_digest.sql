@@ -0,0 +1,23 @@
+-- Opt-in weekly digest emails. digest_week is the Monday starting the last
+-- week whose digest was sent, so a restart never sends one twice.
+
+ALTER TABLE public.users
+    ADD COLUMN weekly_digest boolean DEFAULT false NOT NULL,
+    ADD COLUMN digest_week date;
+
+CREATE OR REPLACE VIEW public.active_users AS
+ SELECT users.id,
+    users.username,
+    users.email,
+    users.created_at,
+    users.updated_at,
+    users.role,
+    users.active,
+    users.password_hash,
+    users.deleted_at,
+    users.last_login,
+    users.timezone,
+    users.daily_template,
+    users.weekly_digest
+   FROM public.users
+  WHERE (users.deleted_at IS NULL);
@@ -0,0 +1,37 @@
+-- Published snapshots keep the tags the note had when it was published, so
+-- public pages and feeds no longer see the draft's.
+
+ALTER TABLE public.notes
+    ADD COLUMN published_tags jsonb;
+
+UPDATE public.notes n
+    SET published_tags = (
+        SELECT COALESCE(jsonb_agg(jsonb_build_object('id', t.id, 'name', t.name) ORDER BY t.name), '[]')
+        FROM public.note_tags nt JOIN public.tags t ON nt.tag_id = t.id WHERE nt.note_id = n.id)
+    WHERE published_at IS NOT NULL;
+
+CREATE OR REPLACE VIEW public.active_notes AS
+ SELECT notes.id,
+    notes.title,
+    notes.content,
+    notes.created_at,
+    notes.updated_at,
+    notes.user_id,
+    notes.is_public,
+    notes.deleted_at,
+    notes.forked_from_note_id,
+    notes.forked_from_user_id,
+    notes.envelope,
+    notes.properties,
+    notes.published_title,
+    notes.published_content,
+    notes.published_at,
+    notes.publish_at,
+    notes.expires_at,
+    notes.expire_action,
+    notes.daily_date,
+    notes.remind_at,
+    notes.version,
+    notes.published_tags
+   FROM public.notes
+  WHERE (notes.deleted_at IS NULL);
@@ -0,0 +1,12 @@
+-- Due reminders notify the owner once per remind_at. reminded_at is the
+-- remind_at last delivered, so moving the reminder makes it due again.
+-- Reminders already past are not delivered late.
+
+ALTER TABLE public.notes
+    ADD COLUMN reminded_at timestamp with time zone;
+
+UPDATE public.notes
+    SET reminded_at = remind_at
+    WHERE remind_at <= now();
+
+CREATE INDEX notes_remind_at_idx ON public.notes USING btree (remind_at) WHERE ((remind_at IS NOT NULL) AND (deleted_at IS NULL));